@@ -0,0 +1,176 @@
+package golog
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultAlertWindow = 1 * time.Minute
+
+// AlertRule triggers an in-process reaction, like reconnecting or notifying
+// the UI, when matching events are logged. Criteria that are left empty match
+// everything.
+type AlertRule struct {
+	// Name identifies the rule in Alerts.
+	Name string
+
+	// Component is the logger prefix to match, without the trailing ": ".
+	Component string
+
+	// MinSeverity is the lowest severity to match, for example "ERROR" (case
+	// insensitive).
+	MinSeverity string
+
+	// Message, if set, must match the message.
+	Message *regexp.Regexp
+
+	// Match, if set, is an additional predicate on the event, for example to
+	// match on a context field.
+	Match func(severity string, message string, values map[string]interface{}) bool
+
+	// Threshold is how many matching events have to be logged within Window
+	// for the rule to fire. Defaults to 1, firing on every matching event.
+	Threshold int
+
+	// Window is the period over which Threshold applies. Defaults to 1
+	// minute.
+	Window time.Duration
+
+	// Cooldown is the minimum time between firings. Matching events during
+	// the cooldown don't count towards the next firing.
+	Cooldown time.Duration
+
+	// Action is called when the rule fires. It's called on its own goroutine
+	// so that it can't hold up logging, and may log itself.
+	Action func(alert *Alert)
+}
+
+// Alert describes the firing of an AlertRule, with the details of the event
+// that made it fire.
+type Alert struct {
+	Rule      string
+	Component string
+	Severity  string
+	Message   string
+	Context   map[string]interface{}
+	// Count is the number of matching events within the rule's Window.
+	Count int
+}
+
+// AlertOutput creates an output that evaluates the given rules against every
+// event before passing it through to out, so that applications can react to
+// specific events without parsing their own logs. It returns an error if a
+// rule's MinSeverity is unknown.
+func AlertOutput(out Output, rules ...*AlertRule) (Output, error) {
+	o := &alertOutput{out: out}
+	for _, rule := range rules {
+		r := *rule
+		if r.Threshold <= 0 {
+			r.Threshold = 1
+		}
+		if r.Window <= 0 {
+			r.Window = defaultAlertWindow
+		}
+		state := &alertRuleState{rule: r}
+		if r.MinSeverity != "" {
+			var err error
+			if state.minSeverity, err = parseLevel(r.MinSeverity); err != nil {
+				return nil, fmt.Errorf("alert rule %q: %v", r.Name, err)
+			}
+		}
+		o.rules = append(o.rules, state)
+	}
+	return o, nil
+}
+
+type alertOutput struct {
+	out   Output
+	rules []*alertRuleState
+}
+
+type alertRuleState struct {
+	rule AlertRule
+	// minSeverity is the level of rule.MinSeverity
+	minSeverity int
+	mx          sync.Mutex
+	matches     []time.Time
+	lastFired   time.Time
+}
+
+func (o *alertOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.evaluate(prefix, severity, arg, values)
+	o.out.Error(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+func (o *alertOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.evaluate(prefix, severity, arg, values)
+	o.out.Debug(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+func (o *alertOutput) evaluate(prefix string, severity string, arg interface{}, values map[string]interface{}) {
+	component := strings.TrimSuffix(prefix, ": ")
+	var message string
+	messageKnown := false
+	for _, state := range o.rules {
+		rule := &state.rule
+		if rule.Component != "" && rule.Component != component {
+			continue
+		}
+		if severityLevel(severity) < state.minSeverity {
+			continue
+		}
+		if !messageKnown {
+			message, messageKnown = argToString(arg), true
+		}
+		if rule.Message != nil && !rule.Message.MatchString(message) {
+			continue
+		}
+		if rule.Match != nil && !rule.Match(severity, message, values) {
+			continue
+		}
+		if count, fire := state.record(time.Now()); fire && rule.Action != nil {
+			alert := &Alert{
+				Rule:      rule.Name,
+				Component: component,
+				Severity:  severity,
+				Message:   message,
+				Context:   copyValues(values),
+				Count:     count,
+			}
+			go rule.Action(alert)
+		}
+	}
+}
+
+// record records a matching event at now and reports whether the rule fires.
+func (s *alertRuleState) record(now time.Time) (count int, fire bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if !s.lastFired.IsZero() && now.Sub(s.lastFired) < s.rule.Cooldown {
+		return 0, false
+	}
+	cutoff := now.Add(-s.rule.Window)
+	i := 0
+	for i < len(s.matches) && !s.matches[i].After(cutoff) {
+		i++
+	}
+	s.matches = append(s.matches[i:], now)
+	count = len(s.matches)
+	if count < s.rule.Threshold {
+		return count, false
+	}
+	s.matches = s.matches[:0]
+	s.lastFired = now
+	return count, true
+}
+
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		copied[key] = value
+	}
+	return copied
+}
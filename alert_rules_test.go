@@ -0,0 +1,77 @@
+package golog
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	alerts := make(chan *Alert, 10)
+	action := func(alert *Alert) {
+		alerts <- alert
+	}
+	out, err := AlertOutput(TextOutput(buf, buf),
+		&AlertRule{
+			Name:        "dial failures",
+			Component:   "proxy",
+			MinSeverity: "error",
+			Message:     regexp.MustCompile(`^dial failed`),
+			Threshold:   2,
+			Cooldown:    time.Hour,
+			Action:      action,
+		},
+		&AlertRule{
+			Name: "blocked",
+			Match: func(severity string, message string, values map[string]interface{}) bool {
+				return values["blocked"] == true
+			},
+			Action: action,
+		},
+	)
+	require.NoError(t, err)
+	reset := SetOutput(out)
+	defer reset()
+
+	proxy := LoggerFor("proxy")
+	proxy.Debug("dial failed at debug")
+	proxy.Error("dial failed: timeout")
+	LoggerFor("other").Error("dial failed: elsewhere")
+	proxy.Error("unrelated")
+	proxy.Error("dial failed: refused")
+	proxy.Error("dial failed: during cooldown")
+	proxy.Error("dial failed: during cooldown")
+	LoggerFor("other").Debugw("censored", Field{Key: "blocked", Value: true})
+
+	received := make(map[string]*Alert)
+	for i := 0; i < 2; i++ {
+		select {
+		case alert := <-alerts:
+			received[alert.Rule] = alert
+		case <-time.After(5 * time.Second):
+			t.Fatal("alert not received")
+		}
+	}
+	select {
+	case alert := <-alerts:
+		t.Fatalf("unexpected alert %+v", alert)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.Contains(t, received, "dial failures")
+	assert.Equal(t, &Alert{Rule: "dial failures", Component: "proxy", Severity: "ERROR", Message: "dial failed: refused", Context: map[string]interface{}{}, Count: 2}, received["dial failures"])
+	require.Contains(t, received, "blocked")
+	assert.Equal(t, "censored", received["blocked"].Message)
+	assert.Equal(t, true, received["blocked"].Context["blocked"])
+	assert.Contains(t, buf.String(), "dial failed: during cooldown", "events should still be logged")
+}
+
+func TestAlertOutputUnknownSeverity(t *testing.T) {
+	_, err := AlertOutput(TextOutput(&bytes.Buffer{}, &bytes.Buffer{}), &AlertRule{Name: "errors", MinSeverity: "err"})
+	assert.EqualError(t, err, `alert rule "errors": unknown level "err"`)
+}
@@ -0,0 +1,241 @@
+package golog
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DropPolicy is what happens to events logged while the buffer of
+// asynchronous logging is full, see EnableAsync.
+type DropPolicy int
+
+const (
+	// DropNone waits for room in the buffer, so that nothing is lost but
+	// logging stalls once the output falls behind by a full buffer.
+	DropNone DropPolicy = iota
+
+	// DropNewest discards the event being logged.
+	DropNewest
+
+	// DropOldest discards the oldest buffered event to make room.
+	DropOldest
+)
+
+// asyncLog is the current asynchronous logger, if any, guarded by outputMx.
+var asyncLog *asyncLogger
+
+// EnableAsync makes logging asynchronous: events are queued in a buffer of
+// bufferSize events and formatted and written to the current output by a
+// background goroutine, so that logging from hot paths doesn't stall on slow
+// destinations like a stderr pipe that nobody reads. The caller, time, stack
+// and context of each event are still captured when it's logged. Events
+// logged while the buffer is full are handled according to dropPolicy, with
+// the number of discarded ones reported as an error on logging and added to
+// the dropped_count field of the next event that's written.
+//
+// Reporting to ErrorReporters stays synchronous. FATAL errors flush the
+// buffer before calling the OnFatal handler, and Shutdown closes it. Calling
+// EnableAsync again closes the previous buffer first.
+func EnableAsync(bufferSize int, dropPolicy DropPolicy) {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	a := &asyncLogger{
+		events: make(chan *asyncEvent, bufferSize),
+		policy: dropPolicy,
+		done:   make(chan struct{}),
+	}
+	a.processedCond = sync.NewCond(&a.processedMx)
+	go a.run()
+
+	Close()
+	outputMx.Lock()
+	asyncLog = a
+	outputMx.Unlock()
+}
+
+// Flush waits until everything logged asynchronously so far has been written
+// to the output (or discarded), see EnableAsync. It doesn't flush the output
+// itself, like a BufferedOutput. It returns right away if logging is
+// synchronous.
+func Flush() {
+	outputMx.RLock()
+	a := asyncLog
+	outputMx.RUnlock()
+	if a != nil {
+		a.flush()
+	}
+}
+
+// Close flushes asynchronous logging and makes logging synchronous again, see
+// EnableAsync. It does nothing if logging is synchronous.
+func Close() {
+	outputMx.Lock()
+	a := asyncLog
+	asyncLog = nil
+	outputMx.Unlock()
+	if a != nil {
+		a.close()
+	}
+}
+
+type asyncEvent struct {
+	write    outputFn
+	prefix   string
+	severity string
+	arg      interface{}
+	values   map[string]interface{}
+}
+
+type asyncLogger struct {
+	// The counters are accessed atomically and come first to be 64-bit
+	// aligned on 32-bit platforms.
+
+	// queued counts events sent to the buffer, processed those written or
+	// discarded from it, for flush
+	queued    uint64
+	processed uint64
+	// dropped counts discarded events not yet reported
+	dropped uint64
+
+	events chan *asyncEvent
+	policy DropPolicy
+	// closeMx is held for reading while sending to events and for writing to
+	// close it
+	closeMx       sync.RWMutex
+	closed        bool
+	done          chan struct{}
+	processedMx   sync.Mutex
+	processedCond *sync.Cond
+}
+
+// outputFn returns a function that queues events for write.
+func (a *asyncLogger) outputFn(write outputFn) outputFn {
+	return func(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+		a.enqueue(write, prefix, skipFrames, printStack, severity, arg, values)
+	}
+}
+
+func (a *asyncLogger) enqueue(write outputFn, prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	a.closeMx.RLock()
+	defer a.closeMx.RUnlock()
+	if a.closed {
+		// closed after write was looked up, log synchronously
+		write(prefix, skipFrames+1, printStack, severity, arg, values)
+		return
+	}
+
+	// Capture everything that depends on the caller now, and render the
+	// message so that the background goroutine doesn't race with the
+	// caller modifying arg
+	pc := make([]uintptr, 10)
+	injected := &injectedArg{
+		fieldsArg: fieldsArg{arg: argToString(arg)},
+		caller:    callerOverride(arg),
+		ts:        eventTime(arg),
+		stack:     stackOverride(arg),
+	}
+	if injected.caller == "" {
+		injected.caller = caller(pc, skipFrames)
+	}
+	if printStack && injected.stack == "" {
+		buf := getBuffer()
+		_ = writeStack(buf, pc)
+		injected.stack = buf.String()
+		returnBuffer(buf)
+	}
+	event := &asyncEvent{
+		write:    write,
+		prefix:   prefix,
+		severity: severity,
+		arg:      injected.asArg(),
+		values:   values,
+	}
+
+	switch a.policy {
+	case DropNewest:
+		select {
+		case a.events <- event:
+			atomic.AddUint64(&a.queued, 1)
+		default:
+			atomic.AddUint64(&a.dropped, 1)
+		}
+	case DropOldest:
+		for {
+			select {
+			case a.events <- event:
+				atomic.AddUint64(&a.queued, 1)
+				return
+			default:
+			}
+			select {
+			case <-a.events:
+				atomic.AddUint64(&a.dropped, 1)
+				a.markProcessed()
+			default:
+			}
+		}
+	default:
+		a.events <- event
+		atomic.AddUint64(&a.queued, 1)
+	}
+}
+
+func (a *asyncLogger) run() {
+	defer close(a.done)
+	for event := range a.events {
+		values := event.values
+		if dropped := atomic.SwapUint64(&a.dropped, 0); dropped > 0 {
+			errorOnLogging(fmt.Errorf("asynchronous logging buffer full, discarded %d events", dropped))
+			values = withDropped(values, false, dropped)
+		}
+		event.write(event.prefix, 3, false, event.severity, event.arg, values)
+		a.markProcessed()
+	}
+}
+
+func (a *asyncLogger) markProcessed() {
+	a.processedMx.Lock()
+	a.processed++
+	a.processedCond.Broadcast()
+	a.processedMx.Unlock()
+}
+
+func (a *asyncLogger) flush() {
+	queued := atomic.LoadUint64(&a.queued)
+	a.processedMx.Lock()
+	for a.processed < queued {
+		a.processedCond.Wait()
+	}
+	a.processedMx.Unlock()
+}
+
+func (a *asyncLogger) close() {
+	a.closeMx.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.events)
+	}
+	a.closeMx.Unlock()
+	<-a.done
+	if dropped := atomic.SwapUint64(&a.dropped, 0); dropped > 0 {
+		errorOnLogging(fmt.Errorf("asynchronous logging buffer full, discarded %d events", dropped))
+	}
+}
+
+// closeAsyncWithin closes asynchronous logging, giving up on waiting for it
+// after timeout.
+func closeAsyncWithin(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		errorOnLogging(fmt.Errorf("timed out waiting for asynchronous logging to be written"))
+	}
+}
@@ -0,0 +1,175 @@
+package golog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsyncMatchesSync(t *testing.T) {
+	os.Setenv("PRINT_STACK", "true")
+	ReloadEnv()
+	defer func() {
+		os.Unsetenv("PRINT_STACK")
+		ReloadEnv()
+	}()
+
+	logAll := func() string {
+		out := newBuffer()
+		reset := SetOutputs(out, out)
+		defer reset()
+		l := LoggerFor("myprefix")
+		l.Debugw("Hello world", Field{"a", 1})
+		l.Errorf("Hello %v", "error")
+		Flush()
+		return out.String()
+	}
+	expected := logAll()
+	EnableAsync(10, DropNone)
+	defer Close()
+	assert.Equal(t, expected, logAll(), "async logging should capture the same caller and stack")
+	assert.Contains(t, expected, "   at github.com/getlantern/golog.TestAsyncMatchesSync")
+}
+
+// blockingWriter blocks writes until released, signaling blocked when the
+// first one starts.
+type blockingWriter struct {
+	buf      bytes.Buffer
+	mx       sync.Mutex
+	blocked  chan struct{}
+	release  chan struct{}
+	signaled sync.Once
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{blocked: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.signaled.Do(func() { close(w.blocked) })
+	<-w.release
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) messages() []string {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(w.buf.String()), "\n") {
+		// SEVERITY prefix: caller message [context]
+		messages = append(messages, strings.Fields(line)[3])
+	}
+	return messages
+}
+
+func TestAsyncDropPolicies(t *testing.T) {
+	for _, test := range []struct {
+		policy   DropPolicy
+		expected []string
+	}{
+		{DropNewest, []string{"first", "1", "2", "3"}},
+		{DropOldest, []string{"first", "8", "9", "10"}},
+	} {
+		w := newBlockingWriter()
+		reset := SetOutputs(ioutil.Discard, w)
+		EnableAsync(3, test.policy)
+		l := LoggerFor("myprefix")
+		l.Debug("first")
+		<-w.blocked
+		done := make(chan struct{})
+		go func() {
+			for i := 1; i <= 10; i++ {
+				l.Debug(i)
+			}
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("logging shouldn't block on a full buffer")
+		}
+		close(w.release)
+		Close()
+		reset()
+		assert.Equal(t, test.expected, w.messages())
+		assert.Contains(t, w.buf.String(), " "+test.expected[1]+" [dropped_count=7]\n", "the next event written should have the number of discarded ones")
+	}
+}
+
+func TestAsyncDropNoneBlocks(t *testing.T) {
+	w := newBlockingWriter()
+	reset := SetOutputs(ioutil.Discard, w)
+	defer reset()
+	EnableAsync(2, DropNone)
+	defer Close()
+	l := LoggerFor("myprefix")
+	l.Debug("first")
+	<-w.blocked
+	done := make(chan struct{})
+	go func() {
+		for i := 1; i <= 3; i++ {
+			l.Debug(i)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("logging should block once the buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(w.release)
+	<-done
+	Flush()
+	assert.Equal(t, []string{"first", "1", "2", "3"}, w.messages())
+}
+
+func TestAsyncFlushAndClose(t *testing.T) {
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	defer reset()
+	var fatalOutput string
+	OnFatal(func(err error) {
+		fatalOutput = out.String()
+	})
+	defer DefaultOnFatal()
+
+	EnableAsync(100, DropNone)
+	l := LoggerFor("myprefix")
+	for i := 0; i < 50; i++ {
+		l.Debug("hello")
+	}
+	Flush()
+	assert.Equal(t, 50, strings.Count(out.String(), "hello"), "Flush should wait for everything logged so far")
+
+	l.Fatal("oh no")
+	assert.Contains(t, fatalOutput, "FATAL myprefix: async_test.go:999 oh no", "FATAL errors should be written before OnFatal is called")
+
+	Close()
+	Close()
+	Flush()
+	l.Debug("synchronous")
+	assert.Contains(t, out.String(), "synchronous", "logging should be synchronous after Close")
+}
+
+func TestAsyncShutdown(t *testing.T) {
+	defer resetShutdown()
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	defer reset()
+	EnableAsync(100, DropNone)
+	LoggerFor("myprefix").Debug("before shutdown")
+	Shutdown()
+	require.Contains(t, out.String(), "before shutdown")
+	outputMx.RLock()
+	assert.Nil(t, asyncLog, "Shutdown should close asynchronous logging")
+	outputMx.RUnlock()
+}
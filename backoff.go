@@ -0,0 +1,204 @@
+package golog
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by Retry when the circuit breaker doesn't allow
+// any more attempts.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BackoffOptions configures exponential backoff for sinks that retry
+// deliveries.
+type BackoffOptions struct {
+	// MaxRetries is the maximum number of retries after the first attempt.
+	// 0 means no limit.
+	MaxRetries int
+
+	// BaseDelay is the delay before the first retry, which doubles on every
+	// subsequent retry. Defaults to 500 milliseconds.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between retries. Defaults to 1 minute.
+	MaxDelay time.Duration
+
+	// Jitter randomizes each delay by up to this fraction of it (between 0 and
+	// 1), so that many clients don't retry in lockstep.
+	Jitter float64
+
+	// Clock is what Retry waits with. Defaults to the system clock.
+	Clock Clock
+}
+
+// Backoff computes successive retry delays according to BackoffOptions. It's
+// not safe for concurrent use.
+type Backoff struct {
+	opts    BackoffOptions
+	retries int
+}
+
+// NewBackoff creates a Backoff. opts may be nil to use the defaults.
+func NewBackoff(opts *BackoffOptions) *Backoff {
+	b := &Backoff{}
+	if opts != nil {
+		b.opts = *opts
+	}
+	if b.opts.BaseDelay <= 0 {
+		b.opts.BaseDelay = 500 * time.Millisecond
+	}
+	if b.opts.MaxDelay <= 0 {
+		b.opts.MaxDelay = 1 * time.Minute
+	}
+	if b.opts.Clock == nil {
+		b.opts.Clock = realClock{}
+	}
+	return b
+}
+
+// Next returns the delay before the next retry, or false if MaxRetries has
+// been reached.
+func (b *Backoff) Next() (time.Duration, bool) {
+	if b.opts.MaxRetries > 0 && b.retries >= b.opts.MaxRetries {
+		return 0, false
+	}
+	delay := b.opts.BaseDelay
+	for i := 0; i < b.retries && delay < b.opts.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > b.opts.MaxDelay {
+		delay = b.opts.MaxDelay
+	}
+	if b.opts.Jitter > 0 {
+		delay += time.Duration(rand.Float64() * b.opts.Jitter * float64(delay))
+	}
+	b.retries++
+	return delay, true
+}
+
+// Reset starts over from BaseDelay, for example after a successful delivery.
+func (b *Backoff) Reset() {
+	b.retries = 0
+}
+
+// CircuitBreakerOptions configures a CircuitBreaker.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures after which the
+	// breaker opens. Defaults to 5.
+	FailureThreshold int
+
+	// ResetTimeout is how long the breaker stays open before letting a single
+	// trial attempt through. Defaults to 30 seconds.
+	ResetTimeout time.Duration
+
+	// Clock times ResetTimeout. Defaults to the system clock.
+	Clock Clock
+}
+
+// CircuitBreaker stops sinks from hammering a destination that keeps failing.
+// After FailureThreshold consecutive failures it opens and rejects attempts
+// until ResetTimeout has passed, then lets one attempt through: if that
+// succeeds the breaker closes again, otherwise it stays open for another
+// ResetTimeout. It's safe for concurrent use.
+type CircuitBreaker struct {
+	opts     CircuitBreakerOptions
+	mx       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// NewCircuitBreaker creates a CircuitBreaker. opts may be nil to use the
+// defaults.
+func NewCircuitBreaker(opts *CircuitBreakerOptions) *CircuitBreaker {
+	cb := &CircuitBreaker{}
+	if opts != nil {
+		cb.opts = *opts
+	}
+	if cb.opts.FailureThreshold <= 0 {
+		cb.opts.FailureThreshold = 5
+	}
+	if cb.opts.ResetTimeout <= 0 {
+		cb.opts.ResetTimeout = 30 * time.Second
+	}
+	if cb.opts.Clock == nil {
+		cb.opts.Clock = realClock{}
+	}
+	return cb
+}
+
+// Allow indicates whether an attempt may be made now.
+func (cb *CircuitBreaker) Allow() bool {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+	if cb.failures < cb.opts.FailureThreshold {
+		return true
+	}
+	if cb.trial || cb.opts.Clock.Now().Sub(cb.openedAt) < cb.opts.ResetTimeout {
+		return false
+	}
+	cb.trial = true
+	return true
+}
+
+// Success records a successful attempt, closing the breaker.
+func (cb *CircuitBreaker) Success() {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+	cb.failures = 0
+	cb.trial = false
+}
+
+// Failure records a failed attempt.
+func (cb *CircuitBreaker) Failure() {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+	cb.failures++
+	cb.trial = false
+	if cb.failures >= cb.opts.FailureThreshold {
+		cb.openedAt = cb.opts.Clock.Now()
+	}
+}
+
+// Open indicates whether the breaker is currently open.
+func (cb *CircuitBreaker) Open() bool {
+	cb.mx.Lock()
+	defer cb.mx.Unlock()
+	return cb.failures >= cb.opts.FailureThreshold
+}
+
+// Retry calls fn until it succeeds, waiting between attempts according to
+// backoff. If breaker isn't nil, attempts are recorded on it and Retry gives
+// up with ErrCircuitOpen when it's open. Retry also gives up when stop is
+// closed or backoff runs out of retries, returning the last error. It waits
+// with the Clock of backoff.
+func Retry(backoff *Backoff, breaker *CircuitBreaker, stop <-chan struct{}, fn func() error) error {
+	for {
+		if breaker != nil && !breaker.Allow() {
+			return ErrCircuitOpen
+		}
+		err := fn()
+		if breaker != nil {
+			if err == nil {
+				breaker.Success()
+			} else {
+				breaker.Failure()
+			}
+		}
+		if err == nil {
+			backoff.Reset()
+			return nil
+		}
+		delay, ok := backoff.Next()
+		if !ok {
+			return err
+		}
+		select {
+		case <-stop:
+			return err
+		case <-backoff.opts.Clock.After(delay):
+		}
+	}
+}
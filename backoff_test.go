@@ -0,0 +1,46 @@
+package golog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	b := NewBackoff(&BackoffOptions{MaxRetries: 4, BaseDelay: time.Second, MaxDelay: 5 * time.Second})
+	var delays []time.Duration
+	for {
+		delay, ok := b.Next()
+		if !ok {
+			break
+		}
+		delays = append(delays, delay)
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, delays)
+
+	b.Reset()
+	delay, ok := b.Next()
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, delay)
+}
+
+func TestRetry(t *testing.T) {
+	attempts := 0
+	err := Retry(NewBackoff(&BackoffOptions{BaseDelay: time.Millisecond}), nil, nil, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("fail")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	cb := NewCircuitBreaker(&CircuitBreakerOptions{FailureThreshold: 2})
+	err = Retry(NewBackoff(&BackoffOptions{BaseDelay: time.Millisecond}), cb, nil, func() error {
+		return errors.New("fail")
+	})
+	assert.Equal(t, ErrCircuitOpen, err)
+}
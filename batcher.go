@@ -0,0 +1,191 @@
+package golog
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatcherMaxEvents = 500
+	defaultBatcherMaxBytes  = 1024 * 1024
+	defaultBatcherMaxAge    = 5 * time.Second
+)
+
+// FlushReason indicates why a Batcher flushed.
+type FlushReason string
+
+const (
+	// FlushFull means the batch reached MaxEvents or MaxBytes.
+	FlushFull FlushReason = "full"
+	// FlushAge means the oldest event in the batch reached MaxAge.
+	FlushAge FlushReason = "age"
+	// FlushExplicit means Flush or Close was called.
+	FlushExplicit FlushReason = "explicit"
+)
+
+// BatcherOptions configures a Batcher.
+type BatcherOptions struct {
+	// MaxEvents is the maximum number of events in a batch. Defaults to 500.
+	MaxEvents int
+
+	// MaxBytes is the maximum total size of the events in a batch. Defaults to
+	// 1 MB. A single event bigger than this is sent as a batch of its own.
+	MaxBytes int
+
+	// MaxAge is how long an event may wait for its batch to fill up. Defaults
+	// to 5 seconds.
+	MaxAge time.Duration
+
+	// Clock times MaxAge. Defaults to the system clock.
+	Clock Clock
+
+	// Flush delivers a batch. It's called from one goroutine at a time, in
+	// order, and blocks Add while it runs. Errors are counted in the stats,
+	// it's up to Flush to retry.
+	Flush func(batch [][]byte, reason FlushReason) error
+}
+
+// BatcherStats describes the batches a Batcher has flushed.
+type BatcherStats struct {
+	// Batches counts flushed batches and Events the events in them.
+	Batches uint64
+	Events  uint64
+	// Bytes is the total size of flushed events.
+	Bytes uint64
+	// MaxBatchEvents is the size of the largest batch so far.
+	MaxBatchEvents int
+	// Reasons counts flushes by FlushReason.
+	Reasons map[FlushReason]uint64
+	// Errors counts flushes that returned an error.
+	Errors uint64
+}
+
+// Batcher accumulates encoded events and hands them to a flush function in
+// batches, whenever a batch is full by count or size or its oldest event gets
+// too old. Batching sinks share it so that throughput and latency are tuned
+// the same way for all destinations. It's safe for concurrent use.
+type Batcher struct {
+	opts  BatcherOptions
+	mx    sync.Mutex
+	batch [][]byte
+	bytes int
+	// stopAging is closed when the current batch is flushed before it's aged
+	stopAging chan struct{}
+	batchID   uint64
+	stats     BatcherStats
+	closed    bool
+}
+
+// NewBatcher creates a Batcher.
+func NewBatcher(opts *BatcherOptions) *Batcher {
+	b := &Batcher{opts: *opts}
+	if b.opts.MaxEvents <= 0 {
+		b.opts.MaxEvents = defaultBatcherMaxEvents
+	}
+	if b.opts.MaxBytes <= 0 {
+		b.opts.MaxBytes = defaultBatcherMaxBytes
+	}
+	if b.opts.MaxAge <= 0 {
+		b.opts.MaxAge = defaultBatcherMaxAge
+	}
+	if b.opts.Clock == nil {
+		b.opts.Clock = realClock{}
+	}
+	b.stats.Reasons = make(map[FlushReason]uint64)
+	return b
+}
+
+// Add adds an event to the current batch, flushing it from the calling
+// goroutine if that makes it full. Events added after Close are dropped.
+func (b *Batcher) Add(event []byte) {
+	b.mx.Lock()
+	if b.closed {
+		b.mx.Unlock()
+		return
+	}
+	if len(b.batch) > 0 && b.bytes+len(event) > b.opts.MaxBytes {
+		// Doesn't fit, send what we have first
+		b.flushLocked(FlushFull)
+	}
+	b.batch = append(b.batch, event)
+	b.bytes += len(event)
+	if len(b.batch) == 1 {
+		batchID, aged, stop := b.batchID, b.opts.Clock.After(b.opts.MaxAge), make(chan struct{})
+		b.stopAging = stop
+		go func() {
+			select {
+			case <-aged:
+				b.flushAged(batchID)
+			case <-stop:
+			}
+		}()
+	}
+	full := len(b.batch) >= b.opts.MaxEvents || b.bytes >= b.opts.MaxBytes
+	if full {
+		b.flushLocked(FlushFull)
+	}
+	b.mx.Unlock()
+}
+
+func (b *Batcher) flushAged(batchID uint64) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if batchID == b.batchID {
+		b.flushLocked(FlushAge)
+	}
+}
+
+// Flush flushes the current batch, if any.
+func (b *Batcher) Flush() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.flushLocked(FlushExplicit)
+}
+
+// flushLocked flushes the current batch. It must be called with mx held,
+// which keeps flushes in order.
+func (b *Batcher) flushLocked(reason FlushReason) {
+	if len(b.batch) == 0 {
+		return
+	}
+	if b.stopAging != nil {
+		close(b.stopAging)
+		b.stopAging = nil
+	}
+	batch, size := b.batch, b.bytes
+	b.batch, b.bytes = nil, 0
+	b.batchID++
+
+	err := b.opts.Flush(batch, reason)
+	b.stats.Batches++
+	b.stats.Events += uint64(len(batch))
+	b.stats.Bytes += uint64(size)
+	b.stats.Reasons[reason]++
+	if len(batch) > b.stats.MaxBatchEvents {
+		b.stats.MaxBatchEvents = len(batch)
+	}
+	if err != nil {
+		b.stats.Errors++
+	}
+}
+
+// Stats returns statistics about the batches flushed so far.
+func (b *Batcher) Stats() BatcherStats {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	stats := b.stats
+	stats.Reasons = make(map[FlushReason]uint64, len(b.stats.Reasons))
+	for reason, count := range b.stats.Reasons {
+		stats.Reasons[reason] = count
+	}
+	return stats
+}
+
+// Close flushes the current batch and stops accepting events.
+func (b *Batcher) Close() error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.flushLocked(FlushExplicit)
+	b.closed = true
+	return nil
+}
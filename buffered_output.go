@@ -0,0 +1,139 @@
+package golog
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BufferedOutputStats counts how often a buffered output flushed, by cause.
+type BufferedOutputStats struct {
+	// Full counts flushes because the buffer reached its maximum size.
+	Full uint64
+	// Interval counts flushes because the flush interval elapsed.
+	Interval uint64
+	// Error counts flushes because an ERROR or FATAL was logged.
+	Error uint64
+	// Explicit counts flushes because of calls to Flush or Close.
+	Explicit uint64
+}
+
+// BufferingOutput is an output that buffers what it writes, see
+// BufferedOutput.
+type BufferingOutput interface {
+	ClosableOutput
+
+	// Flush writes out everything that's buffered.
+	Flush() error
+
+	// Stats returns counts of flushes by cause.
+	Stats() BufferedOutputStats
+}
+
+// BufferedOutput creates an output that uses newOutput (for example
+// TextOutput or JsonOutput) to format events into an in-memory buffer, and
+// writes the buffer to w in larger chunks: whenever maxBytes have accumulated,
+// every flushInterval, right after every ERROR and FATAL, and on Flush and
+// Close. Coalescing small writes considerably reduces overhead for files and
+// network destinations. Close doesn't close w.
+func BufferedOutput(w io.Writer, newOutput func(errorWriter io.Writer, debugWriter io.Writer) Output, maxBytes int, flushInterval time.Duration) BufferingOutput {
+	o := &bufferedOutput{
+		w:        w,
+		maxBytes: maxBytes,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	o.out = newOutput(o, o)
+	go o.run(flushInterval)
+	return o
+}
+
+type bufferedOutput struct {
+	out      Output
+	w        io.Writer
+	maxBytes int
+	mx       sync.Mutex
+	buf      bytes.Buffer
+	stats    BufferedOutputStats
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func (o *bufferedOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.out.Error(prefix, skipFrames+1, printStack, severity, arg, values)
+	o.flush(&o.stats.Error)
+}
+
+func (o *bufferedOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.out.Debug(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+// Write implements io.Writer for the formatting output.
+func (o *bufferedOutput) Write(p []byte) (int, error) {
+	o.mx.Lock()
+	n, _ := o.buf.Write(p)
+	full := o.buf.Len() >= o.maxBytes
+	o.mx.Unlock()
+	if full {
+		o.flush(&o.stats.Full)
+	}
+	return n, nil
+}
+
+func (o *bufferedOutput) run(flushInterval time.Duration) {
+	defer close(o.done)
+	if flushInterval <= 0 {
+		<-o.stop
+		return
+	}
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-ticker.C:
+			o.flush(&o.stats.Interval)
+		}
+	}
+}
+
+func (o *bufferedOutput) flush(cause *uint64) error {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	if o.buf.Len() == 0 {
+		return nil
+	}
+	atomic.AddUint64(cause, 1)
+	_, err := redirectStdout(o.w).Write(o.buf.Bytes())
+	o.buf.Reset()
+	if err != nil {
+		errorOnLogging(err)
+	}
+	return err
+}
+
+func (o *bufferedOutput) Flush() error {
+	return o.flush(&o.stats.Explicit)
+}
+
+func (o *bufferedOutput) Stats() BufferedOutputStats {
+	return BufferedOutputStats{
+		Full:     atomic.LoadUint64(&o.stats.Full),
+		Interval: atomic.LoadUint64(&o.stats.Interval),
+		Error:    atomic.LoadUint64(&o.stats.Error),
+		Explicit: atomic.LoadUint64(&o.stats.Explicit),
+	}
+}
+
+// Close flushes the buffer and stops flushing on an interval.
+func (o *bufferedOutput) Close() error {
+	o.stopOnce.Do(func() {
+		close(o.stop)
+	})
+	<-o.done
+	return o.Flush()
+}
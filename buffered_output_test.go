@@ -0,0 +1,25 @@
+package golog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferedOutput(t *testing.T) {
+	out := newBuffer()
+	buffered := BufferedOutput(out, TextOutput, 1024, time.Hour)
+	reset := SetOutput(buffered)
+	defer reset()
+
+	l := LoggerFor("myprefix")
+	l.Debug("Hello world")
+	assert.Empty(t, out.String(), "debug should be buffered")
+	l.Error("Oh no")
+	assert.Equal(t, "DEBUG myprefix: buffered_output_test.go:999 Hello world\nERROR myprefix: buffered_output_test.go:999 Oh no\n", out.String())
+	l.Debug("Goodbye")
+	assert.NoError(t, buffered.Close())
+	assert.Contains(t, out.String(), "Goodbye")
+	assert.Equal(t, BufferedOutputStats{Error: 1, Explicit: 1}, buffered.Stats())
+}
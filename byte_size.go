@@ -0,0 +1,71 @@
+package golog
+
+import (
+	"strconv"
+	"time"
+)
+
+var byteUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+
+// ByteSize is a number of bytes that's logged in human-friendly binary units
+// in text, like "1.5MiB", and as the plain number of bytes in JSON.
+type ByteSize int64
+
+func (b ByteSize) String() string {
+	return humanBytes(float64(b), "")
+}
+
+// MarshalJSON encodes b as the number of bytes.
+func (b ByteSize) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(b), 10), nil
+}
+
+// BytesPerSecond is a transfer rate that's logged in human-friendly binary
+// units in text, like "1.5MiB/s", and as the plain number of bytes per second
+// in JSON.
+type BytesPerSecond float64
+
+// Rate returns the rate at which n bytes were transferred in d. It's 0 if d
+// isn't positive.
+func Rate(n int64, d time.Duration) BytesPerSecond {
+	if d <= 0 {
+		return 0
+	}
+	return BytesPerSecond(float64(n) / d.Seconds())
+}
+
+func (r BytesPerSecond) String() string {
+	return humanBytes(float64(r), "/s")
+}
+
+// MarshalJSON encodes r as the number of bytes per second, rounded to whole
+// bytes.
+func (r BytesPerSecond) MarshalJSON() ([]byte, error) {
+	return strconv.AppendFloat(nil, float64(r), 'f', 0, 64), nil
+}
+
+// humanBytes formats n bytes using the largest binary unit that keeps the
+// number at or above 1, with at most one decimal.
+func humanBytes(n float64, suffix string) string {
+	negative := n < 0
+	if negative {
+		n = -n
+	}
+	unit := 0
+	for n >= 1024 && unit < len(byteUnits)-1 {
+		n /= 1024
+		unit++
+	}
+	precision := 1
+	if unit == 0 {
+		precision = 0
+	}
+	s := strconv.FormatFloat(n, 'f', precision, 64)
+	if precision > 0 && s[len(s)-2:] == ".0" {
+		s = s[:len(s)-2]
+	}
+	if negative {
+		s = "-" + s
+	}
+	return s + byteUnits[unit] + suffix
+}
@@ -0,0 +1,30 @@
+package golog
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteSize(t *testing.T) {
+	assert.Equal(t, "512B", ByteSize(512).String())
+	assert.Equal(t, "1KiB", ByteSize(1024).String())
+	assert.Equal(t, "1.5MiB", ByteSize(1572864).String())
+	assert.Equal(t, "-2GiB", ByteSize(-2*1024*1024*1024).String())
+
+	b, err := json.Marshal(map[string]interface{}{"size": ByteSize(1572864)})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"size":1572864}`, string(b))
+}
+
+func TestRate(t *testing.T) {
+	r := Rate(3*1024*1024, 2*time.Second)
+	assert.Equal(t, "1.5MiB/s", r.String())
+	assert.Equal(t, BytesPerSecond(0), Rate(100, 0))
+
+	b, err := json.Marshal(map[string]interface{}{"rate": r})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"rate":1572864}`, string(b))
+}
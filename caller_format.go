@@ -0,0 +1,110 @@
+package golog
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// CallerFormat is how the caller of a log call is rendered, see
+// SetCallerFormat.
+type CallerFormat int32
+
+const (
+	// CallerBase renders the base name of the file, like "dial.go:12". This
+	// is the default.
+	CallerBase CallerFormat = iota
+
+	// CallerPackage renders the file along with its directory, like
+	// "dialer/dial.go:12".
+	CallerPackage
+
+	// CallerModule renders the package's import path relative to the main
+	// module, or in full for other modules, along with the file, like
+	// "proxy/dialer/dial.go:12" or "github.com/getlantern/ops/ops.go:80".
+	CallerModule
+
+	// CallerHash renders a short hash of the full path of the file, like
+	// "3f2a9c1b:12", for logs that shouldn't reveal source paths while still
+	// telling apart files with the same name.
+	CallerHash
+)
+
+var (
+	callerFormat int32
+
+	mainModule     string
+	mainModuleOnce sync.Once
+)
+
+// SetCallerFormat sets how callers are rendered by the text output, in the
+// "caller" of JSON events and, for formats other than CallerBase, in the
+// "caller" field of the zap output (in place of zap's own caller).
+func SetCallerFormat(format CallerFormat) {
+	atomic.StoreInt32(&callerFormat, int32(format))
+}
+
+func getCallerFormat() CallerFormat {
+	return CallerFormat(atomic.LoadInt32(&callerFormat))
+}
+
+// firstFrame returns the first frame in pc. Unlike runtime.FuncForPC, this
+// accounts for inlined functions.
+func firstFrame(pc []uintptr) runtime.Frame {
+	frame, _ := runtime.CallersFrames(pc).Next()
+	return frame
+}
+
+// appendCaller appends the location of frame in the current CallerFormat to
+// dst.
+func appendCaller(dst []byte, frame runtime.Frame) []byte {
+	switch getCallerFormat() {
+	case CallerPackage:
+		dst = append(dst, filepath.Base(filepath.Dir(frame.File))...)
+		dst = append(dst, '/')
+		dst = append(dst, filepath.Base(frame.File)...)
+	case CallerModule:
+		pkg := packagePath(frame.Function)
+		if module := getMainModule(); module != "" && strings.HasPrefix(pkg, module+"/") {
+			pkg = pkg[len(module)+1:]
+		}
+		if pkg != "" {
+			dst = append(dst, pkg...)
+			dst = append(dst, '/')
+		}
+		dst = append(dst, filepath.Base(frame.File)...)
+	case CallerHash:
+		sum := sha1.Sum([]byte(frame.File))
+		dst = append(dst, hex.EncodeToString(sum[:4])...)
+	default:
+		dst = append(dst, filepath.Base(frame.File)...)
+	}
+	dst = append(dst, ':')
+	return strconv.AppendInt(dst, int64(frame.Line), 10)
+}
+
+// packagePath returns the import path of the package of the fully qualified
+// function name, like github.com/getlantern/golog for
+// github.com/getlantern/golog.(*logger).Debug.
+func packagePath(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
+
+func getMainModule() string {
+	mainModuleOnce.Do(func() {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			mainModule = info.Main.Path
+		}
+	})
+	return mainModule
+}
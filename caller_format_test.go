@@ -0,0 +1,68 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCallerFormat(t *testing.T) {
+	defer SetCallerFormat(CallerBase)
+
+	tests := []struct {
+		format   CallerFormat
+		expected *regexp.Regexp
+	}{
+		{CallerBase, regexp.MustCompile(`^caller_format_test\.go:\d+$`)},
+		{CallerPackage, regexp.MustCompile(`^[^/]+/caller_format_test\.go:\d+$`)},
+		{CallerModule, regexp.MustCompile(`^github\.com/getlantern/golog/caller_format_test\.go:\d+$`)},
+		{CallerHash, regexp.MustCompile(`^[0-9a-f]{8}:\d+$`)},
+	}
+	for _, test := range tests {
+		SetCallerFormat(test.format)
+
+		buf := &bytes.Buffer{}
+		reset := SetOutputs(buf, buf)
+		LoggerFor("myprefix").Debug("text")
+		reset()
+		fields := bytes.Fields(buf.Bytes())
+		require.True(t, len(fields) > 2, buf.String())
+		assert.Regexp(t, test.expected, string(fields[2]), "text output for format %d", test.format)
+
+		buf.Reset()
+		reset = SetOutput(JsonOutput(buf, buf))
+		LoggerFor("myprefix").Debug("json")
+		reset()
+		var event Event
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+		assert.Regexp(t, test.expected, event.Caller, "json output for format %d", test.format)
+	}
+}
+
+func TestZapCallerFormat(t *testing.T) {
+	defer SetCallerFormat(CallerBase)
+	SetCallerFormat(CallerPackage)
+
+	buf := &bytes.Buffer{}
+	encoderConfig := zap.NewProductionEncoderConfig()
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(buf), zapcore.DebugLevel)
+	reset := SetOutput(ZapOutput(zap.New(core)))
+	LoggerFor("myprefix").Debug("zap")
+	reset()
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Regexp(t, `^[^/]+/caller_format_test\.go:\d+$`, entry["caller"])
+}
+
+func TestPackagePath(t *testing.T) {
+	assert.Equal(t, "github.com/getlantern/golog", packagePath("github.com/getlantern/golog.(*logger).Debug"))
+	assert.Equal(t, "main", packagePath("main.main"))
+	assert.Equal(t, "github.com/a/b.c/d", packagePath("github.com/a/b.c/d.Func.func1"))
+}
@@ -0,0 +1,118 @@
+package golog
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+const defaultMaxChildLoggers = 1000
+
+// ChildLogger returns a logger that logs like parent, which must have been
+// created by LoggerFor (or be another child logger), with the given fields
+// bound to every event it logs. Fields given with the event itself, or present
+// in the ops context, take precedence over bound fields with the same key.
+// Lines written to the child's TraceOut are logged without the bound fields.
+// Any other parent is returned unchanged, without the fields, which is
+// reported on stderr once per type of parent.
+func ChildLogger(parent Logger, fields ...Field) Logger {
+	p, ok := parent.(*logger)
+	if !ok {
+		reportForeignParent("ChildLogger", parent)
+		return parent
+	}
+	child := *p
+	child.fields = make([]Field, 0, len(p.fields)+len(fields))
+	child.fields = append(child.fields, p.fields...)
+	child.fields = append(child.fields, fields...)
+	return &child
+}
+
+// reportedForeignParents are the functions and types of parents already
+// reported by reportForeignParent.
+var reportedForeignParents sync.Map
+
+// reportForeignParent reports that fn got a parent it can't derive a logger
+// from because it wasn't created by LoggerFor, once per fn and type of parent.
+func reportForeignParent(fn string, parent Logger) {
+	key := fmt.Sprintf("%v %T", fn, parent)
+	if _, reported := reportedForeignParents.LoadOrStore(key, true); !reported {
+		errorOnLogging(fmt.Errorf("%v needs a parent created by LoggerFor, returning the %T parent unchanged", fn, parent))
+	}
+}
+
+// ChildLoggers caches short-lived child loggers keyed by an ID, such as a
+// connection or tenant ID, so that code handling the same connection can share
+// a logger without any plumbing. It holds on to at most a fixed number of
+// loggers, evicting the least recently used ones, so that it can't grow
+// without bound as connections come and go.
+type ChildLoggers struct {
+	parent  Logger
+	key     string
+	max     int
+	mx      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type childLoggerEntry struct {
+	id string
+	l  Logger
+}
+
+// NewChildLoggers creates a cache of child loggers of parent that bind the ID
+// they're keyed by to key (for example "conn_id"). max caps the number of
+// cached loggers and defaults to 1000.
+func NewChildLoggers(parent Logger, key string, max int) *ChildLoggers {
+	if max <= 0 {
+		max = defaultMaxChildLoggers
+	}
+	return &ChildLoggers{
+		parent:  parent,
+		key:     key,
+		max:     max,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the child logger for id, creating it with the given fields bound
+// in addition to the ID if it isn't cached. fields are ignored for loggers
+// that are already cached.
+func (c *ChildLoggers) Get(id string, fields ...Field) Logger {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if e, found := c.entries[id]; found {
+		c.lru.MoveToFront(e)
+		return e.Value.(*childLoggerEntry).l
+	}
+	bound := make([]Field, 0, len(fields)+1)
+	bound = append(bound, Field{Key: c.key, Value: id})
+	bound = append(bound, fields...)
+	l := ChildLogger(c.parent, bound...)
+	c.entries[id] = c.lru.PushFront(&childLoggerEntry{id: id, l: l})
+	for c.lru.Len() > c.max {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*childLoggerEntry).id)
+	}
+	return l
+}
+
+// Remove drops the child logger for id from the cache, for example once its
+// connection is closed. Loggers that were handed out keep working.
+func (c *ChildLoggers) Remove(id string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if e, found := c.entries[id]; found {
+		c.lru.Remove(e)
+		delete(c.entries, id)
+	}
+}
+
+// Len returns the number of cached child loggers.
+func (c *ChildLoggers) Len() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.lru.Len()
+}
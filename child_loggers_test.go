@@ -0,0 +1,67 @@
+package golog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChildLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	parent := LoggerFor("myprefix")
+	child := ChildLogger(parent, Field{Key: "tenant", Value: "acme"})
+	grandchild := ChildLogger(child, Field{Key: "conn_id", Value: 5})
+	grandchild.Debug("hello")
+	grandchild.Debugw("overridden", Field{Key: "tenant", Value: "other"})
+	grandchild.Error("oh no")
+	parent.Debug("unbound")
+
+	assert.Regexp(t, `^DEBUG myprefix: child_loggers_test.go:[0-9]+ hello \[conn_id=5 tenant=acme\]
+DEBUG myprefix: child_loggers_test.go:[0-9]+ overridden \[conn_id=5 tenant=other\]
+ERROR myprefix: child_loggers_test.go:[0-9]+ oh no \[conn_id=5 tenant=acme\]
+DEBUG myprefix: child_loggers_test.go:[0-9]+ unbound
+$`, buf.String())
+}
+
+func TestChildLoggers(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	loggers := NewChildLoggers(LoggerFor("myprefix"), "conn_id", 2)
+	a := loggers.Get("a", Field{Key: "client", Value: "1.2.3.4"})
+	assert.Equal(t, a, loggers.Get("a"), "should reuse cached logger")
+	loggers.Get("b")
+	loggers.Get("a")
+	loggers.Get("c")
+	assert.Equal(t, 2, loggers.Len())
+	assert.Equal(t, a, loggers.Get("a"), "most recently used logger shouldn't have been evicted")
+	loggers.Remove("a")
+	assert.Equal(t, 1, loggers.Len())
+
+	a.Debug("still works")
+	assert.Regexp(t, `^DEBUG myprefix: child_loggers_test.go:[0-9]+ still works \[client=1.2.3.4 conn_id=a\]\n$`, buf.String())
+}
+
+// foreignLogger is a Logger that wasn't created by LoggerFor.
+type foreignLogger struct {
+	Logger
+}
+
+func TestChildLoggerForeignParent(t *testing.T) {
+	oldStderr := stderr
+	errs := &bytes.Buffer{}
+	stderr = errs
+	defer func() { stderr = oldStderr }()
+
+	parent := &foreignLogger{}
+	assert.Equal(t, parent, ChildLogger(parent, Field{Key: "tenant", Value: "acme"}))
+	assert.Equal(t, parent, ChildLogger(parent, Field{Key: "tenant", Value: "acme"}))
+	assert.Equal(t, parent, SampledLogger(parent, 10))
+	assert.Equal(t, "Unable to log: ChildLogger needs a parent created by LoggerFor, returning the *golog.foreignLogger parent unchanged\n"+
+		"Unable to log: SampledLogger needs a parent created by LoggerFor, returning the *golog.foreignLogger parent unchanged\n", errs.String())
+}
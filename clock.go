@@ -0,0 +1,24 @@
+package golog
+
+import "time"
+
+// Clock tells the time and waits for the outputs that batch and retry events,
+// so that tests can control their timing, for example with a sinktest.Clock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After is like time.After.
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the Clock of the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
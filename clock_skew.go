@@ -0,0 +1,80 @@
+package golog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// clockSkew is the estimated skew of the local clock in nanoseconds,
+	// valid once clockSkewKnown is 1
+	clockSkew      int64
+	clockSkewKnown int32
+)
+
+// SetClockSkew records an estimate of how far the local clock is ahead of a
+// reference clock, like that of a server or an NTP server, negative if it's
+// behind. See EstimateClockSkew and ClockFields.
+func SetClockSkew(skew time.Duration) {
+	atomic.StoreInt64(&clockSkew, int64(skew))
+	atomic.StoreInt32(&clockSkewKnown, 1)
+}
+
+// ClockSkew returns the estimate recorded with SetClockSkew, if any.
+func ClockSkew() (time.Duration, bool) {
+	if atomic.LoadInt32(&clockSkewKnown) == 0 {
+		return 0, false
+	}
+	return time.Duration(atomic.LoadInt64(&clockSkew)), true
+}
+
+// EstimateClockSkew estimates the skew of the local clock the way NTP does,
+// from a request sent at sent and answered by received, both by the local
+// clock, and the time reported by the other side, like the Date header of an
+// HTTP response. Assuming the request and response took equally long, the
+// reported time corresponds to halfway between sent and received. The
+// estimate can't be more precise than the reported time.
+func EstimateClockSkew(sent time.Time, received time.Time, reported time.Time) time.Duration {
+	return sent.Add(received.Sub(sent) / 2).Sub(reported)
+}
+
+// ClockFields returns fields describing the local clock, so that logs from
+// different machines can be correlated: tz, the name of the local time zone,
+// tz_offset, its current offset from UTC like "+01:00", and clock_skew_ms
+// if an estimate was recorded with SetClockSkew. LogStartup includes them.
+func ClockFields() []Field {
+	now := time.Now()
+	zone, _ := now.Zone()
+	fields := []Field{{"tz", zone}, {"tz_offset", now.Format("-07:00")}}
+	if skew, known := ClockSkew(); known {
+		fields = append(fields, DurationMS("clock_skew_ms", skew))
+	}
+	return fields
+}
+
+// AnnotateClock adds the ClockFields to every event, see
+// RegisterFieldProvider. The time zone is checked at most once a minute to
+// notice daylight saving time. Call unregister to stop adding them.
+func AnnotateClock() (unregister func()) {
+	unregisters := []func(){
+		RegisterFieldProvider(time.Minute, func() (string, interface{}) {
+			zone, _ := time.Now().Zone()
+			return "tz", zone
+		}),
+		RegisterFieldProvider(time.Minute, func() (string, interface{}) {
+			return "tz_offset", time.Now().Format("-07:00")
+		}),
+		RegisterFieldProvider(0, func() (string, interface{}) {
+			skew, known := ClockSkew()
+			if !known {
+				return "", nil
+			}
+			return "clock_skew_ms", Milliseconds(skew)
+		}),
+	}
+	return func() {
+		for _, unregister := range unregisters {
+			unregister()
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package golog
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateClockSkew(t *testing.T) {
+	sent := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	received := sent.Add(2 * time.Second)
+	assert.Equal(t, time.Duration(0), EstimateClockSkew(sent, received, sent.Add(time.Second)))
+	assert.Equal(t, 10*time.Second, EstimateClockSkew(sent, received, sent.Add(-9*time.Second)), "local clock ahead")
+	assert.Equal(t, -time.Minute, EstimateClockSkew(sent, received, sent.Add(61*time.Second)), "local clock behind")
+}
+
+func TestClockFields(t *testing.T) {
+	defer atomic.StoreInt32(&clockSkewKnown, 0)
+
+	fields := ClockFields()
+	require.Len(t, fields, 2, "skew shouldn't be included until known")
+	zone, _ := time.Now().Zone()
+	assert.Equal(t, Field{"tz", zone}, fields[0])
+	assert.Equal(t, Field{"tz_offset", time.Now().Format("-07:00")}, fields[1])
+
+	SetClockSkew(-1500 * time.Millisecond)
+	skew, known := ClockSkew()
+	assert.True(t, known)
+	assert.Equal(t, -1500*time.Millisecond, skew)
+	fields = ClockFields()
+	require.Len(t, fields, 3)
+	assert.Equal(t, "-1500.000", fields[2].Value.(Milliseconds).String())
+}
+
+func TestAnnotateClock(t *testing.T) {
+	defer atomic.StoreInt32(&clockSkewKnown, 0)
+	buf := &bytes.Buffer{}
+	reset := SetOutput(JsonOutput(buf, buf))
+	defer reset()
+	l := LoggerFor("myprefix")
+
+	unregister := AnnotateClock()
+	l.Debug("before skew")
+	SetClockSkew(250 * time.Millisecond)
+	l.Debug("after skew")
+	unregister()
+	l.Debug("unregistered")
+
+	events, err := ReadJSONEvents(buf)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, time.Now().Format("-07:00"), events[0].Context["tz_offset"])
+	assert.NotContains(t, events[0].Context, "clock_skew_ms")
+	assert.Equal(t, float64(250), events[1].Context["clock_skew_ms"])
+	assert.NotContains(t, events[2].Context, "tz")
+}
@@ -0,0 +1,94 @@
+// Package compat exposes golog's historical signatures on top of the current
+// golog package, so that large code bases can upgrade call sites one at a time
+// by changing an import rather than every call.
+//
+// The legacy API maps to golog as follows:
+//
+//	compat.SetOutputs(errorOut, debugOut)  golog.SetOutputs, dropping the reset function
+//	compat.Logger                          the original method set of golog.Logger
+//	compat.LoggerFor(prefix)               golog.LoggerFor
+//	compat.ErrorReporter                   the original reporter, which got the logger's prefix
+//	compat.RegisterReporter(reporter)      golog.RegisterReporter
+//
+// Every golog.Logger is a compat.Logger, so loggers can be passed between
+// upgraded and legacy code freely.
+package compat
+
+import (
+	"io"
+	"log"
+
+	"github.com/getlantern/golog"
+)
+
+// Logger is the original golog Logger interface, before structured fields,
+// DPanic, deprecations and streaming were added.
+type Logger interface {
+	// Debug logs to stdout
+	Debug(arg interface{})
+	// Debugf logs to stdout
+	Debugf(message string, args ...interface{})
+
+	// Error logs to stderr
+	Error(arg interface{}) error
+	// Errorf logs to stderr. It returns the first argument that's an error, or
+	// a new error built using fmt.Errorf if none of the arguments are errors.
+	Errorf(message string, args ...interface{}) error
+
+	// Fatal logs to stderr and then exits with status 1
+	Fatal(arg interface{})
+	// Fatalf logs to stderr and then exits with status 1
+	Fatalf(message string, args ...interface{})
+
+	// Trace logs to stderr only if TRACE=true
+	Trace(arg interface{})
+	// Tracef logs to stderr only if TRACE=true
+	Tracef(message string, args ...interface{})
+
+	// TraceOut provides access to an io.Writer to which trace information can
+	// be streamed.
+	TraceOut() io.Writer
+
+	// IsTraceEnabled() indicates whether or not tracing is enabled for this
+	// logger.
+	IsTraceEnabled() bool
+
+	// AsDebugLogger returns an standard logger that writes Debug messages
+	AsDebugLogger() *log.Logger
+
+	// AsStdLogger returns an standard logger that writes Errors
+	AsStdLogger() *log.Logger
+
+	// AsErrorLogger returns an standard logger that writes Errors
+	AsErrorLogger() *log.Logger
+}
+
+var _ Logger = golog.Logger(nil)
+
+// LoggerFor returns a Logger for the given prefix, see golog.LoggerFor.
+func LoggerFor(prefix string) Logger {
+	return golog.LoggerFor(prefix)
+}
+
+// SetOutputs sets the writers for error and debug logs, see golog.SetOutputs.
+// To restore the previous outputs, call golog.SetOutputs instead.
+func SetOutputs(errorOut io.Writer, debugOut io.Writer) {
+	golog.SetOutputs(errorOut, debugOut)
+}
+
+// ErrorReporter is the original error reporter, which gets the prefix of the
+// logger that logged the error, including the trailing ": ", alongside the
+// error's context.
+type ErrorReporter func(err error, linePrefix string, severity golog.Severity, ctx map[string]interface{})
+
+// RegisterReporter registers the given ErrorReporter, see
+// golog.RegisterReporter.
+func RegisterReporter(reporter ErrorReporter) {
+	golog.RegisterReporter(func(err error, severity golog.Severity, ctx map[string]interface{}) {
+		linePrefix := ""
+		if component, ok := ctx["component"].(string); ok && component != "" {
+			linePrefix = component + ": "
+		}
+		reporter(err, linePrefix, severity, ctx)
+	})
+}
@@ -0,0 +1,37 @@
+package compat
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/getlantern/golog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLegacyCallSites(t *testing.T) {
+	errorOut, debugOut := &bytes.Buffer{}, &bytes.Buffer{}
+	SetOutputs(errorOut, debugOut)
+	defer golog.SetOutputs(ioutil.Discard, ioutil.Discard)
+
+	var reportedPrefix string
+	var reportedSeverity golog.Severity
+	RegisterReporter(func(err error, linePrefix string, severity golog.Severity, ctx map[string]interface{}) {
+		reportedPrefix, reportedSeverity = linePrefix, severity
+	})
+
+	var log Logger = LoggerFor("legacy")
+	log.Debugf("Hello %v", "world")
+	assert.Error(t, log.Error("oops"))
+	assert.Contains(t, debugOut.String(), "DEBUG legacy: compat_test.go:")
+	assert.Contains(t, debugOut.String(), "Hello world")
+	assert.Contains(t, errorOut.String(), "ERROR legacy: compat_test.go:")
+	assert.Equal(t, "legacy: ", reportedPrefix)
+	assert.Equal(t, golog.Severity(golog.ERROR), reportedSeverity)
+
+	// Loggers from upgraded code can be handed to legacy code and back
+	var upgraded golog.Logger = golog.LoggerFor("upgraded")
+	log = upgraded
+	_, isUpgraded := log.(golog.Logger)
+	assert.True(t, isUpgraded)
+}
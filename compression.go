@@ -0,0 +1,239 @@
+package golog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Codec compresses the bodies sent by network outputs. Its Encoding is the
+// name used in the Content-Encoding header, like "gzip" or "zstd".
+type Codec interface {
+	// Encoding is the name of the codec, as used in Content-Encoding.
+	Encoding() string
+
+	// NewWriter returns a writer that compresses to w at the given level. 0
+	// means the codec's default level.
+	NewWriter(w io.Writer, level int) (io.WriteCloser, error)
+
+	// NewReader returns a reader that decompresses r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	codecs   = make(map[string]Codec)
+	codecsMx sync.RWMutex
+)
+
+func init() {
+	RegisterCodec(gzipCodec{})
+	RegisterCodec(deflateCodec{})
+}
+
+// RegisterCodec registers a Codec under its Encoding, so that it can be
+// selected by name in CompressionOptions. Names are case insensitive.
+// Registering a name that's already registered replaces the existing codec.
+// "gzip" and "deflate" are registered by default. Others, like zstd or
+// snappy, can be registered by wrapping a third party implementation.
+func RegisterCodec(codec Codec) {
+	codecsMx.Lock()
+	defer codecsMx.Unlock()
+	codecs[strings.ToLower(codec.Encoding())] = codec
+}
+
+// LookupCodec returns the Codec registered under the given name.
+func LookupCodec(name string) (Codec, bool) {
+	codecsMx.RLock()
+	defer codecsMx.RUnlock()
+	codec, found := codecs[strings.ToLower(name)]
+	return codec, found
+}
+
+// CodecNames returns the names of all registered codecs, sorted.
+func CodecNames() []string {
+	codecsMx.RLock()
+	defer codecsMx.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CompressionOptions configures how a network output compresses what it
+// sends.
+type CompressionOptions struct {
+	// Codecs are the names of the codecs to use, most preferred first, like
+	// []string{"zstd", "gzip"}. Names that aren't registered are skipped.
+	// Bodies are compressed with the most preferred codec that the server
+	// hasn't turned down, either by responding 415 Unsupported Media Type or
+	// by listing the codecs it does accept in an Accept-Encoding response
+	// header (RFC 7694). Once all codecs have been turned down, bodies are
+	// sent uncompressed.
+	Codecs []string
+
+	// Levels are the compression levels by codec name. Codecs without a level
+	// use their default.
+	Levels map[string]int
+}
+
+// compressionNegotiator tracks which codec to use for an output. A nil
+// compressionNegotiator sends everything uncompressed.
+type compressionNegotiator struct {
+	levels map[string]int
+	mx     sync.Mutex
+	codecs []Codec
+}
+
+func newCompressionNegotiator(opts *CompressionOptions) *compressionNegotiator {
+	if opts == nil {
+		return nil
+	}
+	n := &compressionNegotiator{levels: make(map[string]int)}
+	for _, name := range opts.Codecs {
+		if codec, found := LookupCodec(name); found {
+			n.codecs = append(n.codecs, codec)
+		} else {
+			errorOnLogging(fmt.Errorf("unknown compression codec %q, expected one of %v", name, CodecNames()))
+		}
+	}
+	for name, level := range opts.Levels {
+		n.levels[strings.ToLower(name)] = level
+	}
+	return n
+}
+
+func (n *compressionNegotiator) current() Codec {
+	if n == nil {
+		return nil
+	}
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	if len(n.codecs) == 0 {
+		return nil
+	}
+	return n.codecs[0]
+}
+
+// refuse stops using codec.
+func (n *compressionNegotiator) refuse(codec Codec) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	for i, c := range n.codecs {
+		if c.Encoding() == codec.Encoding() {
+			n.codecs = append(n.codecs[:i:i], n.codecs[i+1:]...)
+			return
+		}
+	}
+}
+
+// accept restricts the codecs in use to those in an Accept-Encoding header.
+func (n *compressionNegotiator) accept(header string) {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		name := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		if name != "" {
+			accepted[name] = true
+		}
+	}
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	kept := n.codecs[:0:0]
+	for _, codec := range n.codecs {
+		if accepted[strings.ToLower(codec.Encoding())] {
+			kept = append(kept, codec)
+		}
+	}
+	n.codecs = kept
+}
+
+func (n *compressionNegotiator) compress(codec Codec, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := codec.NewWriter(&buf, n.levels[strings.ToLower(codec.Encoding())])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// do sends body using a request from newRequest, compressing it with the
+// negotiated codec and retrying with the next codec if the server doesn't
+// support it.
+func (n *compressionNegotiator) do(client *http.Client, body []byte, newRequest func(body io.Reader) (*http.Request, error)) (*http.Response, error) {
+	for {
+		codec := n.current()
+		encoded := body
+		if codec != nil {
+			var err error
+			encoded, err = n.compress(codec, body)
+			if err != nil {
+				return nil, fmt.Errorf("unable to compress with %v: %v", codec.Encoding(), err)
+			}
+		}
+		req, err := newRequest(bytes.NewReader(encoded))
+		if err != nil {
+			return nil, err
+		}
+		if codec != nil {
+			req.Header.Set("Content-Encoding", codec.Encoding())
+		}
+		resp, err := client.Do(req)
+		if err != nil || codec == nil {
+			return resp, err
+		}
+		if accepted := resp.Header.Get("Accept-Encoding"); accepted != "" {
+			n.accept(accepted)
+		}
+		if resp.StatusCode != http.StatusUnsupportedMediaType {
+			return resp, nil
+		}
+		n.refuse(codec)
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Encoding() string { return "gzip" }
+
+func (gzipCodec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// deflateCodec is the HTTP deflate encoding, which is zlib framed.
+type deflateCodec struct{}
+
+func (deflateCodec) Encoding() string { return "deflate" }
+
+func (deflateCodec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	if level == 0 {
+		level = zlib.DefaultCompression
+	}
+	return zlib.NewWriterLevel(w, level)
+}
+
+func (deflateCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return zlib.NewReader(r)
+}
@@ -0,0 +1,140 @@
+package golog
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reverseCodec stands in for a third party codec in tests.
+type reverseCodec struct{}
+
+func (reverseCodec) Encoding() string { return "x-reverse" }
+
+func (reverseCodec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return &reverseWriter{w: w}, nil
+}
+
+func (reverseCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(reverse(b))), nil
+}
+
+type reverseWriter struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (w *reverseWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *reverseWriter) Close() error {
+	_, err := w.w.Write(reverse(w.buf.Bytes()))
+	return err
+}
+
+func reverse(b []byte) []byte {
+	result := make([]byte, len(b))
+	for i, c := range b {
+		result[len(b)-1-i] = c
+	}
+	return result
+}
+
+func TestCodecs(t *testing.T) {
+	assert.Contains(t, CodecNames(), "gzip")
+	assert.Contains(t, CodecNames(), "deflate")
+	for _, name := range []string{"gzip", "GZIP", "deflate"} {
+		codec, found := LookupCodec(name)
+		require.True(t, found, name)
+		var buf bytes.Buffer
+		w, err := codec.NewWriter(&buf, 9)
+		require.NoError(t, err)
+		_, err = w.Write([]byte("hello hello hello"))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		r, err := codec.NewReader(&buf)
+		require.NoError(t, err)
+		decoded, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "hello hello hello", string(decoded), name)
+	}
+}
+
+func TestCompressionNegotiation(t *testing.T) {
+	RegisterCodec(reverseCodec{})
+
+	var mx sync.Mutex
+	var encodings, bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mx.Lock()
+		defer mx.Unlock()
+		encoding := req.Header.Get("Content-Encoding")
+		encodings = append(encodings, encoding)
+		if encoding != "gzip" {
+			w.Header().Set("Accept-Encoding", "gzip")
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		codec, _ := LookupCodec(encoding)
+		r, err := codec.NewReader(req.Body)
+		require.NoError(t, err)
+		body, _ := ioutil.ReadAll(r)
+		bodies = append(bodies, string(body))
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+
+	out := ElasticsearchOutput(&ElasticsearchOptions{
+		URL:           srv.URL,
+		FlushInterval: 10 * time.Millisecond,
+		Compression: &CompressionOptions{
+			Codecs: []string{"x-reverse", "deflate", "gzip"},
+			Levels: map[string]int{"gzip": 1},
+		},
+	})
+	reset := SetOutput(out)
+	l := LoggerFor("myprefix")
+	l.Debug("first")
+	time.Sleep(100 * time.Millisecond)
+	l.Debug("second")
+	require.NoError(t, out.Close())
+	reset()
+
+	mx.Lock()
+	defer mx.Unlock()
+	assert.Equal(t, []string{"x-reverse", "gzip", "gzip"}, encodings, "Accept-Encoding should skip straight to gzip")
+	require.Len(t, bodies, 2)
+	assert.Contains(t, bodies[0], "first")
+	assert.Contains(t, bodies[1], "second")
+}
+
+func TestCompressionFallsBackToIdentity(t *testing.T) {
+	var encodings []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		encodings = append(encodings, req.Header.Get("Content-Encoding"))
+		if req.Header.Get("Content-Encoding") != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+		}
+	}))
+	defer srv.Close()
+
+	n := newCompressionNegotiator(&CompressionOptions{Codecs: []string{"gzip", "deflate"}})
+	resp, err := n.do(http.DefaultClient, []byte("hello"), func(body io.Reader) (*http.Request, error) {
+		return http.NewRequest(http.MethodPost, srv.URL, body)
+	})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"gzip", "deflate", ""}, encodings)
+}
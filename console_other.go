@@ -0,0 +1,8 @@
+//go:build !windows
+// +build !windows
+
+package golog
+
+// prepareConsole does nothing, since terminals on other platforms interpret
+// escape sequences and UTF-8 as is.
+func prepareConsole() {}
@@ -0,0 +1,63 @@
+//go:build windows
+// +build windows
+
+package golog
+
+import (
+	"os"
+	"sync"
+	"syscall"
+)
+
+const (
+	enableVirtualTerminalProcessing = 0x0004
+	utf8CodePage                    = 65001
+)
+
+var (
+	kernel32               = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode     = kernel32.NewProc("SetConsoleMode")
+	procSetConsoleOutputCP = kernel32.NewProc("SetConsoleOutputCP")
+
+	prepareConsoleOnce sync.Once
+)
+
+// On Windows, consoles don't interpret ANSI escape sequences and render
+// output using the legacy code page unless told otherwise, which garbles color
+// output and non-ASCII component names and messages. So when stderr or stdout
+// is a real console, we enable virtual terminal processing and switch the
+// console's output code page to UTF-8. This changes the console for the whole
+// process, so it's only done once styled output is first written to a
+// terminal, see severityStyle.
+func prepareConsole() {
+	prepareConsoleOnce.Do(enableConsole)
+}
+
+func enableConsole() {
+	isConsole := false
+	for _, f := range []*os.File{os.Stderr, os.Stdout} {
+		if enableVirtualTerminal(syscall.Handle(f.Fd())) {
+			isConsole = true
+		}
+	}
+	if isConsole {
+		// Best effort, there's nothing useful to do if this fails
+		_, _, _ = procSetConsoleOutputCP.Call(utf8CodePage)
+	}
+}
+
+// enableVirtualTerminal enables virtual terminal processing on the given
+// handle and reports whether the handle is a console.
+func enableVirtualTerminal(handle syscall.Handle) bool {
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		// Not a console (e.g. redirected to a file or pipe)
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing == 0 {
+		// Fails on versions of Windows older than Windows 10, in which case
+		// escape sequences will show up as is
+		_, _, _ = procSetConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+	}
+	return true
+}
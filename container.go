@@ -0,0 +1,66 @@
+package golog
+
+import (
+	"os"
+	"strconv"
+	"sync"
+)
+
+var (
+	inContainer     bool
+	inContainerOnce sync.Once
+
+	// detectContainer reports whether the process runs in a container, replaced
+	// in tests.
+	detectContainer = runningInContainer
+)
+
+// InContainer reports whether the process appears to be running in a
+// container without a terminal attached: stdout isn't a TTY and there's a
+// /.dockerenv or /run/.containerenv file, Kubernetes environment variables or
+// a container ID in /proc/self/cgroup. Setting GOLOG_CONTAINER to true or
+// false overrides the detection.
+//
+// In a container, the default outputs write single-line JSON without
+// timestamps to stdout, since container runtimes collect stdout and stamp each
+// line themselves. Setting PRINT_JSON to false keeps text output, and
+// SetOutputs or SetOutput replace the defaults entirely.
+func InContainer() bool {
+	if v, err := strconv.ParseBool(os.Getenv("GOLOG_CONTAINER")); err == nil {
+		return v
+	}
+	inContainerOnce.Do(func() {
+		inContainer = detectContainer()
+	})
+	return inContainer
+}
+
+func runningInContainer() bool {
+	if stdoutIsTerminal() {
+		return false
+	}
+	for _, marker := range []string{"/.dockerenv", "/run/.containerenv"} {
+		if _, err := os.Stat(marker); err == nil {
+			return true
+		}
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return true
+	}
+	_, found := getHostMetadata()["container_id"]
+	return found
+}
+
+func stdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// printJSONInContainer reports whether the default outputs should write JSON
+// in a container, which they do unless PRINT_JSON says otherwise.
+func printJSONInContainer() bool {
+	if v, err := strconv.ParseBool(os.Getenv("PRINT_JSON")); err == nil {
+		return v
+	}
+	return true
+}
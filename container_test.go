@@ -0,0 +1,77 @@
+package golog
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerDefaults(t *testing.T) {
+	oldStderr, oldStdout := stderr, stdout
+	errBuf, outBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	stderr, stdout = errBuf, outBuf
+	defer func() {
+		stderr, stdout = oldStderr, oldStdout
+		os.Unsetenv("GOLOG_CONTAINER")
+		os.Unsetenv("PRINT_JSON")
+		ResetOutputs()
+	}()
+
+	os.Setenv("GOLOG_CONTAINER", "true")
+	assert.True(t, InContainer())
+	ResetOutputs()
+	l := LoggerFor("myprefix")
+	l.Debug("hello")
+	l.Error("oh no")
+	assert.Empty(t, errBuf.String(), "errors should go to stdout in a container")
+	assert.Regexp(t, `^\{"msg":"hello".*\}\n\{"msg":"oh no".*"level":"ERROR"\}\n$`, outBuf.String())
+
+	outBuf.Reset()
+	os.Setenv("PRINT_JSON", "false")
+	ResetOutputs()
+	l.Debug("hello")
+	assert.Regexp(t, `^DEBUG myprefix: container_test.go:[0-9]+ hello\n$`, outBuf.String())
+
+	outBuf.Reset()
+	os.Unsetenv("PRINT_JSON")
+	os.Setenv("GOLOG_CONTAINER", "false")
+	assert.False(t, InContainer())
+	ResetOutputs()
+	l.Error("oh no")
+	assert.Empty(t, outBuf.String())
+	assert.Contains(t, errBuf.String(), "ERROR myprefix: ")
+}
+
+func TestContainerEnvConfigJSON(t *testing.T) {
+	oldStderr, oldStdout, oldConfig := stderr, stdout, getEnvConfig()
+	errBuf, outBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	stderr, stdout = errBuf, outBuf
+	defer func() {
+		stderr, stdout = oldStderr, oldStdout
+		envConfig.Store(oldConfig)
+		os.Unsetenv("GOLOG_CONTAINER")
+		ResetOutputs()
+	}()
+
+	os.Setenv("GOLOG_CONTAINER", "true")
+	cfg, err := ParseEnvConfig("format=json")
+	require.NoError(t, err)
+	envConfig.Store(cfg)
+	ResetOutputs()
+	l := LoggerFor("myprefix")
+	l.Debug("hello")
+	l.Error("oh no")
+	assert.Empty(t, errBuf.String())
+	assert.Regexp(t, `^\{"msg":"hello".*\}\n\{"msg":"oh no".*"level":"ERROR"\}\n$`, outBuf.String(), "container runtimes stamp lines, so JSON shouldn't have timestamps")
+
+	outBuf.Reset()
+	cfg, err = ParseEnvConfig("format=text")
+	require.NoError(t, err)
+	envConfig.Store(cfg)
+	ResetOutputs()
+	l.Debug("hello")
+	assert.Regexp(t, `^DEBUG myprefix: container_test.go:[0-9]+ hello\n$`, outBuf.String(), "text shouldn't have timestamps either")
+}
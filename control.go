@@ -0,0 +1,214 @@
+package golog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LogControl is the set of operations for adjusting logging while a program
+// runs. Applications can expose it over whatever control channel they already
+// have, like a local RPC or admin socket, so that support can adjust logging
+// on a user's machine interactively. See ServeControlSocket for a reference
+// implementation over a Unix socket.
+type LogControl interface {
+	// SetLevel sets the lowest severity that's logged, see SetLevel.
+	SetLevel(level string) error
+
+	// EnableTrace enables tracing for the given prefixes, or for everything if
+	// none are given, see EnableTrace.
+	EnableTrace(prefixes ...string)
+
+	// DisableTrace undoes EnableTrace.
+	DisableTrace()
+
+	// DumpRecent writes the n most recently logged events (all of them if n
+	// is 0) to w as JSON, one event per line.
+	DumpRecent(w io.Writer, n int) error
+}
+
+// NewLogControl creates a LogControl that controls golog globally and dumps
+// recent events from rb, which may be nil if the application doesn't keep a
+// RingBuffer.
+func NewLogControl(rb *RingBuffer) LogControl {
+	return &logControl{rb: rb}
+}
+
+type logControl struct {
+	rb *RingBuffer
+}
+
+func (c *logControl) SetLevel(level string) error {
+	return SetLevel(level)
+}
+
+func (c *logControl) EnableTrace(prefixes ...string) {
+	EnableTrace(prefixes...)
+}
+
+func (c *logControl) DisableTrace() {
+	DisableTrace()
+}
+
+func (c *logControl) DumpRecent(w io.Writer, n int) error {
+	if c.rb == nil {
+		return errors.New("no ring buffer to dump recent events from")
+	}
+	events := c.rb.Events()
+	if n > 0 && n < len(events) {
+		events = events[len(events)-n:]
+	}
+	encoder := json.NewEncoder(w)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeControlSocket serves control over a Unix socket at path, which is
+// created with permissions allowing only the current user to connect. Clients
+// send one command per line:
+//
+//	level DEBUG          set the level (level with no argument logs everything)
+//	trace on [a,b,...]   enable tracing for the given prefixes, or everything
+//	trace off            disable tracing enabled with trace on
+//	dump [n]             dump the n most recent events, or all of them
+//
+// level and trace reply with "ok" or "error: <reason>", dump replies with the
+// events followed by a line containing a single ".". Close the returned
+// Closer to stop serving and remove the socket.
+func ServeControlSocket(path string, control LogControl) (io.Closer, error) {
+	// Clean up a socket left behind by a previous run, but nothing else
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%v exists and isn't a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	// Create the socket in a directory that only the current user can access
+	// and restrict it before moving it into place, so that it's never
+	// reachable with the default permissions
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".ctl")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	tmpPath := filepath.Join(dir, "s")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmpPath, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	l.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		l.Close()
+		return nil, err
+	}
+	s := &controlServer{l: l, path: path, control: control}
+	go s.serve()
+	return s, nil
+}
+
+type controlServer struct {
+	l         net.Listener
+	path      string
+	control   LogControl
+	closeOnce sync.Once
+}
+
+func (s *controlServer) serve() {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *controlServer) handle(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if err := s.execute(conn, strings.Fields(scanner.Text())); err != nil {
+			fmt.Fprintf(conn, "error: %v\n", err)
+		}
+	}
+}
+
+func (s *controlServer) execute(w io.Writer, command []string) error {
+	if len(command) == 0 {
+		return nil
+	}
+	args := command[1:]
+	switch command[0] {
+	case "level":
+		level := ""
+		if len(args) > 0 {
+			level = args[0]
+		}
+		if err := s.control.SetLevel(level); err != nil {
+			return err
+		}
+	case "trace":
+		switch {
+		case len(args) > 0 && args[0] == "on":
+			var prefixes []string
+			if len(args) > 1 {
+				prefixes = strings.Split(args[1], ",")
+			}
+			s.control.EnableTrace(prefixes...)
+		case len(args) > 0 && args[0] == "off":
+			s.control.DisableTrace()
+		default:
+			return errors.New("expected trace on or trace off")
+		}
+	case "dump":
+		n := 0
+		if len(args) > 0 {
+			var err error
+			if n, err = strconv.Atoi(args[0]); err != nil {
+				return fmt.Errorf("invalid number of events %q", args[0])
+			}
+		}
+		if err := s.control.DumpRecent(w, n); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, ".\n")
+		return err
+	default:
+		return fmt.Errorf("unknown command %q", command[0])
+	}
+	_, err := io.WriteString(w, "ok\n")
+	return err
+}
+
+// Close stops serving and removes the socket.
+func (s *controlServer) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.l.Close()
+		if removeErr := os.Remove(s.path); removeErr != nil && !os.IsNotExist(removeErr) && err == nil {
+			err = removeErr
+		}
+	})
+	return err
+}
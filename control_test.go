@@ -0,0 +1,135 @@
+package golog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+	defer SetLevel("")
+
+	l := LoggerFor("myprefix")
+	require.NoError(t, SetLevel("error"))
+	assert.Equal(t, "ERROR", Level())
+	l.Debug("dropped")
+	l.Error("kept")
+	assert.Regexp(t, `^ERROR myprefix: control_test.go:[0-9]+ kept\n$`, buf.String())
+	assert.Error(t, SetLevel("loud"))
+
+	require.NoError(t, SetLevel(""))
+	assert.Equal(t, "", Level())
+	buf.Reset()
+	l.Debug("kept")
+	assert.Contains(t, buf.String(), "kept")
+}
+
+func TestEnableTrace(t *testing.T) {
+	l := LoggerFor("myprefix")
+	other := LoggerFor("other")
+	defer DisableTrace()
+	assert.False(t, l.IsTraceEnabled())
+
+	EnableTrace("MyPrefix")
+	assert.True(t, l.IsTraceEnabled())
+	assert.False(t, other.IsTraceEnabled())
+
+	EnableTrace()
+	assert.True(t, other.IsTraceEnabled())
+
+	DisableTrace()
+	assert.False(t, l.IsTraceEnabled())
+}
+
+func TestControlSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-control")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "control.sock")
+
+	rb := NewRingBuffer(nil, 10)
+	reset := SetOutput(rb)
+	defer reset()
+	defer SetLevel("")
+	defer DisableTrace()
+	l := LoggerFor("myprefix")
+	l.Debug("one")
+	l.Debug("two")
+
+	server, err := ServeControlSocket(path, NewLogControl(rb))
+	require.NoError(t, err)
+	defer server.Close()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	command := func(cmd string) string {
+		fmt.Fprintln(conn, cmd)
+		var result string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			result += line
+			if line == "ok\n" || line == ".\n" || len(line) > 6 && line[:6] == "error:" {
+				return result
+			}
+		}
+	}
+
+	assert.Equal(t, "ok\n", command("level error"))
+	assert.Equal(t, "ERROR", Level())
+	assert.Equal(t, "ok\n", command("level"))
+	assert.Equal(t, "", Level())
+	assert.Equal(t, "error: unknown level \"loud\"\n", command("level loud"))
+	assert.Equal(t, "ok\n", command("trace on myprefix"))
+	assert.True(t, l.IsTraceEnabled())
+	assert.Equal(t, "ok\n", command("trace off"))
+	assert.False(t, l.IsTraceEnabled())
+	assert.Regexp(t, `^\{"ts":"[^"]+","msg":"two".*\}\n\.\n$`, command("dump 1"))
+	assert.Equal(t, "error: unknown command \"reboot\"\n", command("reboot"))
+}
+
+func TestControlSocketPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-control")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "control.sock")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("precious"), 0600))
+	_, err = ServeControlSocket(path, NewLogControl(nil))
+	assert.Error(t, err, "existing files that aren't sockets should be left alone")
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "precious", string(b))
+	require.NoError(t, os.Remove(path))
+
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	server, err := ServeControlSocket(path, NewLogControl(nil))
+	require.NoError(t, err, "stale sockets should be replaced")
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	conn.Close()
+
+	require.NoError(t, server.Close())
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files, "closing should remove the socket and nothing should be left behind")
+}
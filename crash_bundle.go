@@ -0,0 +1,171 @@
+package golog
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"time"
+)
+
+const (
+	// CrashBundleFormat identifies crash bundles in their manifest.
+	CrashBundleFormat = "golog-crash-bundle"
+
+	// CrashBundleVersion is the version of the crash bundle format. It's
+	// bumped whenever the layout of bundles changes incompatibly.
+	CrashBundleVersion = 1
+)
+
+// CrashBundleOptions configures the contents of a crash bundle.
+type CrashBundleOptions struct {
+	// RingBuffer, if set, supplies the recent events in events.jsonl.
+	RingBuffer *RingBuffer
+
+	// Query, if set, limits events.jsonl to the events in the RingBuffer
+	// that match it.
+	Query *RingBufferQuery
+
+	// Config, if set, is marshalled to JSON as config.json, for a snapshot of
+	// the program's configuration.
+	Config interface{}
+
+	// Signer, if set, signs the SHA-256 digest of manifest.json, and the
+	// signature is included as manifest.sig. The key must be able to sign
+	// SHA-256 digests, like ECDSA and RSA keys.
+	Signer crypto.Signer
+}
+
+// CrashBundleManifest describes a crash bundle. It's always the first file
+// (manifest.json) in the bundle.
+type CrashBundleManifest struct {
+	Format    string                 `json:"format"`
+	Version   int                    `json:"version"`
+	Created   time.Time              `json:"created"`
+	GoVersion string                 `json:"go_version"`
+	Host      map[string]interface{} `json:"host"`
+	Files     []CrashBundleFile      `json:"files"`
+}
+
+// CrashBundleFile describes a file within a crash bundle.
+type CrashBundleFile struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// WriteCrashBundle writes a crash bundle to w, a gzipped tar archive for
+// support tooling to ingest that contains:
+//
+//	manifest.json    the CrashBundleManifest, listing the files below
+//	manifest.sig     the signature of manifest.json, if signed
+//	events.jsonl     the events in the ring buffer, if there is one
+//	goroutines.txt   a dump of all goroutines' stacks
+//	build.json       the build info of the binary
+//	config.json      the configuration snapshot, if there is one
+func WriteCrashBundle(w io.Writer, opts *CrashBundleOptions) error {
+	type file struct {
+		name string
+		data []byte
+	}
+	var files []file
+	if opts.RingBuffer != nil {
+		events := opts.RingBuffer.Events()
+		if opts.Query != nil {
+			var err error
+			if events, err = opts.RingBuffer.Query(opts.Query); err != nil {
+				return err
+			}
+		}
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		for _, event := range events {
+			if err := encoder.Encode(event); err != nil {
+				return err
+			}
+		}
+		files = append(files, file{"events.jsonl", buf.Bytes()})
+	}
+	var goroutines bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&goroutines, 2); err != nil {
+		return err
+	}
+	files = append(files, file{"goroutines.txt", goroutines.Bytes()})
+	buildInfo, _ := debug.ReadBuildInfo()
+	build, err := json.MarshalIndent(buildInfo, "", "  ")
+	if err != nil {
+		return err
+	}
+	files = append(files, file{"build.json", build})
+	if opts.Config != nil {
+		config, err := json.MarshalIndent(opts.Config, "", "  ")
+		if err != nil {
+			return err
+		}
+		files = append(files, file{"config.json", config})
+	}
+
+	manifest := &CrashBundleManifest{
+		Format:    CrashBundleFormat,
+		Version:   CrashBundleVersion,
+		Created:   time.Now(),
+		GoVersion: runtime.Version(),
+		Host:      getHostMetadata(),
+	}
+	for _, f := range files {
+		sum := sha256.Sum256(f.data)
+		manifest.Files = append(manifest.Files, CrashBundleFile{Name: f.name, Size: len(f.data), SHA256: hex.EncodeToString(sum[:])})
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	head := []file{{"manifest.json", manifestJSON}}
+	if opts.Signer != nil {
+		digest := sha256.Sum256(manifestJSON)
+		sig, err := opts.Signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+		if err != nil {
+			return err
+		}
+		head = append(head, file{"manifest.sig", sig})
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, f := range append(head, files...) {
+		hdr := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.data)), ModTime: manifest.Created}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// WriteCrashBundleFile writes a crash bundle to the file at path, replacing
+// anything that was already there.
+func WriteCrashBundleFile(path string, opts *CrashBundleOptions) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err := WriteCrashBundle(file, opts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,75 @@
+package golog
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCrashBundle(t *testing.T) {
+	rb := NewRingBuffer(nil, 10)
+	reset := SetOutput(rb)
+	defer reset()
+	LoggerFor("myprefix").Debug("before the crash")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteCrashBundle(buf, &CrashBundleOptions{
+		RingBuffer: rb,
+		Config:     map[string]interface{}{"proxy": "example.com"},
+		Signer:     key,
+	}))
+
+	gz, err := gzip.NewReader(buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	var names []string
+	files := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		names = append(names, hdr.Name)
+		files[hdr.Name] = data
+	}
+	assert.Equal(t, []string{"manifest.json", "manifest.sig", "events.jsonl", "goroutines.txt", "build.json", "config.json"}, names)
+
+	var manifest CrashBundleManifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	assert.Equal(t, CrashBundleFormat, manifest.Format)
+	assert.Equal(t, CrashBundleVersion, manifest.Version)
+	require.Len(t, manifest.Files, 4)
+	for _, f := range manifest.Files {
+		sum := sha256.Sum256(files[f.Name])
+		assert.Equal(t, hex.EncodeToString(sum[:]), f.SHA256, f.Name)
+		assert.Equal(t, len(files[f.Name]), f.Size, f.Name)
+	}
+
+	digest := sha256.Sum256(files["manifest.json"])
+	var sig struct{ R, S *big.Int }
+	_, err = asn1.Unmarshal(files["manifest.sig"], &sig)
+	require.NoError(t, err)
+	assert.True(t, ecdsa.Verify(&key.PublicKey, digest[:], sig.R, sig.S), "manifest signature should verify")
+	assert.Contains(t, string(files["events.jsonl"]), "before the crash")
+	assert.Contains(t, string(files["goroutines.txt"]), "TestWriteCrashBundle")
+	assert.JSONEq(t, `{"proxy": "example.com"}`, string(files["config.json"]))
+}
@@ -0,0 +1,142 @@
+package golog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultCrashLoopMaxCrashes     = 3
+	defaultCrashLoopWithin         = 10 * time.Minute
+	defaultCrashLoopRingBufferSize = 1000
+)
+
+// CrashLoopOptions configures DetectCrashLoop.
+type CrashLoopOptions struct {
+	// StateFile is the file in which start times of the process are recorded.
+	StateFile string
+
+	// MaxCrashes is the number of crashes within Within after which the
+	// process is considered to be crash looping. Defaults to 3.
+	MaxCrashes int
+
+	// Within is the window in which crashes are counted. Defaults to 10
+	// minutes.
+	Within time.Duration
+
+	// CrashDumpFile, if set, is where the most recent events are dumped if
+	// the process crash loops and then logs a FATAL error.
+	CrashDumpFile string
+
+	// CrashBundleFile, if set, is where a crash bundle (see
+	// WriteCrashBundle) is written if the process crash loops and then logs
+	// a FATAL error.
+	CrashBundleFile string
+
+	// RingBufferSize is the number of events retained for the crash dump.
+	// Defaults to 1000.
+	RingBufferSize int
+}
+
+// DetectCrashLoop records the start of the process in opts.StateFile and
+// checks whether the process has crashed opts.MaxCrashes times within
+// opts.Within. A start counts as a crash unless the process called the
+// returned cleanShutdown function before exiting.
+//
+// If the process is crash looping, golog escalates to emergency verbosity:
+// TRACE logging and stack dumps are enabled for all loggers, and, if
+// opts.CrashDumpFile or opts.CrashBundleFile is set, the current output is
+// wrapped in a RingBuffer that dumps recent events or a crash bundle on FATAL
+// errors. Note that TraceOut writers obtained before escalation keep
+// discarding their output.
+//
+// This should be called early in main.
+func DetectCrashLoop(opts *CrashLoopOptions) (crashLooping bool, cleanShutdown func(), err error) {
+	maxCrashes := opts.MaxCrashes
+	if maxCrashes <= 0 {
+		maxCrashes = defaultCrashLoopMaxCrashes
+	}
+	within := opts.Within
+	if within <= 0 {
+		within = defaultCrashLoopWithin
+	}
+
+	now := time.Now()
+	starts, err := readStartTimes(opts.StateFile, now.Add(-within))
+	if err != nil {
+		return false, func() {}, err
+	}
+	crashLooping = len(starts) >= maxCrashes
+
+	starts = append(starts, now)
+	if err := writeStartTimes(opts.StateFile, starts); err != nil {
+		return false, func() {}, err
+	}
+	cleanShutdown = func() {
+		if err := os.Remove(opts.StateFile); err != nil && !os.IsNotExist(err) {
+			errorOnLogging(err)
+		}
+	}
+
+	if crashLooping {
+		enableEmergencyVerbosity(opts)
+	}
+	return crashLooping, cleanShutdown, nil
+}
+
+func enableEmergencyVerbosity(opts *CrashLoopOptions) {
+	atomic.StoreInt32(&emergencyVerbosity, 1)
+	if opts.CrashDumpFile != "" || opts.CrashBundleFile != "" {
+		size := opts.RingBufferSize
+		if size <= 0 {
+			size = defaultCrashLoopRingBufferSize
+		}
+		outputMx.RLock()
+		current := output
+		outputMx.RUnlock()
+		rb := NewRingBuffer(current, size)
+		rb.DumpOnFatal(opts.CrashDumpFile)
+		rb.BundleOnFatal(opts.CrashBundleFile, nil)
+		SetOutput(rb)
+	}
+	_, _ = fmt.Fprintf(stderr, "Crash loop detected, TRACE logging and stack dumps are enabled\n")
+}
+
+// readStartTimes reads the start times recorded in path that are after since.
+func readStartTimes(path string, since time.Time) ([]time.Time, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var starts []time.Time
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		nanos, err := strconv.ParseInt(scanner.Text(), 10, 64)
+		if err != nil {
+			// ignore corrupt lines
+			continue
+		}
+		if start := time.Unix(0, nanos); start.After(since) {
+			starts = append(starts, start)
+		}
+	}
+	return starts, nil
+}
+
+func writeStartTimes(path string, starts []time.Time) error {
+	var buf bytes.Buffer
+	for _, start := range starts {
+		buf.WriteString(strconv.FormatInt(start.UnixNano(), 10))
+		buf.WriteByte('\n')
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,38 @@
+package golog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectCrashLoop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer atomic.StoreInt32(&emergencyVerbosity, 0)
+
+	opts := &CrashLoopOptions{
+		StateFile:  filepath.Join(dir, "starts"),
+		MaxCrashes: 2,
+	}
+	for i := 0; i < 2; i++ {
+		crashLooping, _, err := DetectCrashLoop(opts)
+		require.NoError(t, err)
+		assert.False(t, crashLooping)
+	}
+	crashLooping, cleanShutdown, err := DetectCrashLoop(opts)
+	require.NoError(t, err)
+	assert.True(t, crashLooping)
+	assert.True(t, LoggerFor("crashloop").IsTraceEnabled())
+
+	cleanShutdown()
+	crashLooping, _, err = DetectCrashLoop(opts)
+	require.NoError(t, err)
+	assert.False(t, crashLooping, "clean shutdown should reset crash count")
+}
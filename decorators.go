@@ -0,0 +1,83 @@
+package golog
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Decorators is an ordered chain of functions that write something at the
+// start (Prependers) or at the end (Appenders) of each message written by a
+// text output, for example a timestamp or the hostname.
+type Decorators struct {
+	Prependers []func(io.Writer)
+	Appenders  []func(io.Writer)
+}
+
+func (d *Decorators) prepend(w io.Writer) {
+	if d == nil {
+		return
+	}
+	for _, p := range d.Prependers {
+		p(w)
+	}
+}
+
+func (d *Decorators) append(w io.Writer) {
+	if d == nil {
+		return
+	}
+	for _, a := range d.Appenders {
+		a(w)
+	}
+}
+
+var (
+	globalDecorators atomic.Value
+	loggerDecorators sync.Map
+)
+
+// SetDecorators sets the chain of decorators applied by all text outputs.
+// They run after the prepender set with SetPrepender. Pass nil to clear them.
+func SetDecorators(d *Decorators) {
+	globalDecorators.Store(&d)
+}
+
+func getDecorators() *Decorators {
+	d, _ := globalDecorators.Load().(**Decorators)
+	if d == nil {
+		return nil
+	}
+	return *d
+}
+
+// SetLoggerDecorators sets the chain of decorators applied to messages from
+// loggers with the given prefix, see LoggerFor. Pass nil to clear them.
+func SetLoggerDecorators(prefix string, d *Decorators) {
+	if d == nil {
+		loggerDecorators.Delete(prefix)
+		return
+	}
+	loggerDecorators.Store(prefix, d)
+}
+
+// decoratorsForLogger returns the decorators for the logger with the given
+// prefix, which includes the trailing ": ".
+func decoratorsForLogger(prefix string) *Decorators {
+	d, found := loggerDecorators.Load(strings.TrimSuffix(prefix, ": "))
+	if !found {
+		return nil
+	}
+	return d.(*Decorators)
+}
+
+// TextOutputWithDecorators is like TextOutput but also applies the given
+// decorators to all messages it writes. Prependers run in order global, then
+// output, then logger, and appenders in the reverse order, so that each
+// chain's output nests around the message.
+func TextOutputWithDecorators(errorWriter io.Writer, debugWriter io.Writer, d *Decorators) Output {
+	o := TextOutput(errorWriter, debugWriter).(*textOutput)
+	o.decorators = d
+	return o
+}
@@ -0,0 +1,36 @@
+package golog
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecorators(t *testing.T) {
+	writer := func(s string) func(io.Writer) {
+		return func(w io.Writer) {
+			io.WriteString(w, s)
+		}
+	}
+
+	SetDecorators(&Decorators{Prependers: []func(io.Writer){writer("<g>")}, Appenders: []func(io.Writer){writer("</g>")}})
+	defer SetDecorators(nil)
+	SetLoggerDecorators("decorated", &Decorators{Prependers: []func(io.Writer){writer("<l>")}, Appenders: []func(io.Writer){writer("</l>")}})
+	defer SetLoggerDecorators("decorated", nil)
+
+	out := &bytes.Buffer{}
+	reset := SetOutput(TextOutputWithDecorators(out, out, &Decorators{
+		Prependers: []func(io.Writer){writer("<o1>"), writer("<o2>")},
+		Appenders:  []func(io.Writer){writer("</o>")},
+	}))
+	defer reset()
+
+	LoggerFor("decorated").Debug("hello")
+	assert.Regexp(t, `^<g><o1><o2><l>DEBUG decorated: decorators_test.go:[0-9]+ hello</l></o></g>\n$`, out.String())
+
+	out.Reset()
+	LoggerFor("plain").Debug("hello")
+	assert.Regexp(t, `^<g><o1><o2>DEBUG plain: decorators_test.go:[0-9]+ hello</o></g>\n$`, out.String())
+}
@@ -0,0 +1,69 @@
+package golog
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Deprecation records a deprecation warning that was triggered, see
+// Logger.Deprecated.
+type Deprecation struct {
+	// Message is the deprecation message.
+	Message string
+	// Caller is where Deprecated was called from, as file:line.
+	Caller string
+	// CalledFrom is the caller of the function that called Deprecated, as
+	// file:line, when the warning was first triggered.
+	CalledFrom string
+	// Time is when the warning was first triggered.
+	Time time.Time
+}
+
+var (
+	deprecations   = make(map[uintptr]*Deprecation)
+	deprecationsMx sync.Mutex
+)
+
+func (l *logger) Deprecated(message string) {
+	pcs := make([]uintptr, 2)
+	n := runtime.Callers(2, pcs)
+	if n == 0 {
+		return
+	}
+	deprecationsMx.Lock()
+	if _, found := deprecations[pcs[0]]; found {
+		deprecationsMx.Unlock()
+		return
+	}
+	d := &Deprecation{Message: message, Caller: frameLocation(pcs[:1]), Time: time.Now()}
+	if n > 1 {
+		d.CalledFrom = frameLocation(pcs[1:2])
+	}
+	deprecations[pcs[0]] = d
+	deprecationsMx.Unlock()
+
+	fields := []Field{{"deprecated", true}}
+	if d.CalledFrom != "" {
+		fields = append(fields, Field{"called_from", d.CalledFrom})
+	}
+	l.logSkipFrames(WithFields("DEPRECATED: "+message, fields...), 1, WARN)
+}
+
+func frameLocation(pc []uintptr) string {
+	return string(appendCaller(nil, firstFrame(pc)))
+}
+
+// Deprecations returns the deprecation warnings triggered so far, oldest
+// first.
+func Deprecations() []Deprecation {
+	deprecationsMx.Lock()
+	result := make([]Deprecation, 0, len(deprecations))
+	for _, d := range deprecations {
+		result = append(result, *d)
+	}
+	deprecationsMx.Unlock()
+	sort.Slice(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	return result
+}
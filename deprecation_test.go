@@ -0,0 +1,42 @@
+package golog
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecated(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+	active, reported := true, 0
+	RegisterReporterAt(WARN, func(err error, severity Severity, ctx map[string]interface{}) {
+		if active && strings.HasPrefix(err.Error(), "DEPRECATED: ") {
+			reported++
+		}
+	})
+	defer func() { active = false }()
+
+	l := LoggerFor("myprefix")
+	oldAPI := func() {
+		l.Deprecated("oldAPI is deprecated, use newAPI")
+	}
+	for i := 0; i < 3; i++ {
+		oldAPI()
+	}
+	assert.Equal(t, 1, strings.Count(buf.String(), "DEPRECATED"))
+	assert.Equal(t, 1, reported, "deprecations should be reported as warnings")
+	assert.Regexp(t, `^WARN myprefix: deprecation_test.go:[0-9]+ DEPRECATED: oldAPI is deprecated, use newAPI \[called_from=deprecation_test.go:[0-9]+ deprecated=true\]`, buf.String())
+
+	var found bool
+	for _, d := range Deprecations() {
+		if d.Message == "oldAPI is deprecated, use newAPI" {
+			found = true
+			assert.Regexp(t, `^deprecation_test.go:[0-9]+$`, d.Caller)
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,144 @@
+package golog
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+const (
+	maxDiffChanges = 50
+	maxDiffDepth   = 10
+)
+
+// DiffChange is the before and after value of a path that Diff found to have
+// changed. A nil Before means the path was added, a nil After that it was
+// removed.
+type DiffChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+func (c DiffChange) String() string {
+	return fmt.Sprintf("%v -> %v", c.Before, c.After)
+}
+
+// Diff compares old and new, such as configurations before and after a
+// reload, and returns fields for logging what changed instead of entire
+// structs:
+//
+//	l.Debugw("config reloaded", golog.Diff(oldConfig, newConfig)...)
+//
+// The "diff" field maps the path of each changed value, like
+// "Servers[0].Port", to a DiffChange. Structs (exported fields only), maps,
+// slices, arrays and pointers are compared recursively, other values with
+// reflect.DeepEqual. At most 50 changes are recorded and nesting beyond 10
+// levels is compared as a whole. If changes were left out, a "diff_truncated"
+// field has the number left out.
+func Diff(old, new interface{}) []Field {
+	d := &differ{changes: make(map[string]interface{})}
+	d.diff("", reflect.ValueOf(old), reflect.ValueOf(new), 0)
+	fields := []Field{{Key: "diff", Value: d.changes}}
+	if d.truncated > 0 {
+		fields = append(fields, Field{Key: "diff_truncated", Value: d.truncated})
+	}
+	return fields
+}
+
+type differ struct {
+	changes   map[string]interface{}
+	truncated int
+}
+
+func (d *differ) diff(path string, a, b reflect.Value, depth int) {
+	if !a.IsValid() || !b.IsValid() || a.Type() != b.Type() || depth >= maxDiffDepth {
+		d.compare(path, a, b)
+		return
+	}
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			d.compare(path, a, b)
+			return
+		}
+		d.diff(path, a.Elem(), b.Elem(), depth+1)
+	case reflect.Struct:
+		exported := 0
+		for i := 0; i < a.NumField(); i++ {
+			field := a.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			exported++
+			d.diff(joinPath(path, field.Name), a.Field(i), b.Field(i), depth+1)
+		}
+		if exported == 0 {
+			// Opaque, like time.Time
+			d.compare(path, a, b)
+		}
+	case reflect.Map:
+		keys := make(map[string]reflect.Value)
+		for _, key := range append(a.MapKeys(), b.MapKeys()...) {
+			keys[fmt.Sprint(key.Interface())] = key
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			key := keys[name]
+			d.diff(fmt.Sprintf("%v[%v]", path, name), a.MapIndex(key), b.MapIndex(key), depth+1)
+		}
+	case reflect.Slice, reflect.Array:
+		n := a.Len()
+		if b.Len() > n {
+			n = b.Len()
+		}
+		for i := 0; i < n; i++ {
+			var ai, bi reflect.Value
+			if i < a.Len() {
+				ai = a.Index(i)
+			}
+			if i < b.Len() {
+				bi = b.Index(i)
+			}
+			d.diff(fmt.Sprintf("%v[%d]", path, i), ai, bi, depth+1)
+		}
+	default:
+		d.compare(path, a, b)
+	}
+}
+
+// compare records a change at path if a and b differ.
+func (d *differ) compare(path string, a, b reflect.Value) {
+	before, after := interfaceOf(a), interfaceOf(b)
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+	if path == "" {
+		path = "."
+	}
+	if len(d.changes) >= maxDiffChanges {
+		d.truncated++
+		return
+	}
+	d.changes[path] = DiffChange{Before: before, After: after}
+}
+
+func interfaceOf(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return nil
+	}
+	return v.Interface()
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
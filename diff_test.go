@@ -0,0 +1,66 @@
+package golog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type diffServer struct {
+	Host string
+	Port int
+}
+
+type diffConfig struct {
+	Name     string
+	Servers  []diffServer
+	Limits   map[string]int
+	Upstream *diffServer
+	secret   string
+}
+
+func TestDiff(t *testing.T) {
+	old := &diffConfig{
+		Name:    "a",
+		Servers: []diffServer{{"one", 80}, {"two", 80}},
+		Limits:  map[string]int{"conns": 10, "rate": 5},
+		secret:  "old",
+	}
+	new := &diffConfig{
+		Name:     "a",
+		Servers:  []diffServer{{"one", 8080}},
+		Limits:   map[string]int{"conns": 20, "burst": 1},
+		Upstream: &diffServer{"up", 443},
+		secret:   "new",
+	}
+	fields := Diff(old, new)
+	assert.Equal(t, []Field{{Key: "diff", Value: map[string]interface{}{
+		"Servers[0].Port": DiffChange{80, 8080},
+		"Servers[1]":      DiffChange{diffServer{"two", 80}, nil},
+		"Limits[burst]":   DiffChange{nil, 1},
+		"Limits[conns]":   DiffChange{10, 20},
+		"Limits[rate]":    DiffChange{5, nil},
+		"Upstream":        DiffChange{nil, &diffServer{"up", 443}},
+	}}}, fields)
+
+	assert.Equal(t, []Field{{Key: "diff", Value: map[string]interface{}{".": DiffChange{1, 2}}}}, Diff(1, 2))
+	assert.Equal(t, []Field{{Key: "diff", Value: map[string]interface{}{}}}, Diff(old, old))
+
+	big, bigger := make([]int, 60), make([]int, 60)
+	for i := range bigger {
+		bigger[i] = i + 1
+	}
+	fields = Diff(big, bigger)
+	assert.Len(t, fields[0].Value, 50)
+	assert.Equal(t, Field{Key: "diff_truncated", Value: 10}, fields[1])
+}
+
+func TestDiffLogging(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	LoggerFor("myprefix").Debugw("config reloaded", Diff(diffServer{"a", 80}, diffServer{"a", 81})...)
+	assert.Regexp(t, `^DEBUG myprefix: diff_test.go:[0-9]+ config reloaded \[diff=map\[Port:80 -> 81\]\]\n$`, buf.String())
+}
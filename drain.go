@@ -0,0 +1,35 @@
+package golog
+
+import (
+	"context"
+	"time"
+)
+
+// drainPollInterval is how often waitForDrain checks whether an output has
+// drained.
+const drainPollInterval = 10 * time.Millisecond
+
+// DrainableOutput is a ClosableOutput that delivers events in the background
+// and can wait for them to be delivered, so that services can bound how long
+// shutdown takes.
+type DrainableOutput interface {
+	ClosableOutput
+
+	// WaitForDrain waits until everything logged so far has been delivered,
+	// or until ctx is done, in which case it returns ctx.Err().
+	WaitForDrain(ctx context.Context) error
+}
+
+// waitForDrain polls drained until it returns true or ctx is done.
+func waitForDrain(ctx context.Context, drained func() bool) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for !drained() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package golog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FieldProvider supplies a field that's added to every event, for slowly
+// changing operational state like the current datacenter. An empty key adds
+// nothing.
+type FieldProvider func() (key string, value interface{})
+
+type dynamicField struct {
+	provider FieldProvider
+	ttl      time.Duration
+	mx       sync.Mutex
+	key      string
+	value    interface{}
+	expires  time.Time
+	// refreshing is set while provider is being called, without holding mx
+	refreshing bool
+}
+
+var (
+	// dynamicFields is a []*dynamicField, replaced whenever providers are
+	// registered or unregistered
+	dynamicFields   atomic.Value
+	dynamicFieldsMx sync.Mutex
+)
+
+// RegisterFieldProvider registers a provider whose field is added to every
+// event that doesn't already have a field with the same key, from the call
+// site, the ops context or the logger's bound fields. The provider is called
+// at log time, at most once per ttl (every time if ttl is 0), with the field
+// cached in between. Events logged while the provider is being called,
+// including by the provider itself, get the previously cached field, if any.
+// Call unregister to stop adding the field.
+func RegisterFieldProvider(ttl time.Duration, provider FieldProvider) (unregister func()) {
+	field := &dynamicField{provider: provider, ttl: ttl}
+	dynamicFieldsMx.Lock()
+	existing, _ := dynamicFields.Load().([]*dynamicField)
+	dynamicFields.Store(append(existing[:len(existing):len(existing)], field))
+	dynamicFieldsMx.Unlock()
+
+	return func() {
+		dynamicFieldsMx.Lock()
+		defer dynamicFieldsMx.Unlock()
+		existing, _ := dynamicFields.Load().([]*dynamicField)
+		updated := make([]*dynamicField, 0, len(existing))
+		for _, f := range existing {
+			if f != field {
+				updated = append(updated, f)
+			}
+		}
+		dynamicFields.Store(updated)
+	}
+}
+
+// dynamicFieldValues returns the current fields of all providers, or nil if
+// there are none, in a map from acquireContext that the caller may release.
+func dynamicFieldValues() map[string]interface{} {
+	fields, _ := dynamicFields.Load().([]*dynamicField)
+	if len(fields) == 0 {
+		return nil
+	}
+	values := acquireContext()
+	for _, field := range fields {
+		if key, value := field.get(); key != "" {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+func (f *dynamicField) get() (string, interface{}) {
+	f.mx.Lock()
+	now := time.Now()
+	if f.refreshing || (f.ttl > 0 && now.Before(f.expires)) {
+		key, value := f.key, f.value
+		f.mx.Unlock()
+		return key, value
+	}
+	f.refreshing = true
+	f.mx.Unlock()
+
+	// The provider is called without holding mx, as it may log
+	var key string
+	var value interface{}
+	defer func() {
+		f.mx.Lock()
+		f.key, f.value = key, value
+		f.expires = now.Add(f.ttl)
+		f.refreshing = false
+		f.mx.Unlock()
+	}()
+	key, value = f.provider()
+	return key, value
+}
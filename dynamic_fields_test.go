@@ -0,0 +1,107 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterFieldProvider(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutput(JsonOutput(buf, buf))
+	defer reset()
+
+	calls := 0
+	datacenter := "ams"
+	unregister := RegisterFieldProvider(time.Hour, func() (string, interface{}) {
+		calls++
+		return "datacenter", datacenter
+	})
+	unregisterEmpty := RegisterFieldProvider(0, func() (string, interface{}) { return "", nil })
+	defer unregisterEmpty()
+
+	log := func(arg interface{}) *Event {
+		buf.Reset()
+		LoggerFor("myprefix").Debug(arg)
+		var event Event
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+		return &event
+	}
+
+	assert.Equal(t, "ams", log("one").Context["datacenter"])
+	datacenter = "fra"
+	assert.Equal(t, "ams", log("two").Context["datacenter"], "field should be cached for the ttl")
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "lon", log(WithFields("three", Field{"datacenter", "lon"})).Context["datacenter"], "call site fields should win")
+
+	unregister()
+	_, found := log("four").Context["datacenter"]
+	assert.False(t, found)
+	assert.NotContains(t, log("five").Context, "")
+}
+
+func TestFieldProviderTTL(t *testing.T) {
+	calls := 0
+	unregister := RegisterFieldProvider(5*time.Millisecond, func() (string, interface{}) {
+		calls++
+		return "circuit", calls
+	})
+	defer unregister()
+
+	assert.Equal(t, 1, dynamicFieldValues()["circuit"])
+	assert.Equal(t, 1, dynamicFieldValues()["circuit"])
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 2, dynamicFieldValues()["circuit"], "field should be refreshed after the ttl")
+}
+
+func TestFieldProviderCollisionPrefix(t *testing.T) {
+	SetKeyNormalization(&KeyNormalization{Collisions: KeyCollisionPrefix})
+	defer SetKeyNormalization(nil)
+	unregister := RegisterFieldProvider(0, func() (string, interface{}) { return "region", "eu" })
+	defer unregister()
+
+	values := eventValues(WithFields("msg", Field{"region", "us"}), nil)
+	assert.Equal(t, "us", values["region"])
+	assert.Equal(t, "eu", values["dyn_region"])
+}
+
+func TestFieldProviderThatLogs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutput(JsonOutput(buf, buf))
+	defer reset()
+
+	log := LoggerFor("myprefix")
+	unregister := RegisterFieldProvider(0, func() (string, interface{}) {
+		log.Debug("looking up datacenter")
+		return "datacenter", "ams"
+	})
+	defer unregister()
+
+	done := make(chan struct{})
+	go func() {
+		log.Debug("one")
+		log.Debug("two")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging from a provider shouldn't deadlock")
+	}
+
+	var messages []string
+	var datacenters []interface{}
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var event Event
+		require.NoError(t, decoder.Decode(&event))
+		messages = append(messages, event.Message)
+		datacenters = append(datacenters, event.Context["datacenter"])
+	}
+	assert.Equal(t, []string{"looking up datacenter", "one", "looking up datacenter", "two"}, messages)
+	assert.Equal(t, []interface{}{nil, "ams", "ams", "ams"}, datacenters, "events logged by the provider should get the cached field")
+}
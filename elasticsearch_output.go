@@ -0,0 +1,517 @@
+package golog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultElasticsearchIndex         = "golog-{date}"
+	defaultElasticsearchDateFormat    = "2006.01.02"
+	defaultElasticsearchBatchSize     = 500
+	defaultElasticsearchFlushInterval = 5 * time.Second
+	defaultElasticsearchMaxQueued     = 10000
+	defaultElasticsearchMaxRetries    = 5
+	elasticsearchMinBackoff           = 500 * time.Millisecond
+	elasticsearchMaxBackoff           = 1 * time.Minute
+
+	// elasticsearchCloseTimeout bounds how long Close keeps retrying events
+	// that the cluster pushes back on.
+	elasticsearchCloseTimeout = 5 * time.Second
+)
+
+// ElasticsearchOptions configures an Elasticsearch (or OpenSearch) output.
+type ElasticsearchOptions struct {
+	// URL is the base URL of the cluster, e.g. "http://localhost:9200".
+	URL string
+
+	// Index is the template for the index to which events are written. It may
+	// reference the placeholders {component}, {severity} and {date}. Defaults
+	// to "golog-{date}".
+	Index string
+
+	// DateFormat is the time layout used for the {date} placeholder. Defaults
+	// to "2006.01.02", giving one index per day.
+	DateFormat string
+
+	// BatchSize is the maximum number of events sent in a single bulk request.
+	// Defaults to 500.
+	BatchSize int
+
+	// FlushInterval is how often queued events are sent even if a full batch
+	// hasn't accumulated. Defaults to 5 seconds.
+	FlushInterval time.Duration
+
+	// MaxQueued bounds the number of events waiting to be sent, including
+	// events waiting to be retried. Once the queue is full, new events are
+	// dropped. Defaults to 10000.
+	MaxQueued int
+
+	// MaxRetries is the number of times an event that was rejected with a 429
+	// or a server error is retried before being dropped. Defaults to 5.
+	MaxRetries int
+
+	// Backoff configures how delivery backs off while the cluster pushes back.
+	// Its MaxRetries is ignored in favour of MaxRetries above. Defaults to a
+	// base delay of 500 milliseconds and a maximum delay of 1 minute.
+	Backoff *BackoffOptions
+
+	// Username and Password, if set, are sent using basic authentication.
+	Username string
+	Password string
+
+	// Token, if set, supplies a bearer token for each request, in place of
+	// basic authentication. While requests are unauthorized, or the token
+	// can't be obtained, events are retried like when the cluster is
+	// unavailable.
+	Token TokenSource
+
+	// InvalidateToken, if set, is called whenever the cluster rejects the
+	// token with a 401, so that a cached token gets replaced, see
+	// CachedTokenSourceWithInvalidate.
+	InvalidateToken func()
+
+	// Client is the http.Client used to talk to the cluster. Defaults to a
+	// client created with NewHTTPClient(Transport).
+	Client *http.Client
+
+	// Transport configures TLS and proxying when Client isn't set.
+	Transport *TransportOptions
+
+	// Compression, if set, compresses bulk requests. Elasticsearch accepts
+	// gzip and deflate once http.compression is enabled.
+	Compression *CompressionOptions
+
+	// Context, once done, aborts in-flight requests and stops delivery for
+	// good. Events that haven't been delivered by then, including queued ones
+	// and ones logged afterwards, are dropped rather than flushed, and counted
+	// in the drops reported on stderr. To deliver queued events at shutdown,
+	// call WaitForDrain or Close before cancelling it. Defaults to
+	// context.Background().
+	Context context.Context
+
+	// Clock times flushes and backoff and decides whether events are
+	// delivered late. Defaults to the real clock.
+	Clock Clock
+}
+
+func (opts *ElasticsearchOptions) applyDefaults() {
+	if opts.Index == "" {
+		opts.Index = defaultElasticsearchIndex
+	}
+	if opts.DateFormat == "" {
+		opts.DateFormat = defaultElasticsearchDateFormat
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultElasticsearchBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultElasticsearchFlushInterval
+	}
+	if opts.MaxQueued <= 0 {
+		opts.MaxQueued = defaultElasticsearchMaxQueued
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = defaultElasticsearchMaxRetries
+	}
+	if opts.Client == nil {
+		opts.Client = NewHTTPClient(opts.Transport)
+	}
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
+	if opts.Clock == nil {
+		opts.Clock = realClock{}
+	}
+	backoff := BackoffOptions{BaseDelay: elasticsearchMinBackoff, MaxDelay: elasticsearchMaxBackoff}
+	if opts.Backoff != nil {
+		backoff = *opts.Backoff
+		backoff.MaxRetries = 0
+	}
+	opts.Backoff = &backoff
+	opts.URL = strings.TrimSuffix(opts.URL, "/")
+}
+
+// ElasticsearchOutput creates an output that ships JSON structured events to
+// Elasticsearch or OpenSearch using the _bulk API. Events are queued in memory
+// and sent by a background goroutine. When the cluster pushes back with a 429
+// or is unavailable, delivery backs off exponentially and the affected events
+// are retried up to MaxRetries times. Close the output to flush remaining
+// events, or use WaitForDrain to bound how long to wait for them. Once
+// opts.Context is done, undelivered events are dropped instead, as are events
+// that Close can't deliver within 5 seconds.
+func ElasticsearchOutput(opts *ElasticsearchOptions) DrainableOutput {
+	o := &elasticsearchOutput{
+		opts:       *opts,
+		batchReady: make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	o.opts.applyDefaults()
+	o.codecs = newCompressionNegotiator(o.opts.Compression)
+	go o.run()
+	return o
+}
+
+type elasticsearchOutput struct {
+	opts       ElasticsearchOptions
+	codecs     *compressionNegotiator
+	mx         sync.Mutex
+	queue      []*bulkItem
+	inFlight   int
+	dropped    int
+	batchReady chan struct{}
+	stop       chan struct{}
+	stopOnce   sync.Once
+	done       chan struct{}
+}
+
+type bulkItem struct {
+	index    string
+	doc      *elasticsearchDocument
+	attempts int
+}
+
+type elasticsearchDocument struct {
+	Timestamp     time.Time `json:"@timestamp"`
+	DeliveredLate bool      `json:"delivered_late,omitempty"`
+	*Event
+}
+
+// MarshalJSON encodes d like its Event, but with the time as "@timestamp"
+// rather than "ts", as Elasticsearch expects.
+func (d *elasticsearchDocument) MarshalJSON() ([]byte, error) {
+	j := d.Event.toJSON()
+	j.Timestamp = nil
+	return json.Marshal(&struct {
+		Timestamp     time.Time `json:"@timestamp"`
+		DeliveredLate bool      `json:"delivered_late,omitempty"`
+		*eventJSON
+	}{d.Timestamp, d.DeliveredLate, j})
+}
+
+// UnmarshalJSON decodes d from the JSON encoding of an elasticsearchDocument.
+func (d *elasticsearchDocument) UnmarshalJSON(b []byte) error {
+	j := &struct {
+		Timestamp     time.Time `json:"@timestamp"`
+		DeliveredLate bool      `json:"delivered_late,omitempty"`
+		*eventJSON
+	}{eventJSON: &eventJSON{}}
+	if err := json.Unmarshal(b, j); err != nil {
+		return err
+	}
+	event := j.toEvent()
+	event.Timestamp = j.Timestamp
+	*d = elasticsearchDocument{Timestamp: j.Timestamp, DeliveredLate: j.DeliveredLate, Event: &event}
+	return nil
+}
+
+func (o *elasticsearchOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.print(prefix, skipFrames, printStack, severity, arg, values)
+}
+
+func (o *elasticsearchOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.print(prefix, skipFrames, printStack, severity, arg, values)
+}
+
+func (o *elasticsearchOutput) print(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	event := buildEvent(make([]uintptr, 10), prefix, skipFrames, printStack, severity, arg, values)
+	now := eventTime(arg)
+	doc := &elasticsearchDocument{Timestamp: now, Event: event}
+	index := strings.Replace(o.opts.Index, "{date}", now.Format(o.opts.DateFormat), -1)
+	index = expandEventTemplate(index, event, indexToken)
+
+	o.mx.Lock()
+	// Once the context is done, nothing will deliver the queue anymore
+	if len(o.queue) >= o.opts.MaxQueued || o.opts.Context.Err() != nil {
+		o.dropped++
+		o.mx.Unlock()
+		return
+	}
+	o.queue = append(o.queue, &bulkItem{index: index, doc: doc})
+	full := len(o.queue) >= o.opts.BatchSize
+	o.mx.Unlock()
+
+	if full {
+		select {
+		case o.batchReady <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (o *elasticsearchOutput) run() {
+	defer close(o.done)
+	flushDue := o.opts.Clock.After(o.opts.FlushInterval)
+
+	backoff := NewBackoff(o.opts.Backoff)
+	for {
+		select {
+		case <-o.stop:
+			o.flushOnStop(backoff)
+			return
+		case <-o.opts.Context.Done():
+			o.abandon()
+			return
+		case <-flushDue:
+			flushDue = o.opts.Clock.After(o.opts.FlushInterval)
+		case <-o.batchReady:
+		}
+
+		if !o.flush() {
+			backoff.Reset()
+			continue
+		}
+
+		delay, _ := backoff.Next()
+		select {
+		case <-o.stop:
+			o.flushOnStop(backoff)
+			return
+		case <-o.opts.Context.Done():
+			o.abandon()
+			return
+		case <-o.opts.Clock.After(delay):
+		}
+	}
+}
+
+// flushOnStop flushes the queue on Close, backing off and retrying what the
+// cluster pushes back on until elasticsearchCloseTimeout has passed, and then
+// drops whatever is left.
+func (o *elasticsearchOutput) flushOnStop(backoff *Backoff) {
+	deadline := o.opts.Clock.After(elasticsearchCloseTimeout)
+	for o.flush() {
+		delay, _ := backoff.Next()
+		select {
+		case <-deadline:
+			o.abandon()
+			return
+		case <-o.opts.Context.Done():
+			o.abandon()
+			return
+		case <-o.opts.Clock.After(delay):
+		}
+	}
+	o.abandon()
+}
+
+// abandon drops whatever is still queued once the context is done, or once
+// Close gives up.
+func (o *elasticsearchOutput) abandon() {
+	o.mx.Lock()
+	o.dropped += len(o.queue)
+	o.queue = nil
+	o.mx.Unlock()
+	o.reportDropped()
+}
+
+// flush sends all queued events in batches, stopping early if the cluster
+// asks us to back off, in which case it returns true.
+func (o *elasticsearchOutput) flush() (backoff bool) {
+	o.reportDropped()
+	for o.opts.Context.Err() == nil {
+		o.mx.Lock()
+		n := len(o.queue)
+		if n > o.opts.BatchSize {
+			n = o.opts.BatchSize
+		}
+		batch := o.queue[:n:n]
+		o.queue = o.queue[n:]
+		o.inFlight = n
+		o.mx.Unlock()
+
+		if len(batch) == 0 {
+			return false
+		}
+
+		retry, err := o.send(batch)
+		if err != nil && o.opts.Context.Err() == nil {
+			errorOnLogging(err)
+		}
+		if len(retry) > 0 {
+			o.requeue(retry)
+		}
+		o.mx.Lock()
+		o.inFlight = 0
+		o.mx.Unlock()
+		if len(retry) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// send posts a batch to the _bulk endpoint and returns the items that should
+// be retried.
+func (o *elasticsearchOutput) send(batch []*bulkItem) ([]*bulkItem, error) {
+	var body bytes.Buffer
+	sent := make([]*bulkItem, 0, len(batch))
+	for _, item := range batch {
+		item.doc.DeliveredLate = deliveredLate(o.opts.Clock.Now(), item.doc.Timestamp, item.attempts > 0)
+		doc, err := json.Marshal(item.doc)
+		if err != nil {
+			errorOnLogging(err)
+			continue
+		}
+		fmt.Fprintf(&body, `{"index":{"_index":%q}}`, item.index)
+		body.WriteByte('\n')
+		body.Write(doc)
+		body.WriteByte('\n')
+		sent = append(sent, item)
+	}
+	batch = sent
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	resp, err := o.codecs.do(o.opts.Client, body.Bytes(), func(body io.Reader) (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, o.opts.URL+"/_bulk", body)
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(o.opts.Context)
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		if o.opts.Token != nil {
+			token, err := o.opts.Token()
+			if err != nil {
+				return nil, fmt.Errorf("unable to get token: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else if o.opts.Username != "" || o.opts.Password != "" {
+			req.SetBasicAuth(o.opts.Username, o.opts.Password)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return batch, fmt.Errorf("unable to send events to elasticsearch: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return batch, nil
+	}
+	if resp.StatusCode == http.StatusUnauthorized && o.opts.Token != nil {
+		// the token may have been revoked or be about to be refreshed
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		if o.opts.InvalidateToken != nil {
+			o.opts.InvalidateToken()
+		}
+		return batch, fmt.Errorf("elasticsearch rejected token with status %v", resp.Status)
+	}
+	if resp.StatusCode >= 300 {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return nil, fmt.Errorf("elasticsearch rejected %d events with status %v", len(batch), resp.Status)
+	}
+
+	// A successful bulk request may still have failed for individual items
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("unable to decode elasticsearch response for %d events: %v", len(batch), err)
+	}
+	if !result.Errors {
+		return nil, nil
+	}
+	var retry []*bulkItem
+	rejected := 0
+	for i, item := range result.Items {
+		if i >= len(batch) {
+			break
+		}
+		for _, status := range item {
+			switch {
+			case status.Status == http.StatusTooManyRequests || status.Status >= 500:
+				retry = append(retry, batch[i])
+			case status.Status >= 300:
+				rejected++
+			}
+		}
+	}
+	if rejected > 0 {
+		return retry, fmt.Errorf("elasticsearch rejected %d events", rejected)
+	}
+	return retry, nil
+}
+
+// requeue puts items back at the front of the queue, dropping any that are out
+// of retries or that no longer fit.
+func (o *elasticsearchOutput) requeue(items []*bulkItem) {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	retry := make([]*bulkItem, 0, len(items)+len(o.queue))
+	for _, item := range items {
+		item.attempts++
+		if item.attempts > o.opts.MaxRetries {
+			o.dropped++
+			continue
+		}
+		retry = append(retry, item)
+	}
+	o.queue = append(retry, o.queue...)
+	if excess := len(o.queue) - o.opts.MaxQueued; excess > 0 {
+		o.queue = o.queue[:o.opts.MaxQueued]
+		o.dropped += excess
+	}
+}
+
+func (o *elasticsearchOutput) reportDropped() {
+	o.mx.Lock()
+	dropped := o.dropped
+	o.dropped = 0
+	o.mx.Unlock()
+	if dropped > 0 {
+		errorOnLogging(fmt.Errorf("dropped %d events destined for elasticsearch", dropped))
+	}
+}
+
+// WaitForDrain waits until all queued events have been delivered or dropped,
+// or until ctx is done.
+func (o *elasticsearchOutput) WaitForDrain(ctx context.Context) error {
+	select {
+	case o.batchReady <- struct{}{}:
+	default:
+	}
+	return waitForDrain(ctx, func() bool {
+		o.mx.Lock()
+		defer o.mx.Unlock()
+		return len(o.queue) == 0 && o.inFlight == 0
+	})
+}
+
+// Close flushes queued events, unless the context is done, and stops the
+// background goroutine. Events that still can't be delivered after 5 seconds
+// of retrying are dropped and reported.
+func (o *elasticsearchOutput) Close() error {
+	o.stopOnce.Do(func() {
+		close(o.stop)
+	})
+	<-o.done
+	o.reportDropped()
+	return nil
+}
+
+// indexToken lowercases value and replaces characters that aren't allowed in
+// index names.
+func indexToken(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '\\', '/', '*', '?', '"', '<', '>', '|', ' ', ',', '#', ':':
+			return '_'
+		}
+		return r
+	}, strings.ToLower(value))
+}
@@ -0,0 +1,112 @@
+package golog
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElasticsearchOutputContext(t *testing.T) {
+	oldStderr := stderr
+	errs := &bytes.Buffer{}
+	stderr = errs
+	defer func() { stderr = oldStderr }()
+
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.Contains(readAll(req), "slow") {
+			select {
+			case <-req.Context().Done():
+			case <-unblock:
+			}
+			return
+		}
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+	defer close(unblock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := ElasticsearchOutput(&ElasticsearchOptions{
+		URL:           srv.URL,
+		FlushInterval: 10 * time.Millisecond,
+		Context:       ctx,
+	})
+	defer out.Close()
+
+	out.Debug("myprefix: ", 0, false, "DEBUG", "fast", nil)
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer drainCancel()
+	assert.NoError(t, out.WaitForDrain(drainCtx))
+
+	out.Debug("myprefix: ", 0, false, "DEBUG", "slow", nil)
+	shortCtx, shortCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer shortCancel()
+	assert.Equal(t, context.DeadlineExceeded, out.WaitForDrain(shortCtx), "delivery should still be in flight")
+
+	cancel()
+	assert.NoError(t, out.WaitForDrain(drainCtx), "cancelling should abort the in-flight delivery")
+	out.Debug("myprefix: ", 0, false, "DEBUG", "too late", nil)
+	assert.NoError(t, out.WaitForDrain(drainCtx), "events logged after cancelling shouldn't be queued")
+	require.NoError(t, out.Close())
+	assert.Equal(t, "Unable to log: dropped 1 events destined for elasticsearch\nUnable to log: dropped 1 events destined for elasticsearch\n", errs.String(), "dropped events should be reported")
+}
+
+func readAll(req *http.Request) string {
+	b, _ := ioutil.ReadAll(req.Body)
+	return string(b)
+}
+
+func TestElasticsearchOutputClose(t *testing.T) {
+	oldStderr := stderr
+	errs := &bytes.Buffer{}
+	stderr = errs
+	defer func() { stderr = oldStderr }()
+
+	var mx sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mx.Lock()
+		defer mx.Unlock()
+		requests++
+		switch {
+		case strings.Contains(readAll(req), "garbled"):
+			w.Write([]byte(`not json`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	out := ElasticsearchOutput(&ElasticsearchOptions{
+		URL:           srv.URL,
+		FlushInterval: time.Hour,
+		MaxRetries:    2,
+		Backoff:       &BackoffOptions{BaseDelay: time.Millisecond},
+	})
+	out.Debug("myprefix: ", 0, false, "DEBUG", "retried", nil)
+	require.NoError(t, out.Close())
+
+	mx.Lock()
+	assert.Equal(t, 3, requests, "Close should keep retrying the event")
+	mx.Unlock()
+	assert.Equal(t, "Unable to log: dropped 1 events destined for elasticsearch\n", errs.String(), "events that Close couldn't deliver should be counted as dropped")
+
+	errs.Reset()
+	out = ElasticsearchOutput(&ElasticsearchOptions{
+		URL:           srv.URL,
+		FlushInterval: time.Hour,
+	})
+	out.Debug("myprefix: ", 0, false, "DEBUG", "garbled", nil)
+	require.NoError(t, out.Close())
+	assert.Contains(t, errs.String(), "unable to decode elasticsearch response for 1 events")
+}
@@ -0,0 +1,288 @@
+package golog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultEmailDigestSubject    = "Error digest"
+	defaultEmailDigestInterval   = 1 * time.Hour
+	defaultEmailDigestMaxEntries = 100
+)
+
+// EmailDigestOptions configures an EmailDigest.
+type EmailDigestOptions struct {
+	// SMTPAddr is the host:port of the SMTP server used to send digests.
+	SMTPAddr string
+
+	// Auth is used to authenticate with the SMTP server, may be nil.
+	Auth smtp.Auth
+
+	// TLS, if set, configures the STARTTLS connection to the SMTP server,
+	// which is then required. Otherwise STARTTLS is used whenever the server
+	// supports it, verifying the server against the host of SMTPAddr.
+	TLS *tls.Config
+
+	// From is the sender address.
+	From string
+
+	// To are the recipient addresses.
+	To []string
+
+	// Subject is the subject of digest emails. Defaults to "Error digest".
+	Subject string
+
+	// Interval is how often a digest is sent, as long as there's something to
+	// report. Defaults to 1 hour.
+	Interval time.Duration
+
+	// Threshold, if positive, sends a digest as soon as this many errors have
+	// accumulated, without waiting for Interval to elapse.
+	Threshold int
+
+	// MaxEntries bounds the number of distinct errors tracked in a single
+	// digest. Further distinct errors are only counted. Defaults to 100.
+	MaxEntries int
+}
+
+// EmailDigest is an ErrorReporter that accumulates summaries of ERRORs and
+// FATALs and periodically emails them as a digest. Errors are grouped by their
+// Fingerprint. FATAL errors cause the digest to be sent immediately, since the
+// process is likely about to exit.
+//
+// Typical usage:
+//
+//	digest := golog.NewEmailDigest(opts)
+//	defer digest.Close()
+//	golog.RegisterReporter(digest.Report)
+type EmailDigest struct {
+	opts      EmailDigestOptions
+	send      func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	mx        sync.Mutex
+	sendMx    sync.Mutex
+	entries   map[string]*digestEntry
+	total     int
+	untracked int
+	since     time.Time
+	trigger   chan struct{}
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+}
+
+type digestEntry struct {
+	severity  Severity
+	component string
+	message   string
+	count     int
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// NewEmailDigest creates a new EmailDigest and starts its schedule.
+func NewEmailDigest(opts *EmailDigestOptions) *EmailDigest {
+	return newEmailDigest(opts, func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		return sendMail(addr, opts.TLS, a, from, to, msg)
+	})
+}
+
+// newEmailDigest is like NewEmailDigest but sends emails with send.
+func newEmailDigest(opts *EmailDigestOptions, send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error) *EmailDigest {
+	d := &EmailDigest{
+		opts:    *opts,
+		send:    send,
+		entries: make(map[string]*digestEntry),
+		since:   time.Now(),
+		trigger: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if d.opts.Subject == "" {
+		d.opts.Subject = defaultEmailDigestSubject
+	}
+	if d.opts.Interval <= 0 {
+		d.opts.Interval = defaultEmailDigestInterval
+	}
+	if d.opts.MaxEntries <= 0 {
+		d.opts.MaxEntries = defaultEmailDigestMaxEntries
+	}
+	go d.run()
+	return d
+}
+
+// Report implements ErrorReporter.
+func (d *EmailDigest) Report(err error, severity Severity, ctx map[string]interface{}) {
+	now := time.Now()
+	fingerprint := Fingerprint(err, ctx)
+	component, _ := ctx["component"].(string)
+
+	d.mx.Lock()
+	d.total++
+	entry := d.entries[fingerprint]
+	if entry == nil {
+		if len(d.entries) < d.opts.MaxEntries {
+			entry = &digestEntry{component: component, firstSeen: now}
+			d.entries[fingerprint] = entry
+		} else {
+			d.untracked++
+		}
+	}
+	if entry != nil {
+		entry.count++
+		entry.lastSeen = now
+		entry.message = fmt.Sprint(err)
+		if severity > entry.severity {
+			entry.severity = severity
+		}
+	}
+	thresholdReached := d.opts.Threshold > 0 && d.total >= d.opts.Threshold
+	d.mx.Unlock()
+
+	if severity >= FATAL {
+		d.flush()
+		return
+	}
+	if thresholdReached {
+		select {
+		case d.trigger <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (d *EmailDigest) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			d.flush()
+			return
+		case <-ticker.C:
+		case <-d.trigger:
+		}
+		d.flush()
+	}
+}
+
+// flush sends a digest of everything accumulated so far, if anything.
+func (d *EmailDigest) flush() {
+	d.sendMx.Lock()
+	defer d.sendMx.Unlock()
+
+	d.mx.Lock()
+	entries, total, untracked, since := d.entries, d.total, d.untracked, d.since
+	d.entries = make(map[string]*digestEntry)
+	d.total, d.untracked, d.since = 0, 0, time.Now()
+	d.mx.Unlock()
+
+	if total == 0 {
+		return
+	}
+	if err := d.send(d.opts.SMTPAddr, d.opts.Auth, d.opts.From, d.opts.To, d.message(entries, total, untracked, since)); err != nil {
+		errorOnLogging(fmt.Errorf("unable to send error digest: %v", err))
+	}
+}
+
+func (d *EmailDigest) message(entries map[string]*digestEntry, total int, untracked int, since time.Time) []byte {
+	sorted := make([]*digestEntry, 0, len(entries))
+	for _, entry := range entries {
+		sorted = append(sorted, entry)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].severity != sorted[j].severity {
+			return sorted[i].severity > sorted[j].severity
+		}
+		return sorted[i].count > sorted[j].count
+	})
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %v\r\n", d.opts.From)
+	fmt.Fprintf(&buf, "To: %v\r\n", strings.Join(d.opts.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %v (%d errors)\r\n", d.opts.Subject, total)
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&buf, "%d errors since %v\r\n\r\n", total, since.Format(time.RFC3339))
+	for _, entry := range sorted {
+		fmt.Fprintf(&buf, "%v %v x%d (first %v, last %v)\r\n  %v\r\n\r\n",
+			entry.severity, entry.component, entry.count,
+			entry.firstSeen.Format(time.RFC3339), entry.lastSeen.Format(time.RFC3339),
+			strings.Replace(entry.message, "\n", "\r\n  ", -1))
+	}
+	if untracked > 0 {
+		fmt.Fprintf(&buf, "%d additional errors not shown\r\n", untracked)
+	}
+	return buf.Bytes()
+}
+
+// sendMail is like smtp.SendMail, but uses tlsConfig for STARTTLS and requires
+// it if tlsConfig is set.
+func sendMail(addr string, tlsConfig *tls.Config, a smtp.Auth, from string, to []string, msg []byte) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		config := &tls.Config{ServerName: host}
+		if tlsConfig != nil {
+			config = tlsConfig.Clone()
+			if config.ServerName == "" {
+				config.ServerName = host
+			}
+		}
+		if err := c.StartTLS(config); err != nil {
+			return err
+		}
+	} else if tlsConfig != nil {
+		return fmt.Errorf("smtp server %v doesn't support STARTTLS", addr)
+	}
+	if a != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp server %v doesn't support AUTH", addr)
+		}
+		if err := c.Auth(a); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Close sends any remaining errors and stops the schedule.
+func (d *EmailDigest) Close() error {
+	d.stopOnce.Do(func() {
+		close(d.stop)
+	})
+	<-d.done
+	return nil
+}
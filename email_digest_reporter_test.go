@@ -0,0 +1,142 @@
+package golog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSender records the emails sent through it, failing with err if
+// it's set.
+type recordingSender struct {
+	sent chan string
+	err  error
+}
+
+func newRecordingSender() *recordingSender {
+	return &recordingSender{sent: make(chan string, 10)}
+}
+
+func (s *recordingSender) send(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	s.sent <- string(msg)
+	return s.err
+}
+
+func (s *recordingSender) next(t *testing.T) string {
+	select {
+	case msg := <-s.sent:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no digest sent")
+		return ""
+	}
+}
+
+func (s *recordingSender) assertNothingSent(t *testing.T) {
+	select {
+	case msg := <-s.sent:
+		t.Fatalf("unexpected digest sent: %v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestEmailDigestBatching(t *testing.T) {
+	sender := newRecordingSender()
+	d := newEmailDigest(&EmailDigestOptions{From: "golog@example.com", To: []string{"a@example.com", "b@example.com"}, MaxEntries: 2}, sender.send)
+	ctx := map[string]interface{}{"component": "myprefix"}
+	d.Report(errors.New("oh no"), ERROR, ctx)
+	d.Report(errors.New("oh no"), ERROR, ctx)
+	d.Report(errors.New("something else"), ERROR, ctx)
+	d.Report(errors.New("untracked"), ERROR, ctx)
+	sender.assertNothingSent(t)
+
+	require.NoError(t, d.Close())
+	msg := sender.next(t)
+	assert.Contains(t, msg, "From: golog@example.com\r\nTo: a@example.com, b@example.com\r\nSubject: Error digest (4 errors)\r\n")
+	assert.Regexp(t, `ERROR myprefix x2 .*\r\n  oh no\r\n\r\nERROR myprefix x1 .*\r\n  something else\r\n`, msg, "errors should be grouped, most frequent first")
+	assert.Contains(t, msg, "1 additional errors not shown")
+	assert.NotContains(t, msg, "untracked")
+}
+
+func TestEmailDigestFlushes(t *testing.T) {
+	sender := newRecordingSender()
+	d := newEmailDigest(&EmailDigestOptions{Interval: 20 * time.Millisecond}, sender.send)
+	d.Report(errors.New("oh no"), ERROR, nil)
+	assert.Contains(t, sender.next(t), "Subject: Error digest (1 errors)", "digest should be sent after the interval")
+	sender.assertNothingSent(t)
+	require.NoError(t, d.Close())
+	sender.assertNothingSent(t)
+
+	d = newEmailDigest(&EmailDigestOptions{Threshold: 2}, sender.send)
+	defer d.Close()
+	d.Report(errors.New("oh no"), ERROR, nil)
+	sender.assertNothingSent(t)
+	d.Report(errors.New("oh no"), ERROR, nil)
+	assert.Contains(t, sender.next(t), "(2 errors)", "digest should be sent once the threshold is reached")
+
+	d.Report(errors.New("fatal"), FATAL, nil)
+	select {
+	case msg := <-sender.sent:
+		assert.Contains(t, msg, "FATAL")
+	default:
+		t.Fatal("FATAL errors should be sent before Report returns")
+	}
+}
+
+func TestEmailDigestSendFailure(t *testing.T) {
+	oldStderr := stderr
+	errs := &bytes.Buffer{}
+	stderr = errs
+	defer func() { stderr = oldStderr }()
+
+	sender := newRecordingSender()
+	sender.err = errors.New("connection refused")
+	d := newEmailDigest(&EmailDigestOptions{}, sender.send)
+	d.Report(errors.New("oh no"), ERROR, nil)
+	require.NoError(t, d.Close())
+	sender.next(t)
+	assert.Equal(t, "Unable to log: unable to send error digest: connection refused\n", errs.String())
+}
+
+func TestEmailDigestRequiresStartTLS(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		conn.Write([]byte("220 localhost ESMTP\r\n"))
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				// doesn't offer STARTTLS
+				conn.Write([]byte("250-localhost\r\n250 8BITMIME\r\n"))
+			case strings.HasPrefix(line, "QUIT"):
+				conn.Write([]byte("221 bye\r\n"))
+				return
+			default:
+				conn.Write([]byte("250 ok\r\n"))
+			}
+		}
+	}()
+
+	err = sendMail(l.Addr().String(), &tls.Config{}, nil, "golog@example.com", []string{"a@example.com"}, []byte("hello"))
+	assert.EqualError(t, err, "smtp server "+l.Addr().String()+" doesn't support STARTTLS")
+}
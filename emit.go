@@ -0,0 +1,180 @@
+package golog
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+// EventBuilder builds an event to inject into the logging pipeline directly,
+// bypassing the Logger methods. It's meant for bridges and adapters, such as
+// syslog receivers or subprocess wrappers, that relay events which originated
+// elsewhere and therefore know their real caller and timestamp.
+type EventBuilder struct {
+	severity string
+	prefix   string
+	msg      string
+	fields   []Field
+	caller   string
+	ts       time.Time
+	stack    string
+	// unreported keeps the event from the ErrorReporters
+	unreported bool
+}
+
+// NewEvent starts building an event with the given severity ("TRACE",
+// "DEBUG", "INFO", "WARN", "ERROR" or "FATAL", case insensitive), prefix and
+// message. Events with any other severity are logged at DEBUG, with the
+// original severity in the field original_severity.
+func NewEvent(severity string, prefix string, msg string) *EventBuilder {
+	b := &EventBuilder{
+		severity: strings.ToUpper(severity),
+		prefix:   prefix,
+		msg:      msg,
+	}
+	if severityLevel(b.severity) == 0 {
+		b.severity = "DEBUG"
+		b.fields = []Field{{"original_severity", severity}}
+	}
+	return b
+}
+
+// With attaches the given fields to the event's context.
+func (b *EventBuilder) With(fields ...Field) *EventBuilder {
+	b.fields = append(b.fields, fields...)
+	return b
+}
+
+// Caller overrides the caller (normally file:line) recorded for the event.
+func (b *EventBuilder) Caller(caller string) *EventBuilder {
+	b.caller = caller
+	return b
+}
+
+// At overrides the time at which the event is recorded as having happened.
+func (b *EventBuilder) At(ts time.Time) *EventBuilder {
+	b.ts = ts
+	return b
+}
+
+// Unreported keeps the event from being sent to the ErrorReporters, for events
+// relayed from sources that aren't trusted to raise alerts.
+func (b *EventBuilder) Unreported() *EventBuilder {
+	b.unreported = true
+	return b
+}
+
+// Emit sends the event to the current output, unless its severity is below
+// the level (see SetLevel), and, unless it's Unreported, to the registered
+// ErrorReporters that want its severity (see RegisterReporterAt), but FATAL
+// events don't trigger OnFatal since the failure happened elsewhere. Unless
+// overridden, the caller is the caller of Emit.
+func (b *EventBuilder) Emit() {
+	arg := (&injectedArg{
+		fieldsArg: fieldsArg{arg: b.msg, fields: b.fields},
+		caller:    b.caller,
+		ts:        b.ts,
+		stack:     b.stack,
+	}).asArg()
+	prefix := b.prefix + ": "
+	severity := Severity(severityLevel(b.severity))
+	if IsDisabled() || !levelEnabled(b.severity) {
+		if !b.unreported {
+			report(arg.(error), severity, prefix)
+		}
+		return
+	}
+	countEvent(prefix, b.severity)
+	values := eventValues(arg, nil)
+	observe(values, b.severity, arg)
+	addErrorCode(values, arg)
+	switch b.severity {
+	case "ERROR", "FATAL":
+		getErrorOut()(prefix, 5, false, b.severity, arg, values)
+	default:
+		getDebugOut()(prefix, 5, false, b.severity, arg, values)
+	}
+	if !b.unreported {
+		report(arg.(error), severity, prefix)
+	}
+}
+
+// eventOrigin is implemented by args that carry the caller and time at which
+// they originally happened, rather than those of the log call itself. Empty
+// values mean no override.
+type eventOrigin interface {
+	origin() (caller string, ts time.Time)
+	originStack() string
+}
+
+type injectedArg struct {
+	fieldsArg
+	caller string
+	ts     time.Time
+	stack  string
+	// pc is the program counter of caller, if known
+	pc uintptr
+}
+
+func (a *injectedArg) origin() (string, time.Time) {
+	return a.caller, a.ts
+}
+
+func (a *injectedArg) originPC() uintptr {
+	return a.pc
+}
+
+func (a *injectedArg) originStack() string {
+	return a.stack
+}
+
+// multiLineInjectedArg is an injectedArg whose message spans multiple lines,
+// like the message of an error with a stack trace.
+type multiLineInjectedArg struct {
+	*injectedArg
+}
+
+func (a multiLineInjectedArg) MultiLinePrinter() func(buf *bytes.Buffer) bool {
+	lines := strings.Split(strings.TrimSuffix(a.String(), "\n"), "\n")
+	i := 0
+	return func(buf *bytes.Buffer) bool {
+		buf.WriteString(lines[i])
+		i++
+		return i < len(lines)
+	}
+}
+
+// asArg returns a as an argument for an Output.
+func (a *injectedArg) asArg() interface{} {
+	if strings.Contains(a.String(), "\n") {
+		return multiLineInjectedArg{a}
+	}
+	return a
+}
+
+// eventTime returns the time at which the event for arg happened.
+func eventTime(arg interface{}) time.Time {
+	if o, ok := arg.(eventOrigin); ok {
+		if _, ts := o.origin(); !ts.IsZero() {
+			return ts
+		}
+	}
+	return time.Now()
+}
+
+// callerOverride returns the caller recorded for arg, if any.
+func callerOverride(arg interface{}) string {
+	if o, ok := arg.(eventOrigin); ok {
+		caller, _ := o.origin()
+		return caller
+	}
+	return ""
+}
+
+// stackOverride returns the stack recorded for arg, if any.
+func stackOverride(arg interface{}) string {
+	if o, ok := arg.(eventOrigin); ok {
+		return o.originStack()
+	}
+	return ""
+}
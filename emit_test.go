@@ -0,0 +1,65 @@
+package golog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmit(t *testing.T) {
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	defer reset()
+
+	NewEvent("debug", "relay", "Hello world").With(Field{"cvarA", "a"}).Emit()
+	NewEvent("ERROR", "relay", "Oh no").Caller("remote.c:12").At(time.Now().Add(-time.Hour)).Emit()
+	assert.Equal(t, "DEBUG relay: emit_test.go:999 Hello world [cvarA=a]\nERROR relay: remote.c:999 Oh no\n", out.String())
+}
+
+func TestEmitTime(t *testing.T) {
+	rb := NewRingBuffer(nil, 10)
+	reset := SetOutput(rb)
+	defer reset()
+
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	NewEvent("DEBUG", "relay", "Hello world").At(ts).Emit()
+	events := rb.Events()
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, ts, events[0].Time)
+		assert.Equal(t, "emit_test.go:999", normalized(events[0].Caller))
+	}
+}
+
+func TestEmitLevel(t *testing.T) {
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	defer reset()
+	require.NoError(t, SetLevel("WARN"))
+	defer SetLevel("")
+	active := true
+	var reported []Severity
+	RegisterReporterAt(ERROR, func(err error, severity Severity, ctx map[string]interface{}) {
+		if active {
+			reported = append(reported, severity)
+		}
+	})
+	defer func() { active = false }()
+
+	NewEvent("DEBUG", "relay", "dropped").Emit()
+	NewEvent("WARN", "relay", "kept").Emit()
+	require.NoError(t, SetLevel("FATAL"))
+	NewEvent("ERROR", "relay", "reported").Emit()
+	assert.Equal(t, "WARN relay: emit_test.go:999 kept\n", out.String())
+	assert.Equal(t, []Severity{ERROR}, reported, "events below the level should still be reported")
+}
+
+func TestEmitUnknownSeverity(t *testing.T) {
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	defer reset()
+
+	NewEvent("notice", "relay", "Hello world").Emit()
+	assert.Equal(t, "DEBUG relay: emit_test.go:999 Hello world [original_severity=notice]\n", out.String())
+}
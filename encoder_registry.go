@@ -0,0 +1,86 @@
+package golog
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OutputOptions configures an Output created by NewOutput.
+type OutputOptions struct {
+	// ErrorWriter receives errors. Defaults to stderr.
+	ErrorWriter io.Writer
+
+	// DebugWriter receives debug and trace messages. Defaults to stdout.
+	DebugWriter io.Writer
+
+	// OmitTimestamps asks formats that write timestamps to leave them out,
+	// for example because a container runtime stamps each line already.
+	OmitTimestamps bool
+}
+
+// EncoderFactory creates an Output that encodes log messages in a particular
+// format.
+type EncoderFactory func(opts *OutputOptions) (Output, error)
+
+var (
+	encoderFactories   = make(map[string]EncoderFactory)
+	encoderFactoriesMx sync.RWMutex
+)
+
+func init() {
+	RegisterEncoderFactory("text", func(opts *OutputOptions) (Output, error) {
+		return TextOutput(opts.ErrorWriter, opts.DebugWriter), nil
+	})
+	RegisterEncoderFactory("json", func(opts *OutputOptions) (Output, error) {
+		return JsonOutputWithOptions(opts.ErrorWriter, opts.DebugWriter, &JsonOutputOptions{OmitTimestamp: opts.OmitTimestamps}), nil
+	})
+}
+
+// RegisterEncoderFactory registers a factory for Outputs using the named
+// format, so that the format can be selected by name with NewOutput, for
+// example from configuration. Names are case insensitive. Registering a name
+// that's already registered replaces the existing factory. "text" and "json"
+// are registered by default.
+func RegisterEncoderFactory(name string, factory EncoderFactory) {
+	encoderFactoriesMx.Lock()
+	defer encoderFactoriesMx.Unlock()
+	encoderFactories[strings.ToLower(name)] = factory
+}
+
+// NewOutput creates an Output using the format registered under the given
+// name. opts may be nil.
+func NewOutput(name string, opts *OutputOptions) (Output, error) {
+	encoderFactoriesMx.RLock()
+	factory, found := encoderFactories[strings.ToLower(name)]
+	encoderFactoriesMx.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("unknown log format %q, expected one of %v", name, EncoderNames())
+	}
+
+	resolved := OutputOptions{}
+	if opts != nil {
+		resolved = *opts
+	}
+	if resolved.ErrorWriter == nil {
+		resolved.ErrorWriter = stderr
+	}
+	if resolved.DebugWriter == nil {
+		resolved.DebugWriter = stdout
+	}
+	return factory(&resolved)
+}
+
+// EncoderNames returns the names of all registered formats, sorted.
+func EncoderNames() []string {
+	encoderFactoriesMx.RLock()
+	defer encoderFactoriesMx.RUnlock()
+	names := make([]string, 0, len(encoderFactories))
+	for name := range encoderFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
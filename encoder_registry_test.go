@@ -0,0 +1,30 @@
+package golog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOutput(t *testing.T) {
+	errorOut := &bytes.Buffer{}
+	debugOut := &bytes.Buffer{}
+	out, err := NewOutput("JSON", &OutputOptions{ErrorWriter: errorOut, DebugWriter: debugOut})
+	require.NoError(t, err)
+	out.Debug("myprefix: ", 0, false, "DEBUG", "hello", nil)
+	assert.Contains(t, debugOut.String(), `"msg":"hello"`)
+
+	RegisterEncoderFactory("custom", func(opts *OutputOptions) (Output, error) {
+		return TextOutput(opts.ErrorWriter, opts.ErrorWriter), nil
+	})
+	out, err = NewOutput("custom", &OutputOptions{ErrorWriter: errorOut, DebugWriter: debugOut})
+	require.NoError(t, err)
+	out.Debug("myprefix: ", 0, false, "DEBUG", "hello", nil)
+	assert.Contains(t, errorOut.String(), "DEBUG myprefix: ")
+	assert.Contains(t, EncoderNames(), "custom")
+
+	_, err = NewOutput("xml", nil)
+	assert.Error(t, err)
+}
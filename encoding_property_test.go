@@ -0,0 +1,73 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"testing/quick"
+)
+
+// TestJsonRoundTripProperty checks that whatever is logged to a JsonOutput
+// decodes back to the same (sanitized) event.
+func TestJsonRoundTripProperty(t *testing.T) {
+	roundTrips := func(component string, msg string, context map[string]string) bool {
+		if component == "" {
+			component = "c"
+		}
+		values := make(map[string]interface{}, len(context))
+		for key, value := range context {
+			values[key] = value
+		}
+		buf := &bytes.Buffer{}
+		JsonOutput(buf, buf).Error(component+": ", 0, false, "ERROR", msg, values)
+		events, err := ReadJSONEvents(buf)
+		if err != nil || len(events) != 1 {
+			return false
+		}
+		event := events[0]
+		if event.Component != component || event.Severity != "ERROR" || event.Message != sanitize(msg) {
+			return false
+		}
+		if len(event.Context) != len(context) {
+			return false
+		}
+		for key, value := range context {
+			if event.Context[key] != sanitize(value) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(roundTrips, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestRecordedEventRoundTripProperty checks that RingBuffer dumps read back
+// with ReadRecordedEvents are unchanged.
+func TestRecordedEventRoundTripProperty(t *testing.T) {
+	roundTrips := func(msg string, component string, caller string, stack string, n float64) bool {
+		event := &RecordedEvent{Event: Event{
+			Message:   msg,
+			Component: component,
+			Caller:    caller,
+			Stack:     stack,
+			Severity:  "DEBUG",
+			Context:   map[string]interface{}{"n": n},
+		}}
+		b, err := json.Marshal(event)
+		if err != nil {
+			return false
+		}
+		events, err := ReadRecordedEvents(bytes.NewReader(append(b, '\n')))
+		if err != nil || len(events) != 1 {
+			return false
+		}
+		decoded := events[0]
+		return decoded.Message == msg && decoded.Component == component && decoded.Caller == caller &&
+			decoded.Stack == stack && decoded.Context["n"] == n
+	}
+	if err := quick.Check(roundTrips, nil); err != nil {
+		t.Error(err)
+	}
+}
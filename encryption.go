@@ -0,0 +1,223 @@
+package golog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Encrypted log files consist of segments, each starting with a header that
+// records which key encrypts the rest of the segment and when the segment
+// was started:
+//
+//	"GLE1" | uint8 len(key ID) | key ID | int64 unix nanos
+//
+// followed by records, one per write:
+//
+//	uint32 len(sealed) | sealed
+//
+// where sealed is a 12 byte nonce followed by the AES-GCM ciphertext, with the
+// key ID as additional data. Reopening a file with a new key appends a new
+// segment, so files may span several keys.
+var encryptedSegmentMagic = []byte("GLE1")
+
+const maxEncryptedRecord = 16 << 20
+
+// EncryptionKey is a key for encrypting log files.
+type EncryptionKey struct {
+	// ID identifies the key in the files it encrypts, so that the right key
+	// can be found for decrypting them. At most 255 bytes.
+	ID string
+
+	// Key is the AES key, 16, 24 or 32 bytes long.
+	Key []byte
+}
+
+// Keyring holds the keys able to decrypt log files, by ID.
+type Keyring map[string][]byte
+
+// EncryptedSegment describes a segment of an encrypted log file.
+type EncryptedSegment struct {
+	KeyID   string
+	Started time.Time
+	Records int
+}
+
+func (key *EncryptionKey) aead() (cipher.AEAD, error) {
+	if len(key.ID) > 255 {
+		return nil, fmt.Errorf("key ID longer than 255 bytes")
+	}
+	block, err := aes.NewCipher(key.Key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// NewEncryptingWriter returns a writer that encrypts each write to w as a
+// record of a new segment using key. The segment header is written
+// immediately.
+func NewEncryptingWriter(w io.Writer, key *EncryptionKey) (io.Writer, error) {
+	aead, err := key.aead()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, len(encryptedSegmentMagic)+1+len(key.ID)+8)
+	header = append(header, encryptedSegmentMagic...)
+	header = append(header, byte(len(key.ID)))
+	header = append(header, key.ID...)
+	var started [8]byte
+	binary.BigEndian.PutUint64(started[:], uint64(time.Now().UnixNano()))
+	header = append(header, started[:]...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptingWriter{w: w, aead: aead, keyID: []byte(key.ID)}, nil
+}
+
+type encryptingWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	keyID []byte
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	if len(p) > maxEncryptedRecord {
+		return 0, fmt.Errorf("write of %d bytes exceeds maximum encrypted record size", len(p))
+	}
+	nonceSize := w.aead.NonceSize()
+	record := make([]byte, 4+nonceSize, 4+nonceSize+len(p)+w.aead.Overhead())
+	if _, err := rand.Read(record[4:]); err != nil {
+		return 0, err
+	}
+	record = w.aead.Seal(record, record[4:], p, w.keyID)
+	binary.BigEndian.PutUint32(record, uint32(len(record)-4))
+	if _, err := w.w.Write(record); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// DecryptLog decrypts an encrypted log file read from r into w, using the
+// keys in keys for each segment. It returns the segments that were read.
+func DecryptLog(w io.Writer, r io.Reader, keys Keyring) ([]*EncryptedSegment, error) {
+	return readEncrypted(r, keys, func(plain []byte) error {
+		_, err := w.Write(plain)
+		return err
+	})
+}
+
+func readEncrypted(r io.Reader, keys Keyring, record func(plain []byte) error) ([]*EncryptedSegment, error) {
+	br := bufio.NewReader(r)
+	var segments []*EncryptedSegment
+	var segment *EncryptedSegment
+	var aead cipher.AEAD
+	var prefix [4]byte
+	for {
+		if _, err := io.ReadFull(br, prefix[:]); err != nil {
+			if err == io.EOF {
+				return segments, nil
+			}
+			return segments, fmt.Errorf("truncated encrypted log: %v", err)
+		}
+		if bytes.Equal(prefix[:], encryptedSegmentMagic) {
+			idLen, err := br.ReadByte()
+			if err != nil {
+				return segments, fmt.Errorf("truncated segment header: %v", err)
+			}
+			header := make([]byte, int(idLen)+8)
+			if _, err := io.ReadFull(br, header); err != nil {
+				return segments, fmt.Errorf("truncated segment header: %v", err)
+			}
+			keyID := string(header[:idLen])
+			started := int64(binary.BigEndian.Uint64(header[idLen:]))
+			key, found := keys[keyID]
+			if !found {
+				return segments, fmt.Errorf("no key with ID %q", keyID)
+			}
+			aead, err = (&EncryptionKey{ID: keyID, Key: key}).aead()
+			if err != nil {
+				return segments, fmt.Errorf("invalid key %q: %v", keyID, err)
+			}
+			segment = &EncryptedSegment{KeyID: keyID, Started: time.Unix(0, started)}
+			segments = append(segments, segment)
+			continue
+		}
+		if segment == nil {
+			return segments, fmt.Errorf("not an encrypted log")
+		}
+		n := binary.BigEndian.Uint32(prefix[:])
+		if n > maxEncryptedRecord+uint32(aead.NonceSize()+aead.Overhead()) || int(n) < aead.NonceSize() {
+			return segments, fmt.Errorf("corrupt record of %d bytes", n)
+		}
+		sealed := make([]byte, n)
+		if _, err := io.ReadFull(br, sealed); err != nil {
+			return segments, fmt.Errorf("truncated record: %v", err)
+		}
+		nonceSize := aead.NonceSize()
+		plain, err := aead.Open(sealed[nonceSize:nonceSize], sealed[:nonceSize], sealed[nonceSize:], []byte(segment.KeyID))
+		if err != nil {
+			return segments, fmt.Errorf("unable to decrypt record %d of segment with key %q: %v", segment.Records, segment.KeyID, err)
+		}
+		segment.Records++
+		if err := record(plain); err != nil {
+			return segments, err
+		}
+	}
+}
+
+// ReencryptFile re-encrypts the encrypted log file at path under newKey,
+// decrypting it with keys, so that old keys can be retired without losing
+// historical logs. Records are kept as they were, in a single segment. The
+// file is only replaced once it's been fully re-encrypted, and mustn't be
+// written to in the meantime, so this is meant for archives rather than
+// active log files.
+func ReencryptFile(path string, keys Keyring, newKey *EncryptionKey) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".rekey-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	bw := bufio.NewWriter(tmp)
+	w, err := NewEncryptingWriter(bw, newKey)
+	if err == nil {
+		_, err = readEncrypted(in, keys, func(plain []byte) error {
+			_, err := w.Write(plain)
+			return err
+		})
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = tmp.Chmod(info.Mode())
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("unable to re-encrypt %v: %v", path, err)
+	}
+	// close before renaming, which Windows requires
+	in.Close()
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,83 @@
+package golog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedLogFileKeyRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-encrypted")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+
+	oldKey := &EncryptionKey{ID: "2025-01", Key: bytes.Repeat([]byte{1}, 32)}
+	newKey := &EncryptionKey{ID: "2026-01", Key: bytes.Repeat([]byte{2}, 16)}
+
+	f, err := OpenEncryptedLogFile(path, oldKey)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("old secret\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// rotating keys continues the file in a new segment
+	f, err = OpenEncryptedLogFile(path, newKey)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("new secret\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	raw, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "secret")
+
+	var plain bytes.Buffer
+	segments, err := DecryptLog(&plain, bytes.NewReader(raw), Keyring{oldKey.ID: oldKey.Key, newKey.ID: newKey.Key})
+	require.NoError(t, err)
+	assert.Equal(t, "old secret\nnew secret\n", plain.String())
+	require.Len(t, segments, 2)
+	assert.Equal(t, "2025-01", segments[0].KeyID)
+	assert.Equal(t, "2026-01", segments[1].KeyID)
+	assert.Equal(t, 1, segments[1].Records)
+	assert.False(t, segments[1].Started.Before(segments[0].Started))
+
+	_, err = DecryptLog(ioutil.Discard, bytes.NewReader(raw), Keyring{newKey.ID: newKey.Key})
+	assert.EqualError(t, err, `no key with ID "2025-01"`)
+
+	// re-keying lets the old key be retired
+	require.NoError(t, ReencryptFile(path, Keyring{oldKey.ID: oldKey.Key, newKey.ID: newKey.Key}, newKey))
+	raw, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	plain.Reset()
+	segments, err = DecryptLog(&plain, bytes.NewReader(raw), Keyring{newKey.ID: newKey.Key})
+	require.NoError(t, err)
+	assert.Equal(t, "old secret\nnew secret\n", plain.String())
+	require.Len(t, segments, 1)
+	assert.Equal(t, 2, segments[0].Records)
+}
+
+func TestEncryptedLogTampering(t *testing.T) {
+	key := &EncryptionKey{ID: "k", Key: bytes.Repeat([]byte{3}, 32)}
+	var buf bytes.Buffer
+	w, err := NewEncryptingWriter(&buf, key)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("hello"))
+	require.NoError(t, err)
+
+	raw := buf.Bytes()
+	raw[len(raw)-1] ^= 1
+	_, err = DecryptLog(ioutil.Discard, bytes.NewReader(raw), Keyring{"k": key.Key})
+	assert.Error(t, err)
+
+	_, err = DecryptLog(ioutil.Discard, bytes.NewReader(raw[:len(raw)-3]), Keyring{"k": key.Key})
+	assert.Error(t, err)
+
+	_, err = OpenEncryptedLogFile("unused", &EncryptionKey{ID: "short", Key: []byte("short")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,123 @@
+package golog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// envConfig holds the *EnvConfig from GOLOG_CONFIG, parsed on first use
+	// and again by ReloadEnv.
+	envConfig     atomic.Value
+	envConfigOnce sync.Once
+)
+
+// getEnvConfig returns the configuration from GOLOG_CONFIG.
+func getEnvConfig() *EnvConfig {
+	envConfigOnce.Do(func() {
+		envConfig.Store(envConfigFromEnv())
+	})
+	return envConfig.Load().(*EnvConfig)
+}
+
+// EnvConfig is the configuration that can be given in the GOLOG_CONFIG
+// environment variable, for platforms where environment variables are the
+// only way to configure a program. See ParseEnvConfig.
+type EnvConfig struct {
+	// Level is the lowest severity ("TRACE", "DEBUG", "INFO", "WARN",
+	// "ERROR" or "FATAL") that's logged, see SetLevel. It's applied at init
+	// and by ReloadEnv. TRACE also enables trace logging for all loggers.
+	Level string
+
+	// Format is the name of the format of the default outputs, see NewOutput.
+	Format string
+
+	// Output is where the default outputs write, "stderr" or "stdout" for
+	// everything to go there. Empty for the usual split between stderr and
+	// stdout.
+	Output string
+
+	// Sampling are the sampling rules for the default outputs.
+	Sampling []SamplingRule
+}
+
+// ParseEnvConfig parses a GOLOG_CONFIG spec, which is either URL query style,
+// like
+//
+//	level=info&format=json&output=stderr&sample=10
+//
+// or a JSON object with the same keys, like
+//
+//	{"level": "info", "format": "json", "output": "stderr", "sample": 10}
+//
+// sample is the percentage of DEBUG and TRACE events to keep. All keys are
+// optional.
+func ParseEnvConfig(spec string) (*EnvConfig, error) {
+	values := make(map[string]string)
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "{") {
+		fields := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(spec), &fields); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		for key, value := range fields {
+			values[key] = fmt.Sprint(value)
+		}
+	} else {
+		query, err := url.ParseQuery(spec)
+		if err != nil {
+			return nil, err
+		}
+		for key := range query {
+			values[key] = query.Get(key)
+		}
+	}
+
+	cfg := &EnvConfig{}
+	for key, value := range values {
+		switch strings.ToLower(key) {
+		case "level":
+			cfg.Level = strings.ToUpper(value)
+			if _, err := parseLevel(value); err != nil {
+				return nil, err
+			}
+		case "format":
+			cfg.Format = strings.ToLower(value)
+		case "output":
+			cfg.Output = strings.ToLower(value)
+			if cfg.Output != "stderr" && cfg.Output != "stdout" {
+				return nil, fmt.Errorf("unknown output %q, expected stderr or stdout", value)
+			}
+		case "sample":
+			percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil || percent < 0 || percent > 100 {
+				return nil, fmt.Errorf("sample must be a percentage between 0 and 100, not %q", value)
+			}
+			for _, severity := range []string{"DEBUG", "TRACE"} {
+				cfg.Sampling = append(cfg.Sampling, SamplingRule{Component: "*", Severity: severity, Keep: percent / 100})
+			}
+		default:
+			return nil, fmt.Errorf("unknown key %q", key)
+		}
+	}
+	return cfg, nil
+}
+
+func envConfigFromEnv() *EnvConfig {
+	spec := os.Getenv("GOLOG_CONFIG")
+	if spec == "" {
+		return &EnvConfig{}
+	}
+	cfg, err := ParseEnvConfig(spec)
+	if err != nil {
+		errorOnLogging(fmt.Errorf("ignoring GOLOG_CONFIG: %v", err))
+		return &EnvConfig{}
+	}
+	return cfg
+}
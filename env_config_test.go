@@ -0,0 +1,65 @@
+package golog
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnvConfig(t *testing.T) {
+	for _, spec := range []string{
+		"level=info&format=json&output=stderr&sample=10",
+		`{"level": "info", "format": "json", "output": "stderr", "sample": 10}`,
+	} {
+		cfg, err := ParseEnvConfig(spec)
+		require.NoError(t, err, spec)
+		assert.Equal(t, &EnvConfig{
+			Level:  "INFO",
+			Format: "json",
+			Output: "stderr",
+			Sampling: []SamplingRule{
+				{Component: "*", Severity: "DEBUG", Keep: 0.1},
+				{Component: "*", Severity: "TRACE", Keep: 0.1},
+			},
+		}, cfg, spec)
+	}
+
+	cfg, err := ParseEnvConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, &EnvConfig{}, cfg)
+
+	for _, spec := range []string{"level=loud", "output=file", "sample=200", "colour=red", "{"} {
+		_, err := ParseEnvConfig(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestEnvConfigDefaults(t *testing.T) {
+	oldStderr, oldStdout, oldConfig := stderr, stdout, getEnvConfig()
+	errBuf, outBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	stderr, stdout = errBuf, outBuf
+	defer func() {
+		stderr, stdout = oldStderr, oldStdout
+		envConfig.Store(oldConfig)
+		os.Unsetenv("GOLOG_CONTAINER")
+		ResetOutputs()
+		SetLevel("")
+	}()
+	// outside of containers, JSON has timestamps
+	os.Setenv("GOLOG_CONTAINER", "false")
+
+	cfg, err := ParseEnvConfig("level=info&format=json&output=stderr")
+	require.NoError(t, err)
+	envConfig.Store(cfg)
+	ResetOutputs()
+	require.NoError(t, SetLevel(cfg.Level))
+
+	l := LoggerFor("myprefix")
+	l.Debug("dropped")
+	l.Error("kept")
+	assert.Empty(t, outBuf.String())
+	assert.Regexp(t, `^\{"ts":"[^"]+","msg":"kept","component":"myprefix".*"level":"ERROR"\}\n$`, errBuf.String())
+}
@@ -0,0 +1,105 @@
+package golog
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// knownEnvVars are the environment variables that configure golog.
+var knownEnvVars = []string{
+	"GOLOG_CONFIG",
+	"GOLOG_CONTAINER",
+	"GOLOG_CONTEXT",
+	"GOLOG_SAMPLING",
+	"GOLOG_SILENT",
+	"PRINT_JSON",
+	"PRINT_STACK",
+	"TRACE",
+}
+
+func init() {
+	for _, err := range checkEnvNames(os.Environ()) {
+		errorOnLogging(err)
+	}
+}
+
+// checkEnvNames returns an error for each variable in environ, as returned by
+// os.Environ, that looks like a misspelling of one that configures golog:
+// unknown variables starting with GOLOG_, and variables a single typo away
+// from a known one, like TRCE. Where possible, the errors suggest the variable
+// that was probably meant.
+func checkEnvNames(environ []string) []error {
+	known := make(map[string]bool, len(knownEnvVars))
+	for _, name := range knownEnvVars {
+		known[name] = true
+	}
+	var errs []error
+	for _, kv := range environ {
+		name := strings.SplitN(kv, "=", 2)[0]
+		if name == "" || known[name] {
+			continue
+		}
+		suggestion, distance := closestEnvVar(strings.ToUpper(name))
+		switch {
+		case distance <= 1 || (strings.HasPrefix(strings.ToUpper(name), "GOLOG_") && distance <= 2):
+			errs = append(errs, fmt.Errorf("ignoring unknown environment variable %v, did you mean %v?", name, suggestion))
+		case strings.HasPrefix(name, "GOLOG_"):
+			errs = append(errs, fmt.Errorf("ignoring unknown environment variable %v, expected one of %v", name, strings.Join(gologEnvVars(), ", ")))
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errs
+}
+
+// gologEnvVars returns the known variables that start with GOLOG_.
+func gologEnvVars() []string {
+	var names []string
+	for _, name := range knownEnvVars {
+		if strings.HasPrefix(name, "GOLOG_") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// closestEnvVar returns the known variable closest to name, and its edit
+// distance from name.
+func closestEnvVar(name string) (string, int) {
+	closest, min := "", -1
+	for _, candidate := range knownEnvVars {
+		if d := editDistance(name, candidate); min < 0 || d < min {
+			closest, min = candidate, d
+		}
+	}
+	return closest, min
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, minInt(cur[j-1]+1, prev[j-1]+cost))
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,37 @@
+package golog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckEnvNames(t *testing.T) {
+	errs := checkEnvNames([]string{
+		"TRACE=true",
+		"TRCE=true",
+		"print_json=true",
+		"GOLOG_SAMPLNG=5",
+		"GOLOG_LEVL=debug",
+		"HOME=/root",
+		"PATH=/bin",
+		"=C:=C:\\",
+	})
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	assert.Equal(t, []string{
+		"ignoring unknown environment variable GOLOG_LEVL, expected one of GOLOG_CONFIG, GOLOG_CONTAINER, GOLOG_CONTEXT, GOLOG_SAMPLING, GOLOG_SILENT",
+		"ignoring unknown environment variable GOLOG_SAMPLNG, did you mean GOLOG_SAMPLING?",
+		"ignoring unknown environment variable TRCE, did you mean TRACE?",
+		"ignoring unknown environment variable print_json, did you mean PRINT_JSON?",
+	}, messages)
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("TRACE", "TRACE"))
+	assert.Equal(t, 1, editDistance("TRCE", "TRACE"))
+	assert.Equal(t, 2, editDistance("GOLOG_SLIENT", "GOLOG_SILENT"))
+	assert.Equal(t, 5, editDistance("", "TRACE"))
+}
@@ -0,0 +1,146 @@
+package golog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultErrorBudgetWindow = 1 * time.Minute
+
+// ErrorBudgetOptions configures an error budget output.
+type ErrorBudgetOptions struct {
+	// Window is the period over which ERRORs are counted and after which a
+	// summary is logged. Defaults to 1 minute.
+	Window time.Duration
+
+	// Budget is the number of ERRORs a component may log per window. Once a
+	// component exceeds its budget, further ERROR lines from it are muted
+	// until the window ends (they're still counted). FATAL errors are never
+	// muted. If 0, nothing is muted.
+	Budget int
+
+	// OnSummary, if set, is called at the end of each window for every
+	// component that logged ERRORs, instead of logging a summary line.
+	OnSummary func(component string, errors int, muted int)
+}
+
+// ErrorBudgetOutput creates an output that counts ERRORs per component and at
+// the end of each window logs a one-line summary for each component that had
+// errors, optionally muting components that exceed their budget so that a
+// runaway component can't drown the log. Everything else is passed through to
+// out. Close the output to log the final summary.
+func ErrorBudgetOutput(out Output, opts *ErrorBudgetOptions) ClosableOutput {
+	o := &errorBudgetOutput{
+		out:    out,
+		opts:   *opts,
+		counts: make(map[string]*errorBudgetCount),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if o.opts.Window <= 0 {
+		o.opts.Window = defaultErrorBudgetWindow
+	}
+	go o.run()
+	return o
+}
+
+type errorBudgetOutput struct {
+	out      Output
+	opts     ErrorBudgetOptions
+	mx       sync.Mutex
+	counts   map[string]*errorBudgetCount
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+type errorBudgetCount struct {
+	errors int
+	muted  int
+}
+
+func (o *errorBudgetOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	if o.count(prefix, severity) {
+		return
+	}
+	o.out.Error(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+func (o *errorBudgetOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.out.Debug(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+// count counts an error for the component with the given prefix and returns
+// true if it should be muted.
+func (o *errorBudgetOutput) count(prefix string, severity string) (mute bool) {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	c := o.counts[prefix]
+	if c == nil {
+		c = &errorBudgetCount{}
+		o.counts[prefix] = c
+	}
+	c.errors++
+	if o.opts.Budget > 0 && c.errors > o.opts.Budget && severity != "FATAL" {
+		c.muted++
+		return true
+	}
+	return false
+}
+
+func (o *errorBudgetOutput) run() {
+	defer close(o.done)
+	ticker := time.NewTicker(o.opts.Window)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.stop:
+			o.summarize()
+			return
+		case <-ticker.C:
+			o.summarize()
+		}
+	}
+}
+
+func (o *errorBudgetOutput) summarize() {
+	o.mx.Lock()
+	counts := o.counts
+	o.counts = make(map[string]*errorBudgetCount)
+	o.mx.Unlock()
+
+	prefixes := make([]string, 0, len(counts))
+	for prefix := range counts {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		c := counts[prefix]
+		if o.opts.OnSummary != nil {
+			o.opts.OnSummary(strings.TrimSuffix(prefix, ": "), c.errors, c.muted)
+			continue
+		}
+		msg := fmt.Sprintf("%d errors in the last %v", c.errors, o.opts.Window)
+		if c.muted > 0 {
+			msg = fmt.Sprintf("%v, %d muted for exceeding the budget of %d", msg, c.muted, o.opts.Budget)
+		}
+		arg := (&injectedArg{
+			fieldsArg: fieldsArg{arg: msg},
+			caller:    "error_budget",
+		}).asArg()
+		values := map[string]interface{}{"errors": c.errors, "muted": c.muted}
+		o.out.Error(prefix, 2, false, "ERROR", arg, values)
+	}
+}
+
+// Close logs the final summary and stops the output's background goroutine.
+func (o *errorBudgetOutput) Close() error {
+	o.stopOnce.Do(func() {
+		close(o.stop)
+	})
+	<-o.done
+	return nil
+}
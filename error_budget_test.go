@@ -0,0 +1,29 @@
+package golog
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorBudgetOutput(t *testing.T) {
+	out := newBuffer()
+	budget := ErrorBudgetOutput(TextOutput(out, ioutil.Discard), &ErrorBudgetOptions{
+		Window: time.Hour,
+		Budget: 2,
+	})
+	reset := SetOutput(budget)
+	defer reset()
+
+	l := LoggerFor("noisy")
+	for i := 0; i < 5; i++ {
+		l.Error("Oh no")
+	}
+	assert.NoError(t, budget.Close())
+	assert.Equal(t, `ERROR noisy: error_budget_test.go:999 Oh no
+ERROR noisy: error_budget_test.go:999 Oh no
+ERROR noisy: error_budget 999 errors in the last 999h999m999s, 999 muted for exceeding the budget of 999 [errors=999 muted=999]
+`, out.String())
+}
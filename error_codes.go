@@ -0,0 +1,72 @@
+package golog
+
+import (
+	"sync"
+)
+
+// errorCodeKey is the context key under which error codes are logged.
+const errorCodeKey = "error_code"
+
+var (
+	errorCodes   = make(map[string]string)
+	errorCodesMx sync.RWMutex
+)
+
+// coder is implemented by errors that carry an error code.
+type coder interface {
+	Code() string
+}
+
+// RegisterErrorCode registers a stable error code, such as
+// "PROXY_DIAL_TIMEOUT", along with a description of what it means.
+func RegisterErrorCode(code string, description string) {
+	errorCodesMx.Lock()
+	defer errorCodesMx.Unlock()
+	errorCodes[code] = description
+}
+
+// ErrorCodes returns all error codes registered with RegisterErrorCode, with
+// their descriptions.
+func ErrorCodes() map[string]string {
+	errorCodesMx.RLock()
+	defer errorCodesMx.RUnlock()
+	result := make(map[string]string, len(errorCodes))
+	for code, description := range errorCodes {
+		result[code] = description
+	}
+	return result
+}
+
+// Code returns a field that attaches the given error code to an event as
+// "error_code", so that dashboards can rely on the code rather than on the
+// message text. Errors that implement a Code() string method get their code
+// attached automatically. Codes aren't registered by using them, so that
+// dynamic codes don't grow the registry, see RegisterErrorCode.
+func Code(code string) Field {
+	return Field{errorCodeKey, code}
+}
+
+// addErrorCode adds the code of arg, or of any error it wraps, to values
+// unless values already has one.
+func addErrorCode(values map[string]interface{}, arg interface{}) {
+	if _, found := values[errorCodeKey]; found {
+		return
+	}
+	for arg != nil {
+		if c, ok := arg.(coder); ok {
+			if code := c.Code(); code != "" {
+				values[errorCodeKey] = code
+				return
+			}
+		}
+		u, ok := arg.(interface{ Unwrap() error })
+		if !ok {
+			return
+		}
+		next := u.Unwrap()
+		if next == nil {
+			return
+		}
+		arg = next
+	}
+}
@@ -0,0 +1,54 @@
+package golog
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type codedError struct {
+	code string
+}
+
+func (e *codedError) Error() string {
+	return "coded error"
+}
+
+func (e *codedError) Code() string {
+	return e.code
+}
+
+type wrappingError struct {
+	err error
+}
+
+func (e *wrappingError) Error() string {
+	return fmt.Sprintf("wrapped: %v", e.err)
+}
+
+func (e *wrappingError) Unwrap() error {
+	return e.err
+}
+
+func TestErrorCodes(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	RegisterErrorCode("PROXY_DIAL_TIMEOUT", "timed out dialing upstream")
+	l := LoggerFor("myprefix")
+	_ = l.Error(WithFields("dial failed", Code("PROXY_DIAL_TIMEOUT")))
+	assert.Contains(t, buf.String(), "error_code=PROXY_DIAL_TIMEOUT")
+
+	buf.Reset()
+	_ = l.Error(&wrappingError{&codedError{"UPSTREAM_RESET"}})
+	assert.Contains(t, buf.String(), "error_code=UPSTREAM_RESET")
+
+	codes := ErrorCodes()
+	assert.Equal(t, "timed out dialing upstream", codes["PROXY_DIAL_TIMEOUT"])
+	_ = Code("UNREGISTERED")
+	_, found := ErrorCodes()["UNREGISTERED"]
+	assert.False(t, found, "using a code shouldn't register it")
+}
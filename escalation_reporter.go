@@ -0,0 +1,318 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultEscalationErrorWindow = 1 * time.Minute
+	defaultEscalationQuietPeriod = 30 * time.Minute
+)
+
+// Incident describes a problem that needs the attention of an operator.
+type Incident struct {
+	// DedupKey identifies the incident so that repeated occurrences of the
+	// same problem are grouped together by the alerting service.
+	DedupKey string
+
+	// Summary is a short, human readable description of the incident.
+	Summary string
+
+	// Component is the prefix of the logger that reported the error.
+	Component string
+
+	// Severity is the severity of the error that triggered the incident.
+	Severity Severity
+
+	// Details is the context of the error that triggered the incident.
+	Details map[string]interface{}
+}
+
+// IncidentNotifier opens and resolves incidents in an alerting service such as
+// PagerDuty or Opsgenie.
+type IncidentNotifier interface {
+	// Trigger opens the given incident, or updates it if an incident with the
+	// same DedupKey is already open.
+	Trigger(incident *Incident) error
+
+	// Resolve resolves the incident with the given DedupKey.
+	Resolve(dedupKey string) error
+}
+
+// EscalationOptions configures an Escalator.
+type EscalationOptions struct {
+	// ErrorThreshold is the number of ERRORs reported by a single component
+	// within ErrorWindow that opens an incident. FATAL errors always open an
+	// incident. If 0, ERRORs never open an incident.
+	ErrorThreshold int
+
+	// ErrorWindow is the window over which ERRORs are counted. Defaults to 1
+	// minute.
+	ErrorWindow time.Duration
+
+	// QuietPeriod is how long an incident has to go without recurring before
+	// it's automatically resolved. Defaults to 30 minutes.
+	QuietPeriod time.Duration
+}
+
+// Escalator is an ErrorReporter that opens incidents using an
+// IncidentNotifier whenever a FATAL error occurs or a component's ERROR rate
+// crosses a threshold. Incidents are deduplicated by the Fingerprint of the
+// error and are resolved once they haven't recurred for a while.
+//
+// Typical usage:
+//
+//	golog.RegisterReporter(golog.NewEscalator(golog.PagerDutyNotifier(routingKey, nil), opts).Report)
+type Escalator struct {
+	notifier IncidentNotifier
+	opts     EscalationOptions
+	mx       sync.Mutex
+	windows  map[string]*errorWindow
+	open     map[string]*openIncident
+}
+
+// openIncident is an incident that's waiting to be resolved.
+type openIncident struct {
+	timer *time.Timer
+	// generation is incremented whenever resolution is pushed back, so that
+	// timers that already fired can tell that they're stale
+	generation int
+}
+
+type errorWindow struct {
+	start     time.Time
+	count     int
+	triggered bool
+}
+
+// NewEscalator creates a new Escalator that notifies the given notifier.
+func NewEscalator(notifier IncidentNotifier, opts *EscalationOptions) *Escalator {
+	e := &Escalator{
+		notifier: notifier,
+		opts:     *opts,
+		windows:  make(map[string]*errorWindow),
+		open:     make(map[string]*openIncident),
+	}
+	if e.opts.ErrorWindow <= 0 {
+		e.opts.ErrorWindow = defaultEscalationErrorWindow
+	}
+	if e.opts.QuietPeriod <= 0 {
+		e.opts.QuietPeriod = defaultEscalationQuietPeriod
+	}
+	return e
+}
+
+// Report implements ErrorReporter.
+func (e *Escalator) Report(err error, severity Severity, ctx map[string]interface{}) {
+	component, _ := ctx["component"].(string)
+	dedupKey := Fingerprint(err, ctx)
+
+	e.mx.Lock()
+	defer e.mx.Unlock()
+
+	if incident, open := e.open[dedupKey]; open {
+		// Still happening, push back automatic resolution
+		incident.timer.Stop()
+		incident.generation++
+		incident.timer = e.resolveAfterQuietPeriod(dedupKey, incident, incident.generation)
+		return
+	}
+
+	trigger := severity >= FATAL
+	if !trigger && e.opts.ErrorThreshold > 0 {
+		now := time.Now()
+		w := e.windows[component]
+		if w == nil || now.Sub(w.start) > e.opts.ErrorWindow {
+			w = &errorWindow{start: now}
+			e.windows[component] = w
+		}
+		w.count++
+		if w.count >= e.opts.ErrorThreshold && !w.triggered {
+			w.triggered = true
+			trigger = true
+		}
+	}
+	if !trigger {
+		return
+	}
+
+	open := &openIncident{}
+	open.timer = e.resolveAfterQuietPeriod(dedupKey, open, 0)
+	e.open[dedupKey] = open
+	incident := &Incident{
+		DedupKey:  dedupKey,
+		Summary:   fmt.Sprintf("%v in %v: %v", severity, component, err),
+		Component: component,
+		Severity:  severity,
+		Details:   ctx,
+	}
+	go func() {
+		if err := e.notifier.Trigger(incident); err != nil {
+			errorOnLogging(err)
+		}
+	}()
+}
+
+// resolveAfterQuietPeriod resolves the incident once the quiet period has
+// elapsed, unless it has recurred since, which makes generation stale. e.mx
+// must be held.
+func (e *Escalator) resolveAfterQuietPeriod(dedupKey string, incident *openIncident, generation int) *time.Timer {
+	return time.AfterFunc(e.opts.QuietPeriod, func() {
+		e.mx.Lock()
+		if e.open[dedupKey] != incident || incident.generation != generation {
+			e.mx.Unlock()
+			return
+		}
+		delete(e.open, dedupKey)
+		e.mx.Unlock()
+		if err := e.notifier.Resolve(dedupKey); err != nil {
+			errorOnLogging(err)
+		}
+	})
+}
+
+// NotifierOptions configures how the built in IncidentNotifiers connect.
+type NotifierOptions struct {
+	// Client is the http.Client used to talk to the alerting service.
+	// Defaults to a client created with NewHTTPClient(Transport).
+	Client *http.Client
+
+	// Transport configures TLS and proxying when Client isn't set.
+	Transport *TransportOptions
+}
+
+// client returns the http.Client configured by opts, which may be nil.
+func (opts *NotifierOptions) client() *http.Client {
+	if opts == nil {
+		return NewHTTPClient(nil)
+	}
+	if opts.Client != nil {
+		return opts.Client
+	}
+	return NewHTTPClient(opts.Transport)
+}
+
+// PagerDutyNotifier creates an IncidentNotifier that uses the PagerDuty Events
+// API v2 with the given integration routing key. opts may be nil.
+func PagerDutyNotifier(routingKey string, opts *NotifierOptions) IncidentNotifier {
+	return &pagerDutyNotifier{
+		routingKey: routingKey,
+		url:        "https://events.pagerduty.com/v2/enqueue",
+		client:     opts.client(),
+	}
+}
+
+type pagerDutyNotifier struct {
+	routingKey string
+	url        string
+	client     *http.Client
+}
+
+func (n *pagerDutyNotifier) Trigger(incident *Incident) error {
+	severity := "error"
+	if incident.Severity >= FATAL {
+		severity = "critical"
+	}
+	return postJSON(n.client, n.url, nil, map[string]interface{}{
+		"routing_key":  n.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    incident.DedupKey,
+		"payload": map[string]interface{}{
+			"summary":        incident.Summary,
+			"source":         incident.Component,
+			"component":      incident.Component,
+			"severity":       severity,
+			"custom_details": incident.Details,
+		},
+	})
+}
+
+func (n *pagerDutyNotifier) Resolve(dedupKey string) error {
+	return postJSON(n.client, n.url, nil, map[string]interface{}{
+		"routing_key":  n.routingKey,
+		"event_action": "resolve",
+		"dedup_key":    dedupKey,
+	})
+}
+
+// OpsgenieNotifier creates an IncidentNotifier that uses the Opsgenie Alert API
+// with the given API key. opts may be nil.
+func OpsgenieNotifier(apiKey string, opts *NotifierOptions) IncidentNotifier {
+	return &opsgenieNotifier{
+		apiKey: apiKey,
+		url:    "https://api.opsgenie.com/v2/alerts",
+		client: opts.client(),
+	}
+}
+
+type opsgenieNotifier struct {
+	apiKey string
+	url    string
+	client *http.Client
+}
+
+func (n *opsgenieNotifier) Trigger(incident *Incident) error {
+	priority := "P3"
+	if incident.Severity >= FATAL {
+		priority = "P1"
+	}
+	details := make(map[string]string, len(incident.Details))
+	for key, value := range incident.Details {
+		details[key] = fmt.Sprint(value)
+	}
+	message := incident.Summary
+	if utf8.RuneCountInString(message) > 130 {
+		// Opsgenie limits messages to 130 characters
+		message = string([]rune(message)[:130])
+	}
+	return postJSON(n.client, n.url, n.headers(), map[string]interface{}{
+		"message":     message,
+		"alias":       incident.DedupKey,
+		"description": incident.Summary,
+		"source":      incident.Component,
+		"details":     details,
+		"priority":    priority,
+	})
+}
+
+func (n *opsgenieNotifier) Resolve(dedupKey string) error {
+	return postJSON(n.client, n.url+"/"+url.PathEscape(dedupKey)+"/close?identifierType=alias", n.headers(), map[string]interface{}{})
+}
+
+func (n *opsgenieNotifier) headers() http.Header {
+	return http.Header{"Authorization": []string{"GenieKey " + n.apiKey}}
+}
+
+func postJSON(client *http.Client, url string, header http.Header, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status posting to %v: %v", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package golog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingNotifier struct {
+	mx        sync.Mutex
+	triggered []*Incident
+	resolved  []string
+}
+
+func (n *recordingNotifier) Trigger(incident *Incident) error {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	n.triggered = append(n.triggered, incident)
+	return nil
+}
+
+func (n *recordingNotifier) Resolve(dedupKey string) error {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	n.resolved = append(n.resolved, dedupKey)
+	return nil
+}
+
+func (n *recordingNotifier) counts() (int, int) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	return len(n.triggered), len(n.resolved)
+}
+
+func TestEscalator(t *testing.T) {
+	notifier := &recordingNotifier{}
+	e := NewEscalator(notifier, &EscalationOptions{
+		ErrorThreshold: 3,
+		QuietPeriod:    50 * time.Millisecond,
+	})
+	ctx := func() map[string]interface{} {
+		return map[string]interface{}{"component": "proxy", "error": "dial failed"}
+	}
+
+	for i := 0; i < 2; i++ {
+		e.Report(nil, ERROR, ctx())
+	}
+	time.Sleep(10 * time.Millisecond)
+	triggered, _ := notifier.counts()
+	assert.Equal(t, 0, triggered, "below threshold shouldn't trigger")
+
+	for i := 0; i < 3; i++ {
+		e.Report(nil, ERROR, ctx())
+	}
+	time.Sleep(10 * time.Millisecond)
+	triggered, resolved := notifier.counts()
+	assert.Equal(t, 1, triggered, "crossing threshold should trigger exactly once")
+	assert.Equal(t, 0, resolved)
+
+	time.Sleep(100 * time.Millisecond)
+	_, resolved = notifier.counts()
+	assert.Equal(t, 1, resolved, "incident should resolve after quiet period")
+	assert.Equal(t, notifier.triggered[0].DedupKey, notifier.resolved[0])
+}
+
+func TestEscalatorRecurrenceAfterTimerFired(t *testing.T) {
+	notifier := &recordingNotifier{}
+	e := NewEscalator(notifier, &EscalationOptions{QuietPeriod: 10 * time.Millisecond})
+	ctx := map[string]interface{}{"component": "proxy", "error": "crashed"}
+
+	e.Report(nil, FATAL, ctx)
+	// recur just as the resolution timer fires, with the recurrence first in
+	// line for the lock
+	e.mx.Lock()
+	reported := make(chan struct{})
+	go func() {
+		e.Report(nil, FATAL, ctx)
+		close(reported)
+	}()
+	time.Sleep(30 * time.Millisecond)
+	e.mx.Unlock()
+	<-reported
+
+	time.Sleep(50 * time.Millisecond)
+	triggered, resolved := notifier.counts()
+	assert.Equal(t, triggered, resolved, "every incident should be resolved exactly once")
+}
+
+func TestNotifierTLS(t *testing.T) {
+	var mx sync.Mutex
+	var paths []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mx.Lock()
+		paths = append(paths, req.URL.Path)
+		mx.Unlock()
+	}))
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	opts := &NotifierOptions{Transport: &TransportOptions{TLS: &tls.Config{RootCAs: roots}}}
+	incident := &Incident{DedupKey: "abc", Summary: "FATAL in proxy: crashed", Severity: FATAL}
+
+	pagerDuty := PagerDutyNotifier("key", opts).(*pagerDutyNotifier)
+	pagerDuty.url = server.URL + "/enqueue"
+	assert.NoError(t, pagerDuty.Trigger(incident))
+	opsgenie := OpsgenieNotifier("key", opts).(*opsgenieNotifier)
+	opsgenie.url = server.URL + "/alerts"
+	assert.NoError(t, opsgenie.Trigger(incident))
+	assert.Equal(t, []string{"/enqueue", "/alerts"}, paths)
+
+	untrusted := PagerDutyNotifier("key", nil).(*pagerDutyNotifier)
+	untrusted.url = server.URL + "/enqueue"
+	assert.Error(t, untrusted.Trigger(incident), "the server's certificate shouldn't be trusted without the TLS config")
+}
+
+func TestOpsgenieMessageLength(t *testing.T) {
+	var body struct {
+		Message string `json:"message"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		json.NewDecoder(req.Body).Decode(&body)
+	}))
+	defer server.Close()
+
+	opsgenie := OpsgenieNotifier("key", nil).(*opsgenieNotifier)
+	opsgenie.url = server.URL
+	assert.NoError(t, opsgenie.Trigger(&Incident{DedupKey: "abc", Summary: strings.Repeat("é", 200)}))
+	assert.Equal(t, strings.Repeat("é", 130), body.Message, "messages should be cut to 130 characters, not bytes")
+}
@@ -0,0 +1,100 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledContext is the largest context map that's reused, so that the pool
+// doesn't hold on to the buckets of a few huge contexts.
+const maxPooledContext = 64
+
+var (
+	eventPool = sync.Pool{
+		New: func() interface{} {
+			return &Event{}
+		},
+	}
+
+	contextPool = sync.Pool{
+		New: func() interface{} {
+			return make(map[string]interface{})
+		},
+	}
+
+	jsonEncodingPool = sync.Pool{
+		New: func() interface{} {
+			e := &jsonEncoding{}
+			e.encoder = json.NewEncoder(&e.buf)
+			return e
+		},
+	}
+)
+
+// AcquireEvent returns an empty Event from a pool. Outputs that are done with
+// an Event once they've encoded it should call Release so that it can be
+// reused by a later log call. Events that are retained, for example in a
+// queue, simply aren't released.
+func AcquireEvent() *Event {
+	return eventPool.Get().(*Event)
+}
+
+// Release resets e and returns it to the pool. e must not be used afterwards.
+// The Context map is only reused if golog made it for e, like when sanitizing
+// values, since it's otherwise owned by whoever built it.
+func (e *Event) Release() {
+	if e.ownsContext {
+		releaseContext(e.Context)
+	}
+	*e = Event{}
+	eventPool.Put(e)
+}
+
+// acquireContext returns an empty context map from a pool, for maps that
+// golog owns until they're passed to releaseContext. The contexts handed to
+// Outputs aren't pooled, since outputs may keep them, for example in a queue.
+func acquireContext() map[string]interface{} {
+	return contextPool.Get().(map[string]interface{})
+}
+
+// releaseContext empties m and returns it to the pool. m must not be used
+// afterwards.
+func releaseContext(m map[string]interface{}) {
+	if len(m) > maxPooledContext {
+		return
+	}
+	for key := range m {
+		delete(m, key)
+	}
+	contextPool.Put(m)
+}
+
+// jsonEncoding is the scratch space for encoding an event as a line of JSON,
+// reused across events, see acquireJSONEncoding.
+type jsonEncoding struct {
+	j       eventJSON
+	buf     bytes.Buffer
+	encoder *json.Encoder
+}
+
+func acquireJSONEncoding() *jsonEncoding {
+	return jsonEncodingPool.Get().(*jsonEncoding)
+}
+
+// encode encodes e into the buffer, followed by a newline, replacing what
+// was there before.
+func (enc *jsonEncoding) encode(e *Event) ([]byte, error) {
+	e.fillJSON(&enc.j)
+	enc.buf.Reset()
+	err := enc.encoder.Encode(&enc.j)
+	return enc.buf.Bytes(), err
+}
+
+func (enc *jsonEncoding) release() {
+	if enc.buf.Cap() > maxBufferSize {
+		return
+	}
+	enc.j = eventJSON{}
+	jsonEncodingPool.Put(enc)
+}
@@ -0,0 +1,44 @@
+package golog
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventPool(t *testing.T) {
+	e := AcquireEvent()
+	e.Message = "hello"
+	e.Context = map[string]interface{}{"a": 1}
+	e.Release()
+
+	for i := 0; i < 10; i++ {
+		e := AcquireEvent()
+		assert.Equal(t, Event{}, *e, "acquired events should be empty")
+		e.Release()
+	}
+}
+
+func TestEventPoolSanitizedContext(t *testing.T) {
+	values := map[string]interface{}{"a": "bad\x00value", "b": 1}
+	e := buildEvent(make([]uintptr, 10), "myprefix: ", 0, false, "DEBUG", "hello", values)
+	assert.True(t, e.ownsContext, "the sanitized copy should belong to the event")
+	assert.Equal(t, 1, e.Context["b"])
+	assert.NotEqual(t, values["a"], e.Context["a"])
+	e.Release()
+	assert.Equal(t, "bad\x00value", values["a"], "releasing should leave the original values alone")
+
+	e = buildEvent(make([]uintptr, 10), "myprefix: ", 0, false, "DEBUG", "hello", map[string]interface{}{"b": 1})
+	assert.False(t, e.ownsContext, "values that don't need sanitizing shouldn't be copied")
+	e.Release()
+}
+
+func BenchmarkJsonOutput(b *testing.B) {
+	out := JsonOutput(ioutil.Discard, ioutil.Discard)
+	values := map[string]interface{}{"conn_id": 7, "host": "example.com"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		out.Debug("myprefix: ", 0, false, "DEBUG", "hello", values)
+	}
+}
@@ -0,0 +1,105 @@
+package golog
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+const defaultFallbackMaxFailures = 3
+
+// FallbackOptions configures a FallbackOutput.
+type FallbackOptions struct {
+	// MaxFailures is the number of consecutive failed writes after which errors
+	// are mirrored to Fallback. Defaults to 3.
+	MaxFailures int
+
+	// Fallback is where errors are mirrored to. Defaults to stderr.
+	Fallback io.Writer
+}
+
+// FallbackOutput creates an output that uses newOutput (for example
+// TextOutput or JsonOutput) to write to errorWriter and debugWriter. Once
+// opts.MaxFailures consecutive writes to errorWriter have failed, ERROR and
+// FATAL events are also written to opts.Fallback. Failures are counted
+// separately for each writer, and once either reaches opts.MaxFailures a
+// single diagnostic is written to opts.Fallback explaining why. Mirroring
+// stops as soon as a write to errorWriter succeeds again. This makes sure
+// that a misconfigured log destination doesn't mean total silence during an
+// incident. opts may be nil.
+func FallbackOutput(errorWriter io.Writer, debugWriter io.Writer, newOutput func(errorWriter io.Writer, debugWriter io.Writer) Output, opts *FallbackOptions) Output {
+	o := &fallbackOutput{
+		maxFailures: defaultFallbackMaxFailures,
+		fallback:    stderr,
+	}
+	if opts != nil {
+		if opts.MaxFailures > 0 {
+			o.maxFailures = int64(opts.MaxFailures)
+		}
+		if opts.Fallback != nil {
+			o.fallback = opts.Fallback
+		}
+	}
+	o.out = newOutput(&failureCountingWriter{w: errorWriter, o: o, counts: &o.errorFailures, name: "errors"}, &failureCountingWriter{w: debugWriter, o: o, counts: &o.debugFailures, name: "debug messages"})
+	o.fallbackOut = newOutput(o.fallback, o.fallback)
+	return o
+}
+
+type fallbackOutput struct {
+	// accessed atomically, first to be 64-bit aligned on 32-bit platforms
+	errorFailures failureCounts
+	debugFailures failureCounts
+
+	out         Output
+	fallbackOut Output
+	fallback    io.Writer
+	maxFailures int64
+}
+
+// failureCounts tracks the consecutive failed writes to one writer. It's
+// padded to a multiple of 8 bytes so that failures stays 64-bit aligned in
+// consecutive failureCounts.
+type failureCounts struct {
+	failures  int64
+	diagnosed int32
+	_         int32
+}
+
+func (o *fallbackOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.out.Error(prefix, skipFrames+1, printStack, severity, arg, values)
+	if atomic.LoadInt64(&o.errorFailures.failures) >= o.maxFailures {
+		o.fallbackOut.Error(prefix, skipFrames+1, printStack, severity, arg, values)
+	}
+}
+
+func (o *fallbackOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.out.Debug(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+func (o *fallbackOutput) recordWrite(counts *failureCounts, name string, err error) {
+	if err == nil {
+		atomic.StoreInt64(&counts.failures, 0)
+		atomic.StoreInt32(&counts.diagnosed, 0)
+		return
+	}
+	if atomic.AddInt64(&counts.failures, 1) >= o.maxFailures && atomic.CompareAndSwapInt32(&counts.diagnosed, 0, 1) {
+		if counts == &o.errorFailures {
+			_, _ = fmt.Fprintf(o.fallback, "golog: %d consecutive writes failed for %v, last error: %v; mirroring errors here until writes succeed again\n", o.maxFailures, name, err)
+		} else {
+			_, _ = fmt.Fprintf(o.fallback, "golog: %d consecutive writes failed for %v, last error: %v\n", o.maxFailures, name, err)
+		}
+	}
+}
+
+type failureCountingWriter struct {
+	w      io.Writer
+	o      *fallbackOutput
+	counts *failureCounts
+	name   string
+}
+
+func (w *failureCountingWriter) Write(p []byte) (int, error) {
+	n, err := redirectStdout(w.w).Write(p)
+	w.o.recordWrite(w.counts, w.name, err)
+	return n, err
+}
@@ -0,0 +1,65 @@
+package golog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type toggleWriter struct {
+	bytes.Buffer
+	broken bool
+}
+
+func (w *toggleWriter) Write(p []byte) (int, error) {
+	if w.broken {
+		return 0, errors.New("disk full")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestFallbackOutput(t *testing.T) {
+	primary := &toggleWriter{broken: true}
+	fallback := &bytes.Buffer{}
+	out := FallbackOutput(primary, primary, TextOutput, &FallbackOptions{MaxFailures: 2, Fallback: fallback})
+
+	out.Error("myprefix: ", 0, false, "ERROR", "first", nil)
+	assert.Empty(t, fallback.String(), "shouldn't fall back before reaching max failures")
+	out.Error("myprefix: ", 0, false, "ERROR", "second", nil)
+	out.Debug("myprefix: ", 0, false, "DEBUG", "debug", nil)
+	out.Error("myprefix: ", 0, false, "ERROR", "third", nil)
+	logged := fallback.String()
+	assert.Equal(t, 1, strings.Count(logged, "consecutive writes failed"))
+	assert.Contains(t, logged, "second")
+	assert.Contains(t, logged, "third")
+	assert.NotContains(t, logged, "debug")
+
+	primary.broken = false
+	fallback.Reset()
+	out.Error("myprefix: ", 0, false, "ERROR", "recovered", nil)
+	assert.Empty(t, fallback.String())
+	assert.Contains(t, primary.String(), "recovered")
+}
+
+func TestFallbackOutputCountsWritersSeparately(t *testing.T) {
+	errorWriter, debugWriter := &toggleWriter{}, &toggleWriter{broken: true}
+	fallback := &bytes.Buffer{}
+	out := FallbackOutput(errorWriter, debugWriter, TextOutput, &FallbackOptions{MaxFailures: 2, Fallback: fallback})
+
+	for i := 0; i < 3; i++ {
+		out.Debug("myprefix: ", 0, false, "DEBUG", "debug", nil)
+	}
+	out.Error("myprefix: ", 0, false, "ERROR", "error", nil)
+	assert.Equal(t, "golog: 2 consecutive writes failed for debug messages, last error: disk full\n", fallback.String(), "errors shouldn't be mirrored while their writer works")
+
+	errorWriter.broken, debugWriter.broken = true, false
+	fallback.Reset()
+	out.Error("myprefix: ", 0, false, "ERROR", "first", nil)
+	out.Debug("myprefix: ", 0, false, "DEBUG", "debug", nil)
+	out.Error("myprefix: ", 0, false, "ERROR", "second", nil)
+	assert.Contains(t, fallback.String(), "consecutive writes failed for errors", "debug writes shouldn't reset the count of failed error writes")
+	assert.Contains(t, fallback.String(), "second")
+}
@@ -0,0 +1,51 @@
+package golog
+
+import (
+	"fmt"
+
+	"github.com/getlantern/context"
+)
+
+// Field is a key/value pair that's attached to a log event as structured
+// context, alongside the ops context.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// WithFields returns an argument for the Logger methods that logs arg with the
+// given fields attached to its context. arg is printed as usual.
+func WithFields(arg interface{}, fields ...Field) interface{} {
+	return &fieldsArg{arg: arg, fields: fields}
+}
+
+// fieldsArg attaches fields to an argument by implementing
+// context.Contextual, which ops picks up when building the context map.
+type fieldsArg struct {
+	arg    interface{}
+	fields []Field
+}
+
+func (a *fieldsArg) Fill(m context.Map) {
+	if c, ok := a.arg.(context.Contextual); ok {
+		c.Fill(m)
+	}
+	for _, field := range a.fields {
+		m[field.Key] = field.Value
+	}
+}
+
+func (a *fieldsArg) String() string {
+	return fmt.Sprint(a.arg)
+}
+
+// Error makes fieldsArg usable with the error methods without losing its
+// fields.
+func (a *fieldsArg) Error() string {
+	return a.String()
+}
+
+func (a *fieldsArg) Unwrap() error {
+	err, _ := a.arg.(error)
+	return err
+}
@@ -0,0 +1,273 @@
+package golog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the format of the time in backup file names. It avoids
+// colons, which Windows doesn't allow in file names.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// FileOutputOptions configures a FileOutput.
+type FileOutputOptions struct {
+	// Format is the name of the format to write in, as registered with
+	// RegisterEncoder, like "text" (the default) or "json".
+	Format string
+
+	// MaxSize, if positive, is the size in bytes beyond which the file is
+	// rotated. A single event larger than MaxSize is still written whole.
+	MaxSize int64
+
+	// MaxAge, if positive, is how long backups are kept for, based on the
+	// time in their names.
+	MaxAge time.Duration
+
+	// MaxBackups, if positive, is the number of backups to keep. The oldest
+	// ones are removed first.
+	MaxBackups int
+
+	// Compress gzips backups, adding .gz to their names.
+	Compress bool
+}
+
+// FileOutput creates an output that writes errors and debug messages to the
+// log file at path, creating it and its directory if necessary. With a
+// MaxSize, the file is rotated once it grows past it, by renaming it to a
+// backup named after the file and the time of rotation in UTC, like
+// app-2020-01-02T03-04-05.000.log for app.log. Compressing and removing
+// backups happen in the background. Use it with SetOutput, and Close it once
+// it's no longer in use. FileOutputForApp puts the file in the application's
+// LogDir.
+func FileOutput(path string, opts FileOutputOptions) (ClosableOutput, error) {
+	if opts.Format == "" {
+		opts.Format = "text"
+	}
+	file, err := OpenLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rf := &rotatingFile{
+		file: file,
+		opts: opts,
+		mill: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	out, err := NewOutput(opts.Format, &OutputOptions{ErrorWriter: rf, DebugWriter: rf})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	go rf.millBackups()
+	// clean up after previous runs
+	rf.mill <- struct{}{}
+	return &fileOutput{Output: out, rf: rf}, nil
+}
+
+type fileOutput struct {
+	Output
+	rf *rotatingFile
+}
+
+func (o *fileOutput) wrapped() Output {
+	return o.Output
+}
+
+// Close closes the file after waiting for backups to be compressed and
+// removed.
+func (o *fileOutput) Close() error {
+	return o.rf.Close()
+}
+
+// rotatingFile writes to a LogFile, rotating it according to the options.
+type rotatingFile struct {
+	file      *LogFile
+	opts      FileOutputOptions
+	mx        sync.Mutex
+	closeOnce sync.Once
+	// closed is set under mx before mill is closed, so that rotate stops
+	// signalling it
+	closed bool
+	// mill signals the millBackups goroutine that there's a new backup
+	mill chan struct{}
+	done chan struct{}
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if f.opts.MaxSize > 0 {
+		if size := f.file.currentSize(); size > 0 && size+int64(len(p)) > f.opts.MaxSize {
+			if err := f.rotate(); err != nil {
+				errorOnLogging(err)
+			}
+		}
+	}
+	return f.file.Write(p)
+}
+
+func (f *rotatingFile) rotate() error {
+	t := time.Now()
+	backup := f.backupName(t)
+	for exists(backup) || exists(backup+".gz") {
+		// rotated more than once within a millisecond
+		t = t.Add(time.Millisecond)
+		backup = f.backupName(t)
+	}
+	if err := f.file.Rotate(backup); err != nil {
+		return err
+	}
+	if f.closed {
+		return nil
+	}
+	select {
+	case f.mill <- struct{}{}:
+	default:
+		// already signalled
+	}
+	return nil
+}
+
+// backupName returns the name of the backup for a rotation at the given
+// time.
+func (f *rotatingFile) backupName(t time.Time) string {
+	dir, prefix, ext := f.backupParts()
+	return filepath.Join(dir, prefix+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+func (f *rotatingFile) backupParts() (dir string, prefix string, ext string) {
+	dir, name := filepath.Split(f.file.Path())
+	ext = filepath.Ext(name)
+	return dir, strings.TrimSuffix(name, ext) + "-", ext
+}
+
+func (f *rotatingFile) Close() error {
+	var err error
+	f.closeOnce.Do(func() {
+		f.mx.Lock()
+		f.closed = true
+		close(f.mill)
+		f.mx.Unlock()
+		<-f.done
+		f.mx.Lock()
+		err = f.file.Close()
+		f.mx.Unlock()
+	})
+	return err
+}
+
+func (f *rotatingFile) millBackups() {
+	defer close(f.done)
+	for range f.mill {
+		if err := f.millOnce(); err != nil {
+			errorOnLogging(err)
+		}
+	}
+}
+
+type backupFile struct {
+	path string
+	t    time.Time
+}
+
+// millOnce compresses and removes backups according to the options.
+func (f *rotatingFile) millOnce() error {
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+	// newest first
+	sort.Slice(backups, func(i, j int) bool { return backups[i].t.After(backups[j].t) })
+	cutoff := time.Time{}
+	if f.opts.MaxAge > 0 {
+		cutoff = time.Now().Add(-f.opts.MaxAge)
+	}
+	var errs []string
+	for i, backup := range backups {
+		if (f.opts.MaxBackups > 0 && i >= f.opts.MaxBackups) || backup.t.Before(cutoff) {
+			if err := os.Remove(backup.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err.Error())
+			}
+			continue
+		}
+		if f.opts.Compress && !strings.HasSuffix(backup.path, ".gz") {
+			if err := compressFile(backup.path); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to clean up backups of %v: %v", f.file.Path(), strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// backups lists the backups of the file, with the times in their names.
+func (f *rotatingFile) backups() ([]backupFile, error) {
+	dir, prefix, ext := f.backupParts()
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		t, err := time.Parse(backupTimeFormat, strings.TrimPrefix(stamp, prefix))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), t: t})
+	}
+	return backups, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// compressFile gzips the file at path to path.gz and removes the original.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := path + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	in.Close()
+	return os.Remove(path)
+}
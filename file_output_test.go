@@ -0,0 +1,121 @@
+package golog
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-fileoutput")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	out, err := FileOutput(path, FileOutputOptions{Format: "json", MaxSize: 200, MaxBackups: 2, Compress: true})
+	require.NoError(t, err)
+	reset := SetOutput(out)
+	l := LoggerFor("myprefix")
+	for i := 0; i < 10; i++ {
+		l.Debugf("event %d", i)
+	}
+	reset()
+	require.NoError(t, out.Close())
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var event Event
+	require.NoError(t, json.Unmarshal([]byte(strings.SplitN(string(b), "\n", 2)[0]), &event))
+	assert.Equal(t, "myprefix", event.Component)
+	assert.True(t, len(b) <= 200)
+	assert.Contains(t, string(b), "event 9")
+
+	names := dirNames(t, dir)
+	if assert.Len(t, names, 3, "should have kept only the newest backups") {
+		assert.Equal(t, "app.log", names[2])
+		names = names[:2]
+		for _, name := range names {
+			assert.True(t, strings.HasPrefix(name, "app-"), name)
+			assert.True(t, strings.HasSuffix(name, ".log.gz"), name)
+		}
+		gz, err := os.Open(filepath.Join(dir, names[1]))
+		require.NoError(t, err)
+		defer gz.Close()
+		r, err := gzip.NewReader(gz)
+		require.NoError(t, err)
+		backup, err := ioutil.ReadAll(r)
+		require.NoError(t, err)
+		assert.NotContains(t, string(backup), "event 0", "oldest backups should have been removed")
+		assert.Contains(t, string(backup), "myprefix")
+	}
+}
+
+func TestFileOutputMaxAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-fileoutput")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	old := filepath.Join(dir, "app-"+time.Now().Add(-48*time.Hour).UTC().Format(backupTimeFormat)+".log")
+	recent := filepath.Join(dir, "app-"+time.Now().Add(-time.Hour).UTC().Format(backupTimeFormat)+".log")
+	unrelated := filepath.Join(dir, "app-notes.log")
+	for _, name := range []string{old, recent, unrelated} {
+		require.NoError(t, ioutil.WriteFile(name, []byte("backup\n"), 0600))
+	}
+
+	out, err := FileOutput(filepath.Join(dir, "app.log"), FileOutputOptions{MaxAge: 24 * time.Hour})
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+	assert.Equal(t, []string{filepath.Base(recent), "app-notes.log", "app.log"}, dirNames(t, dir))
+}
+
+func TestFileOutputUnknownFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-fileoutput")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = FileOutput(filepath.Join(dir, "app.log"), FileOutputOptions{Format: "xml"})
+	assert.Error(t, err)
+}
+
+func dirNames(t *testing.T, dir string) []string {
+	entries, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFileOutputCloseWhileWriting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-fileoutput")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	out, err := FileOutput(filepath.Join(dir, "app.log"), FileOutputOptions{MaxSize: 100})
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				out.Debug("myprefix: ", 0, false, "DEBUG", "rotate often", nil)
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	assert.NoError(t, out.Close())
+	wg.Wait()
+}
@@ -0,0 +1,30 @@
+package golog
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Fingerprint returns a short, stable identifier for the kind of error that
+// was reported, for use in grouping and deduplicating reports. It's derived
+// from the reporting component, the error's type, the error's message
+// template (so that differing arguments yield the same fingerprint) and the
+// function in which the error was created. ctx is the context passed to an
+// ErrorReporter.
+func Fingerprint(err error, ctx map[string]interface{}) string {
+	text, _ := ctx["error"].(string)
+	if text == "" && err != nil {
+		text = err.Error()
+	}
+	location, _ := ctx["error_location"].(string)
+	// Ignore the file and line number so that unrelated code changes don't
+	// change the fingerprint
+	if i := strings.Index(location, " ("); i >= 0 {
+		location = location[:i]
+	}
+	h := sha1.New()
+	fmt.Fprintf(h, "%v\x00%v\x00%v\x00%v", ctx["component"], ctx["error_type"], text, location)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
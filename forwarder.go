@@ -0,0 +1,167 @@
+package golog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"sync"
+)
+
+// maxForwardedLineSize is the size beyond which forwarded lines are
+// truncated.
+var maxForwardedLineSize = maxJSONLineSize
+
+// ForwardOptions configures Forward.
+type ForwardOptions struct {
+	// Prefix is the component for lines that aren't golog JSON events.
+	// Defaults to "forwarded".
+	Prefix string
+
+	// Severity is the severity of lines that aren't golog JSON events.
+	// Defaults to DEBUG.
+	Severity string
+
+	// Source is recorded as the caller of lines that aren't golog JSON
+	// events. Defaults to "stdin".
+	Source string
+
+	// Fields are added to every forwarded event, like the name of the helper
+	// process. They don't override fields of the events.
+	Fields []Field
+}
+
+// Forward reads golog JSON events, one per line, like those written by a
+// JsonOutput in a helper process, from r and re-emits them through this
+// process's outputs and reporters, preserving their original component,
+// severity, caller, stack, context and, where recorded, time. Other lines are
+// emitted as messages of their own. Lines longer than 16 MB are truncated,
+// with a warning on stderr, and forwarded as messages too. It returns once r
+// is exhausted. opts may be nil.
+func Forward(r io.Reader, opts *ForwardOptions) error {
+	resolved := ForwardOptions{}
+	if opts != nil {
+		resolved = *opts
+	}
+	if resolved.Prefix == "" {
+		resolved.Prefix = "forwarded"
+	}
+	if resolved.Severity == "" {
+		resolved.Severity = "DEBUG"
+	}
+	if resolved.Source == "" {
+		resolved.Source = "stdin"
+	}
+
+	reader := bufio.NewReaderSize(r, 64*1024)
+	var line []byte
+	truncated := false
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !truncated {
+			if room := maxForwardedLineSize - len(line); len(chunk) > room {
+				chunk = chunk[:room]
+				truncated = true
+			}
+			line = append(line, chunk...)
+		}
+		if isPrefix {
+			// keep reading the rest of the line
+			continue
+		}
+		if truncated {
+			errorOnLogging(fmt.Errorf("truncated forwarded line longer than %d bytes", maxForwardedLineSize))
+		}
+		forwardLine(bytes.TrimSpace(line), truncated, &resolved)
+		line = line[:0]
+		truncated = false
+	}
+}
+
+func forwardLine(line []byte, truncated bool, opts *ForwardOptions) {
+	if len(line) == 0 {
+		return
+	}
+	var event RecordedEvent
+	if truncated || line[0] != '{' || json.Unmarshal(line, &event) != nil || event.Message == "" {
+		NewEvent(opts.Severity, opts.Prefix, string(line)).
+			Caller(opts.Source).
+			With(opts.Fields...).
+			Emit()
+		return
+	}
+	forwardEvent(&event, opts)
+}
+
+func forwardEvent(event *RecordedEvent, opts *ForwardOptions) {
+	severity := event.Severity
+	if severity == "" {
+		severity = "DEBUG"
+	}
+	fields := make([]Field, 0, len(event.Context)+len(opts.Fields))
+	for key, value := range event.Context {
+		fields = append(fields, Field{key, value})
+	}
+	for _, field := range opts.Fields {
+		if _, found := event.Context[field.Key]; !found {
+			fields = append(fields, field)
+		}
+	}
+	caller := event.Caller
+	if caller == "" {
+		caller = opts.Source
+	}
+	b := NewEvent(severity, event.Component, event.Message).
+		With(fields...).
+		Caller(caller).
+		At(event.Time)
+	b.stack = event.Stack
+	b.Emit()
+}
+
+// ForwardCommand runs cmd, forwarding what it writes to stdout and stderr as
+// with Forward, and waits for it to exit. Lines that aren't golog JSON
+// events are recorded with "stdout" or "stderr" as their caller.
+func ForwardCommand(cmd *exec.Cmd, opts *ForwardOptions) error {
+	resolved := ForwardOptions{}
+	if opts != nil {
+		resolved = *opts
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	forward := func(r io.Reader, source string) {
+		defer wg.Done()
+		streamOpts := resolved
+		streamOpts.Source = source
+		if err := Forward(r, &streamOpts); err != nil {
+			errorOnLogging(err)
+			_, _ = io.Copy(ioutil.Discard, r)
+		}
+	}
+	wg.Add(2)
+	go forward(stdout, "stdout")
+	go forward(stderr, "stderr")
+	// the pipes must be drained before waiting
+	wg.Wait()
+	return cmd.Wait()
+}
@@ -0,0 +1,91 @@
+package golog
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForward(t *testing.T) {
+	// what a helper process logs
+	child := &bytes.Buffer{}
+	reset := SetOutput(JsonOutput(child, child))
+	LoggerFor("helper.dial").Debugw("dialing", Field{"addr", "example.com:443"})
+	LoggerFor("helper.dial").Error("dial failed")
+	reset()
+	child.WriteString(`{"ts":"2026-01-02T03:04:05Z","msg":"recorded","component":"helper","caller":"main.go:7","level":"TRACE","stack":"main.main()\n\tmain.go:7"}` + "\n")
+	child.WriteString("panic: something went wrong\n")
+
+	rb := NewRingBuffer(nil, 10)
+	reset = SetOutput(rb)
+	err := Forward(child, &ForwardOptions{Prefix: "helper", Fields: []Field{{"pid", 1234}, {"addr", "ignored"}}})
+	reset()
+	require.NoError(t, err)
+
+	events := rb.Events()
+	require.Len(t, events, 4)
+	assert.Equal(t, "helper.dial", events[0].Component)
+	assert.Equal(t, "DEBUG", events[0].Severity)
+	assert.Equal(t, "dialing", events[0].Message)
+	assert.Regexp(t, `^forwarder_test\.go:\d+$`, events[0].Caller, "caller should be that of the helper")
+	assert.Equal(t, "example.com:443", events[0].Context["addr"], "forwarded fields shouldn't override the event's")
+	assert.Equal(t, 1234, events[0].Context["pid"])
+
+	assert.Equal(t, "ERROR", events[1].Severity)
+	assert.Contains(t, events[1].Message, "dial failed")
+
+	assert.Equal(t, "TRACE", events[2].Severity)
+	assert.Equal(t, "main.go:7", events[2].Caller)
+	assert.Equal(t, "main.main()\n\tmain.go:7", events[2].Stack)
+	assert.True(t, events[2].Time.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)), "original time should be kept")
+
+	assert.Equal(t, "helper", events[3].Component)
+	assert.Equal(t, "panic: something went wrong", events[3].Message)
+	assert.Equal(t, "stdin", events[3].Caller)
+}
+
+func TestForwardCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no shell")
+	}
+	rb := NewRingBuffer(nil, 10)
+	reset := SetOutput(rb)
+	cmd := exec.Command("sh", "-c", `echo '{"msg":"hello","component":"child","caller":"child.go:1","level":"DEBUG"}'; echo oops >&2`)
+	err := ForwardCommand(cmd, nil)
+	reset()
+	require.NoError(t, err)
+
+	var lines []string
+	for _, event := range rb.Events() {
+		lines = append(lines, event.Component+" "+event.Caller+" "+event.Message)
+	}
+	assert.ElementsMatch(t, []string{"child child.go:1 hello", "forwarded stderr oops"}, lines, strings.Join(lines, "\n"))
+}
+
+func TestForwardLongLine(t *testing.T) {
+	oldMax := maxForwardedLineSize
+	maxForwardedLineSize = 10
+	defer func() { maxForwardedLineSize = oldMax }()
+	oldStderr := stderr
+	errs := &bytes.Buffer{}
+	stderr = errs
+	defer func() { stderr = oldStderr }()
+
+	rb := NewRingBuffer(nil, 10)
+	reset := SetOutput(rb)
+	err := Forward(strings.NewReader("short\n"+strings.Repeat("x", 100*1024)+"\nafter"), nil)
+	reset()
+	require.NoError(t, err)
+
+	var messages []string
+	for _, event := range rb.Events() {
+		messages = append(messages, event.Message)
+	}
+	assert.Equal(t, []string{"short", "xxxxxxxxxx", "after"}, messages, "lines after an oversized one should still be forwarded")
+	assert.Equal(t, "Unable to log: truncated forwarded line longer than 10 bytes\n", errs.String())
+}
@@ -0,0 +1,121 @@
+//go:build go1.18
+// +build go1.18
+
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"unicode/utf8"
+)
+
+// Besides the f.Add calls, the seed corpora of FuzzJsonOutput, FuzzTextOutput
+// and FuzzParseSyslog are in testdata/fuzz/FuzzName, where go test -fuzz=FuzzName
+// also saves any failing inputs it finds.
+
+func FuzzJsonOutput(f *testing.F) {
+	f.Add("hello", "key", "value")
+	f.Add("multi\nline\tmessage", "", "\x1b[31mred")
+	f.Add("\xff\xfe invalid", "k\"ey", "\x00\x01\x02\x00")
+	f.Fuzz(func(t *testing.T, msg string, key string, value string) {
+		buf := &bytes.Buffer{}
+		out := JsonOutput(buf, buf)
+		out.Debug("fuzz: ", 0, false, "DEBUG", msg, map[string]interface{}{key: value})
+
+		line := buf.Bytes()
+		if bytes.Count(line, []byte("\n")) != 1 || line[len(line)-1] != '\n' {
+			t.Fatalf("expected a single line, got %q", line)
+		}
+		var event Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		if event.Component != "fuzz" || event.Severity != "DEBUG" {
+			t.Fatalf("unexpected component or severity in %q", line)
+		}
+		if !utf8.ValidString(event.Message) {
+			t.Fatalf("invalid UTF-8 in message %q", event.Message)
+		}
+		if utf8.ValidString(msg) && event.Message != sanitize(msg) {
+			t.Fatalf("message %q didn't round trip, got %q", msg, event.Message)
+		}
+	})
+}
+
+func FuzzTextOutput(f *testing.F) {
+	f.Add("hello", "key", "value")
+	f.Add("\x1b]0;title\x07", "\r", "\x1b[2J")
+	f.Add("multi\nline", "k", "\n")
+	f.Fuzz(func(t *testing.T, msg string, key string, value string) {
+		buf := &bytes.Buffer{}
+		out := TextOutput(buf, buf)
+		out.Debug("fuzz: ", 0, false, "DEBUG", msg, map[string]interface{}{key: value})
+
+		written := buf.Bytes()
+		if len(written) == 0 || written[len(written)-1] != '\n' {
+			t.Fatalf("expected output ending with a newline, got %q", written)
+		}
+		if !bytes.HasPrefix(written, []byte("DEBUG fuzz: ")) {
+			t.Fatalf("unexpected header in %q", written)
+		}
+		if bytes.ContainsAny(written, "\x1b\r") {
+			t.Fatalf("terminal control characters weren't escaped in %q", written)
+		}
+	})
+}
+
+func FuzzReadRecordedEvents(f *testing.F) {
+	f.Add([]byte(`{"ts":"2026-01-02T03:04:05Z","msg":"hi","component":"c","level":"DEBUG","context":{"n":1}}` + "\n"))
+	f.Add([]byte("not json\n{\"msg\":\n"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		events, _ := ReadRecordedEvents(bytes.NewReader(data))
+		for _, event := range events {
+			if event == nil {
+				t.Fatal("nil event")
+			}
+		}
+	})
+}
+
+func FuzzParseSyslog(f *testing.F) {
+	f.Add([]byte(`<165>1 2026-10-11T22:14:15.003Z host app 42 ID47 [id@1 a="1" b="x\"y"] message`))
+	f.Add([]byte("<34>Oct 11 22:14:15 host su[123]: message"))
+	f.Add([]byte("<13>1 - - - - - ["))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r, err := ParseSyslog(data)
+		if err != nil {
+			return
+		}
+		if r.Severity < 0 || r.Severity > 7 || r.Facility < 0 || r.Facility > 23 {
+			t.Fatalf("priority out of range: %d/%d", r.Facility, r.Severity)
+		}
+	})
+}
+
+func FuzzParseEnvConfig(f *testing.F) {
+	f.Add("level=info&format=json&output=stderr&sample=10")
+	f.Add(`{"level": "debug", "sample": "5%"}`)
+	f.Fuzz(func(t *testing.T, spec string) {
+		cfg, err := ParseEnvConfig(spec)
+		if err != nil {
+			return
+		}
+		for _, rule := range cfg.Sampling {
+			if rule.Keep < 0 || rule.Keep > 1 {
+				t.Fatalf("sampling rule out of range: %v", rule.Keep)
+			}
+		}
+	})
+}
+
+func FuzzReadJournalExport(f *testing.F) {
+	f.Add([]byte("PRIORITY=3\nMESSAGE=hi\n\nMESSAGE\n\x02\x00\x00\x00\x00\x00\x00\x00a\nb\n\n"))
+	f.Add([]byte("MESSAGE\n\xff\xff\xff\xff\xff\xff\xff\xff"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reset := SetOutput(JsonOutput(ioutil.Discard, ioutil.Discard))
+		defer reset()
+		_ = ReadJournalExport(bytes.NewReader(data), "fuzz")
+	})
+}
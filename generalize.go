@@ -0,0 +1,133 @@
+package golog
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// FieldTransformer transforms the value of a context field before it's
+// output, see TransformingOutput.
+type FieldTransformer func(value interface{}) interface{}
+
+// TransformingOutput creates an output that applies the transformer registered
+// for a context field's key to the field's value before passing events
+// through to out, for example to generalize data that identifies users:
+//
+//	golog.TransformingOutput(out, map[string]golog.FieldTransformer{
+//		"client_ip": golog.TruncateIP,
+//		"user_id":   golog.HashIdentifier(24 * time.Hour),
+//	})
+func TransformingOutput(out Output, transformers map[string]FieldTransformer) Output {
+	return &transformingOutput{out: out, transformers: transformers}
+}
+
+type transformingOutput struct {
+	out          Output
+	transformers map[string]FieldTransformer
+}
+
+func (o *transformingOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.out.Error(prefix, skipFrames+1, printStack, severity, arg, o.transform(values))
+}
+
+func (o *transformingOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.out.Debug(prefix, skipFrames+1, printStack, severity, arg, o.transform(values))
+}
+
+// transform returns a copy of values with the transformers applied, or values
+// itself if none apply.
+func (o *transformingOutput) transform(values map[string]interface{}) map[string]interface{} {
+	var transformed map[string]interface{}
+	for key, transformer := range o.transformers {
+		value, found := values[key]
+		if !found {
+			continue
+		}
+		if transformed == nil {
+			transformed = make(map[string]interface{}, len(values))
+			for k, v := range values {
+				transformed[k] = v
+			}
+		}
+		transformed[key] = transformer(value)
+	}
+	if transformed == nil {
+		return values
+	}
+	return transformed
+}
+
+// TruncateIP is a FieldTransformer that truncates IPv4 addresses to their /24
+// and IPv6 addresses to their /48 network, dropping any port. Values can be
+// net.IPs, net.Addrs or strings. Values that aren't IP addresses are replaced
+// with "[redacted]" rather than risk logging them as they are.
+func TruncateIP(value interface{}) interface{} {
+	var ip net.IP
+	switch v := value.(type) {
+	case net.IP:
+		ip = v
+	case *net.IPAddr:
+		ip = v.IP
+	case *net.TCPAddr:
+		ip = v.IP
+	case *net.UDPAddr:
+		ip = v.IP
+	default:
+		s := fmt.Sprint(value)
+		if host, _, err := net.SplitHostPort(s); err == nil {
+			s = host
+		}
+		ip = net.ParseIP(s)
+	}
+	if ip == nil {
+		return "[redacted]"
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// HashIdentifier returns a FieldTransformer that replaces values with a keyed
+// hash, so that events for the same identifier can still be correlated
+// without logging the identifier itself. The key is random and replaced with a
+// new one every rotation, after which the same identifier hashes differently,
+// limiting correlation to one rotation period. If rotation is 0, the key lasts
+// for the life of the process.
+func HashIdentifier(rotation time.Duration) FieldTransformer {
+	h := &identifierHasher{rotation: rotation}
+	return h.hash
+}
+
+type identifierHasher struct {
+	rotation time.Duration
+	mx       sync.Mutex
+	salt     []byte
+	expires  time.Time
+}
+
+func (h *identifierHasher) hash(value interface{}) interface{} {
+	mac := hmac.New(sha256.New, h.currentSalt())
+	fmt.Fprint(mac, value)
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+func (h *identifierHasher) currentSalt() []byte {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	now := time.Now()
+	if h.salt == nil || (h.rotation > 0 && !now.Before(h.expires)) {
+		h.salt = make([]byte, 32)
+		if _, err := rand.Read(h.salt); err != nil {
+			errorOnLogging(fmt.Errorf("unable to generate salt for hashing identifiers: %v", err))
+		}
+		h.expires = now.Add(h.rotation)
+	}
+	return h.salt
+}
@@ -0,0 +1,51 @@
+package golog
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateIP(t *testing.T) {
+	assert.Equal(t, "1.2.3.0", TruncateIP("1.2.3.4"))
+	assert.Equal(t, "1.2.3.0", TruncateIP("1.2.3.4:443"))
+	assert.Equal(t, "1.2.3.0", TruncateIP(net.ParseIP("1.2.3.4")))
+	assert.Equal(t, "1.2.3.0", TruncateIP(&net.TCPAddr{IP: net.ParseIP("1.2.3.4"), Port: 80}))
+	assert.Equal(t, "2001:db8:abcd::", TruncateIP("2001:db8:abcd:12:34::1"))
+	assert.Equal(t, "2001:db8:abcd::", TruncateIP("[2001:db8:abcd:12:34::1]:443"))
+	assert.Equal(t, "[redacted]", TruncateIP("not an ip"))
+}
+
+func TestHashIdentifier(t *testing.T) {
+	hash := HashIdentifier(0)
+	a := hash("alice")
+	assert.Len(t, a, 16)
+	assert.NotEqual(t, "alice", a)
+	assert.Equal(t, a, hash("alice"), "same identifier should hash the same")
+	assert.NotEqual(t, a, hash("bob"))
+	assert.NotEqual(t, a, HashIdentifier(0)("alice"), "different hashers should use different salts")
+
+	rotating := HashIdentifier(10 * time.Millisecond)
+	before := rotating("alice")
+	time.Sleep(20 * time.Millisecond)
+	assert.NotEqual(t, before, rotating("alice"), "salt should have rotated")
+}
+
+func TestTransformingOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutput(TransformingOutput(TextOutput(buf, buf), map[string]FieldTransformer{
+		"client_ip": TruncateIP,
+		"user_id":   func(interface{}) interface{} { return "hashed" },
+	}))
+	defer reset()
+
+	l := LoggerFor("myprefix")
+	l.Debugw("hello", Field{Key: "client_ip", Value: "10.1.2.3"}, Field{Key: "user_id", Value: "alice"}, Field{Key: "other", Value: 1})
+	l.Debug("no fields")
+	assert.Regexp(t, `^DEBUG myprefix: generalize_test.go:[0-9]+ hello \[client_ip=10.1.2.0 other=1 user_id=hashed\]
+DEBUG myprefix: generalize_test.go:[0-9]+ no fields
+$`, buf.String())
+}
@@ -3,13 +3,12 @@ module github.com/getlantern/golog
 go 1.12
 
 require (
-	github.com/getlantern/context v0.0.0-20190109183933-c447772a6520 // indirect
+	github.com/getlantern/context v0.0.0-20190109183933-c447772a6520
 	github.com/getlantern/errors v1.0.1
 	github.com/getlantern/hex v0.0.0-20190417191902-c6586a6fe0b7 // indirect
 	github.com/getlantern/hidden v0.0.0-20190325191715-f02dbb02be55
 	github.com/getlantern/ops v0.0.0-20190325191751-d70cb0d6f85f
 	github.com/go-stack/stack v1.8.0 // indirect
-	github.com/oxtoacart/bpool v0.0.0-20190530202638-03653db5a59c
 	github.com/stretchr/testify v1.7.0
 	go.uber.org/zap v1.19.1
 )
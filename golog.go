@@ -20,6 +20,9 @@
 //	    through the "TRACE" environment variable like this: "TRACE=prefix1,prefix2"
 //
 // A stack dump will be printed after the message if "PRINT_STACK=true".
+//
+// Loggers look at TRACE and PRINT_STACK when they first log. To apply
+// environment variables set after that, call ReloadEnv.
 package golog
 
 import (
@@ -27,7 +30,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"os"
 	"runtime"
@@ -38,10 +40,21 @@ import (
 
 	"github.com/getlantern/errors"
 	"github.com/getlantern/hidden"
-	"github.com/getlantern/ops"
 )
 
 const (
+	// TRACE is the Severity of trace messages
+	TRACE = 100
+
+	// DEBUG is the Severity of debug messages
+	DEBUG = 200
+
+	// INFO is the Severity of informational messages
+	INFO = 250
+
+	// WARN is the Severity of warnings
+	WARN = 300
+
 	// ERROR is an error Severity
 	ERROR = 500
 
@@ -60,15 +73,33 @@ type Output interface {
 	Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{})
 }
 
+// ClosableOutput is an Output that holds on to resources, such as queued events
+// or network connections, that need to be released once it's no longer used.
+// Closing flushes any events that haven't been delivered yet.
+type ClosableOutput interface {
+	Output
+	io.Closer
+}
+
 var (
 	output         Output
 	outputMx       sync.RWMutex
 	prepender      atomic.Value
-	reporters      []ErrorReporter
+	reporters      []registeredReporter
 	reportersMutex sync.RWMutex
 
 	onFatal atomic.Value
 
+	// defaultOutput is the Output set by ResetOutputs, see ReloadEnv.
+	defaultOutput Output
+
+	// development is set to 1 to make DPanic panic, see SetDevelopment.
+	development int32
+
+	// emergencyVerbosity is set to 1 to force TRACE logging and stack dumps for
+	// all loggers, see DetectCrashLoop.
+	emergencyVerbosity int32
+
 	// enableTraceThroughLinker is set through a linker flag. It's used to
 	// enforce tracing through a linker flag. It can either be set to "true",
 	// "1", or "TRUE". Any other value will be ignored.
@@ -81,6 +112,14 @@ type Severity int
 
 func (s Severity) String() string {
 	switch s {
+	case TRACE:
+		return "TRACE"
+	case DEBUG:
+		return "DEBUG"
+	case INFO:
+		return "INFO"
+	case WARN:
+		return "WARN"
 	case ERROR:
 		return "ERROR"
 	case FATAL:
@@ -94,10 +133,14 @@ func init() {
 	DefaultOnFatal()
 	ResetOutputs()
 	ResetPrepender()
+	if err := SetLevel(getEnvConfig().Level); err != nil {
+		errorOnLogging(err)
+	}
 }
 
 // SetPrepender sets a function to write something, e.g., the timestamp, before
-// each line of the log.
+// each line of the log. It runs ahead of any Decorators, which allow chaining
+// several prependers and appenders and scoping them to outputs or loggers.
 func SetPrepender(p func(io.Writer)) {
 	prepender.Store(p)
 }
@@ -113,12 +156,45 @@ func GetPrepender() func(io.Writer) {
 // SetOutputs sets the outputs for error and debug logs to use the given Outputs.
 // Returns a function that resets outputs to their original values prior to calling SetOutputs.
 // If env variable PRINT_JSON is set, use JSON output instead of plain text
+// If env variable GOLOG_SAMPLING is set, sample the output according to the
+// rules it specifies (see ParseSamplingRules)
+// If env variable GOLOG_CONFIG is set, its format and sampling apply (see
+// ParseEnvConfig)
 func SetOutputs(errorOut io.Writer, debugOut io.Writer) (reset func()) {
-	if printJson, _ := strconv.ParseBool(os.Getenv("PRINT_JSON")); printJson {
-		return SetOutput(JsonOutput(errorOut, debugOut))
-	}
+	printJson, _ := strconv.ParseBool(os.Getenv("PRINT_JSON"))
+	return setOutputs(errorOut, debugOut, printJson)
+}
 
-	return SetOutput(TextOutput(errorOut, debugOut))
+func setOutputs(errorOut io.Writer, debugOut io.Writer, printJson bool) (reset func()) {
+	return SetOutput(newEnvOutput(errorOut, debugOut, printJson, false))
+}
+
+// newEnvOutput creates an Output writing to the given writers as configured
+// by the environment. omitTimestamps leaves timestamps out of the output,
+// whether it's JSON because of printJson or a format from GOLOG_CONFIG.
+func newEnvOutput(errorOut io.Writer, debugOut io.Writer, printJson bool, omitTimestamps bool) Output {
+	envConfig := getEnvConfig()
+	var out Output
+	switch {
+	case envConfig.Format != "":
+		var err error
+		out, err = NewOutput(envConfig.Format, &OutputOptions{ErrorWriter: errorOut, DebugWriter: debugOut, OmitTimestamps: omitTimestamps})
+		if err != nil {
+			errorOnLogging(fmt.Errorf("ignoring format in GOLOG_CONFIG: %v", err))
+			out = TextOutput(errorOut, debugOut)
+		}
+	case printJson:
+		out = JsonOutputWithOptions(errorOut, debugOut, &JsonOutputOptions{OmitTimestamp: omitTimestamps})
+	default:
+		out = TextOutput(errorOut, debugOut)
+	}
+	// GOLOG_SAMPLING comes last so that its rules win over equally specific
+	// ones from GOLOG_CONFIG
+	rules := append(append([]SamplingRule(nil), envConfig.Sampling...), samplingRulesFromEnv()...)
+	if len(rules) > 0 {
+		out = SamplingOutput(out, rules...)
+	}
+	return out
 }
 
 // SetOutput sets the Output to use for errors and debug messages
@@ -136,40 +212,111 @@ func SetOutput(out Output) (reset func()) {
 
 // Deprecated: instead of calling ResetOutputs, use the reset function returned by SetOutputs.
 func ResetOutputs() {
-	SetOutputs(os.Stderr, os.Stdout)
+	out := newDefaultOutput()
+	outputMx.Lock()
+	output, defaultOutput = out, out
+	outputMx.Unlock()
+}
+
+// newDefaultOutput creates the Output that golog uses by default, as
+// configured by the environment.
+func newDefaultOutput() Output {
+	errorOut, debugOut := stderr, stdout
+	printJson, _ := strconv.ParseBool(os.Getenv("PRINT_JSON"))
+	// container runtimes stamp each line themselves
+	inContainer := InContainer()
+	if inContainer {
+		errorOut, printJson = stdout, printJSONInContainer()
+	}
+	switch getEnvConfig().Output {
+	case "stderr":
+		errorOut, debugOut = stderr, stderr
+	case "stdout":
+		errorOut, debugOut = stdout, stdout
+	}
+	return newEnvOutput(errorOut, debugOut, printJson, inContainer)
 }
 
 func getErrorOut() outputFn {
 	outputMx.RLock()
 	defer outputMx.RUnlock()
-	return output.Error
+	write := outputFn(output.Error)
+	if subs := currentSubscriptions(); len(subs) > 0 {
+		write = publishingOutputFn(write, subs)
+	}
+	if asyncLog != nil {
+		return asyncLog.outputFn(write)
+	}
+	return write
 }
 
 func getDebugOut() outputFn {
 	outputMx.RLock()
 	defer outputMx.RUnlock()
-	return output.Debug
+	write := outputFn(output.Debug)
+	if subs := currentSubscriptions(); len(subs) > 0 {
+		write = publishingOutputFn(write, subs)
+	}
+	if asyncLog != nil {
+		return asyncLog.outputFn(write)
+	}
+	return write
+}
+
+type registeredReporter struct {
+	min      Severity
+	reporter ErrorReporter
 }
 
 // RegisterReporter registers the given ErrorReporter. All logged Errors are
 // sent to this reporter.
 func RegisterReporter(reporter ErrorReporter) {
+	RegisterReporterAt(ERROR, reporter)
+}
+
+// RegisterReporterAt registers the given ErrorReporter for events at the
+// given Severity and above, for example WARN to also get warnings. INFO and
+// WARN events are sent as errors whose text is the message, and reporters
+// can tell them apart by their severity.
+func RegisterReporterAt(min Severity, reporter ErrorReporter) {
 	reportersMutex.Lock()
-	reporters = append(reporters, reporter)
+	reporters = append(reporters, registeredReporter{min, reporter})
 	reportersMutex.Unlock()
 }
 
+// reportsWanted reports whether any reporter wants events of the given
+// Severity.
+func reportsWanted(severity Severity) bool {
+	reportersMutex.RLock()
+	defer reportersMutex.RUnlock()
+	for _, r := range reporters {
+		if severity >= r.min {
+			return true
+		}
+	}
+	return false
+}
+
 // OnFatal configures golog to call the given function on any FATAL error. By
-// default, golog calls os.Exit(1) on any FATAL error.
+// default, golog calls os.Exit(1) on any FATAL error (except under js/wasm,
+// where it doesn't exit).
 func OnFatal(fn func(err error)) {
 	onFatal.Store(fn)
 }
 
+// SetDevelopment enables or disables development mode. In development mode,
+// DPanic and DPanicf panic after logging.
+func SetDevelopment(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&development, v)
+}
+
 // DefaultOnFatal enables the default behavior for OnFatal
 func DefaultOnFatal() {
-	onFatal.Store(func(err error) {
-		os.Exit(1)
-	})
+	onFatal.Store(exitOnFatal)
 }
 
 // MultiLine is an interface for arguments that support multi-line output.
@@ -184,16 +331,43 @@ type MultiLine interface {
 
 // ErrorReporter is a function to which the logger will report errors.
 // It the given error and corresponding message along with associated ops
-// context. This should return quickly as it executes on the critical code
+// context, which includes the "severity" and the "component" (the logger's
+// prefix). This should return quickly as it executes on the critical code
 // path. The recommended approach is to buffer as much as possible and discard
 // new reports if the buffer becomes saturated.
 type ErrorReporter func(err error, severity Severity, ctx map[string]interface{})
 
+// Logger is the API of the loggers returned by LoggerFor. New methods are
+// added to it as the API grows, so other implementations should embed a Logger
+// to keep compiling.
 type Logger interface {
 	// Debug logs to stdout
 	Debug(arg interface{})
 	// Debugf logs to stdout
 	Debugf(message string, args ...interface{})
+	// Debugw logs to stdout with the given fields attached to the context
+	Debugw(message string, fields ...Field)
+	// DebugStream logs whatever fn writes to stdout as a single debug
+	// message. Outputs that support it (see StreamingOutput) stream it
+	// directly, instead of buffering it, which makes this suitable for very
+	// large diagnostic dumps.
+	DebugStream(fn func(w io.Writer))
+
+	// Info logs an informational message to stdout
+	Info(arg interface{})
+	// Infof logs an informational message to stdout
+	Infof(message string, args ...interface{})
+	// Infow logs an informational message to stdout with the given fields
+	// attached to the context
+	Infow(message string, fields ...Field)
+
+	// Warn logs a warning to stdout
+	Warn(arg interface{})
+	// Warnf logs a warning to stdout
+	Warnf(message string, args ...interface{})
+	// Warnw logs a warning to stdout with the given fields attached to the
+	// context
+	Warnw(message string, fields ...Field)
 
 	// Error logs to stderr
 	Error(arg interface{}) error
@@ -201,6 +375,18 @@ type Logger interface {
 	// a new error built using fmt.Errorf if none of the arguments are errors.
 	Errorf(message string, args ...interface{}) error
 
+	// DPanic logs to stderr like Error and, if development mode is enabled
+	// (see SetDevelopment), then panics with the resulting error. Use it for
+	// impossible states that should fail loudly in tests but not crash
+	// production.
+	DPanic(arg interface{}) error
+	// DPanicf is like DPanic but with a format string, like Errorf.
+	DPanicf(message string, args ...interface{}) error
+
+	// Deprecated logs a WARN that something is deprecated, exactly once per
+	// call site, see Deprecations. Call it from the deprecated function.
+	Deprecated(message string)
+
 	// Fatal logs to stderr and then exits with status 1
 	Fatal(arg interface{})
 	// Fatalf logs to stderr and then exits with status 1
@@ -230,6 +416,9 @@ type Logger interface {
 
 	// AsErrorLogger returns an standard logger that writes Errors
 	AsErrorLogger() *log.Logger
+
+	// slogLogger adds AsSlogLogger from Go 1.21 on, see slog.go
+	slogLogger
 }
 
 // shouldEnableTrace returns true if tracing was enforced through a linker
@@ -256,6 +445,10 @@ func shouldEnableTrace(currentPrefix string) bool {
 
 	// Environment variable checks
 	// ---------------------
+	// If GOLOG_CONFIG sets the level to TRACE, trace everything
+	if getEnvConfig().Level == "TRACE" {
+		return true
+	}
 	// If TRACE=true is set in the environment, return true
 	envVar := os.Getenv("TRACE")
 	if envVar == "" {
@@ -275,34 +468,44 @@ func shouldEnableTrace(currentPrefix string) bool {
 	return false
 }
 
+// LoggerFor returns a Logger for the given prefix. Whether it traces and
+// prints stacks (see the top-level comment) is decided from the environment
+// when it first logs, and again after ReloadEnv, so loggers created in
+// package-level variables still honor environment variables set in main.
 func LoggerFor(prefix string) Logger {
-	l := &logger{
+	return &logger{
 		prefix: prefix + ": ",
+		env:    &lazyEnv{},
 	}
-
-	l.traceOn = shouldEnableTrace(prefix)
-	if l.traceOn {
-		fmt.Printf("TRACE logging is enabled for prefix [%s]\n", prefix)
-		l.traceOut = l.newTraceWriter()
-	} else {
-		l.traceOut = ioutil.Discard
-	}
-
-	printStack := os.Getenv("PRINT_STACK")
-	l.printStack, _ = strconv.ParseBool(printStack)
-
-	return l
 }
 
 type logger struct {
-	prefix     string
-	traceOn    bool
-	traceOut   io.Writer
-	printStack bool
+	prefix string
+	// env is shared with the child loggers, see ChildLogger
+	env *lazyEnv
+	// fields are bound to every event logged, see ChildLogger
+	fields []Field
+	// sampler, if set, drops repetitive events, see SampledLogger
+	sampler *logSampler
 }
 
 func (l *logger) print(write outputFn, skipFrames int, severity string, arg interface{}) {
-	write(l.prefix, skipFrames+2, l.printStack, severity, arg, ops.AsMap(arg, false))
+	if IsDisabled() || !levelEnabled(severity) {
+		return
+	}
+	countEvent(l.prefix, severity)
+	printStack := l.loggerEnv().printStack || atomic.LoadInt32(&emergencyVerbosity) == 1
+	values := eventValues(arg, l.fields)
+	observe(values, severity, arg)
+	if l.sampler != nil && severity != "FATAL" {
+		keep, dropped := l.sampler.sample(skipFrames-2, severity, arg)
+		if !keep {
+			return
+		}
+		values = withDropped(values, true, dropped)
+	}
+	addErrorCode(values, arg)
+	write(l.prefix, skipFrames+2, printStack, severity, arg, values)
 }
 
 func (l *logger) printf(write outputFn, skipFrames int, severity string, message string, args ...interface{}) {
@@ -317,6 +520,43 @@ func (l *logger) Debugf(message string, args ...interface{}) {
 	l.printf(getDebugOut(), 4, "DEBUG", message, args...)
 }
 
+func (l *logger) Debugw(message string, fields ...Field) {
+	l.print(getDebugOut(), 4, "DEBUG", WithFields(message, fields...))
+}
+
+func (l *logger) Info(arg interface{}) {
+	l.logSkipFrames(arg, 1, INFO)
+}
+
+func (l *logger) Infof(message string, args ...interface{}) {
+	l.logSkipFrames(fmt.Sprintf(message, args...), 1, INFO)
+}
+
+func (l *logger) Infow(message string, fields ...Field) {
+	l.logSkipFrames(WithFields(message, fields...), 1, INFO)
+}
+
+func (l *logger) Warn(arg interface{}) {
+	l.logSkipFrames(arg, 1, WARN)
+}
+
+func (l *logger) Warnf(message string, args ...interface{}) {
+	l.logSkipFrames(fmt.Sprintf(message, args...), 1, WARN)
+}
+
+func (l *logger) Warnw(message string, fields ...Field) {
+	l.logSkipFrames(WithFields(message, fields...), 1, WARN)
+}
+
+// logSkipFrames logs an INFO or WARN event to the debug output, reporting it
+// to the reporters that want it (see RegisterReporterAt) like errors are.
+func (l *logger) logSkipFrames(arg interface{}, skipFrames int, severity Severity) {
+	l.print(getDebugOut(), skipFrames+4, severity.String(), arg)
+	if reportsWanted(severity) {
+		report(asError(arg), severity, l.prefix)
+	}
+}
+
 func (l *logger) Error(arg interface{}) error {
 	return l.errorSkipFrames(arg, 1, ERROR)
 }
@@ -325,6 +565,21 @@ func (l *logger) Errorf(message string, args ...interface{}) error {
 	return l.errorSkipFrames(errors.NewOffset(1, message, args...), 1, ERROR)
 }
 
+func (l *logger) DPanic(arg interface{}) error {
+	return dpanic(l.errorSkipFrames(arg, 1, ERROR))
+}
+
+func (l *logger) DPanicf(message string, args ...interface{}) error {
+	return dpanic(l.errorSkipFrames(errors.NewOffset(1, message, args...), 1, ERROR))
+}
+
+func dpanic(err error) error {
+	if atomic.LoadInt32(&development) == 1 {
+		panic(err)
+	}
+	return err
+}
+
 func (l *logger) Fatal(arg interface{}) {
 	fatal(l.errorSkipFrames(arg, 1, FATAL))
 }
@@ -334,49 +589,49 @@ func (l *logger) Fatalf(message string, args ...interface{}) {
 }
 
 func fatal(err error) {
+	Flush()
 	fn := onFatal.Load().(func(err error))
 	fn(err)
 }
 
 func (l *logger) errorSkipFrames(arg interface{}, skipFrames int, severity Severity) error {
-	var err error
-	switch e := arg.(type) {
-	case error:
-		err = e
-	default:
-		err = fmt.Errorf("%v", e)
-	}
+	err := asError(arg)
 	l.print(getErrorOut(), skipFrames+4, severity.String(), err)
-	return report(err, severity)
+	return report(err, severity, l.prefix)
+}
+
+// asError returns arg if it's an error, or an error with its text otherwise.
+func asError(arg interface{}) error {
+	if err, ok := arg.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", arg)
 }
 
 func (l *logger) Trace(arg interface{}) {
-	if l.traceOn {
+	if l.IsTraceEnabled() {
 		l.print(getDebugOut(), 4, "TRACE", arg)
 	}
 }
 
 func (l *logger) Tracef(message string, args ...interface{}) {
-	if l.traceOn {
+	if l.IsTraceEnabled() {
 		l.printf(getDebugOut(), 4, "TRACE", message, args...)
 	}
 }
 
 func (l *logger) TraceOut() io.Writer {
-	return l.traceOut
+	return l.loggerEnv().traceOut
 }
 
 func (l *logger) IsTraceEnabled() bool {
-	return l.traceOn
+	return !IsDisabled() && (l.loggerEnv().traceOn || atomic.LoadInt32(&emergencyVerbosity) == 1 || traceEnabledAtRuntime(l.prefix)) && levelEnabled("TRACE")
 }
 
 func (l *logger) newTraceWriter() io.Writer {
 	pr, pw := io.Pipe()
 	br := bufio.NewReader(pr)
 
-	if !l.traceOn {
-		return pw
-	}
 	go func() {
 		defer func() {
 			if err := pr.Close(); err != nil {
@@ -447,25 +702,37 @@ func (l *logger) AsStdLogger() *log.Logger {
 }
 
 func errorOnLogging(err error) {
-	_, _ = fmt.Fprintf(os.Stderr, "Unable to log: %v\n", err)
+	if IsDisabled() {
+		return
+	}
+	_, _ = fmt.Fprintf(stderr, "Unable to log: %v\n", err)
 }
 
-func report(err error, severity Severity) error {
+func report(err error, severity Severity, prefix string) error {
 	var reportersCopy []ErrorReporter
 	reportersMutex.RLock()
-	if len(reporters) > 0 {
-		reportersCopy = make([]ErrorReporter, len(reporters))
-		copy(reportersCopy, reporters)
+	for _, r := range reporters {
+		if severity >= r.min {
+			reportersCopy = append(reportersCopy, r.reporter)
+		}
 	}
 	reportersMutex.RUnlock()
+	trackError := severity >= ERROR
+	if len(reportersCopy) == 0 && !trackError {
+		return err
+	}
 
-	if len(reportersCopy) > 0 {
-		ctx := ops.AsMap(err, true)
-		ctx["severity"] = severity.String()
-		for _, reporter := range reportersCopy {
-			// We include globals when reporting
-			reporter(err, severity, ctx)
-		}
+	// We include globals when reporting
+	ctx := reportValues(err)
+	removeObservers(ctx)
+	addErrorCode(ctx, err)
+	ctx["severity"] = severity.String()
+	ctx["component"] = strings.TrimSuffix(prefix, ": ")
+	if trackError {
+		countError(err, ctx)
+	}
+	for _, reporter := range reportersCopy {
+		reporter(err, severity, ctx)
 	}
 	return err
 }
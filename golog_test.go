@@ -52,6 +52,10 @@ ERROR myprefix: golog_test.go:999   at runtime.goexit (asm_amd999.s:999)
 
 var (
 	replaceNumbers = regexp.MustCompile("[0-9]+")
+	jsonTimestamp  = regexp.MustCompile(`^\{"ts":"[^"]*",`)
+	// replaceAsmFile makes the file of runtime.goexit the same on all
+	// architectures
+	replaceAsmFile = regexp.MustCompile(`asm_[a-z0-9]+\.s`)
 )
 
 func init() {
@@ -62,8 +66,14 @@ func expected(severity string, log string) string {
 	return strings.Replace(log, "SEVERITY", severity, -1)
 }
 
+// withoutTimestamp removes the "ts" key from a line of JSON output, whose
+// digits normalized garbles.
+func withoutTimestamp(line string) string {
+	return jsonTimestamp.ReplaceAllString(line, "{")
+}
+
 func normalized(log string) string {
-	return replaceNumbers.ReplaceAllString(log, "999")
+	return replaceNumbers.ReplaceAllString(replaceAsmFile.ReplaceAllString(log, "asm_amd64.s"), "999")
 }
 
 func TestReport(t *testing.T) {
@@ -89,6 +99,20 @@ func TestReport(t *testing.T) {
 	assert.Equal(t, 1, fatalCount)
 }
 
+func TestDPanic(t *testing.T) {
+	out := newBuffer()
+	SetOutputs(out, ioutil.Discard)
+	l := LoggerFor("myprefix")
+	assert.Error(t, l.DPanic("Hello world"))
+	assert.Equal(t, "ERROR myprefix: golog_test.go:999 Hello world\n", out.String())
+
+	SetDevelopment(true)
+	defer SetDevelopment(false)
+	assert.Panics(t, func() {
+		l.DPanicf("Hello %v", true)
+	})
+}
+
 func TestDebug(t *testing.T) {
 	out := newBuffer()
 	SetOutputs(ioutil.Discard, out)
@@ -99,6 +123,15 @@ func TestDebug(t *testing.T) {
 	assert.Equal(t, expected("DEBUG", expectedLog), out.String())
 }
 
+func TestDebugw(t *testing.T) {
+	out := newBuffer()
+	SetOutputs(ioutil.Discard, out)
+	l := LoggerFor("myprefix")
+	defer ops.Begin("name").End()
+	l.Debugw("Hello world", Field{"cvarA", "a"}, WithRuntimeStats())
+	assert.Regexp(t, `^DEBUG myprefix: golog_test.go:999 Hello world \[cvarA=a op=name root_op=name runtime=map\[.*goroutines:999.*\]\]\n$`, out.String())
+}
+
 func TestDebugJson(t *testing.T) {
 	out := newBuffer()
 	SetOutput(JsonOutput(ioutil.Discard, out))
@@ -113,7 +146,7 @@ func TestDebugJson(t *testing.T) {
 		var expected Event
 		var got Event
 		assert.NoError(t, json.Unmarshal([]byte(expectedLines[i]), &expected))
-		assert.NoError(t, json.Unmarshal([]byte(gotLines[i]), &got))
+		assert.NoError(t, json.Unmarshal([]byte(withoutTimestamp(gotLines[i])), &got))
 		assert.EqualValues(t, expected, got)
 	}
 }
@@ -187,7 +220,7 @@ func TestErrorJson(t *testing.T) {
 		var expected Event
 		var got Event
 		assert.NoError(t, json.Unmarshal([]byte(expectedLines[i]), &expected))
-		assert.NoError(t, json.Unmarshal([]byte(gotLines[i]), &got))
+		assert.NoError(t, json.Unmarshal([]byte(withoutTimestamp(gotLines[i])), &got))
 		assert.EqualValues(t, expected, got)
 	}
 }
@@ -290,6 +323,16 @@ func TestTraceDisabled(t *testing.T) {
 // 	assert.Equal(t, expected("TRACE", expectedStdLog), out.String())
 // }
 
+// resetShutdown undoes Shutdown and OnShutdown for the next test.
+func resetShutdown() {
+	shutdownMx.Lock()
+	shutdownDone = false
+	shutdownMx.Unlock()
+	shutdownHooksMx.Lock()
+	shutdownHooks = nil
+	shutdownHooksMx.Unlock()
+}
+
 func newBuffer() *synchronizedbuffer {
 	return &synchronizedbuffer{orig: &bytes.Buffer{}}
 }
@@ -0,0 +1,154 @@
+package golog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getlantern/ops"
+)
+
+// groupKey is the ops context key under which a grouped op stores its
+// *opGroup.
+const groupKey = "log_group"
+
+var nextGroupID uint64
+
+// GroupedRecord is the composite record written by a GroupingOutput for all
+// events logged within a grouped op.
+type GroupedRecord struct {
+	Op         string    `json:"op"`
+	Start      time.Time `json:"start"`
+	DurationMS float64   `json:"duration_ms"`
+	Entries    []*Event  `json:"entries"`
+}
+
+// Group marks op so that all events logged within it, including within nested
+// ops, are buffered by a GroupingOutput and written as a single GroupedRecord
+// when the returned Op ends. Make sure to call End on the returned Op rather
+// than on the original one.
+//
+//	op := golog.Group(ops.Begin("request"))
+//	defer op.End()
+func Group(op ops.Op) ops.Op {
+	g := &opGroup{
+		id:    atomic.AddUint64(&nextGroupID, 1),
+		start: time.Now(),
+	}
+	if name, ok := ops.AsMap(nil, false)["op"].(string); ok {
+		g.name = name
+	}
+	op.Set(groupKey, g)
+	return &groupedOp{Op: op, g: g}
+}
+
+type opGroup struct {
+	id      uint64
+	name    string
+	start   time.Time
+	mx      sync.Mutex
+	out     *groupingOutput
+	entries []*Event
+}
+
+func (g *opGroup) String() string {
+	return fmt.Sprintf("#%d", g.id)
+}
+
+func (g *opGroup) add(out *groupingOutput, event *Event) {
+	g.mx.Lock()
+	if g.out == nil {
+		g.out = out
+	}
+	g.entries = append(g.entries, event)
+	g.mx.Unlock()
+}
+
+func (g *opGroup) flush() {
+	g.mx.Lock()
+	out, entries := g.out, g.entries
+	g.entries = nil
+	g.mx.Unlock()
+	if out == nil || len(entries) == 0 {
+		return
+	}
+	out.write(&GroupedRecord{
+		Op:         g.name,
+		Start:      g.start,
+		DurationMS: float64(time.Since(g.start)) / float64(time.Millisecond),
+		Entries:    entries,
+	})
+}
+
+type groupedOp struct {
+	ops.Op
+	g *opGroup
+}
+
+func (o *groupedOp) Set(key string, value interface{}) ops.Op {
+	o.Op.Set(key, value)
+	return o
+}
+
+func (o *groupedOp) SetDynamic(key string, valueFN func() interface{}) ops.Op {
+	o.Op.SetDynamic(key, valueFN)
+	return o
+}
+
+func (o *groupedOp) End() {
+	o.g.flush()
+	o.Op.End()
+}
+
+// GroupingOutput creates an output that buffers the events logged within ops
+// marked with Group and writes each group to w as a single JSON GroupedRecord
+// once the op ends. Events that aren't logged within a grouped op are written
+// to out.
+func GroupingOutput(w io.Writer, out Output) Output {
+	return &groupingOutput{w: w, out: out}
+}
+
+type groupingOutput struct {
+	w   io.Writer
+	mx  sync.Mutex
+	out Output
+}
+
+func (o *groupingOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	if g, ok := values[groupKey].(*opGroup); ok {
+		o.record(g, prefix, skipFrames, printStack, severity, arg, values)
+		return
+	}
+	o.out.Error(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+func (o *groupingOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	if g, ok := values[groupKey].(*opGroup); ok {
+		o.record(g, prefix, skipFrames, printStack, severity, arg, values)
+		return
+	}
+	o.out.Debug(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+func (o *groupingOutput) record(g *opGroup, prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	event := buildEvent(make([]uintptr, 10), prefix, skipFrames, printStack, severity, arg, values)
+	context := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if key != groupKey {
+			context[key] = value
+		}
+	}
+	event.Context = context
+	g.add(o, event)
+}
+
+func (o *groupingOutput) write(record *GroupedRecord) {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	if err := json.NewEncoder(redirectStdout(o.w)).Encode(record); err != nil {
+		errorOnLogging(err)
+	}
+}
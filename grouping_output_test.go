@@ -0,0 +1,41 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/getlantern/ops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupingOutput(t *testing.T) {
+	grouped := &bytes.Buffer{}
+	ungrouped := newBuffer()
+	reset := SetOutput(GroupingOutput(grouped, TextOutput(ungrouped, ungrouped)))
+	defer reset()
+
+	l := LoggerFor("myprefix")
+	l.Debug("before")
+	op := Group(ops.Begin("request")).Set("cvarA", "a")
+	l.Debug("first")
+	nested := ops.Begin("nested")
+	l.Error("second")
+	nested.End()
+	assert.Empty(t, grouped.String(), "nothing should be written before the op ends")
+	op.End()
+	l.Debug("after")
+
+	assert.Equal(t, "DEBUG myprefix: grouping_output_test.go:999 before\nDEBUG myprefix: grouping_output_test.go:999 after\n", ungrouped.String())
+	var record GroupedRecord
+	require.NoError(t, json.Unmarshal(grouped.Bytes(), &record))
+	assert.Equal(t, "request", record.Op)
+	if assert.Len(t, record.Entries, 2) {
+		assert.Equal(t, "first", record.Entries[0].Message)
+		assert.Equal(t, "a", record.Entries[0].Context["cvarA"])
+		assert.NotContains(t, record.Entries[0].Context, groupKey)
+		assert.Equal(t, "nested", record.Entries[1].Context["op"])
+		assert.Equal(t, "ERROR", record.Entries[1].Severity)
+	}
+}
@@ -0,0 +1,46 @@
+package golog
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	processStart = time.Now()
+	heartbeatLog = LoggerFor("heartbeat")
+)
+
+// StartHeartbeat starts periodically logging a structured "alive" DEBUG event
+// that carries the process uptime, the number of goroutines and memory
+// statistics, plus the given fields. Call the returned function to stop the
+// heartbeat.
+func StartHeartbeat(interval time.Duration, fields ...Field) (stop func()) {
+	stopCh := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				heartbeatLog.Debug(WithFields("alive", heartbeatFields(fields)...))
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stopCh)
+		})
+	}
+}
+
+func heartbeatFields(fields []Field) []Field {
+	result := []Field{{"uptime", time.Since(processStart).Round(time.Second).String()}}
+	for key, value := range runtimeStats() {
+		result = append(result, Field{key, value})
+	}
+	return append(result, fields...)
+}
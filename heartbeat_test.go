@@ -0,0 +1,27 @@
+package golog
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeat(t *testing.T) {
+	out := newBuffer()
+	reset := SetOutputs(ioutil.Discard, out)
+	defer reset()
+
+	stop := StartHeartbeat(10*time.Millisecond, Field{"service", "test"})
+	time.Sleep(35 * time.Millisecond)
+	stop()
+	stop()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.True(t, len(lines) >= 2, "should have emitted multiple heartbeats")
+	assert.Contains(t, lines[0], "DEBUG heartbeat: heartbeat.go:999 alive [")
+	assert.Contains(t, lines[0], "goroutines=")
+	assert.Contains(t, lines[0], "service=test")
+}
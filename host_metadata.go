@@ -0,0 +1,93 @@
+package golog
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"sync"
+)
+
+var (
+	hostMetadata     map[string]interface{}
+	hostMetadataOnce sync.Once
+
+	containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+)
+
+// HostMetadata returns fields describing the host and process: host, pid, os,
+// arch and, when running in a container, container_id.
+func HostMetadata() []Field {
+	metadata := getHostMetadata()
+	fields := make([]Field, 0, len(metadata))
+	for _, key := range []string{"host", "pid", "os", "arch", "container_id"} {
+		if value, found := metadata[key]; found {
+			fields = append(fields, Field{Key: key, Value: value})
+		}
+	}
+	return fields
+}
+
+func getHostMetadata() map[string]interface{} {
+	hostMetadataOnce.Do(func() {
+		hostMetadata = map[string]interface{}{
+			"pid":  os.Getpid(),
+			"os":   runtime.GOOS,
+			"arch": runtime.GOARCH,
+		}
+		if host, err := os.Hostname(); err == nil {
+			hostMetadata["host"] = host
+		}
+		if f, err := os.Open("/proc/self/cgroup"); err == nil {
+			if id := containerID(f); id != "" {
+				hostMetadata["container_id"] = id
+			}
+			f.Close()
+		}
+	})
+	return hostMetadata
+}
+
+// containerID extracts the container ID from the contents of
+// /proc/self/cgroup, if there is one.
+func containerID(cgroup io.Reader) string {
+	scanner := bufio.NewScanner(cgroup)
+	for scanner.Scan() {
+		if id := containerIDPattern.FindString(scanner.Text()); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// HostMetadataOutput wraps out so that every event carries the fields from
+// HostMetadata in its context. Fields already present in the context are left
+// alone.
+func HostMetadataOutput(out Output) Output {
+	return &hostMetadataOutput{out}
+}
+
+type hostMetadataOutput struct {
+	out Output
+}
+
+func (o *hostMetadataOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.out.Error(prefix, skipFrames+1, printStack, severity, arg, o.withMetadata(values))
+}
+
+func (o *hostMetadataOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.out.Debug(prefix, skipFrames+1, printStack, severity, arg, o.withMetadata(values))
+}
+
+func (o *hostMetadataOutput) withMetadata(values map[string]interface{}) map[string]interface{} {
+	metadata := getHostMetadata()
+	result := make(map[string]interface{}, len(values)+len(metadata))
+	for key, value := range metadata {
+		result[key] = value
+	}
+	for key, value := range values {
+		result[key] = value
+	}
+	return result
+}
@@ -0,0 +1,26 @@
+package golog
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerID(t *testing.T) {
+	id := strings.Repeat("0123456789abcdef", 4)
+	assert.Equal(t, id, containerID(strings.NewReader("12:pids:/docker/"+id+"\n0::/\n")))
+	assert.Equal(t, "", containerID(strings.NewReader("0::/user.slice/user-1000.slice\n")))
+}
+
+func TestHostMetadataOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	out := HostMetadataOutput(TextOutput(buf, buf))
+	out.Debug("myprefix: ", 0, false, "DEBUG", "hello", map[string]interface{}{"pid": "mine"})
+	assert.Contains(t, buf.String(), "pid=mine")
+	assert.Contains(t, buf.String(), "arch=")
+	assert.NotContains(t, buf.String(), "pid="+strconv.Itoa(os.Getpid()))
+}
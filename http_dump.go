@@ -0,0 +1,170 @@
+package golog
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultHTTPDumpMaxBody = 1024
+	redacted               = "[redacted]"
+)
+
+var defaultSensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+}
+
+// HTTPDumpOptions configures the dumps produced by HTTPRequest and
+// HTTPResponse.
+type HTTPDumpOptions struct {
+	// MaxBody is the maximum number of bytes of the body included in a dump.
+	// Defaults to 1024. Negative values leave out the body entirely.
+	MaxBody int
+
+	// SensitiveHeaders are headers whose values are masked. Defaults to
+	// Authorization, Proxy-Authorization, Cookie, Set-Cookie, X-Api-Key and
+	// X-Auth-Token.
+	SensitiveHeaders []string
+
+	// Logger, if set, is the logger that dumps are logged with at TRACE
+	// level. Bodies are only read while it's tracing. Otherwise they're only
+	// read while the level (see SetLevel) includes TRACE.
+	Logger Logger
+}
+
+var defaultHTTPDumpOptions = &HTTPDumpOptions{}
+
+// HTTPRequest returns a field with a dump of r for protocol debugging,
+// typically at TRACE level, with sensitive headers masked and the body
+// truncated, see HTTPDumpOptions. The body is left intact for subsequent
+// readers.
+func HTTPRequest(r *http.Request) Field {
+	return defaultHTTPDumpOptions.Request(r)
+}
+
+// HTTPResponse is like HTTPRequest but for responses.
+func HTTPResponse(resp *http.Response) Field {
+	return defaultHTTPDumpOptions.Response(resp)
+}
+
+// Request is like HTTPRequest but uses these options.
+func (opts *HTTPDumpOptions) Request(r *http.Request) Field {
+	dump := map[string]interface{}{
+		"method":  r.Method,
+		"url":     redactURL(r.URL),
+		"proto":   r.Proto,
+		"host":    r.Host,
+		"headers": opts.headers(r.Header),
+	}
+	r.Body = opts.dumpBody(r.Body, dump)
+	return Field{"http_request", dump}
+}
+
+// Response is like HTTPResponse but uses these options.
+func (opts *HTTPDumpOptions) Response(resp *http.Response) Field {
+	dump := map[string]interface{}{
+		"status":  resp.Status,
+		"proto":   resp.Proto,
+		"headers": opts.headers(resp.Header),
+	}
+	resp.Body = opts.dumpBody(resp.Body, dump)
+	return Field{"http_response", dump}
+}
+
+// redactURL returns u as a string with any password masked.
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		redactedURL := *u
+		redactedURL.User = url.UserPassword(u.User.Username(), "redacted")
+		return redactedURL.String()
+	}
+	return u.String()
+}
+
+func (opts *HTTPDumpOptions) headers(header http.Header) map[string]string {
+	sensitive := opts.SensitiveHeaders
+	if sensitive == nil {
+		sensitive = defaultSensitiveHeaders
+	}
+	result := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		for _, s := range sensitive {
+			if strings.EqualFold(name, s) {
+				value = redacted
+				break
+			}
+		}
+		result[name] = value
+	}
+	return result
+}
+
+// dumpBody adds up to MaxBody bytes of body to dump and returns a body that
+// still yields everything.
+func (opts *HTTPDumpOptions) dumpBody(body io.ReadCloser, dump map[string]interface{}) io.ReadCloser {
+	maxBody := opts.MaxBody
+	if maxBody == 0 {
+		maxBody = defaultHTTPDumpMaxBody
+	}
+	if body == nil || body == http.NoBody || maxBody < 0 || !opts.tracing() {
+		return body
+	}
+	head, err := ioutil.ReadAll(io.LimitReader(body, int64(maxBody)+1))
+	if err != nil {
+		dump["body_error"] = err.Error()
+	}
+	shown := head
+	if len(shown) > maxBody {
+		shown = truncateUTF8(shown, maxBody)
+		dump["body_truncated"] = true
+	}
+	if utf8.Valid(shown) {
+		dump["body"] = string(shown)
+	} else {
+		dump["body"] = "[binary]"
+	}
+	return &replayedBody{Reader: io.MultiReader(bytes.NewReader(head), body), Closer: body}
+}
+
+// tracing reports whether dumps are logged, so that bodies are worth reading.
+func (opts *HTTPDumpOptions) tracing() bool {
+	if opts.Logger != nil {
+		return opts.Logger.IsTraceEnabled()
+	}
+	return levelEnabled("TRACE")
+}
+
+// truncateUTF8 cuts b, which is longer than max, to at most max bytes, backing
+// up to the start of a rune that would be cut in half so that text stays valid
+// UTF-8.
+func truncateUTF8(b []byte, max int) []byte {
+	i := max
+	for i > 0 && max-i < utf8.UTFMax && !utf8.RuneStart(b[i]) {
+		i--
+	}
+	if utf8.RuneStart(b[i]) {
+		return b[:i]
+	}
+	return b[:max]
+}
+
+// replayedBody returns what was already read from a body before the rest of
+// it.
+type replayedBody struct {
+	io.Reader
+	io.Closer
+}
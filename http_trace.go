@@ -0,0 +1,120 @@
+package golog
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// WithHTTPTrace returns a copy of req that's instrumented with
+// net/http/httptrace to log the progress of the request with l: DNS
+// resolution, connecting, the TLS handshake and the first response byte are
+// each logged as TRACE events as they complete, and once the first response
+// byte arrives a single DEBUG line breaks down the timings as dns_ms,
+// connect_ms, tls_ms and ttfb_ms (measured from the start of the request).
+// Phases that didn't happen, for example because a connection was reused,
+// are left out.
+func WithHTTPTrace(req *http.Request, l Logger) *http.Request {
+	t := &httpTrace{l: l, start: time.Now(), url: redactURL(req.URL), starts: make(map[string]time.Time)}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.begin("dns")
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			fields := []Field{{"addrs", len(info.Addrs)}}
+			if info.Err != nil {
+				fields = append(fields, Field{"error", info.Err.Error()})
+			}
+			t.end("dns", "dns", &t.dns, info.Err == nil, fields...)
+		},
+		ConnectStart: func(network, addr string) {
+			// dials to several addresses can be in flight at once
+			t.begin("connect " + network + " " + addr)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			fields := []Field{{"addr", addr}}
+			if err != nil {
+				fields = append(fields, Field{"error", err.Error()})
+			}
+			t.end("connect", "connect "+network+" "+addr, &t.connect, err == nil, fields...)
+		},
+		TLSHandshakeStart: func() {
+			t.begin("tls")
+		},
+		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
+			fields := []Field{{"tls_version", tlsVersionName(cs.Version)}}
+			if err != nil {
+				fields = append(fields, Field{"error", err.Error()})
+			}
+			t.end("tls", "tls", &t.tls, err == nil, fields...)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mx.Lock()
+			t.reused = info.Reused
+			t.mx.Unlock()
+		},
+		GotFirstResponseByte: t.firstByte,
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+type httpTrace struct {
+	l     Logger
+	url   string
+	start time.Time
+	mx    sync.Mutex
+	// starts are the start times of the phases in progress, by key
+	starts  map[string]time.Time
+	dns     time.Duration
+	connect time.Duration
+	tls     time.Duration
+	reused  bool
+}
+
+func (t *httpTrace) begin(key string) {
+	t.mx.Lock()
+	t.starts[key] = time.Now()
+	t.mx.Unlock()
+}
+
+// end logs the end of the phase that began with key and records how long it
+// took in elapsed, unless it failed and another attempt already succeeded.
+func (t *httpTrace) end(phase string, key string, elapsed *time.Duration, succeeded bool, fields ...Field) {
+	t.mx.Lock()
+	start, found := t.starts[key]
+	if !found {
+		t.mx.Unlock()
+		return
+	}
+	delete(t.starts, key)
+	d := time.Since(start)
+	if succeeded || *elapsed == 0 {
+		*elapsed = d
+	}
+	t.mx.Unlock()
+	if t.l.IsTraceEnabled() {
+		t.l.Trace(WithFields(phase+" done", append(fields, Field{"url", t.url}, DurationMS(phase+"_ms", d))...))
+	}
+}
+
+func (t *httpTrace) firstByte() {
+	ttfb := time.Since(t.start)
+	t.mx.Lock()
+	fields := []Field{{"url", t.url}, {"reused_conn", t.reused}}
+	for _, phase := range []struct {
+		key string
+		d   time.Duration
+	}{{"dns_ms", t.dns}, {"connect_ms", t.connect}, {"tls_ms", t.tls}} {
+		if phase.d > 0 {
+			fields = append(fields, DurationMS(phase.key, phase.d))
+		}
+	}
+	t.mx.Unlock()
+	fields = append(fields, DurationMS("ttfb_ms", ttfb))
+	if t.l.IsTraceEnabled() {
+		t.l.Trace(WithFields("first response byte", Field{"url", t.url}, DurationMS("ttfb_ms", ttfb)))
+	}
+	t.l.Debugw("request timings", fields...)
+}
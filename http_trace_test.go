@@ -0,0 +1,61 @@
+package golog
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithHTTPTrace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: &http.Transport{}}).Do(WithHTTPTrace(req, LoggerFor("myprefix")))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Regexp(t, `DEBUG myprefix: http_trace.go:[0-9]+ request timings \[connect_ms=[0-9.]+ reused_conn=false ttfb_ms=[0-9.]+ url=http://127.0.0.1:[0-9]+\]`, buf.String())
+}
+
+func TestWithHTTPTraceConcurrentDials(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	trace := httptrace.ContextClientTrace(WithHTTPTrace(req, LoggerFor("myprefix")).Context())
+	trace.ConnectStart("tcp", "[::1]:80")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		trace.ConnectStart("tcp", "127.0.0.1:80")
+		time.Sleep(10 * time.Millisecond)
+		trace.ConnectDone("tcp", "127.0.0.1:80", nil)
+		wg.Done()
+	}()
+	go func() {
+		trace.ConnectDone("tcp", "[::1]:80", errors.New("unreachable"))
+		wg.Done()
+	}()
+	wg.Wait()
+	trace.GotFirstResponseByte()
+
+	assert.Regexp(t, `request timings \[connect_ms=[1-9][0-9]+[. ]`, buf.String(), "the successful dial should be timed from its own start")
+}
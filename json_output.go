@@ -2,18 +2,34 @@ package golog
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
-	"path/filepath"
 	"runtime"
+	"time"
 )
 
 // JsonOutput creates an output that writes JSON structured log to different io.Writers for errors and debug
 func JsonOutput(errorWriter io.Writer, debugWriter io.Writer) Output {
+	return JsonOutputWithOptions(errorWriter, debugWriter, nil)
+}
+
+// JsonOutputOptions configures JsonOutputWithOptions.
+type JsonOutputOptions struct {
+	// OmitTimestamp leaves "ts" out of every event, for when whatever
+	// collects the output stamps each line itself, like container runtimes.
+	OmitTimestamp bool
+}
+
+// JsonOutputWithOptions is like JsonOutput but configured by opts, which may
+// be nil for the defaults.
+func JsonOutputWithOptions(errorWriter io.Writer, debugWriter io.Writer, opts *JsonOutputOptions) Output {
+	if opts == nil {
+		opts = &JsonOutputOptions{}
+	}
 	return &jsonOutput{
-		E:  errorWriter,
-		D:  debugWriter,
-		pc: make([]uintptr, 10),
+		E:             errorWriter,
+		D:             debugWriter,
+		omitTimestamp: opts.OmitTimestamp,
+		pc:            make([]uintptr, 10),
 	}
 }
 
@@ -21,19 +37,109 @@ type jsonOutput struct {
 	// E is the error writer
 	E io.Writer
 	// D is the debug writer
-	D  io.Writer
-	pc []uintptr
+	D             io.Writer
+	omitTimestamp bool
+	pc            []uintptr
 }
 
+// Event is a single log event as written by JsonOutput, one JSON object per
+// line. It's also what RingBuffer, SpoolOutput and the network outputs encode,
+// and what ReadJSONEvents decodes, so log processors can decode golog JSON
+// with it rather than guessing at the schema.
 type Event struct {
+	// Timestamp is when the event happened, encoded as "ts" in RFC 3339
+	// format with nanoseconds. It's left out if zero.
+	Timestamp time.Time
+
+	// Severity is the level of the event, like "DEBUG" or "ERROR", encoded
+	// as "level".
+	Severity string
+
+	// Component is the prefix of the logger, encoded as "component".
+	Component string
+
+	// Caller is the file:line that logged the event, encoded as "caller".
+	Caller string
+
+	// Message is the message, encoded as "msg".
+	Message string
+
+	// Context holds the event's fields, encoded as "context".
+	Context map[string]interface{}
+
+	// Stack is the stack trace, if any, encoded as "stack".
+	Stack string
+
+	// ownsContext is set when Context was taken from the context pool, see
+	// Release.
+	ownsContext bool
+}
+
+// eventJSON is how an Event is encoded, with the keys in a fixed order and
+// empty values left out.
+type eventJSON struct {
+	Timestamp *time.Time             `json:"ts,omitempty"`
 	Message   string                 `json:"msg,omitempty"`
 	Component string                 `json:"component,omitempty"`
 	Caller    string                 `json:"caller,omitempty"`
 	Context   map[string]interface{} `json:"context,omitempty"`
-	Severity  string                 `json:"level,omitempty"`
+	Level     string                 `json:"level,omitempty"`
 	Stack     string                 `json:"stack,omitempty"`
 }
 
+func (e *Event) toJSON() *eventJSON {
+	j := &eventJSON{}
+	e.fillJSON(j)
+	return j
+}
+
+// fillJSON sets j to the encoding of e.
+func (e *Event) fillJSON(j *eventJSON) {
+	*j = eventJSON{
+		Message:   e.Message,
+		Component: e.Component,
+		Caller:    e.Caller,
+		Context:   e.Context,
+		Level:     e.Severity,
+		Stack:     e.Stack,
+	}
+	if !e.Timestamp.IsZero() {
+		j.Timestamp = &e.Timestamp
+	}
+}
+
+func (j *eventJSON) toEvent() Event {
+	e := Event{
+		Message:   j.Message,
+		Component: j.Component,
+		Caller:    j.Caller,
+		Context:   j.Context,
+		Severity:  j.Level,
+		Stack:     j.Stack,
+	}
+	if j.Timestamp != nil {
+		e.Timestamp = *j.Timestamp
+	}
+	return e
+}
+
+// MarshalJSON encodes e as a JSON object with the keys ts, msg, component,
+// caller, context, level and stack, leaving out empty ones.
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.toJSON())
+}
+
+// UnmarshalJSON decodes e from the JSON encoding of an Event, replacing all
+// of its fields. Unknown keys are ignored.
+func (e *Event) UnmarshalJSON(b []byte) error {
+	j := &eventJSON{}
+	if err := json.Unmarshal(b, j); err != nil {
+		return err
+	}
+	*e = j.toEvent()
+	return nil
+}
+
 func (o *jsonOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
 	o.print(o.E, prefix, skipFrames, printStack, severity, arg, values)
 }
@@ -43,26 +149,57 @@ func (o *jsonOutput) Debug(prefix string, skipFrames int, printStack bool, sever
 }
 
 func (o *jsonOutput) print(writer io.Writer, prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	event := buildEvent(o.pc, prefix, skipFrames, printStack, severity, arg, values)
+	defer event.Release()
+	if o.omitTimestamp {
+		event.Timestamp = time.Time{}
+	}
+	enc := acquireJSONEncoding()
+	defer enc.release()
+	line, err := enc.encode(event)
+	if err == nil {
+		_, err = redirectStdout(writer).Write(line)
+	}
+	if err != nil {
+		errorOnLogging(err)
+	}
+}
+
+// buildEvent builds the Event for a single log call, using pc as scratch space
+// for looking up the caller and stack. It must be called directly from an
+// Output's print function so that skipFrames lines up with the caller. The
+// Event comes from the pool, see AcquireEvent.
+func buildEvent(pc []uintptr, prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) *Event {
 	cleanPrefix := prefix[0 : len(prefix)-2] // prefix contains ': ' at the end, strip it
-	event := Event{Component: cleanPrefix, Severity: severity, Caller: o.caller(skipFrames), Context: values}
+	event := AcquireEvent()
+	event.Component = cleanPrefix
+	event.Severity = severity
+	event.Timestamp = eventTime(arg)
+	event.Caller = caller(pc, skipFrames+1)
+	event.Context, event.ownsContext = sanitizeValues(values)
+	if override := callerOverride(arg); override != "" {
+		event.Caller = override
+	}
 	if printStack {
 		buf := getBuffer()
 		defer returnBuffer(buf)
-		_ = writeStack(buf, o.pc)
+		_ = writeStack(buf, pc)
 		event.Stack = buf.String()
 	}
-	encoder := json.NewEncoder(writer)
-	event.Message = argToString(arg)
-
-	if err := encoder.Encode(event); err != nil {
-		errorOnLogging(err)
+	if stack := stackOverride(arg); stack != "" {
+		event.Stack = stack
 	}
+	event.Message = sanitize(argToString(arg))
+	return event
 }
 
 // returns the file and line number corresponding to the log message
-func (o *jsonOutput) caller(skipFrames int) string {
-	runtime.Callers(skipFrames, o.pc)
-	funcForPc := runtime.FuncForPC(o.pc[0])
-	file, line := funcForPc.FileLine(o.pc[0] - 1)
-	return fmt.Sprintf("%s:%d", filepath.Base(file), line)
+func caller(pc []uintptr, skipFrames int) string {
+	n := runtime.Callers(skipFrames, pc)
+	if n == 0 {
+		// skipped past the top of the stack, make do with what's in pc
+		n = 1
+	}
+	var buf [64]byte
+	return string(appendCaller(buf[:0], firstFrame(pc[:n])))
 }
@@ -0,0 +1,91 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventJSON(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC)
+	event := Event{
+		Timestamp: ts,
+		Severity:  "WARN",
+		Component: "myprefix",
+		Caller:    "file.go:12",
+		Message:   "hello",
+		Context:   map[string]interface{}{"a": "b"},
+		Stack:     "  at main.main (file.go:12)\n",
+	}
+	b, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.Equal(t, `{"ts":"2026-01-02T03:04:05.000006Z","msg":"hello","component":"myprefix","caller":"file.go:12","context":{"a":"b"},"level":"WARN","stack":"  at main.main (file.go:12)\n"}`, string(b))
+
+	var decoded Event
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	assert.True(t, decoded.Timestamp.Equal(ts))
+	decoded.Timestamp = ts
+	assert.Equal(t, event, decoded)
+
+	b, err = json.Marshal(&Event{Message: "bare"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"msg":"bare"}`, string(b), "empty values should be left out")
+
+	decoded = Event{Message: "stale", Stack: "stale"}
+	require.NoError(t, json.Unmarshal([]byte(`{"msg":"fresh","unknown":1}`), &decoded))
+	assert.Equal(t, Event{Message: "fresh"}, decoded, "decoding should replace all fields")
+	assert.Error(t, json.Unmarshal([]byte(`{"ts":"yesterday"}`), &decoded))
+}
+
+func TestJsonOutputTimestamp(t *testing.T) {
+	buf := &bytes.Buffer{}
+	before := time.Now()
+	JsonOutput(buf, buf).Debug("myprefix: ", 0, false, "DEBUG", "hello", nil)
+	assert.True(t, strings.HasPrefix(buf.String(), `{"ts":"`), buf.String())
+	var event Event
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.False(t, event.Timestamp.Before(before.Truncate(time.Second)))
+	assert.Equal(t, "myprefix", event.Component)
+
+	buf.Reset()
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	reset := SetOutput(JsonOutput(buf, buf))
+	NewEvent("DEBUG", "relayed", "hello").At(ts).Emit()
+	reset()
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.True(t, event.Timestamp.Equal(ts), "injected time should be kept")
+}
+
+func TestRecordedEventJSON(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	recorded := &RecordedEvent{
+		Time:      ts,
+		Monotonic: Monotonic{Mono: 5, Process: "p"},
+		Event:     Event{Timestamp: ts, Message: "hello", Severity: "DEBUG"},
+	}
+	b, err := json.Marshal(recorded)
+	require.NoError(t, err)
+	assert.Equal(t, `{"ts":"2026-01-02T03:04:05Z","mono":5,"proc":"p","msg":"hello","level":"DEBUG"}`, string(b))
+
+	decoded := &RecordedEvent{}
+	require.NoError(t, json.Unmarshal(b, decoded))
+	assert.True(t, decoded.Time.Equal(ts))
+	assert.True(t, decoded.Timestamp.Equal(ts))
+	assert.Equal(t, recorded.Monotonic, decoded.Monotonic)
+	assert.Equal(t, "hello", decoded.Message)
+
+	spooled := &SpooledEvent{Time: ts, DeliveredLate: true, Event: Event{Message: "hello"}}
+	b, err = json.Marshal(spooled)
+	require.NoError(t, err)
+	assert.Equal(t, `{"ts":"2026-01-02T03:04:05Z","delivered_late":true,"msg":"hello"}`, string(b))
+	decodedSpooled := &SpooledEvent{}
+	require.NoError(t, json.Unmarshal(b, decodedSpooled))
+	assert.True(t, decodedSpooled.DeliveredLate)
+	assert.True(t, decodedSpooled.Time.Equal(ts))
+	assert.Equal(t, "hello", decodedSpooled.Message)
+}
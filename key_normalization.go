@@ -0,0 +1,215 @@
+package golog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/getlantern/context"
+	"github.com/getlantern/ops"
+)
+
+// KeyCollisionPolicy is what happens when the same field key is set by more
+// than one of an event's sources: the fields given at the call site, the ops
+// context, the fields bound to the logger (see ChildLogger) and the fields
+// from providers (see RegisterFieldProvider).
+type KeyCollisionPolicy int
+
+const (
+	// KeyCollisionLastWins keeps the value from the most specific source:
+	// call site fields win over the ops context, which wins over bound
+	// fields, which win over provided fields. This is the default.
+	KeyCollisionLastWins KeyCollisionPolicy = iota
+
+	// KeyCollisionPrefix keeps the value from the most specific source under
+	// the key, and the other values under the key prefixed with "ctx_" (for
+	// the ops context), "bound_" (for bound fields) or "dyn_" (for provided
+	// fields).
+	KeyCollisionPrefix
+
+	// KeyCollisionError behaves like KeyCollisionLastWins but also reports
+	// each colliding key as an error on logging, once per key.
+	KeyCollisionError
+)
+
+// KeyNormalization configures how field keys are normalized and how
+// collisions between them are resolved, see SetKeyNormalization.
+type KeyNormalization struct {
+	// SnakeCase converts keys to snake_case, so cvarA and cvar-a both become
+	// cvar_a.
+	SnakeCase bool
+
+	// MaxLength, if positive, truncates keys to this many bytes.
+	MaxLength int
+
+	// Collisions is the policy for keys set by more than one source,
+	// including keys that only collide after normalization.
+	Collisions KeyCollisionPolicy
+}
+
+var (
+	keyNormalization atomic.Value
+
+	// reportedCollisions are the keys already reported under
+	// KeyCollisionError.
+	reportedCollisions sync.Map
+)
+
+// SetKeyNormalization sets how field keys are normalized before events reach
+// the outputs, so that text, JSON and zap all see the same keys. nil restores
+// the default, which leaves keys alone and resolves collisions with
+// KeyCollisionLastWins.
+func SetKeyNormalization(n *KeyNormalization) {
+	keyNormalization.Store(n)
+}
+
+// eventValues builds an event's context from the fields of arg, the ops
+// context, the bound fields and the provided fields, normalizing keys as
+// configured.
+func eventValues(arg interface{}, bound []Field) map[string]interface{} {
+	return contextValues(arg, bound, false)
+}
+
+// reportValues builds the context passed to the ErrorReporters for err like
+// eventValues, but including the global ops context.
+func reportValues(err error) map[string]interface{} {
+	return contextValues(err, nil, true)
+}
+
+func contextValues(arg interface{}, bound []Field, includeGlobals bool) map[string]interface{} {
+	n, _ := keyNormalization.Load().(*KeyNormalization)
+	if n == nil {
+		values := ops.AsMap(arg, includeGlobals)
+		for _, field := range bound {
+			if _, found := values[field.Key]; !found {
+				values[field.Key] = field.Value
+			}
+		}
+		if dynamic := dynamicFieldValues(); dynamic != nil {
+			for key, value := range dynamic {
+				if _, found := values[key]; !found {
+					values[key] = value
+				}
+			}
+			releaseContext(dynamic)
+		}
+		return values
+	}
+
+	// The sources are only needed while merging, so they're pooled
+	callSite := acquireContext()
+	defer releaseContext(callSite)
+	if c, ok := arg.(context.Contextual); ok {
+		c.Fill(callSite)
+	}
+	boundValues := acquireContext()
+	defer releaseContext(boundValues)
+	for _, field := range bound {
+		boundValues[field.Key] = field.Value
+	}
+	m := &keyMerger{n: n, values: make(map[string]interface{}), sources: make(map[string]string)}
+	m.merge(callSite, "")
+	m.merge(ops.AsMap(nil, includeGlobals), "ctx_")
+	m.merge(boundValues, "bound_")
+	if dynamic := dynamicFieldValues(); dynamic != nil {
+		m.merge(dynamic, "dyn_")
+		releaseContext(dynamic)
+	}
+	return m.values
+}
+
+// normalizeKeys normalizes the keys of values as configured, for values that
+// leave golog without going through eventValues, like pprof labels and
+// propagated contexts.
+func normalizeKeys(values map[string]interface{}) map[string]interface{} {
+	n, _ := keyNormalization.Load().(*KeyNormalization)
+	if n == nil {
+		return values
+	}
+	m := &keyMerger{n: n, values: make(map[string]interface{}, len(values)), sources: make(map[string]string, len(values))}
+	m.merge(values, "")
+	return m.values
+}
+
+type keyMerger struct {
+	n       *KeyNormalization
+	values  map[string]interface{}
+	sources map[string]string
+}
+
+// merge merges from, a less specific source than those merged before, into
+// the values, prefixing keys with prefix if they collide under
+// KeyCollisionPrefix.
+func (m *keyMerger) merge(from map[string]interface{}, prefix string) {
+	keys := make([]string, 0, len(from))
+	for key := range from {
+		keys = append(keys, key)
+	}
+	// Sort so that collisions within a source are resolved the same way
+	// every time, with keys that are already normalized winning
+	sort.Strings(keys)
+	for _, key := range keys {
+		normalized := m.n.normalize(key)
+		source, collides := m.sources[normalized]
+		if !collides {
+			m.values[normalized] = from[key]
+			m.sources[normalized] = prefix
+			continue
+		}
+		if source == prefix && key == normalized {
+			m.values[normalized] = from[key]
+		}
+		switch m.n.Collisions {
+		case KeyCollisionPrefix:
+			if source != prefix {
+				prefixed := m.n.normalize(prefix + normalized)
+				if _, found := m.sources[prefixed]; !found {
+					m.values[prefixed] = from[key]
+					m.sources[prefixed] = prefix
+				}
+			}
+		case KeyCollisionError:
+			if _, reported := reportedCollisions.LoadOrStore(normalized, true); !reported {
+				errorOnLogging(fmt.Errorf("field key %q is set more than once", normalized))
+			}
+		}
+	}
+}
+
+func (n *KeyNormalization) normalize(key string) string {
+	if n.SnakeCase {
+		original := key
+		key = snakeCase(key)
+		key = strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+				return r
+			}
+			return '_'
+		}, key)
+		for strings.Contains(key, "__") {
+			key = strings.Replace(key, "__", "_", -1)
+		}
+		key = strings.Trim(key, "_")
+		if key == "" {
+			// nothing's left of keys like "ключ", keep them rather than
+			// writing fields without keys or merging them all
+			key = original
+		}
+	}
+	if n.MaxLength > 0 && len(key) > n.MaxLength {
+		// don't cut a rune in half
+		cut := n.MaxLength
+		for cut > 0 && !utf8.RuneStart(key[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			// not UTF-8 anyway
+			cut = n.MaxLength
+		}
+		key = key[:cut]
+	}
+	return key
+}
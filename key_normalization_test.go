@@ -0,0 +1,87 @@
+package golog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/getlantern/ops"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyNormalization(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+	defer SetKeyNormalization(nil)
+
+	op := ops.Begin("normalization").Set("sharedKey", "ctx")
+	defer op.End()
+	l := ChildLogger(LoggerFor("myprefix"), Field{Key: "shared_key", Value: "bound"}, Field{Key: "conn-id", Value: 5})
+	log := func() string {
+		buf.Reset()
+		l.Debugw("hello", Field{Key: "sharedKey", Value: "call site"}, Field{Key: "aVeryLongKeyName", Value: 1})
+		return buf.String()
+	}
+
+	assert.Regexp(t, `\[aVeryLongKeyName=1 conn-id=5 op=normalization root_op=normalization sharedKey=call site shared_key=bound\]`, log())
+
+	SetKeyNormalization(&KeyNormalization{SnakeCase: true, MaxLength: 10})
+	assert.Regexp(t, `\[a_very_lon=1 conn_id=5 op=normalization root_op=normalization shared_key=call site\]`, log())
+
+	SetKeyNormalization(&KeyNormalization{SnakeCase: true, Collisions: KeyCollisionPrefix})
+	assert.Regexp(t, `\[a_very_long_key_name=1 bound_shared_key=bound conn_id=5 ctx_shared_key=ctx op=normalization root_op=normalization shared_key=call site\]`, log())
+
+	SetKeyNormalization(&KeyNormalization{SnakeCase: true, Collisions: KeyCollisionError})
+	assert.Regexp(t, `shared_key=call site\]`, log())
+	_, reported := reportedCollisions.Load("shared_key")
+	assert.True(t, reported)
+}
+
+func TestKeyNormalizationEverywhere(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+	SetKeyNormalization(&KeyNormalization{SnakeCase: true})
+	defer SetKeyNormalization(nil)
+	unregister := RegisterFieldProvider(time.Hour, func() (string, interface{}) { return "dataCenter", "ams" })
+	defer unregister()
+	active := true
+	var reported map[string]interface{}
+	RegisterReporter(func(err error, severity Severity, ctx map[string]interface{}) {
+		if active {
+			reported = ctx
+		}
+	})
+	defer func() { active = false }()
+
+	op := ops.Begin("normalization").Set("connId", 5)
+	defer op.End()
+	l := ChildLogger(LoggerFor("myprefix"), Field{Key: "boundKey", Value: 1})
+
+	assert.Error(t, l.Error("oh no"))
+	assert.Equal(t, 5, reported["conn_id"], "reported keys should be normalized")
+	assert.NotContains(t, reported, "connId")
+	assert.Equal(t, "ams", reported["data_center"], "reporters should get provided fields")
+
+	l.DebugStream(func(w io.Writer) {
+		io.WriteString(w, "streamed")
+	})
+	assert.Regexp(t, `streaming output follows \[bound_key=1 conn_id=5 data_center=ams op=normalization root_op=normalization\]`, buf.String())
+
+	assert.Equal(t, "conn_id=5,parent_op=normalization,root_op=normalization", EncodeContext())
+	DoWithOpsLabels(context.Background(), func(ctx context.Context) {
+		assert.Contains(t, PprofLabels(ctx), Field{"conn_id", "5"})
+	})
+}
+
+func TestKeyNormalizationNonASCII(t *testing.T) {
+	n := &KeyNormalization{SnakeCase: true}
+	assert.Equal(t, "ключ", n.normalize("ключ"), "keys with nothing left after normalizing should be kept")
+	assert.Equal(t, "键", n.normalize("键"))
+	assert.Equal(t, "user_id", n.normalize("userИД_Id"))
+	n.MaxLength = 3
+	assert.Equal(t, "к", n.normalize("ключ"), "truncation shouldn't cut runes in half")
+}
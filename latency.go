@@ -0,0 +1,115 @@
+package golog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBounds are the upper bounds of the buckets of latency histograms.
+var latencyBounds = []time.Duration{
+	1 * time.Microsecond,
+	5 * time.Microsecond,
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	1 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+}
+
+var (
+	latencyHistograms   = make(map[string]*latencyHistogram)
+	latencyHistogramsMx sync.Mutex
+)
+
+// LatencyHistogram is a snapshot of the time spent in an output.
+type LatencyHistogram struct {
+	// Bounds are the upper bounds of the buckets. Counts has one more entry
+	// than Bounds, counting calls that took longer than the last bound.
+	Bounds []time.Duration
+	Counts []uint64
+	// Count is the total number of calls and Sum the total time spent.
+	Count uint64
+	Sum   time.Duration
+}
+
+type latencyHistogram struct {
+	// count and sum come first to keep them 64-bit aligned for atomic access
+	count  uint64
+	sum    int64
+	counts []uint64
+}
+
+func (h *latencyHistogram) record(elapsed time.Duration) {
+	i := 0
+	for ; i < len(latencyBounds); i++ {
+		if elapsed <= latencyBounds[i] {
+			break
+		}
+	}
+	atomic.AddUint64(&h.counts[i], 1)
+	atomic.AddUint64(&h.count, 1)
+	atomic.AddInt64(&h.sum, int64(elapsed))
+}
+
+func (h *latencyHistogram) snapshot() LatencyHistogram {
+	s := LatencyHistogram{
+		Bounds: append([]time.Duration(nil), latencyBounds...),
+		Counts: make([]uint64, len(h.counts)),
+		Count:  atomic.LoadUint64(&h.count),
+		Sum:    time.Duration(atomic.LoadInt64(&h.sum)),
+	}
+	for i := range h.counts {
+		s.Counts[i] = atomic.LoadUint64(&h.counts[i])
+	}
+	return s
+}
+
+// LatencyOutput wraps out so that the time spent in each call to it, which
+// covers encoding and writing, is recorded in a histogram under the given
+// name. Wrapping several outputs with the same name aggregates them. Use
+// LatencyHistograms to read the histograms, for example to export them as
+// metrics.
+func LatencyOutput(name string, out Output) Output {
+	latencyHistogramsMx.Lock()
+	defer latencyHistogramsMx.Unlock()
+	h := latencyHistograms[name]
+	if h == nil {
+		h = &latencyHistogram{counts: make([]uint64, len(latencyBounds)+1)}
+		latencyHistograms[name] = h
+	}
+	return &latencyOutput{out: out, h: h}
+}
+
+// LatencyHistograms returns snapshots of all latency histograms by name, see
+// LatencyOutput.
+func LatencyHistograms() map[string]LatencyHistogram {
+	latencyHistogramsMx.Lock()
+	defer latencyHistogramsMx.Unlock()
+	result := make(map[string]LatencyHistogram, len(latencyHistograms))
+	for name, h := range latencyHistograms {
+		result[name] = h.snapshot()
+	}
+	return result
+}
+
+type latencyOutput struct {
+	out Output
+	h   *latencyHistogram
+}
+
+func (o *latencyOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	start := time.Now()
+	o.out.Error(prefix, skipFrames+1, printStack, severity, arg, values)
+	o.h.record(time.Since(start))
+}
+
+func (o *latencyOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	start := time.Now()
+	o.out.Debug(prefix, skipFrames+1, printStack, severity, arg, values)
+	o.h.record(time.Since(start))
+}
@@ -0,0 +1,24 @@
+package golog
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyOutput(t *testing.T) {
+	out := LatencyOutput("latency_test", TextOutput(ioutil.Discard, ioutil.Discard))
+	out.Debug("myprefix: ", 0, false, "DEBUG", "hello", nil)
+	out.Error("myprefix: ", 0, false, "ERROR", "oh no", nil)
+
+	h := LatencyHistograms()["latency_test"]
+	assert.EqualValues(t, 2, h.Count)
+	assert.True(t, h.Sum > 0)
+	assert.Len(t, h.Counts, len(h.Bounds)+1)
+	total := uint64(0)
+	for _, count := range h.Counts {
+		total += count
+	}
+	assert.EqualValues(t, 2, total)
+}
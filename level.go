@@ -0,0 +1,114 @@
+package golog
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	// minLevel is the level below which events aren't logged, see SetLevel.
+	minLevel int32
+
+	// runtimeTrace holds the *traceOverride set with EnableTrace.
+	runtimeTrace atomic.Value
+)
+
+// levels maps the names of severities to their levels.
+var levels = map[string]int{
+	"TRACE": TRACE,
+	"DEBUG": DEBUG,
+	"INFO":  INFO,
+	"WARN":  WARN,
+	"ERROR": ERROR,
+	"FATAL": FATAL,
+}
+
+// severityLevel returns the numeric level of the given severity name, for
+// comparing severities, which is the value of the corresponding Severity, or 0
+// if it's unknown.
+func severityLevel(severity string) int {
+	return levels[severity]
+}
+
+// parseLevel is like severityLevel for names from configuration, which it
+// matches case insensitively, returning an error for unknown ones.
+func parseLevel(name string) (int, error) {
+	level, found := levels[strings.ToUpper(name)]
+	if !found {
+		return 0, fmt.Errorf("unknown level %q", name)
+	}
+	return level, nil
+}
+
+// SetLevel sets the lowest severity ("TRACE", "DEBUG", "INFO", "WARN",
+// "ERROR" or "FATAL") that loggers log, case insensitively. Events below it
+// are dropped, though ERRORs are still sent to the registered ErrorReporters.
+// An empty level logs everything, which is the default.
+func SetLevel(level string) error {
+	if level == "" {
+		atomic.StoreInt32(&minLevel, 0)
+		return nil
+	}
+	value, err := parseLevel(level)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(&minLevel, int32(value))
+	return nil
+}
+
+// Level returns the level set with SetLevel, or "" if everything is logged.
+func Level() string {
+	current := int(atomic.LoadInt32(&minLevel))
+	for name, value := range levels {
+		if value == current {
+			return name
+		}
+	}
+	return ""
+}
+
+// levelEnabled reports whether events with the given severity are logged at
+// the current level. Unknown severities are always logged.
+func levelEnabled(severity string) bool {
+	min := atomic.LoadInt32(&minLevel)
+	if min == 0 {
+		return true
+	}
+	level := severityLevel(severity)
+	return level == 0 || int32(level) >= min
+}
+
+type traceOverride struct {
+	all      bool
+	prefixes map[string]bool
+}
+
+// EnableTrace enables TRACE logging at runtime for loggers with the given
+// prefixes (case insensitive), or for all loggers if none are given, in
+// addition to those enabled through the TRACE environment variable or linker
+// flags. Note that TraceOut writers of loggers that weren't already tracing
+// keep discarding their output.
+func EnableTrace(prefixes ...string) {
+	override := &traceOverride{all: len(prefixes) == 0, prefixes: make(map[string]bool, len(prefixes))}
+	for _, prefix := range prefixes {
+		override.prefixes[strings.ToLower(prefix)] = true
+	}
+	runtimeTrace.Store(override)
+}
+
+// DisableTrace undoes EnableTrace.
+func DisableTrace() {
+	runtimeTrace.Store(&traceOverride{})
+}
+
+// traceEnabledAtRuntime reports whether EnableTrace enabled tracing for the
+// given prefix, which includes the trailing ": ".
+func traceEnabledAtRuntime(prefix string) bool {
+	override, _ := runtimeTrace.Load().(*traceOverride)
+	if override == nil {
+		return false
+	}
+	return override.all || override.prefixes[strings.ToLower(strings.TrimSuffix(prefix, ": "))]
+}
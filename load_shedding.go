@@ -0,0 +1,156 @@
+package golog
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultLoadSheddingCheckInterval = 1 * time.Second
+
+// readHeapAlloc returns the number of bytes currently allocated on the heap.
+var readHeapAlloc = func() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// LoadSheddingOptions configures a load shedding output. Shedding starts when
+// any of the configured thresholds is exceeded.
+type LoadSheddingOptions struct {
+	// MaxHeapBytes is the heap size above which events are shed. 0 disables
+	// the check.
+	MaxHeapBytes uint64
+
+	// QueueDepth, if set, reports the current depth of some queue, such as the
+	// backlog of an asynchronous output.
+	QueueDepth func() int
+
+	// MaxQueueDepth is the queue depth above which events are shed.
+	MaxQueueDepth int
+
+	// CheckInterval is how often the thresholds are checked. Defaults to 1
+	// second.
+	CheckInterval time.Duration
+}
+
+// LoadSheddingOutput creates an output that periodically checks the process's
+// memory and, optionally, the depth of a queue, and while either exceeds its
+// threshold drops TRACE, DEBUG, INFO and WARN events instead of passing them
+// to out. ERROR and FATAL events are always kept. When the pressure subsides, and on
+// Close, an ERROR summarizing how many events were shed is logged to out.
+func LoadSheddingOutput(out Output, opts *LoadSheddingOptions) ClosableOutput {
+	o := &loadSheddingOutput{
+		out:  out,
+		opts: *opts,
+		shed: make(map[string]int),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if o.opts.CheckInterval <= 0 {
+		o.opts.CheckInterval = defaultLoadSheddingCheckInterval
+	}
+	go o.run()
+	return o
+}
+
+type loadSheddingOutput struct {
+	out      Output
+	opts     LoadSheddingOptions
+	shedding int32
+	mx       sync.Mutex
+	shed     map[string]int
+	since    time.Time
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func (o *loadSheddingOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.out.Error(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+func (o *loadSheddingOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	if atomic.LoadInt32(&o.shedding) == 1 {
+		o.mx.Lock()
+		o.shed[severity]++
+		o.mx.Unlock()
+		return
+	}
+	o.out.Debug(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+func (o *loadSheddingOutput) overloaded() bool {
+	if o.opts.QueueDepth != nil && o.opts.MaxQueueDepth > 0 && o.opts.QueueDepth() > o.opts.MaxQueueDepth {
+		return true
+	}
+	return o.opts.MaxHeapBytes > 0 && readHeapAlloc() > o.opts.MaxHeapBytes
+}
+
+func (o *loadSheddingOutput) run() {
+	defer close(o.done)
+	ticker := time.NewTicker(o.opts.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.stop:
+			o.summarize()
+			return
+		case <-ticker.C:
+			if o.overloaded() {
+				if atomic.CompareAndSwapInt32(&o.shedding, 0, 1) {
+					o.mx.Lock()
+					o.since = time.Now()
+					o.mx.Unlock()
+				}
+			} else if atomic.CompareAndSwapInt32(&o.shedding, 1, 0) {
+				o.summarize()
+			}
+		}
+	}
+}
+
+func (o *loadSheddingOutput) summarize() {
+	o.mx.Lock()
+	shed, since := o.shed, o.since
+	o.shed = make(map[string]int)
+	o.mx.Unlock()
+
+	total := 0
+	severities := make([]string, 0, len(shed))
+	values := make(map[string]interface{}, len(shed))
+	for severity, count := range shed {
+		total += count
+		severities = append(severities, severity)
+		values["shed_"+severity] = count
+	}
+	if total == 0 {
+		return
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		return severityLevel(severities[i]) < severityLevel(severities[j])
+	})
+	counts := make([]string, 0, len(severities))
+	for _, severity := range severities {
+		counts = append(counts, fmt.Sprintf("%d %v", shed[severity], severity))
+	}
+	msg := fmt.Sprintf("shed %d events (%v) under load since %v", total, strings.Join(counts, ", "), since.Format(time.RFC3339))
+	arg := (&injectedArg{
+		fieldsArg: fieldsArg{arg: msg},
+		caller:    "load_shedding",
+	}).asArg()
+	o.out.Error("golog: ", 2, false, "ERROR", arg, values)
+}
+
+// Close logs a final summary and stops the output's background goroutine.
+func (o *loadSheddingOutput) Close() error {
+	o.stopOnce.Do(func() {
+		close(o.stop)
+	})
+	<-o.done
+	return nil
+}
@@ -0,0 +1,41 @@
+package golog
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSheddingOutput(t *testing.T) {
+	var depth int32
+	buf := &bytes.Buffer{}
+	out := LoadSheddingOutput(TextOutput(buf, buf), &LoadSheddingOptions{
+		QueueDepth:    func() int { return int(atomic.LoadInt32(&depth)) },
+		MaxQueueDepth: 10,
+		CheckInterval: 5 * time.Millisecond,
+	})
+
+	out.Debug("myprefix: ", 0, false, "DEBUG", "kept", nil)
+	atomic.StoreInt32(&depth, 11)
+	time.Sleep(30 * time.Millisecond)
+	out.Debug("myprefix: ", 0, false, "DEBUG", "dropped event", nil)
+	out.Debug("myprefix: ", 0, false, "TRACE", "dropped event", nil)
+	out.Debug("myprefix: ", 0, false, "WARN", "dropped event", nil)
+	out.Debug("myprefix: ", 0, false, "INFO", "dropped event", nil)
+	out.Debug("myprefix: ", 0, false, "INFO", "dropped event", nil)
+	out.Error("myprefix: ", 0, false, "ERROR", "error kept", nil)
+	atomic.StoreInt32(&depth, 0)
+	time.Sleep(30 * time.Millisecond)
+	out.Debug("myprefix: ", 0, false, "DEBUG", "kept again", nil)
+	assert.NoError(t, out.Close())
+
+	logged := buf.String()
+	assert.Contains(t, logged, "kept")
+	assert.NotContains(t, logged, "dropped event")
+	assert.Contains(t, logged, "error kept")
+	assert.Contains(t, logged, "kept again")
+	assert.Regexp(t, `ERROR golog: load_shedding shed 5 events \(1 TRACE, 1 DEBUG, 2 INFO, 1 WARN\) under load since .* \[shed_DEBUG=1 shed_INFO=2 shed_TRACE=1 shed_WARN=1\]`, logged)
+}
@@ -0,0 +1,78 @@
+package golog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// LogDir returns the conventional per-user directory for the logs of the
+// named application on this platform:
+//
+//   - Linux and other Unixes: $XDG_STATE_HOME/app, defaulting to
+//     ~/.local/state/app
+//   - macOS: ~/Library/Logs/app
+//   - Windows: %LOCALAPPDATA%\app\Logs
+//   - Android and iOS: the user cache directory, see os.UserCacheDir
+//
+// The directory isn't created, see OpenAppLogFile and FileOutputForApp.
+func LogDir(app string) (string, error) {
+	return logDirFor(runtime.GOOS, app, os.Getenv, os.UserHomeDir)
+}
+
+func logDirFor(goos string, app string, getenv func(string) string, home func() (string, error)) (string, error) {
+	if app == "" {
+		return "", fmt.Errorf("no application name")
+	}
+	switch goos {
+	case "windows":
+		if dir := getenv("LOCALAPPDATA"); dir != "" {
+			return filepath.Join(dir, app, "Logs"), nil
+		}
+		return "", fmt.Errorf("%%LOCALAPPDATA%% is not set")
+	case "darwin":
+		dir, err := home()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, "Library", "Logs", app), nil
+	case "android", "ios":
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, app, "logs"), nil
+	case "js", "plan9":
+		return "", fmt.Errorf("no log directory on %v", goos)
+	}
+	if dir := getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, app), nil
+	}
+	dir, err := home()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ".local", "state", app), nil
+}
+
+// OpenAppLogFile opens the log file with the given name in the LogDir of the
+// named application, like OpenLogFile.
+func OpenAppLogFile(app string, name string) (*LogFile, error) {
+	dir, err := LogDir(app)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine log directory: %v", err)
+	}
+	return OpenLogFile(filepath.Join(dir, name))
+}
+
+// FileOutputForApp creates a FileOutput writing to the log file with the given
+// name in the LogDir of the named application, creating the directory if
+// necessary.
+func FileOutputForApp(app string, name string, opts FileOutputOptions) (ClosableOutput, error) {
+	dir, err := LogDir(app)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine log directory: %v", err)
+	}
+	return FileOutput(filepath.Join(dir, name), opts)
+}
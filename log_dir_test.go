@@ -0,0 +1,70 @@
+package golog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogDirFor(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(name string) string { return env[name] }
+	home := func() (string, error) { return "/home/me", nil }
+
+	dir, err := logDirFor("linux", "lantern", getenv, home)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/home/me", ".local", "state", "lantern"), dir)
+
+	env["XDG_STATE_HOME"] = "relative/ignored"
+	dir, _ = logDirFor("freebsd", "lantern", getenv, home)
+	assert.Equal(t, filepath.Join("/home/me", ".local", "state", "lantern"), dir, "relative XDG dirs should be ignored")
+
+	env["XDG_STATE_HOME"] = "/var/state"
+	dir, _ = logDirFor("linux", "lantern", getenv, home)
+	assert.Equal(t, filepath.Join("/var/state", "lantern"), dir)
+
+	dir, _ = logDirFor("darwin", "lantern", getenv, home)
+	assert.Equal(t, filepath.Join("/home/me", "Library", "Logs", "lantern"), dir)
+
+	_, err = logDirFor("windows", "lantern", getenv, home)
+	assert.Error(t, err)
+	env["LOCALAPPDATA"] = `C:\Users\me\AppData\Local`
+	dir, _ = logDirFor("windows", "lantern", getenv, home)
+	assert.Equal(t, filepath.Join(`C:\Users\me\AppData\Local`, "lantern", "Logs"), dir)
+
+	_, err = logDirFor("linux", "lantern", getenv, func() (string, error) { return "", fmt.Errorf("no home") })
+	assert.NoError(t, err, "XDG_STATE_HOME doesn't need a home directory")
+	_, err = logDirFor("linux", "", getenv, home)
+	assert.Error(t, err)
+}
+
+func TestFileOutputForApp(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("relies on XDG_STATE_HOME")
+	}
+	dir, err := ioutil.TempDir("", "golog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	oldStateHome := os.Getenv("XDG_STATE_HOME")
+	os.Setenv("XDG_STATE_HOME", dir)
+	defer os.Setenv("XDG_STATE_HOME", oldStateHome)
+
+	out, err := FileOutputForApp("lantern", "lantern.log", FileOutputOptions{})
+	require.NoError(t, err)
+	reset := SetOutput(out)
+	LoggerFor("myprefix").Debug("hello")
+	reset()
+	require.NoError(t, out.Close())
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "lantern", "lantern.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "DEBUG myprefix: log_dir_test.go:")
+	_, err = FileOutputForApp("", "lantern.log", FileOutputOptions{})
+	assert.Error(t, err)
+}
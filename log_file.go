@@ -0,0 +1,161 @@
+package golog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// logFileCheckInterval is how often a LogFile checks whether its file has
+// been truncated, removed or replaced.
+var logFileCheckInterval = time.Second
+
+// LogFile is an io.WriteCloser that appends to a log file, for use with
+// TextOutput or JsonOutput. It's safe for concurrent use.
+//
+// If something else truncates the file (like logrotate's copytruncate),
+// removes it or replaces it, the LogFile notices within a second and
+// reopens or recreates it, reporting this once on stderr, rather than writing
+// into a deleted file forever.
+type LogFile struct {
+	path      string
+	key       *EncryptionKey
+	mx        sync.Mutex
+	file      *os.File
+	w         io.Writer
+	counter   *countingWriter
+	size      int64
+	lastCheck time.Time
+}
+
+// OpenLogFile opens the log file at path for appending, creating it and its
+// directory if necessary.
+func OpenLogFile(path string) (*LogFile, error) {
+	f := &LogFile{path: path}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// OpenEncryptedLogFile opens the log file at path like OpenLogFile, but
+// encrypts everything written to it with key. An existing file is appended to
+// in a new segment, so a file can be reopened with a new key whenever keys are
+// rotated. See DecryptLog and ReencryptFile.
+func OpenEncryptedLogFile(path string, key *EncryptionKey) (*LogFile, error) {
+	if _, err := key.aead(); err != nil {
+		return nil, err
+	}
+	f := &LogFile{path: path, key: key}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *LogFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	counter := &countingWriter{w: file}
+	var w io.Writer = counter
+	if f.key != nil {
+		w, err = NewEncryptingWriter(counter, f.key)
+		if err != nil {
+			file.Close()
+			return err
+		}
+	}
+	f.file, f.w, f.counter, f.size = file, w, counter, info.Size()
+	f.lastCheck = time.Now()
+	return nil
+}
+
+// check reopens the file if it's been truncated, removed or replaced since
+// the last check.
+func (f *LogFile) check() {
+	reason := ""
+	current, err := f.file.Stat()
+	if err != nil {
+		return
+	}
+	if info, err := os.Stat(f.path); err != nil {
+		reason = "removed"
+	} else if !os.SameFile(info, current) {
+		reason = "replaced"
+	} else if current.Size() < f.size+f.counter.n {
+		reason = "truncated"
+	}
+	if reason == "" {
+		return
+	}
+
+	if reason == "truncated" && f.key == nil {
+		// appending continues at the new end of the file
+		f.size, f.counter.n = current.Size(), 0
+	} else {
+		// for removed and replaced files, and for encrypted files, which
+		// need a new segment header
+		old, oldW, oldCounter, oldSize := f.file, f.w, f.counter, f.size
+		if err := f.open(); err != nil {
+			f.file, f.w, f.counter, f.size = old, oldW, oldCounter, oldSize
+			errorOnLogging(fmt.Errorf("log file %v was %v and can't be reopened: %v", f.path, reason, err))
+			return
+		}
+		old.Close()
+	}
+	errorOnLogging(fmt.Errorf("log file %v was %v, reopened it", f.path, reason))
+}
+
+// currentSize returns the size of the file, including what's been written
+// since it was opened.
+func (f *LogFile) currentSize() int64 {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if f.counter == nil {
+		return 0
+	}
+	return f.size + f.counter.n
+}
+
+// Path returns the path of the file.
+func (f *LogFile) Path() string {
+	return f.path
+}
+
+func (f *LogFile) Write(p []byte) (int, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if now := time.Now(); now.Sub(f.lastCheck) >= logFileCheckInterval {
+		f.lastCheck = now
+		f.check()
+	}
+	return f.w.Write(p)
+}
+
+// Close closes the file. Writes after Close fail.
+func (f *LogFile) Close() error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file, f.w = nil, nil
+	return err
+}
@@ -0,0 +1,112 @@
+package golog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-logfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "nested", "app.log")
+	f, err := OpenLogFile(path)
+	require.NoError(t, err)
+	reset := SetOutputs(f, f)
+	LoggerFor("myprefix").Debug("to file")
+	reset()
+	require.NoError(t, f.Close())
+	_, err = f.Write([]byte("late"))
+	assert.Error(t, err)
+
+	f, err = OpenLogFile(path)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("appended\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "to file")
+	assert.Contains(t, string(b), "appended")
+}
+
+func TestLogFileReopens(t *testing.T) {
+	errs := &bytes.Buffer{}
+	oldStderr, oldInterval := stderr, logFileCheckInterval
+	stderr, logFileCheckInterval = errs, 0
+	defer func() { stderr, logFileCheckInterval = oldStderr, oldInterval }()
+
+	dir, err := ioutil.TempDir("", "golog-logfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	f, err := OpenLogFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	write := func(s string) {
+		_, err := f.Write([]byte(s))
+		require.NoError(t, err)
+	}
+	read := func() string {
+		b, _ := ioutil.ReadFile(path)
+		return string(b)
+	}
+
+	write("one\n")
+	require.NoError(t, os.Remove(path))
+	write("two\n")
+	assert.Equal(t, "two\n", read(), "removed file should have been recreated")
+	assert.Contains(t, errs.String(), "was removed, reopened it")
+
+	errs.Reset()
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, ioutil.WriteFile(path, nil, 0600))
+	write("three\n")
+	assert.Equal(t, "three\n", read(), "replaced file should have been reopened")
+	assert.Contains(t, errs.String(), "was replaced, reopened it")
+
+	errs.Reset()
+	require.NoError(t, os.Truncate(path, 0))
+	write("four\n")
+	write("five\n")
+	assert.Equal(t, "four\nfive\n", read())
+	assert.Equal(t, 1, bytes.Count(errs.Bytes(), []byte("Unable to log")), "truncation should be noticed once")
+	assert.Contains(t, errs.String(), "was truncated, reopened it")
+}
+
+func TestEncryptedLogFileTruncated(t *testing.T) {
+	oldStderr, oldInterval := stderr, logFileCheckInterval
+	stderr, logFileCheckInterval = ioutil.Discard, 0
+	defer func() { stderr, logFileCheckInterval = oldStderr, oldInterval }()
+
+	dir, err := ioutil.TempDir("", "golog-logfile")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	key := &EncryptionKey{ID: "k", Key: bytes.Repeat([]byte{1}, 32)}
+	f, err := OpenEncryptedLogFile(path, key)
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.Write([]byte("before\n"))
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, 0))
+	_, err = f.Write([]byte("after\n"))
+	require.NoError(t, err)
+
+	raw, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var plain bytes.Buffer
+	_, err = DecryptLog(&plain, bytes.NewReader(raw), Keyring{"k": key.Key})
+	require.NoError(t, err, "truncated file should start with a new segment header")
+	assert.Equal(t, "after\n", plain.String())
+}
@@ -0,0 +1,56 @@
+package golog
+
+import (
+	"strings"
+)
+
+// MobileWriter receives log events in a form that can be bound with gomobile,
+// so that Android and iOS host apps can implement it in Java/Kotlin or
+// Objective-C/Swift, e.g. to show logs in an in-app debug screen.
+//
+// level is the numeric severity: 100 for TRACE, 200 for DEBUG, 250 for INFO,
+// 300 for WARN, 500 for ERROR and 600 for FATAL, matching the values of
+// Severity. tag is the logger's prefix and msg the rest of the line, including
+// the caller, the context and, if enabled, the stack.
+type MobileWriter interface {
+	Write(level int, tag, msg string)
+}
+
+// SetMobileWriter sets the output to write to the given MobileWriter. Unlike
+// SetOutput, it doesn't return a reset function, which gomobile can't bind.
+func SetMobileWriter(w MobileWriter) {
+	SetOutput(MobileOutput(w))
+}
+
+// MobileOutput creates an output that writes to the given MobileWriter.
+func MobileOutput(w MobileWriter) Output {
+	return &mobileOutput{w}
+}
+
+type mobileOutput struct {
+	w MobileWriter
+}
+
+func (o *mobileOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.print(prefix, skipFrames, printStack, severity, arg, values)
+}
+
+func (o *mobileOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.print(prefix, skipFrames, printStack, severity, arg, values)
+}
+
+func (o *mobileOutput) print(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	event := buildEvent(make([]uintptr, 10), prefix, skipFrames, printStack, severity, arg, values)
+	defer event.Release()
+	buf := getBuffer()
+	defer returnBuffer(buf)
+	buf.WriteString(event.Caller)
+	buf.WriteByte(' ')
+	buf.WriteString(strings.TrimSuffix(event.Message, "\n"))
+	printContext(buf, values)
+	if event.Stack != "" {
+		buf.WriteByte('\n')
+		buf.WriteString(event.Stack)
+	}
+	o.w.Write(severityLevel(severity), event.Component, buf.String())
+}
@@ -0,0 +1,56 @@
+package golog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mobileLine struct {
+	level int
+	tag   string
+	msg   string
+}
+
+type recordingMobileWriter struct {
+	lines []mobileLine
+}
+
+func (w *recordingMobileWriter) Write(level int, tag, msg string) {
+	w.lines = append(w.lines, mobileLine{level, tag, normalized(msg)})
+}
+
+func TestMobileOutput(t *testing.T) {
+	w := &recordingMobileWriter{}
+	SetMobileWriter(w)
+	defer ResetOutputs()
+
+	l := LoggerFor("myprefix").(*logger)
+	l.Debugw("Hello world", Field{"a", "b"})
+	l.Infof("info %d", 1)
+	l.Warn("careful")
+	_ = l.Error("Oh no")
+	assert.Equal(t, []mobileLine{
+		{DEBUG, "myprefix", "mobile_output_test.go:999 Hello world [a=b]"},
+		{INFO, "myprefix", "mobile_output_test.go:999 info 999"},
+		{WARN, "myprefix", "mobile_output_test.go:999 careful"},
+		{ERROR, "myprefix", "mobile_output_test.go:999 Oh no"},
+	}, w.lines)
+}
+
+func TestMobileOutputSeverities(t *testing.T) {
+	w := &recordingMobileWriter{}
+	out := MobileOutput(w)
+	out.Debug("myprefix: ", 0, false, "TRACE", "trace", nil)
+	out.Error("myprefix: ", 0, false, "FATAL", "fatal", nil)
+	out.Debug("myprefix: ", 0, false, "UNKNOWN", "unknown", nil)
+	out.Error("myprefix: ", 0, true, "ERROR", "with stack\n", nil)
+	var levels []int
+	for _, line := range w.lines {
+		levels = append(levels, line.level)
+	}
+	assert.Equal(t, []int{TRACE, FATAL, 0, ERROR}, levels, "unknown severities should map to 0")
+	if assert.Len(t, w.lines, 4) {
+		assert.Regexp(t, ` with stack\n\tgithub.com/getlantern/golog`, w.lines[3].msg, "the stack should follow the message")
+	}
+}
@@ -0,0 +1,137 @@
+package golog
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// monotonicEnabled is 1 once SetMonotonicTime(true) is called.
+	monotonicEnabled int32
+
+	// processID tells apart the offsets of different runs of the process,
+	// which are relative to processStart. Unlike the wall clock, Go's
+	// monotonic clock isn't affected by the clock being set, but on most
+	// platforms it also stands still while the system sleeps.
+	processID = newProcessID()
+)
+
+func newProcessID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102T150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+// Monotonic is when an event was logged according to the monotonic clock of
+// the process that logged it, which keeps ticking steadily when the wall
+// clock jumps, for example as a laptop wakes up and syncs its clock. See
+// SetMonotonicTime.
+type Monotonic struct {
+	// Mono is the time between the process starting and the event being
+	// logged, by the monotonic clock.
+	Mono time.Duration `json:"mono,omitempty"`
+
+	// Process identifies the run of the process that Mono is relative to.
+	Process string `json:"proc,omitempty"`
+}
+
+// SetMonotonicTime sets whether RecordedEvents and SpooledEvents include
+// their Monotonic time, so that OrderEvents, ClockJumps and Elapsed can make
+// sense of them when the wall clock jumped. It's off by default. Events
+// injected with their own time (see EventBuilder.At) never have a Monotonic
+// time since they happened elsewhere.
+func SetMonotonicTime(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&monotonicEnabled, v)
+}
+
+// monotonicNow returns the Monotonic time of an event for arg, if enabled.
+func monotonicNow(arg interface{}) Monotonic {
+	if atomic.LoadInt32(&monotonicEnabled) == 0 {
+		return Monotonic{}
+	}
+	if o, ok := arg.(eventOrigin); ok {
+		if _, ts := o.origin(); !ts.IsZero() {
+			return Monotonic{}
+		}
+	}
+	return Monotonic{Mono: time.Since(processStart), Process: processID}
+}
+
+// Elapsed returns the time between events a and b, by their monotonic clock
+// if they were logged by the same run of a process, or by their wall time
+// otherwise.
+func Elapsed(a *RecordedEvent, b *RecordedEvent) time.Duration {
+	if a.Process != "" && a.Process == b.Process {
+		return b.Mono - a.Mono
+	}
+	return b.Time.Sub(a.Time)
+}
+
+// OrderEvents sorts events into the order in which they were logged. Events
+// from the same run of a process are ordered by their monotonic time, so that
+// wall clock jumps don't change their order, and runs are ordered by the wall
+// time of their first event. Events without a monotonic time are ordered by
+// wall time.
+func OrderEvents(events []*RecordedEvent) {
+	runStart := make(map[string]time.Time)
+	for _, event := range events {
+		if event.Process == "" {
+			continue
+		}
+		if start, found := runStart[event.Process]; !found || event.Time.Before(start) {
+			runStart[event.Process] = event.Time
+		}
+	}
+	sortTime := func(event *RecordedEvent) time.Time {
+		if event.Process == "" {
+			return event.Time
+		}
+		return runStart[event.Process]
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if a.Process != "" && a.Process == b.Process {
+			return a.Mono < b.Mono
+		}
+		return sortTime(a).Before(sortTime(b))
+	})
+}
+
+// ClockJump is a jump of the wall clock between two consecutive events logged
+// by the same run of a process, see ClockJumps.
+type ClockJump struct {
+	// Index is the index of the first event after the jump.
+	Index int
+
+	// Jump is how far the wall clock jumped, beyond the time that passed by
+	// the monotonic clock. It's negative if the clock was set back.
+	Jump time.Duration
+}
+
+// ClockJumps finds where the wall clock jumped by more than threshold between
+// consecutive events, which must be ordered (see OrderEvents). Jumps forward
+// are either the system sleeping or the clock being set, jumps back are the
+// clock being set.
+func ClockJumps(events []*RecordedEvent, threshold time.Duration) []ClockJump {
+	var jumps []ClockJump
+	for i := 1; i < len(events); i++ {
+		prev, cur := events[i-1], events[i]
+		if cur.Process == "" || cur.Process != prev.Process {
+			continue
+		}
+		jump := cur.Time.Sub(prev.Time) - (cur.Mono - prev.Mono)
+		if jump > threshold || jump < -threshold {
+			jumps = append(jumps, ClockJump{Index: i, Jump: jump})
+		}
+	}
+	return jumps
+}
@@ -0,0 +1,71 @@
+package golog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonotonicTime(t *testing.T) {
+	rb := NewRingBuffer(nil, 10)
+	reset := SetOutput(rb)
+	defer reset()
+
+	l := LoggerFor("myprefix")
+	l.Debug("without")
+	SetMonotonicTime(true)
+	defer SetMonotonicTime(false)
+	l.Debug("first")
+	time.Sleep(5 * time.Millisecond)
+	l.Debug("second")
+	NewEvent("DEBUG", "relay", "injected").At(time.Now()).Emit()
+
+	events := rb.Events()
+	require.Len(t, events, 4)
+	assert.Empty(t, events[0].Process)
+	assert.NotEmpty(t, events[1].Process)
+	assert.Equal(t, events[1].Process, events[2].Process)
+	assert.True(t, Elapsed(events[1], events[2]) >= 5*time.Millisecond)
+	assert.Empty(t, events[3].Process, "injected events happened elsewhere")
+
+	// round trips through JSON
+	buf := &bytes.Buffer{}
+	_, err := rb.WriteTo(buf)
+	require.NoError(t, err)
+	read, err := ReadRecordedEvents(buf)
+	require.NoError(t, err)
+	assert.Equal(t, events[2].Monotonic, read[2].Monotonic)
+}
+
+func TestOrderEventsAndClockJumps(t *testing.T) {
+	wall := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	event := func(msg string, proc string, mono time.Duration, ts time.Time) *RecordedEvent {
+		return &RecordedEvent{Time: ts, Monotonic: Monotonic{Mono: mono, Process: proc}, Event: Event{Message: msg}}
+	}
+	events := []*RecordedEvent{
+		// the clock was set back by an hour between b and c
+		event("c", "run2", 3*time.Second, wall.Add(-time.Hour)),
+		event("a", "run2", 1*time.Second, wall),
+		event("b", "run2", 2*time.Second, wall.Add(time.Second)),
+		// the laptop slept for a day between d and e
+		event("e", "run1", 2*time.Second, wall.Add(-48*time.Hour+24*time.Hour)),
+		event("d", "run1", 1*time.Second, wall.Add(-48*time.Hour)),
+		event("x", "", 0, wall.Add(-72*time.Hour)),
+	}
+	OrderEvents(events)
+	var order string
+	for _, e := range events {
+		order += e.Message
+	}
+	assert.Equal(t, "xdeabc", order)
+
+	assert.Equal(t, []ClockJump{
+		{Index: 2, Jump: 24*time.Hour - time.Second},
+		{Index: 5, Jump: -time.Hour - 2*time.Second},
+	}, ClockJumps(events, time.Minute))
+	assert.Equal(t, time.Second, Elapsed(events[4], events[5]))
+	assert.Equal(t, 72*time.Hour, Elapsed(events[0], events[3]))
+}
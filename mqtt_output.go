@@ -0,0 +1,100 @@
+package golog
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// MQTTClient publishes a message to an MQTT broker. Most client libraries
+// return a token from Publish, so in practice this is satisfied by a small
+// adapter that waits on the token and returns its error.
+type MQTTClient interface {
+	Publish(topic string, qos byte, retained bool, payload []byte) error
+}
+
+// MQTTOptions configures an MQTT output.
+type MQTTOptions struct {
+	// Topic is the topic to publish events to. Like NATSOutput, it may
+	// reference the placeholders {component} and {severity}, for example
+	// "lantern/logs/{component}/{severity}".
+	Topic string
+
+	// QoS is the MQTT quality of service level (0, 1 or 2) used when
+	// publishing events.
+	QoS byte
+
+	// Retained marks published events as retained, so that the broker hands
+	// the latest event on each topic to new subscribers.
+	Retained bool
+
+	// WillTopic is the topic on which the broker will publish WillMessage if
+	// the client disconnects uncleanly. Leave empty to not use a Last Will.
+	WillTopic string
+
+	// WillMessage is the message of the Last Will event.
+	WillMessage string
+}
+
+// LastWill returns the topic and the payload to register as the MQTT client's
+// Last Will and Testament when connecting. The payload is a FATAL JSON event
+// for the given component, so subscribers can process it like any other
+// event. If no WillTopic is configured, topic is empty.
+func (opts *MQTTOptions) LastWill(component string) (topic string, payload []byte, err error) {
+	if opts.WillTopic == "" {
+		return "", nil, nil
+	}
+	event := &Event{Component: component, Severity: "FATAL", Message: opts.WillMessage}
+	payload, err = json.Marshal(event)
+	if err != nil {
+		return "", nil, err
+	}
+	return expandEventTemplate(opts.WillTopic, event, topicLevel), payload, nil
+}
+
+// MQTTOutput creates an output that publishes JSON structured events to an
+// MQTT broker using the given client.
+func MQTTOutput(client MQTTClient, opts *MQTTOptions) Output {
+	return &mqttOutput{
+		client: client,
+		opts:   *opts,
+	}
+}
+
+type mqttOutput struct {
+	client MQTTClient
+	opts   MQTTOptions
+}
+
+func (o *mqttOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.print(prefix, skipFrames, printStack, severity, arg, values)
+}
+
+func (o *mqttOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.print(prefix, skipFrames, printStack, severity, arg, values)
+}
+
+func (o *mqttOutput) print(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	event := buildEvent(make([]uintptr, 10), prefix, skipFrames, printStack, severity, arg, values)
+	defer event.Release()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		errorOnLogging(err)
+		return
+	}
+	topic := expandEventTemplate(o.opts.Topic, event, topicLevel)
+	if err := o.client.Publish(topic, o.opts.QoS, o.opts.Retained, payload); err != nil {
+		errorOnLogging(err)
+	}
+}
+
+// topicLevel makes sure value can't introduce wildcards or additional levels
+// into a topic.
+func topicLevel(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '+', '#', '/':
+			return '_'
+		}
+		return r
+	}, value)
+}
@@ -0,0 +1,84 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mqttMessage struct {
+	topic    string
+	qos      byte
+	retained bool
+	event    Event
+}
+
+type recordingMQTTClient struct {
+	messages []mqttMessage
+	err      error
+}
+
+func (c *recordingMQTTClient) Publish(topic string, qos byte, retained bool, payload []byte) error {
+	if c.err != nil {
+		return c.err
+	}
+	msg := mqttMessage{topic: topic, qos: qos, retained: retained}
+	if err := json.Unmarshal(payload, &msg.event); err != nil {
+		return err
+	}
+	c.messages = append(c.messages, msg)
+	return nil
+}
+
+func TestMQTTOutput(t *testing.T) {
+	client := &recordingMQTTClient{}
+	reset := SetOutput(MQTTOutput(client, &MQTTOptions{Topic: "logs/{component}/{severity}", QoS: 1, Retained: true}))
+	defer reset()
+
+	l := LoggerFor("my/prefix+#")
+	l.Debugw("Hello world", Field{"a", "b"})
+	l.Error("Oh no")
+
+	require.Len(t, client.messages, 2)
+	assert.Equal(t, "logs/my_prefix__/DEBUG", client.messages[0].topic, "component shouldn't add levels or wildcards")
+	assert.Equal(t, "logs/my_prefix__/ERROR", client.messages[1].topic)
+	assert.Equal(t, byte(1), client.messages[0].qos)
+	assert.True(t, client.messages[0].retained)
+	assert.Equal(t, "Hello world", client.messages[0].event.Message)
+	assert.Equal(t, "b", client.messages[0].event.Context["a"])
+	assert.Equal(t, "my/prefix+#", client.messages[1].event.Component)
+	assert.Equal(t, "mqtt_output_test.go:999", normalized(client.messages[1].event.Caller))
+}
+
+func TestMQTTOutputPublishError(t *testing.T) {
+	oldStderr := stderr
+	errs := &bytes.Buffer{}
+	stderr = errs
+	defer func() { stderr = oldStderr }()
+
+	client := &recordingMQTTClient{err: errors.New("not connected")}
+	reset := SetOutput(MQTTOutput(client, &MQTTOptions{Topic: "logs"}))
+	defer reset()
+
+	LoggerFor("myprefix").Debug("Hello world")
+	assert.Equal(t, "Unable to log: not connected\n", errs.String())
+}
+
+func TestMQTTLastWill(t *testing.T) {
+	topic, payload, err := (&MQTTOptions{}).LastWill("myprefix")
+	require.NoError(t, err)
+	assert.Empty(t, topic, "there should be no Last Will without a WillTopic")
+	assert.Nil(t, payload)
+
+	opts := &MQTTOptions{WillTopic: "logs/{component}/{severity}", WillMessage: "disconnected"}
+	topic, payload, err = opts.LastWill("my/prefix")
+	require.NoError(t, err)
+	assert.Equal(t, "logs/my_prefix/FATAL", topic)
+	var event Event
+	require.NoError(t, json.Unmarshal(payload, &event))
+	assert.Equal(t, Event{Component: "my/prefix", Severity: "FATAL", Message: "disconnected"}, event)
+}
@@ -0,0 +1,276 @@
+package golog
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+)
+
+// DuplicatePolicy determines what MultiOutput does when several of its
+// outputs write to the same destination.
+type DuplicatePolicy int
+
+const (
+	// DuplicateWarn reports duplicate destinations on stderr but still writes
+	// to all outputs. This is the default.
+	DuplicateWarn DuplicatePolicy = iota
+
+	// DuplicateDrop reports duplicate destinations on stderr and only writes
+	// each event to the first output for any given destination.
+	DuplicateDrop
+)
+
+// MultiOutputOptions configures a MultiOutput.
+type MultiOutputOptions struct {
+	// Duplicates is what to do about outputs writing to the same destination.
+	Duplicates DuplicatePolicy
+}
+
+// MultiOutput creates an Output that writes every event to each of outs in
+// turn. opts may be nil. Flush, Stats and Close fan out to those of outs that
+// buffer or need closing, including when they're wrapped like below.
+//
+// Destinations are known for TextOutput and JsonOutput, including when
+// they're wrapped in a SamplingOutput, TransformingOutput,
+// HostMetadataOutput, LatencyOutput, AlertOutput or another MultiOutput. Two
+// writers are the same destination if they're equal (other than
+// ioutil.Discard) or are *os.Files for the same file, so that fanning out to
+// stderr twice via different wrappers doesn't silently print every line twice.
+func MultiOutput(opts *MultiOutputOptions, outs ...Output) BufferingOutput {
+	if opts == nil {
+		opts = &MultiOutputOptions{}
+	}
+	o := &multiOutput{
+		outs:      outs,
+		skipError: make([]bool, len(outs)),
+		skipDebug: make([]bool, len(outs)),
+	}
+	var errorWriters, debugWriters [][]io.Writer
+	for i, out := range outs {
+		e, d := outputWriters(out)
+		if j := firstDuplicate(errorWriters, e); j >= 0 {
+			errorOnLogging(fmt.Errorf("output %d writes errors to the same destination (%v) as output %d", i, describeWriter(e[0]), j))
+			o.skipError[i] = opts.Duplicates == DuplicateDrop
+		}
+		if j := firstDuplicate(debugWriters, d); j >= 0 {
+			errorOnLogging(fmt.Errorf("output %d writes debug messages to the same destination (%v) as output %d", i, describeWriter(d[0]), j))
+			o.skipDebug[i] = opts.Duplicates == DuplicateDrop
+		}
+		errorWriters = append(errorWriters, e)
+		debugWriters = append(debugWriters, d)
+	}
+	return o
+}
+
+type multiOutput struct {
+	outs      []Output
+	skipError []bool
+	skipDebug []bool
+}
+
+func (o *multiOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	for i, out := range o.outs {
+		if !o.skipError[i] {
+			out.Error(prefix, skipFrames+1, printStack, severity, arg, values)
+		}
+	}
+}
+
+func (o *multiOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	for i, out := range o.outs {
+		if !o.skipDebug[i] {
+			out.Debug(prefix, skipFrames+1, printStack, severity, arg, values)
+		}
+	}
+}
+
+// Flush flushes the BufferingOutputs among outs, returning the first error.
+func (o *multiOutput) Flush() error {
+	var firstErr error
+	o.walkChildren(func(out Output) bool {
+		b, ok := out.(BufferingOutput)
+		if ok {
+			if err := b.Flush(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return !ok
+	})
+	return firstErr
+}
+
+// Stats adds up the stats of the BufferingOutputs among outs.
+func (o *multiOutput) Stats() BufferedOutputStats {
+	var stats BufferedOutputStats
+	o.walkChildren(func(out Output) bool {
+		b, ok := out.(BufferingOutput)
+		if ok {
+			s := b.Stats()
+			stats.Full += s.Full
+			stats.Interval += s.Interval
+			stats.Error += s.Error
+			stats.Explicit += s.Explicit
+		}
+		return !ok
+	})
+	return stats
+}
+
+// Close closes the ClosableOutputs among outs, returning the first error.
+func (o *multiOutput) Close() error {
+	var firstErr error
+	o.walkChildren(func(out Output) bool {
+		c, ok := out.(ClosableOutput)
+		if ok {
+			if err := c.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return !ok
+	})
+	return firstErr
+}
+
+// walkChildren walks outs and the outputs they pass events through to, see
+// walkOutputs.
+func (o *multiOutput) walkChildren(visit func(out Output) (descend bool)) {
+	for _, out := range o.outs {
+		walkOutputs(out, visit)
+	}
+}
+
+func (o *multiOutput) writers() (errorWriters []io.Writer, debugWriters []io.Writer) {
+	for i, out := range o.outs {
+		e, d := outputWriters(out)
+		if !o.skipError[i] {
+			errorWriters = append(errorWriters, e...)
+		}
+		if !o.skipDebug[i] {
+			debugWriters = append(debugWriters, d...)
+		}
+	}
+	return
+}
+
+// writerOutput is implemented by outputs that know which io.Writers they
+// write to.
+type writerOutput interface {
+	writers() (errorWriters []io.Writer, debugWriters []io.Writer)
+}
+
+// wrappingOutput is implemented by outputs that pass events through to a
+// single other Output.
+type wrappingOutput interface {
+	wrapped() Output
+}
+
+// parentOutput is implemented by outputs that pass events through to several
+// other Outputs.
+type parentOutput interface {
+	children() []Output
+}
+
+func (o *textOutput) writers() ([]io.Writer, []io.Writer) {
+	return []io.Writer{o.E}, []io.Writer{o.D}
+}
+
+func (o *jsonOutput) writers() ([]io.Writer, []io.Writer) {
+	return []io.Writer{o.E}, []io.Writer{o.D}
+}
+
+func (o *multiOutput) children() []Output    { return o.outs }
+func (o *fallbackOutput) children() []Output { return []Output{o.out, o.fallbackOut} }
+
+func (o *routerOutput) children() []Output {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	return append([]Output{o.opts.Default}, o.created...)
+}
+
+func (o *samplingOutput) wrapped() Output     { return o.out }
+func (o *transformingOutput) wrapped() Output { return o.out }
+func (o *hostMetadataOutput) wrapped() Output { return o.out }
+func (o *latencyOutput) wrapped() Output      { return o.out }
+func (o *alertOutput) wrapped() Output        { return o.out }
+
+func outputWriters(out Output) ([]io.Writer, []io.Writer) {
+	for {
+		switch o := out.(type) {
+		case writerOutput:
+			return o.writers()
+		case wrappingOutput:
+			out = o.wrapped()
+		default:
+			return nil, nil
+		}
+	}
+}
+
+// walkOutputs calls visit with out and, for as long as visit returns true,
+// the outputs it passes events through to.
+func walkOutputs(out Output, visit func(out Output) (descend bool)) {
+	if out == nil || !visit(out) {
+		return
+	}
+	switch o := out.(type) {
+	case wrappingOutput:
+		walkOutputs(o.wrapped(), visit)
+	case parentOutput:
+		for _, child := range o.children() {
+			walkOutputs(child, visit)
+		}
+	}
+}
+
+// firstDuplicate returns the index of the first entry in seen holding any
+// of writers, or -1. When found, the duplicated writer is moved to the front
+// of writers.
+func firstDuplicate(seen [][]io.Writer, writers []io.Writer) int {
+	for i, earlier := range seen {
+		for _, a := range earlier {
+			for k, b := range writers {
+				if sameWriter(a, b) {
+					writers[0], writers[k] = writers[k], writers[0]
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+func sameWriter(a, b io.Writer) bool {
+	if a == nil || b == nil || a == ioutil.Discard {
+		// writing to nothing twice is harmless
+		return false
+	}
+	if reflect.TypeOf(a).Comparable() && reflect.TypeOf(a) == reflect.TypeOf(b) && a == b {
+		return true
+	}
+	fa, ok := a.(*os.File)
+	if !ok {
+		return false
+	}
+	fb, ok := b.(*os.File)
+	if !ok {
+		return false
+	}
+	sa, err := fa.Stat()
+	if err != nil {
+		return false
+	}
+	sb, err := fb.Stat()
+	if err != nil {
+		return false
+	}
+	return os.SameFile(sa, sb)
+}
+
+func describeWriter(w io.Writer) string {
+	if f, ok := w.(*os.File); ok {
+		return f.Name()
+	}
+	return fmt.Sprintf("%T", w)
+}
@@ -0,0 +1,100 @@
+package golog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiOutput(t *testing.T) {
+	errs := &bytes.Buffer{}
+	oldStderr := stderr
+	stderr = errs
+	defer func() { stderr = oldStderr }()
+
+	a, b := &bytes.Buffer{}, &bytes.Buffer{}
+	out := MultiOutput(nil, TextOutput(a, a), JsonOutput(b, b))
+	assert.Empty(t, errs.String(), "distinct writers shouldn't be reported")
+	reset := SetOutput(out)
+	LoggerFor("myprefix").Debug("hello")
+	reset()
+	assert.Contains(t, a.String(), "hello")
+	assert.Contains(t, b.String(), "hello")
+}
+
+func TestMultiOutputDuplicates(t *testing.T) {
+	errs := &bytes.Buffer{}
+	oldStderr := stderr
+	stderr = errs
+	defer func() { stderr = oldStderr }()
+
+	buf := &bytes.Buffer{}
+	out := MultiOutput(&MultiOutputOptions{Duplicates: DuplicateDrop},
+		TextOutput(buf, ioutil.Discard),
+		HostMetadataOutput(SamplingOutput(TextOutput(buf, ioutil.Discard))))
+	assert.Contains(t, errs.String(), "output 1 writes errors to the same destination (*bytes.Buffer) as output 0")
+	assert.NotContains(t, errs.String(), "debug messages")
+
+	reset := SetOutput(out)
+	LoggerFor("myprefix").Error("once")
+	reset()
+	assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("once")))
+
+	errs.Reset()
+	buf.Reset()
+	out = MultiOutput(nil, TextOutput(buf, buf), TextOutput(buf, buf))
+	assert.Contains(t, errs.String(), "writes errors")
+	assert.Contains(t, errs.String(), "writes debug messages")
+	reset = SetOutput(out)
+	LoggerFor("myprefix").Debug("twice")
+	reset()
+	assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte("twice")), "warning shouldn't drop anything")
+}
+
+func TestMultiOutputSameFile(t *testing.T) {
+	errs := &bytes.Buffer{}
+	oldStderr := stderr
+	stderr = errs
+	defer func() { stderr = oldStderr }()
+
+	dir, err := ioutil.TempDir("", "golog-multi")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log.txt")
+	f1, err := os.Create(path)
+	require.NoError(t, err)
+	defer f1.Close()
+	f2, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	defer f2.Close()
+
+	MultiOutput(nil, JsonOutput(f1, ioutil.Discard), TextOutput(f2, &bytes.Buffer{}))
+	assert.Contains(t, errs.String(), "writes errors to the same destination ("+path+")")
+}
+
+func TestMultiOutputFlushAndClose(t *testing.T) {
+	buffered := newBuffer()
+	closed := 0
+	out := MultiOutput(nil,
+		SamplingOutput(BufferedOutput(buffered, TextOutput, 1<<20, time.Hour)),
+		&closeRecordingOutput{Output: TextOutput(ioutil.Discard, ioutil.Discard), onClose: func() { closed++ }},
+	)
+	out.Debug("myprefix: ", 0, false, "DEBUG", "hello", nil)
+	assert.Empty(t, buffered.String(), "the event should still be buffered")
+
+	require.NoError(t, out.Flush())
+	assert.Contains(t, buffered.String(), "hello", "Flush should flush wrapped buffered outputs")
+	assert.Equal(t, uint64(1), out.Stats().Explicit)
+	assert.Equal(t, 0, closed)
+
+	out.Debug("myprefix: ", 0, false, "DEBUG", "goodbye", nil)
+	require.NoError(t, out.Close())
+	assert.Contains(t, buffered.String(), "goodbye", "Close should flush buffered outputs")
+	assert.Equal(t, 1, closed)
+}
@@ -0,0 +1,73 @@
+package golog
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Publisher publishes a message to a subject on a message bus. *nats.Conn from
+// github.com/nats-io/nats.go satisfies this interface.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// NATSOutput creates an output that publishes JSON structured events to a NATS
+// subject. The subject may reference the placeholders {component} and
+// {severity}, for example "logs.{component}.{severity}", which lets
+// subscribers use NATS wildcards to pick the streams they care about. Dots,
+// wildcards and whitespace in the values are replaced with underscores, so
+// that each placeholder expands to a single token.
+func NATSOutput(publisher Publisher, subject string) Output {
+	return &natsOutput{
+		publisher: publisher,
+		subject:   subject,
+	}
+}
+
+type natsOutput struct {
+	publisher Publisher
+	subject   string
+}
+
+func (o *natsOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.print(prefix, skipFrames, printStack, severity, arg, values)
+}
+
+func (o *natsOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.print(prefix, skipFrames, printStack, severity, arg, values)
+}
+
+func (o *natsOutput) print(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	event := buildEvent(make([]uintptr, 10), prefix, skipFrames, printStack, severity, arg, values)
+	defer event.Release()
+	data, err := json.Marshal(event)
+	if err != nil {
+		errorOnLogging(err)
+		return
+	}
+	if err := o.publisher.Publish(expandEventTemplate(o.subject, event, subjectToken), data); err != nil {
+		errorOnLogging(err)
+	}
+}
+
+// expandEventTemplate replaces the {component} and {severity} placeholders in
+// template with the corresponding values from event, after passing them
+// through token to keep them safe for the destination's naming rules.
+func expandEventTemplate(template string, event *Event, token func(string) string) string {
+	return strings.NewReplacer(
+		"{component}", token(event.Component),
+		"{severity}", token(event.Severity),
+	).Replace(template)
+}
+
+// subjectToken makes sure value can't introduce wildcards, whitespace or
+// additional tokens into a subject.
+func subjectToken(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, value)
+}
@@ -0,0 +1,44 @@
+package golog
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingPublisher struct {
+	mx       sync.Mutex
+	subjects []string
+	events   []Event
+}
+
+func (p *recordingPublisher) Publish(subject string, data []byte) error {
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.subjects = append(p.subjects, subject)
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestNATSOutput(t *testing.T) {
+	pub := &recordingPublisher{}
+	reset := SetOutput(NATSOutput(pub, "logs.{component}.{severity}"))
+	defer reset()
+
+	l := LoggerFor("my prefix.>")
+	l.Debug("Hello world")
+	l.Error("Oh no")
+
+	assert.Equal(t, []string{"logs.my_prefix__.DEBUG", "logs.my_prefix__.ERROR"}, pub.subjects)
+	if assert.Len(t, pub.events, 2) {
+		assert.Equal(t, "Hello world", pub.events[0].Message)
+		assert.Equal(t, "my prefix.>", pub.events[1].Component)
+		assert.Equal(t, "nats_output_test.go:999", normalized(pub.events[1].Caller))
+	}
+}
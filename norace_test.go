@@ -0,0 +1,7 @@
+//go:build !race
+// +build !race
+
+package golog
+
+// raceEnabled is whether the race detector is on, which adds allocations.
+const raceEnabled = false
@@ -0,0 +1,203 @@
+package golog
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultNotificationBufferSize     = 16
+	defaultNotificationCoalesceWindow = 10 * time.Second
+
+	// UserMessageKey is the context key for a message meant for end users.
+	// When present, notifications use it instead of the log message.
+	UserMessageKey = "user_message"
+)
+
+// Notification is an event forwarded to a UI, see NotificationOutput.
+type Notification struct {
+	Severity  string
+	Component string
+	// Message is the user-facing message.
+	Message string
+	// Count is the number of identical events this notification stands for.
+	Count int
+	Time  time.Time
+}
+
+// NotificationOptions configures a notification output.
+type NotificationOptions struct {
+	// MinSeverity is the lowest severity forwarded, case insensitively.
+	// Defaults to "WARN".
+	MinSeverity string
+
+	// BufferSize is the capacity of the notification channel. Notifications
+	// that don't fit are dropped rather than holding up logging. Defaults to
+	// 16.
+	BufferSize int
+
+	// CoalesceWindow is the period within which identical notifications
+	// (same component and message) after the first are suppressed. When the
+	// window ends, a single notification carries the count of the suppressed
+	// ones and starts another window, so a continuous stream of identical
+	// events produces one notification per window. Defaults to 10 seconds.
+	CoalesceWindow time.Duration
+
+	// UserMessage, if set, extracts the user-facing message from an event, or
+	// returns "" to skip the event. By default, the value of the
+	// UserMessageKey field is used, or else the first line of the message.
+	UserMessage func(severity string, message string, values map[string]interface{}) string
+}
+
+// NotificationOutput creates an output that passes all events through to out
+// and also forwards WARN and more severe events on the returned channel, for
+// consumption by a UI that wants to surface notices like "connection
+// degraded" that originate in logs. Closing the output closes the channel. It
+// returns an error if opts.MinSeverity is unknown.
+func NotificationOutput(out Output, opts *NotificationOptions) (ClosableOutput, <-chan *Notification, error) {
+	o := &notificationOutput{
+		out:      out,
+		opts:     *opts,
+		coalesce: make(map[notificationKey]*coalescedNotification),
+	}
+	if o.opts.MinSeverity == "" {
+		o.opts.MinSeverity = "WARN"
+	}
+	var err error
+	if o.minSeverity, err = parseLevel(o.opts.MinSeverity); err != nil {
+		return nil, nil, err
+	}
+	if o.opts.BufferSize <= 0 {
+		o.opts.BufferSize = defaultNotificationBufferSize
+	}
+	if o.opts.CoalesceWindow <= 0 {
+		o.opts.CoalesceWindow = defaultNotificationCoalesceWindow
+	}
+	if o.opts.UserMessage == nil {
+		o.opts.UserMessage = defaultUserMessage
+	}
+	o.ch = make(chan *Notification, o.opts.BufferSize)
+	return o, o.ch, nil
+}
+
+type notificationOutput struct {
+	out  Output
+	opts NotificationOptions
+	// minSeverity is the level of opts.MinSeverity
+	minSeverity int
+	mx          sync.Mutex
+	ch          chan *Notification
+	closed      bool
+	coalesce    map[notificationKey]*coalescedNotification
+}
+
+type notificationKey struct {
+	component string
+	message   string
+}
+
+type coalescedNotification struct {
+	severity   string
+	suppressed int
+	timer      *time.Timer
+}
+
+func (o *notificationOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.notify(prefix, severity, arg, values)
+	o.out.Error(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+func (o *notificationOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.notify(prefix, severity, arg, values)
+	o.out.Debug(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+func (o *notificationOutput) notify(prefix string, severity string, arg interface{}, values map[string]interface{}) {
+	if severityLevel(severity) < o.minSeverity {
+		return
+	}
+	message := o.opts.UserMessage(severity, argToString(arg), values)
+	if message == "" {
+		return
+	}
+	key := notificationKey{strings.TrimSuffix(prefix, ": "), message}
+	now := time.Now()
+
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	if o.closed {
+		return
+	}
+	if c := o.coalesce[key]; c != nil {
+		c.severity = severity
+		c.suppressed++
+		return
+	}
+	if o.send(&Notification{Severity: severity, Component: key.component, Message: message, Count: 1, Time: now}) {
+		c := &coalescedNotification{}
+		c.timer = time.AfterFunc(o.opts.CoalesceWindow, func() {
+			o.endWindow(key, c)
+		})
+		o.coalesce[key] = c
+	}
+}
+
+// endWindow sends the count of the notifications suppressed during c's
+// window, if any, and starts another window, or otherwise forgets c so that
+// the map doesn't grow without bound.
+func (o *notificationOutput) endWindow(key notificationKey, c *coalescedNotification) {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	if o.closed || o.coalesce[key] != c {
+		return
+	}
+	if c.suppressed == 0 {
+		delete(o.coalesce, key)
+		return
+	}
+	if !o.send(&Notification{Severity: c.severity, Component: key.component, Message: key.message, Count: c.suppressed, Time: time.Now()}) {
+		delete(o.coalesce, key)
+		return
+	}
+	c.suppressed = 0
+	c.timer.Reset(o.opts.CoalesceWindow)
+}
+
+// send sends n unless the channel is full, returning whether it did. o.mx
+// must be held.
+func (o *notificationOutput) send(n *Notification) bool {
+	select {
+	case o.ch <- n:
+		return true
+	default:
+		// The UI isn't keeping up, drop the notification
+		return false
+	}
+}
+
+// Close closes the notification channel. Events are still passed through to
+// the wrapped output.
+func (o *notificationOutput) Close() error {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	if !o.closed {
+		o.closed = true
+		close(o.ch)
+		for _, c := range o.coalesce {
+			c.timer.Stop()
+		}
+		o.coalesce = nil
+	}
+	return nil
+}
+
+func defaultUserMessage(severity string, message string, values map[string]interface{}) string {
+	if userMessage, ok := values[UserMessageKey].(string); ok {
+		return userMessage
+	}
+	if i := strings.IndexByte(message, '\n'); i >= 0 {
+		message = message[:i]
+	}
+	return strings.TrimSpace(message)
+}
@@ -0,0 +1,62 @@
+package golog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	out, notifications, err := NotificationOutput(TextOutput(buf, buf), &NotificationOptions{
+		MinSeverity:    "warn",
+		BufferSize:     4,
+		CoalesceWindow: 50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	reset := SetOutput(out)
+	defer reset()
+
+	l := LoggerFor("proxy")
+	l.Debug("not severe enough")
+	NewEvent("WARN", "proxy", "connection degraded").Emit()
+	NewEvent("WARN", "proxy", "connection degraded").Emit()
+	NewEvent("WARN", "proxy", "connection degraded").Emit()
+	l.Error(WithFields("dial tcp 1.2.3.4:443: i/o timeout", Field{Key: UserMessageKey, Value: "Unable to reach the proxy"}))
+	// the first window ends with the suppressed count, the second one with
+	// nothing to report
+	time.Sleep(130 * time.Millisecond)
+	o := out.(*notificationOutput)
+	o.mx.Lock()
+	assert.Empty(t, o.coalesce, "expired windows should be forgotten")
+	o.mx.Unlock()
+	NewEvent("WARN", "proxy", "connection degraded").Emit()
+	l.Error("dropped since the channel is full")
+	require.NoError(t, out.Close())
+	l.Error("after close")
+
+	var received []*Notification
+	for n := range notifications {
+		received = append(received, n)
+	}
+	require.Len(t, received, 4)
+	assert.Equal(t, "connection degraded", received[0].Message)
+	assert.Equal(t, 1, received[0].Count)
+	assert.Equal(t, "WARN", received[0].Severity)
+	assert.Equal(t, "proxy", received[0].Component)
+	assert.Equal(t, "Unable to reach the proxy", received[1].Message)
+	assert.Equal(t, "ERROR", received[1].Severity)
+	assert.Equal(t, "connection degraded", received[2].Message)
+	assert.Equal(t, 2, received[2].Count, "should carry the count of coalesced notifications")
+	assert.Equal(t, "connection degraded", received[3].Message)
+	assert.Equal(t, 1, received[3].Count, "should start over once a window passes without duplicates")
+	assert.Contains(t, buf.String(), "after close")
+}
+
+func TestNotificationOutputUnknownSeverity(t *testing.T) {
+	_, _, err := NotificationOutput(TextOutput(&bytes.Buffer{}, &bytes.Buffer{}), &NotificationOptions{MinSeverity: "warning"})
+	assert.EqualError(t, err, `unknown level "warning"`)
+}
@@ -0,0 +1,56 @@
+package golog
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Milliseconds is a duration that's logged as a number of milliseconds with
+// exactly three decimals, both in text and in JSON, so that durations look
+// the same in every output.
+type Milliseconds time.Duration
+
+func (ms Milliseconds) String() string {
+	return strconv.FormatFloat(float64(ms)/float64(time.Millisecond), 'f', 3, 64)
+}
+
+// MarshalJSON encodes ms as a JSON number. Unlike with Float, that's always
+// valid, since a time.Duration is always finite.
+func (ms Milliseconds) MarshalJSON() ([]byte, error) {
+	return []byte(ms.String()), nil
+}
+
+// Float is a float64 that's always logged in plain decimal notation, never in
+// exponent form, both in text and in JSON.
+type Float float64
+
+func (f Float) String() string {
+	return strconv.FormatFloat(float64(f), 'f', -1, 64)
+}
+
+// MarshalJSON encodes f as a JSON number, or as the string "NaN", "+Inf" or
+// "-Inf" if it isn't finite, since JSON has no numbers for those.
+func (f Float) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+		return []byte(strconv.Quote(f.String())), nil
+	}
+	return []byte(f.String()), nil
+}
+
+// DurationMS returns a field holding d in milliseconds, see Milliseconds. By
+// convention, key should end in "_ms".
+func DurationMS(key string, d time.Duration) Field {
+	return Field{key, Milliseconds(d)}
+}
+
+// Bytes returns a field holding a number of bytes as a plain integer. By
+// convention, key should end in "_bytes".
+func Bytes(key string, n int64) Field {
+	return Field{key, n}
+}
+
+// FloatField returns a field holding f, see Float.
+func FloatField(key string, f float64) Field {
+	return Field{key, Float(f)}
+}
@@ -0,0 +1,37 @@
+package golog
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumberFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	l := LoggerFor("myprefix")
+	l.Debugw("done", DurationMS("elapsed_ms", 1234567*time.Microsecond), Bytes("sent_bytes", 2048), FloatField("ratio", 1e6))
+	assert.Contains(t, buf.String(), "[elapsed_ms=1234.567 ratio=1000000 sent_bytes=2048]")
+
+	buf.Reset()
+	reset = SetOutput(JsonOutput(buf, buf))
+	defer reset()
+	l.Debugw("done", DurationMS("elapsed_ms", 1500*time.Microsecond), FloatField("ratio", 0.5))
+	assert.Contains(t, buf.String(), `"elapsed_ms":1.500`)
+	assert.Contains(t, buf.String(), `"ratio":0.5`)
+
+	buf.Reset()
+	l.Debugw("done", FloatField("nan", math.NaN()), FloatField("inf", math.Inf(1)), FloatField("neg_inf", math.Inf(-1)))
+	events, err := ReadJSONEvents(buf)
+	require.NoError(t, err, "non-finite floats should still make valid JSON")
+	require.Len(t, events, 1)
+	assert.Equal(t, "NaN", events[0].Context["nan"])
+	assert.Equal(t, "+Inf", events[0].Context["inf"])
+	assert.Equal(t, "-Inf", events[0].Context["neg_inf"])
+}
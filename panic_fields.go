@@ -0,0 +1,75 @@
+package golog
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// maxPanicFrames bounds the number of stack frames included in panic fields.
+const maxPanicFrames = 20
+
+// PanicFields returns fields describing a recovered panic value:
+// panic_type with its Go type, panic_value with its text (the message for
+// errors, the string itself for strings and the %+v rendering, which includes
+// field names, for anything else) and panic_stack with the frames leading up
+// to the panic, skipping the runtime's own frames. Call it while handling the
+// recovered panic, otherwise the stack won't show where the panic happened.
+func PanicFields(value interface{}) []Field {
+	return []Field{
+		{"panic_type", fmt.Sprintf("%T", value)},
+		{"panic_value", panicValue(value)},
+		{"panic_stack", panicStack()},
+	}
+}
+
+func panicValue(value interface{}) string {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprintf("%+v", v)
+	}
+}
+
+// panicStack returns the stack above the innermost call to panic as
+// "function (file:line)" entries, leaving out the runtime's frames.
+func panicStack() []string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(1, pcs)
+	var stack []string
+	frames := runtime.CallersFrames(pcs[:n])
+	for more := n > 0; more; {
+		var frame runtime.Frame
+		frame, more = frames.Next()
+		switch {
+		case frame.Function == "runtime.gopanic":
+			// everything so far was recovering from the panic
+			stack = stack[:0]
+		case strings.HasPrefix(frame.Function, "runtime."):
+		default:
+			stack = append(stack, fmt.Sprintf("%s (%s:%d)", frame.Function, filepath.Base(frame.File), frame.Line))
+		}
+	}
+	if len(stack) > maxPanicFrames {
+		stack = stack[:maxPanicFrames]
+	}
+	return stack
+}
+
+// RecoverAndLog recovers from a panic, if there is one, and logs it as an
+// ERROR with l, including the fields from PanicFields. It must be deferred
+// directly, as in defer golog.RecoverAndLog(log). The panic doesn't
+// propagate any further.
+func RecoverAndLog(l Logger) {
+	value := recover()
+	if value == nil {
+		return
+	}
+	_ = l.Error(WithFields(fmt.Sprintf("recovered from panic: %v", panicValue(value)), PanicFields(value)...))
+}
@@ -0,0 +1,57 @@
+package golog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type panicStruct struct {
+	Reason string
+}
+
+func panicker(value interface{}) {
+	panic(value)
+}
+
+func TestPanicFields(t *testing.T) {
+	for _, test := range []struct {
+		value         interface{}
+		expectedType  string
+		expectedValue string
+	}{
+		{errors.New("broken"), "*errors.errorString", "broken"},
+		{"oh no", "string", "oh no"},
+		{panicStruct{"bad input"}, "golog.panicStruct", "{Reason:bad input}"},
+	} {
+		var fields []Field
+		func() {
+			defer func() {
+				fields = PanicFields(recover())
+			}()
+			panicker(test.value)
+		}()
+		require.Len(t, fields, 3)
+		assert.Equal(t, test.expectedType, fields[0].Value)
+		assert.Equal(t, test.expectedValue, fields[1].Value)
+		stack := fields[2].Value.([]string)
+		require.NotEmpty(t, stack)
+		assert.Regexp(t, `^github.com/getlantern/golog.panicker \(panic_fields_test.go:[0-9]+\)$`, stack[0])
+	}
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	func() {
+		defer RecoverAndLog(LoggerFor("myprefix"))
+		panicker("oh no")
+	}()
+	assert.Regexp(t, `ERROR myprefix: .* recovered from panic: oh no \[.*panic_stack=\[github.com/getlantern/golog.panicker \(panic_fields_test.go:[0-9]+\)`, buf.String())
+	assert.Contains(t, buf.String(), "panic_type=string panic_value=oh no")
+}
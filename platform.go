@@ -0,0 +1,18 @@
+//go:build !(js && wasm)
+// +build !js !wasm
+
+package golog
+
+import (
+	"io"
+	"os"
+)
+
+var (
+	stderr io.Writer = os.Stderr
+	stdout io.Writer = os.Stdout
+)
+
+func exitOnFatal(err error) {
+	os.Exit(1)
+}
@@ -0,0 +1,54 @@
+//go:build js && wasm
+// +build js,wasm
+
+package golog
+
+import (
+	"io"
+	"strings"
+	"sync/atomic"
+	"syscall/js"
+)
+
+// Under js/wasm there are no usable stderr and stdout, so golog writes each
+// line to a console function instead, which by default logs to the
+// JavaScript console.
+var (
+	stderr io.Writer = &consoleWriter{isError: true}
+	stdout io.Writer = &consoleWriter{isError: false}
+
+	console atomic.Value
+)
+
+func init() {
+	SetConsole(func(isError bool, line string) {
+		method := "log"
+		if isError {
+			method = "error"
+		}
+		js.Global().Get("console").Call(method, line)
+	})
+}
+
+// SetConsole sets the function that receives the lines that golog would
+// otherwise write to stderr (isError true) and stdout (isError false). Lines
+// don't include the trailing newline. Only available under js/wasm.
+func SetConsole(fn func(isError bool, line string)) {
+	console.Store(fn)
+}
+
+type consoleWriter struct {
+	isError bool
+}
+
+func (w *consoleWriter) Write(p []byte) (int, error) {
+	if fn, ok := console.Load().(func(bool, string)); ok {
+		fn(w.isError, strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+// exitOnFatal doesn't exit under js/wasm, where exiting kills the whole Go
+// program for the page, so FATAL errors only invoke the OnFatal hook.
+func exitOnFatal(err error) {
+}
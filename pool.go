@@ -2,28 +2,158 @@ package golog
 
 import (
 	"bytes"
-
-	"github.com/oxtoacart/bpool"
+	"sync/atomic"
 )
 
 const (
-	// Together, these bound the buffer pool to 307 KB
-	bufferPoolSize = 200
-	maxBufferSize  = 1024
+	// Together, these bound the buffer pool to about 1.3 MB
+	smallBufferSize  = 256
+	mediumBufferSize = 4 * 1024
+	largeBufferSize  = 64 * 1024
+	smallPoolSize    = 200
+	mediumPoolSize   = 50
+	largePoolSize    = 16
+
+	// maxBufferSize is the largest capacity of buffers that are reused
+	maxBufferSize = largeBufferSize
+
+	// sizeSmoothing is the weight of each new observation in the moving
+	// average of line sizes, as a power of 2 (1/16)
+	sizeSmoothing = 4
 )
 
 var (
-	_bufferPool = bpool.NewBufferPool(bufferPoolSize)
+	_bufferPool = newBufferPool()
 )
 
+// BufferPoolStats describes the usage of the buffer pool used for formatting
+// log lines.
+type BufferPoolStats struct {
+	// Gets counts the buffers handed out and Allocations how many of those had
+	// to be newly allocated.
+	Gets        uint64
+	Allocations uint64
+	// Discarded counts buffers that grew too big to be put back in the pool.
+	Discarded uint64
+	// AverageSize is the moving average of the sizes of formatted lines.
+	AverageSize int
+	// Small, Medium and Large are the numbers of idle buffers in each tier.
+	Small  int
+	Medium int
+	Large  int
+}
+
+// adaptivePool pools buffers in three tiers by capacity and hands out buffers
+// from the tier that fits the sizes of recently formatted lines, so that short
+// debug lines don't hold on to big buffers and big stack traces don't have to
+// grow small ones.
+type adaptivePool struct {
+	// stats are first to keep them 64-bit aligned for atomic access
+	gets        uint64
+	allocations uint64
+	discarded   uint64
+	averageSize int64
+	tiers       []*bufferTier
+}
+
+type bufferTier struct {
+	capacity int
+	buffers  chan *bytes.Buffer
+}
+
+func newBufferPool() *adaptivePool {
+	return &adaptivePool{
+		averageSize: smallBufferSize,
+		tiers: []*bufferTier{
+			{smallBufferSize, make(chan *bytes.Buffer, smallPoolSize)},
+			{mediumBufferSize, make(chan *bytes.Buffer, mediumPoolSize)},
+			{largeBufferSize, make(chan *bytes.Buffer, largePoolSize)},
+		},
+	}
+}
+
+// tierFor returns the smallest tier that fits lines of the given size, or nil
+// if none does.
+func (p *adaptivePool) tierFor(size int) *bufferTier {
+	for _, tier := range p.tiers {
+		if size <= tier.capacity {
+			return tier
+		}
+	}
+	return nil
+}
+
+func (p *adaptivePool) Get() *bytes.Buffer {
+	atomic.AddUint64(&p.gets, 1)
+	tier := p.tierFor(int(atomic.LoadInt64(&p.averageSize)))
+	if tier == nil {
+		tier = p.tiers[len(p.tiers)-1]
+	}
+	select {
+	case buf := <-tier.buffers:
+		return buf
+	default:
+		atomic.AddUint64(&p.allocations, 1)
+		return bytes.NewBuffer(make([]byte, 0, tier.capacity))
+	}
+}
+
+func (p *adaptivePool) Put(buf *bytes.Buffer) bool {
+	p.observe(buf.Len())
+	if buf.Cap() > maxBufferSize {
+		atomic.AddUint64(&p.discarded, 1)
+		return false
+	}
+	// Put the buffer in the biggest tier whose capacity it has, so that every
+	// buffer in a tier fits lines of that tier's size
+	tier := p.tiers[0]
+	for _, t := range p.tiers {
+		if buf.Cap() >= t.capacity {
+			tier = t
+		}
+	}
+	buf.Reset()
+	select {
+	case tier.buffers <- buf:
+	default:
+		// tier is full, let the buffer be garbage collected
+	}
+	return true
+}
+
+// observe updates the moving average of line sizes.
+func (p *adaptivePool) observe(size int) {
+	for {
+		avg := atomic.LoadInt64(&p.averageSize)
+		updated := avg + (int64(size)-avg)>>sizeSmoothing
+		if atomic.CompareAndSwapInt64(&p.averageSize, avg, updated) {
+			return
+		}
+	}
+}
+
+func (p *adaptivePool) stats() BufferPoolStats {
+	return BufferPoolStats{
+		Gets:        atomic.LoadUint64(&p.gets),
+		Allocations: atomic.LoadUint64(&p.allocations),
+		Discarded:   atomic.LoadUint64(&p.discarded),
+		AverageSize: int(atomic.LoadInt64(&p.averageSize)),
+		Small:       len(p.tiers[0].buffers),
+		Medium:      len(p.tiers[1].buffers),
+		Large:       len(p.tiers[2].buffers),
+	}
+}
+
+// GetBufferPoolStats returns statistics about the buffer pool used for
+// formatting log lines.
+func GetBufferPoolStats() BufferPoolStats {
+	return _bufferPool.stats()
+}
+
 func getBuffer() *bytes.Buffer {
 	return _bufferPool.Get()
 }
 
 func returnBuffer(buf *bytes.Buffer) bool {
-	if buf.Cap() <= maxBufferSize {
-		_bufferPool.Put(buf)
-		return true
-	}
-	return false
+	return _bufferPool.Put(buf)
 }
@@ -3,15 +3,18 @@ package golog
 import (
 	"testing"
 
-	"github.com/oxtoacart/bpool"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestPool(t *testing.T) {
-	_bufferPool = bpool.NewBufferPool(bufferPoolSize)
+	_bufferPool = newBufferPool()
+	// buf is written to after being returned, don't leave it in the pool
+	defer func() {
+		_bufferPool = newBufferPool()
+	}()
 	buf := _bufferPool.Get()
 	require.NotNil(t, buf)
-	// Write 768 bytes (the max before the buffer's capacity exceeds )
 	for i := 0; i < maxBufferSize; i++ {
 		buf.WriteByte('C')
 	}
@@ -20,4 +23,32 @@ func TestPool(t *testing.T) {
 		buf.WriteByte('C')
 	}
 	require.False(t, returnBuffer(buf))
+	assert.EqualValues(t, 1, GetBufferPoolStats().Discarded)
+}
+
+func TestPoolAdapts(t *testing.T) {
+	_bufferPool = newBufferPool()
+	defer func() {
+		_bufferPool = newBufferPool()
+	}()
+
+	buf := getBuffer()
+	assert.Equal(t, smallBufferSize, buf.Cap(), "should start out with small buffers")
+	returnBuffer(buf)
+
+	// Log a bunch of big lines
+	for i := 0; i < 100; i++ {
+		buf := getBuffer()
+		buf.Write(make([]byte, 2000))
+		returnBuffer(buf)
+	}
+	buf = getBuffer()
+	assert.Equal(t, mediumBufferSize, buf.Cap(), "should have switched to medium buffers")
+	returnBuffer(buf)
+
+	stats := GetBufferPoolStats()
+	assert.EqualValues(t, 102, stats.Gets)
+	assert.True(t, stats.Allocations < stats.Gets)
+	assert.True(t, stats.AverageSize > smallBufferSize)
+	assert.True(t, stats.Medium > 0)
 }
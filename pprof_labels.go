@@ -0,0 +1,46 @@
+package golog
+
+import (
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"sort"
+
+	"github.com/getlantern/ops"
+)
+
+// PprofLabels returns the pprof labels carried by ctx as Fields, so that log
+// lines can be matched up with the CPU profile samples they correspond to:
+//
+//	log.Debugw("handshake complete", golog.PprofLabels(ctx)...)
+func PprofLabels(ctx context.Context) []Field {
+	var fields []Field
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		fields = append(fields, Field{key, value})
+		return true
+	})
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Key < fields[j].Key
+	})
+	return fields
+}
+
+// DoWithOpsLabels is like pprof.Do, but labels fn with the values from the
+// current goroutine's ops context (the same values that are attached to log
+// lines), so that CPU profiles can be broken down by op and correlated with
+// logs. Global ops values are not included, and keys are normalized like
+// those of log lines (see SetKeyNormalization). Goroutines started by fn
+// inherit the labels.
+func DoWithOpsLabels(ctx context.Context, fn func(context.Context)) {
+	values := normalizeKeys(ops.AsMap(nil, false))
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	labels := make([]string, 0, len(values)*2)
+	for _, key := range keys {
+		labels = append(labels, key, fmt.Sprint(values[key]))
+	}
+	pprof.Do(ctx, pprof.Labels(labels...), fn)
+}
@@ -0,0 +1,16 @@
+package golog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/getlantern/ops"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPprofLabels(t *testing.T) {
+	defer ops.Begin("name").Set("cvarA", "a").End()
+	DoWithOpsLabels(context.Background(), func(ctx context.Context) {
+		assert.Equal(t, []Field{{"cvarA", "a"}, {"op", "name"}, {"root_op", "name"}}, PprofLabels(ctx))
+	})
+}
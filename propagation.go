@@ -0,0 +1,142 @@
+package golog
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/getlantern/ops"
+)
+
+const (
+	// ContextEnvVar is the environment variable carrying the ops context to
+	// child processes, see ContextEnv and BeginFromEnv.
+	ContextEnvVar = "GOLOG_CONTEXT"
+
+	// ContextHeader is the HTTP header carrying the ops context to other
+	// services, see InjectContextHeader and BeginFromHeader.
+	ContextHeader = "Golog-Context"
+
+	// maxEncodedContext bounds the size of an encoded context, so that it
+	// fits in headers and environments. Keys beyond it are left out.
+	maxEncodedContext = 4096
+)
+
+// EncodeContext encodes the ops context of the current goroutine, excluding
+// globals, as comma separated key=value pairs with the keys and values
+// percent-encoded, like W3C baggage. Values are formatted with fmt.Sprint.
+// The "op" key is encoded as "parent_op", so that the op in which a child
+// process or service was started is kept alongside its own ops. If keys are
+// given, only those keys are encoded. Keys are normalized like those of log
+// lines (see SetKeyNormalization).
+func EncodeContext(keys ...string) string {
+	values := ops.AsMap(nil, false)
+	if len(keys) > 0 {
+		selected := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			if value, found := values[key]; found {
+				selected[key] = value
+			}
+		}
+		values = selected
+	}
+	values = normalizeKeys(values)
+	if op, found := values["op"]; found {
+		delete(values, "op")
+		values["parent_op"] = op
+	}
+
+	sorted := make([]string, 0, len(values))
+	for key := range values {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	var b strings.Builder
+	for _, key := range sorted {
+		pair := url.QueryEscape(key) + "=" + url.QueryEscape(fmt.Sprint(values[key]))
+		if b.Len()+1+len(pair) > maxEncodedContext {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pair)
+	}
+	return b.String()
+}
+
+// DecodeContext decodes a context encoded with EncodeContext.
+func DecodeContext(encoded string) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, pair := range strings.Split(encoded, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid context pair %q", pair)
+		}
+		key, err := url.QueryUnescape(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid context key %q: %v", parts[0], err)
+		}
+		value, err := url.QueryUnescape(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid context value for %q: %v", key, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// ContextEnv returns the current ops context as an environment entry for a
+// child process, for example:
+//
+//	cmd := exec.Command("helper")
+//	cmd.Env = append(os.Environ(), golog.ContextEnv())
+func ContextEnv(keys ...string) string {
+	return ContextEnvVar + "=" + EncodeContext(keys...)
+}
+
+// InjectContextHeader sets the ContextHeader of h to the current ops context.
+func InjectContextHeader(h http.Header, keys ...string) {
+	if encoded := EncodeContext(keys...); encoded != "" {
+		h.Set(ContextHeader, encoded)
+	}
+}
+
+// BeginFromEnv begins an op with the given name carrying the context passed
+// from the parent process in ContextEnvVar, so that the child's events can be
+// correlated with the parent's, for example via root_op. A missing or invalid
+// context just begins an op.
+func BeginFromEnv(name string) ops.Op {
+	return beginWithContext(name, os.Getenv(ContextEnvVar))
+}
+
+// BeginFromHeader begins an op with the given name carrying the context from
+// the ContextHeader of h, like BeginFromEnv.
+func BeginFromHeader(name string, h http.Header) ops.Op {
+	return beginWithContext(name, h.Get(ContextHeader))
+}
+
+func beginWithContext(name string, encoded string) ops.Op {
+	op := ops.Begin(name)
+	if encoded == "" {
+		return op
+	}
+	values, err := DecodeContext(encoded)
+	if err != nil {
+		errorOnLogging(fmt.Errorf("ignoring propagated context: %v", err))
+		return op
+	}
+	for key, value := range values {
+		if key != "op" {
+			op.Set(key, value)
+		}
+	}
+	return op
+}
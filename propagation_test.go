@@ -0,0 +1,67 @@
+package golog
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/getlantern/ops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextPropagation(t *testing.T) {
+	op := ops.Begin("connect").Set("client_id", "abc 123").Set("attempt", 2)
+	defer op.End()
+
+	encoded := EncodeContext()
+	assert.Equal(t, "attempt=2,client_id=abc+123,parent_op=connect,root_op=connect", encoded)
+	assert.Equal(t, "client_id=abc+123", EncodeContext("client_id", "missing"))
+
+	env := ContextEnv()
+	require.True(t, strings.HasPrefix(env, ContextEnvVar+"="))
+	defer os.Setenv(ContextEnvVar, os.Getenv(ContextEnvVar))
+	os.Setenv(ContextEnvVar, strings.TrimPrefix(env, ContextEnvVar+"="))
+
+	// as if in the child process, on a goroutine that doesn't inherit the
+	// context
+	values := inFreshGoroutine(func() map[string]interface{} {
+		child := BeginFromEnv("helper")
+		defer child.End()
+		return ops.AsMap(nil, false)
+	})
+	assert.Equal(t, "helper", values["op"])
+	assert.Equal(t, "connect", values["parent_op"])
+	assert.Equal(t, "connect", values["root_op"])
+	assert.Equal(t, "abc 123", values["client_id"])
+	assert.Equal(t, "2", values["attempt"])
+
+	h := http.Header{}
+	InjectContextHeader(h, "client_id")
+	assert.Equal(t, "client_id=abc+123", h.Get(ContextHeader))
+	values = inFreshGoroutine(func() map[string]interface{} {
+		handler := BeginFromHeader("serve", h)
+		defer handler.End()
+		return ops.AsMap(nil, false)
+	})
+	assert.Equal(t, "abc 123", values["client_id"])
+	assert.Equal(t, "serve", values["op"])
+}
+
+func inFreshGoroutine(fn func() map[string]interface{}) map[string]interface{} {
+	result := make(chan map[string]interface{})
+	go func() { result <- fn() }()
+	return <-result
+}
+
+func TestDecodeContext(t *testing.T) {
+	values, err := DecodeContext("a=1, b=x%2Cy,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "x,y"}, values)
+
+	_, err = DecodeContext("novalue")
+	assert.Error(t, err)
+	_, err = DecodeContext("a=%zz")
+	assert.Error(t, err)
+}
@@ -0,0 +1,211 @@
+package golog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// gzipMagic starts every gzipped file.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// PurgeMode is what PurgeFile does with log lines that contain the identifier
+// being purged.
+type PurgeMode string
+
+const (
+	// PurgeRedact replaces the identifier within matching lines.
+	PurgeRedact PurgeMode = "redact"
+
+	// PurgeDelete removes matching lines entirely.
+	PurgeDelete PurgeMode = "delete"
+)
+
+// PurgeOptions configures PurgeFile.
+type PurgeOptions struct {
+	// Mode is what to do with matching lines. Defaults to PurgeRedact.
+	Mode PurgeMode
+
+	// Replacement replaces the identifier when redacting. Defaults to
+	// "[purged]".
+	Replacement string
+
+	// Audit, if set, receives a JSON audit record for each purged file. The
+	// record identifies the purged identifier only by its SHA-256 hash.
+	Audit io.Writer
+}
+
+// PurgeRecord records the purge of an identifier from a log file.
+type PurgeRecord struct {
+	Time             time.Time `json:"ts"`
+	File             string    `json:"file"`
+	IdentifierSHA256 string    `json:"identifier_sha256"`
+	Mode             PurgeMode `json:"mode"`
+	Lines            int       `json:"lines"`
+	MatchedLines     int       `json:"matched_lines"`
+}
+
+// PurgeFile scrubs an identifier, such as a user ID or email address, from a
+// log file written by the text or JSON outputs, so that data subject deletion
+// requests can be honored without destroying the whole log. Depending on the
+// mode, matching lines are redacted or deleted. The file is rewritten to a
+// temporary file next to it which then replaces it, so it's never left half
+// purged. Gzipped files, like backups compressed by FileOutput, are
+// decompressed and rewritten compressed. Encrypted files can't be purged. The
+// identifier is matched as it appears in the file, so identifiers that JSON
+// escapes (like ones containing quotes) need to be given escaped to purge them
+// from JSON logs.
+//
+// A file that's still being logged to must be purged with LogFile.Purge
+// instead, since the LogFile would otherwise keep appending to the file that
+// was replaced.
+func PurgeFile(path string, identifier string, opts *PurgeOptions) (*PurgeRecord, error) {
+	if identifier == "" {
+		return nil, errors.New("no identifier to purge")
+	}
+	resolved := PurgeOptions{}
+	if opts != nil {
+		resolved = *opts
+	}
+	if resolved.Mode == "" {
+		resolved.Mode = PurgeRedact
+	}
+	if resolved.Mode != PurgeRedact && resolved.Mode != PurgeDelete {
+		return nil, fmt.Errorf("unknown purge mode %q", resolved.Mode)
+	}
+	if resolved.Replacement == "" {
+		resolved.Replacement = "[purged]"
+	}
+	sum := sha256.Sum256([]byte(identifier))
+	record := &PurgeRecord{
+		File:             path,
+		IdentifierSHA256: hex.EncodeToString(sum[:]),
+		Mode:             resolved.Mode,
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(in)
+	prefix, _ := r.Peek(len(encryptedSegmentMagic))
+	if bytes.HasPrefix(prefix, encryptedSegmentMagic) {
+		return nil, fmt.Errorf("unable to purge encrypted log file %v", path)
+	}
+	compressed := bytes.HasPrefix(prefix, gzipMagic)
+	if compressed {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		r = bufio.NewReader(gz)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".purge")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	id, replacement := []byte(identifier), []byte(resolved.Replacement)
+	var out io.Writer = tmp
+	var gz *gzip.Writer
+	if compressed {
+		gz = gzip.NewWriter(tmp)
+		out = gz
+	}
+	w := bufio.NewWriter(out)
+	for {
+		line, readErr := r.ReadBytes('\n')
+		if len(line) > 0 {
+			record.Lines++
+			if bytes.Contains(line, id) {
+				record.MatchedLines++
+				if resolved.Mode == PurgeDelete {
+					line = nil
+				} else {
+					line = bytes.Replace(line, id, replacement, -1)
+				}
+			}
+			if _, err := w.Write(line); err != nil {
+				tmp.Close()
+				return nil, err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			tmp.Close()
+			return nil, readErr
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			tmp.Close()
+			return nil, err
+		}
+	}
+	if err := tmp.Chmod(info.Mode()); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	// Close the original before replacing it, which Windows requires
+	in.Close()
+	if record.MatchedLines > 0 {
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return nil, err
+		}
+	}
+
+	record.Time = time.Now()
+	if resolved.Audit != nil {
+		if err := json.NewEncoder(resolved.Audit).Encode(record); err != nil {
+			return record, fmt.Errorf("purged %v but unable to write audit record: %v", path, err)
+		}
+	}
+	return record, nil
+}
+
+// Purge scrubs an identifier from the file like PurgeFile, holding up writes
+// to it in the meantime and then continuing to log to the purged file.
+// Encrypted LogFiles can't be purged.
+func (f *LogFile) Purge(identifier string, opts *PurgeOptions) (*PurgeRecord, error) {
+	if f.key != nil {
+		return nil, fmt.Errorf("unable to purge encrypted log file %v", f.path)
+	}
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if f.file == nil {
+		return nil, os.ErrClosed
+	}
+	// Close before purging, since Windows doesn't allow replacing open files
+	f.file.Close()
+	record, err := PurgeFile(f.path, identifier, opts)
+	if openErr := f.open(); openErr != nil {
+		f.file, f.w = nil, nil
+		return record, fmt.Errorf("unable to reopen %v after purging: %v", f.path, openErr)
+	}
+	return record, err
+}
@@ -0,0 +1,117 @@
+package golog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-purge")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	contents := "DEBUG app: a.go:1 login [user=alice@example.com]\n" +
+		"DEBUG app: a.go:2 login [user=bob@example.com]\n" +
+		"ERROR app: a.go:3 failed for alice@example.com, alice@example.com"
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	audit := &bytes.Buffer{}
+	record, err := PurgeFile(path, "alice@example.com", &PurgeOptions{Audit: audit})
+	require.NoError(t, err)
+	assert.Equal(t, 3, record.Lines)
+	assert.Equal(t, 2, record.MatchedLines)
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "DEBUG app: a.go:1 login [user=[purged]]\n"+
+		"DEBUG app: a.go:2 login [user=bob@example.com]\n"+
+		"ERROR app: a.go:3 failed for [purged], [purged]", string(b))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	var audited PurgeRecord
+	require.NoError(t, json.Unmarshal(audit.Bytes(), &audited))
+	assert.Equal(t, PurgeRedact, audited.Mode)
+	assert.Equal(t, 2, audited.MatchedLines)
+	assert.Len(t, audited.IdentifierSHA256, 64)
+	assert.NotContains(t, audit.String(), "alice")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	record, err = PurgeFile(path, "bob@example.com", &PurgeOptions{Mode: PurgeDelete})
+	require.NoError(t, err)
+	assert.Equal(t, 1, record.MatchedLines)
+	b, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "bob")
+	assert.Contains(t, string(b), "a.go:3")
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1, "temporary file should have been cleaned up")
+}
+
+func TestPurgeCompressedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-purge")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app-2026-01-01T00-00-00.000.log.gz")
+	compressed := &bytes.Buffer{}
+	gz := gzip.NewWriter(compressed)
+	_, err = gz.Write([]byte("DEBUG app: a.go:1 login [user=alice@example.com]\nDEBUG app: a.go:2 other\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.NoError(t, ioutil.WriteFile(path, compressed.Bytes(), 0600))
+
+	record, err := PurgeFile(path, "alice@example.com", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, record.MatchedLines)
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	require.NoError(t, err, "purged file should still be gzipped")
+	b, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "DEBUG app: a.go:1 login [user=[purged]]\nDEBUG app: a.go:2 other\n", string(b))
+}
+
+func TestPurgeLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-purge")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	f, err := OpenLogFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.Write([]byte("login alice@example.com\n"))
+	require.NoError(t, err)
+	record, err := f.Purge("alice@example.com", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, record.MatchedLines)
+	_, err = f.Write([]byte("after purge\n"))
+	require.NoError(t, err)
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "login [purged]\nafter purge\n", string(b), "writes should continue to the purged file")
+
+	audit := &bytes.Buffer{}
+	encrypted, err := OpenEncryptedLogFile(filepath.Join(dir, "encrypted.log"), &EncryptionKey{ID: "k", Key: bytes.Repeat([]byte{1}, 32)})
+	require.NoError(t, err)
+	defer encrypted.Close()
+	_, err = encrypted.Write([]byte("login alice@example.com\n"))
+	require.NoError(t, err)
+	_, err = encrypted.Purge("alice@example.com", &PurgeOptions{Audit: audit})
+	assert.Error(t, err)
+	_, err = PurgeFile(encrypted.Path(), "alice@example.com", &PurgeOptions{Audit: audit})
+	assert.Error(t, err, "encrypted files can't be purged")
+	assert.Empty(t, audit.String(), "no purge should be audited")
+}
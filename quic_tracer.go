@@ -0,0 +1,96 @@
+package golog
+
+import (
+	"net"
+	"time"
+)
+
+// QUICConnectionTracer logs the events of a single QUIC connection to a
+// Logger, with the connection IDs attached as fields: connection level events
+// at DEBUG and packet level events at TRACE. golog doesn't depend on quic-go;
+// the github.com/getlantern/golog/quictrace module wires it into quic-go's
+// logging.ConnectionTracer. Its methods are safe to call from multiple
+// goroutines.
+type QUICConnectionTracer struct {
+	l      Logger
+	fields []Field
+	start  time.Time
+}
+
+// NewQUICConnectionTracer creates a tracer for the connection with the given
+// perspective ("client" or "server") and original destination connection ID.
+func NewQUICConnectionTracer(l Logger, perspective string, odcid string) *QUICConnectionTracer {
+	return &QUICConnectionTracer{
+		l:      l,
+		fields: []Field{{"quic_perspective", perspective}, {"quic_odcid", odcid}},
+		start:  time.Now(),
+	}
+}
+
+func (t *QUICConnectionTracer) with(fields ...Field) []Field {
+	return append(append(make([]Field, 0, len(t.fields)+len(fields)), t.fields...), fields...)
+}
+
+func (t *QUICConnectionTracer) trace(msg string, fields ...Field) {
+	if t.l.IsTraceEnabled() {
+		t.l.Trace(WithFields(msg, t.with(fields...)...))
+	}
+}
+
+// StartedConnection logs the start of the connection.
+func (t *QUICConnectionTracer) StartedConnection(local, remote net.Addr, srcConnID, destConnID string) {
+	t.l.Debugw("quic connection started", t.with(
+		Field{"local_addr", addrString(local)},
+		Field{"remote_addr", addrString(remote)},
+		Field{"quic_src_conn_id", srcConnID},
+		Field{"quic_dest_conn_id", destConnID},
+	)...)
+}
+
+// NegotiatedVersion logs the negotiated QUIC version.
+func (t *QUICConnectionTracer) NegotiatedVersion(version string) {
+	t.l.Debugw("quic version negotiated", t.with(Field{"quic_version", version})...)
+}
+
+// SentPacket logs a sent packet.
+func (t *QUICConnectionTracer) SentPacket(packetType string, packetNumber int64, size int, frames []string) {
+	t.trace("quic packet sent", Field{"packet_type", packetType}, Field{"packet_number", packetNumber}, Bytes("size_bytes", int64(size)), Field{"frames", frames})
+}
+
+// ReceivedPacket logs a received packet.
+func (t *QUICConnectionTracer) ReceivedPacket(packetType string, packetNumber int64, size int, frames []string) {
+	t.trace("quic packet received", Field{"packet_type", packetType}, Field{"packet_number", packetNumber}, Bytes("size_bytes", int64(size)), Field{"frames", frames})
+}
+
+// DroppedPacket logs a dropped packet.
+func (t *QUICConnectionTracer) DroppedPacket(packetType string, size int, reason string) {
+	t.trace("quic packet dropped", Field{"packet_type", packetType}, Bytes("size_bytes", int64(size)), Field{"reason", reason})
+}
+
+// LostPacket logs a packet that was declared lost.
+func (t *QUICConnectionTracer) LostPacket(packetType string, packetNumber int64, reason string) {
+	t.trace("quic packet lost", Field{"packet_type", packetType}, Field{"packet_number", packetNumber}, Field{"reason", reason})
+}
+
+// UpdatedMetrics logs the connection's congestion metrics.
+func (t *QUICConnectionTracer) UpdatedMetrics(smoothedRTT, minRTT time.Duration, congestionWindow, bytesInFlight int64) {
+	t.trace("quic metrics updated", DurationMS("smoothed_rtt_ms", smoothedRTT), DurationMS("min_rtt_ms", minRTT), Bytes("cwnd_bytes", congestionWindow), Bytes("in_flight_bytes", bytesInFlight))
+}
+
+// ClosedConnection logs the end of the connection, as an error if err isn't
+// nil.
+func (t *QUICConnectionTracer) ClosedConnection(err error) {
+	fields := t.with(DurationMS("duration_ms", time.Since(t.start)))
+	if err != nil {
+		_ = t.l.Error(WithFields(err, fields...))
+		return
+	}
+	t.l.Debugw("quic connection closed", fields...)
+}
+
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
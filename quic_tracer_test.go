@@ -0,0 +1,26 @@
+package golog
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQUICConnectionTracer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	tracer := NewQUICConnectionTracer(LoggerFor("quic"), "client", "abcd")
+	tracer.StartedConnection(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}, nil, "src", "dst")
+	tracer.SentPacket("1-RTT", 1, 1200, []string{"STREAM"})
+	tracer.ClosedConnection(errors.New("idle timeout"))
+
+	logged := buf.String()
+	assert.Regexp(t, `DEBUG quic: .* quic connection started \[local_addr=127.0.0.1:1 quic_dest_conn_id=dst quic_odcid=abcd quic_perspective=client quic_src_conn_id=src remote_addr=\]`, logged)
+	assert.NotContains(t, logged, "quic packet sent", "packets are only logged at TRACE")
+	assert.Regexp(t, `ERROR quic: .* idle timeout \[.*quic_odcid=abcd`, logged)
+}
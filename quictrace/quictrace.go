@@ -0,0 +1,127 @@
+// Package quictrace logs the events of quic-go connections with golog. It's a
+// separate module so that golog itself doesn't depend on quic-go.
+//
+// To trace all connections of a quic.Transport or quic.Config:
+//
+//	config := &quic.Config{Tracer: quictrace.NewTracer(golog.LoggerFor("quic"))}
+package quictrace
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/getlantern/golog"
+	"github.com/quic-go/quic-go/logging"
+)
+
+// NewTracer returns a function for quic.Config.Tracer that traces each
+// connection with ConnectionTracer.
+func NewTracer(l golog.Logger) func(ctx context.Context, p logging.Perspective, odcid logging.ConnectionID) *logging.ConnectionTracer {
+	return func(ctx context.Context, p logging.Perspective, odcid logging.ConnectionID) *logging.ConnectionTracer {
+		return ConnectionTracer(l, p, odcid)
+	}
+}
+
+// ConnectionTracer returns a logging.ConnectionTracer that logs the events of
+// the connection with the given perspective and original destination
+// connection ID to l, using golog.QUICConnectionTracer: connection level
+// events at DEBUG and packet level events at TRACE.
+func ConnectionTracer(l golog.Logger, p logging.Perspective, odcid logging.ConnectionID) *logging.ConnectionTracer {
+	t := golog.NewQUICConnectionTracer(l, p.String(), odcid.String())
+	return &logging.ConnectionTracer{
+		StartedConnection: func(local, remote net.Addr, srcConnID, destConnID logging.ConnectionID) {
+			t.StartedConnection(local, remote, srcConnID.String(), destConnID.String())
+		},
+		NegotiatedVersion: func(chosen logging.Version, clientVersions, serverVersions []logging.Version) {
+			t.NegotiatedVersion(chosen.String())
+		},
+		SentLongHeaderPacket: func(hdr *logging.ExtendedHeader, size logging.ByteCount, ecn logging.ECN, ack *logging.AckFrame, frames []logging.Frame) {
+			t.SentPacket(packetTypeName(logging.PacketTypeFromHeader(&hdr.Header)), int64(hdr.PacketNumber), int(size), frameNames(ack, frames))
+		},
+		SentShortHeaderPacket: func(hdr *logging.ShortHeader, size logging.ByteCount, ecn logging.ECN, ack *logging.AckFrame, frames []logging.Frame) {
+			t.SentPacket(packetTypeName(logging.PacketType1RTT), int64(hdr.PacketNumber), int(size), frameNames(ack, frames))
+		},
+		ReceivedLongHeaderPacket: func(hdr *logging.ExtendedHeader, size logging.ByteCount, ecn logging.ECN, frames []logging.Frame) {
+			t.ReceivedPacket(packetTypeName(logging.PacketTypeFromHeader(&hdr.Header)), int64(hdr.PacketNumber), int(size), frameNames(nil, frames))
+		},
+		ReceivedShortHeaderPacket: func(hdr *logging.ShortHeader, size logging.ByteCount, ecn logging.ECN, frames []logging.Frame) {
+			t.ReceivedPacket(packetTypeName(logging.PacketType1RTT), int64(hdr.PacketNumber), int(size), frameNames(nil, frames))
+		},
+		DroppedPacket: func(packetType logging.PacketType, pn logging.PacketNumber, size logging.ByteCount, reason logging.PacketDropReason) {
+			t.DroppedPacket(packetTypeName(packetType), int(size), dropReasonName(reason))
+		},
+		LostPacket: func(encLevel logging.EncryptionLevel, pn logging.PacketNumber, reason logging.PacketLossReason) {
+			t.LostPacket(encLevel.String(), int64(pn), lossReasonName(reason))
+		},
+		UpdatedMetrics: func(rttStats *logging.RTTStats, cwnd, bytesInFlight logging.ByteCount, packetsInFlight int) {
+			t.UpdatedMetrics(rttStats.SmoothedRTT(), rttStats.MinRTT(), int64(cwnd), int64(bytesInFlight))
+		},
+		ClosedConnection: t.ClosedConnection,
+	}
+}
+
+var packetTypeNames = map[logging.PacketType]string{
+	logging.PacketTypeInitial:            "initial",
+	logging.PacketTypeHandshake:          "handshake",
+	logging.PacketTypeRetry:              "retry",
+	logging.PacketType0RTT:               "0RTT",
+	logging.PacketTypeVersionNegotiation: "version_negotiation",
+	logging.PacketType1RTT:               "1RTT",
+	logging.PacketTypeStatelessReset:     "stateless_reset",
+}
+
+func packetTypeName(packetType logging.PacketType) string {
+	if name, found := packetTypeNames[packetType]; found {
+		return name
+	}
+	return "unknown"
+}
+
+var dropReasonNames = map[logging.PacketDropReason]string{
+	logging.PacketDropKeyUnavailable:               "key_unavailable",
+	logging.PacketDropUnknownConnectionID:          "unknown_connection_id",
+	logging.PacketDropHeaderParseError:             "header_parse_error",
+	logging.PacketDropPayloadDecryptError:          "payload_decrypt_error",
+	logging.PacketDropProtocolViolation:            "protocol_violation",
+	logging.PacketDropDOSPrevention:                "dos_prevention",
+	logging.PacketDropUnsupportedVersion:           "unsupported_version",
+	logging.PacketDropUnexpectedPacket:             "unexpected_packet",
+	logging.PacketDropUnexpectedSourceConnectionID: "unexpected_source_connection_id",
+	logging.PacketDropUnexpectedVersion:            "unexpected_version",
+	logging.PacketDropDuplicate:                    "duplicate",
+}
+
+func dropReasonName(reason logging.PacketDropReason) string {
+	if name, found := dropReasonNames[reason]; found {
+		return name
+	}
+	return "unknown"
+}
+
+func lossReasonName(reason logging.PacketLossReason) string {
+	switch reason {
+	case logging.PacketLossReorderingThreshold:
+		return "reordering_threshold"
+	case logging.PacketLossTimeThreshold:
+		return "time_threshold"
+	default:
+		return "unknown"
+	}
+}
+
+// frameNames returns the names of the frames in a packet, like "ack" or
+// "stream".
+func frameNames(ack *logging.AckFrame, frames []logging.Frame) []string {
+	names := make([]string, 0, len(frames)+1)
+	if ack != nil {
+		names = append(names, "ack")
+	}
+	for _, frame := range frames {
+		name := fmt.Sprintf("%T", frame)
+		name = name[strings.LastIndex(name, ".")+1:]
+		names = append(names, strings.ToLower(strings.TrimSuffix(name, "Frame")))
+	}
+	return names
+}
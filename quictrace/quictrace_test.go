@@ -0,0 +1,38 @@
+package quictrace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/getlantern/golog"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectionTracer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := golog.SetOutputs(buf, buf)
+	defer reset()
+	golog.EnableTrace("quic")
+	defer golog.DisableTrace()
+
+	tracer := NewTracer(golog.LoggerFor("quic"))(context.Background(), logging.PerspectiveClient, quic.ConnectionIDFromBytes([]byte{0xab, 0xcd}))
+	tracer.StartedConnection(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}, nil, quic.ConnectionIDFromBytes([]byte{1}), quic.ConnectionIDFromBytes([]byte{2}))
+	tracer.NegotiatedVersion(quic.Version1, nil, nil)
+	tracer.SentShortHeaderPacket(&logging.ShortHeader{PacketNumber: 7}, 1200, logging.ECNUnsupported, &logging.AckFrame{}, []logging.Frame{&logging.StreamFrame{}})
+	tracer.DroppedPacket(logging.PacketTypeInitial, 0, 100, logging.PacketDropDuplicate)
+	tracer.LostPacket(logging.Encryption1RTT, 3, logging.PacketLossTimeThreshold)
+	tracer.ClosedConnection(errors.New("idle timeout"))
+
+	logged := buf.String()
+	assert.Regexp(t, `DEBUG quic: .* quic connection started \[local_addr=127.0.0.1:1 quic_dest_conn_id=02 quic_odcid=abcd quic_perspective=client quic_src_conn_id=01 remote_addr=\]`, logged)
+	assert.Regexp(t, `DEBUG quic: .* quic version negotiated \[.*quic_version=v1\]`, logged)
+	assert.Regexp(t, `TRACE quic: .* quic packet sent \[frames=\[ack stream\] packet_number=7 packet_type=1RTT .*size_bytes=1200`, logged)
+	assert.Regexp(t, `TRACE quic: .* quic packet dropped \[packet_type=initial .*reason=duplicate`, logged)
+	assert.Regexp(t, `TRACE quic: .* quic packet lost \[packet_number=3 packet_type=1-RTT .*reason=time_threshold`, logged)
+	assert.Regexp(t, `ERROR quic: .* idle timeout \[.*quic_odcid=abcd`, logged)
+}
@@ -0,0 +1,100 @@
+package golog
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// envGeneration is incremented by ReloadEnv, so that loggers know to look at
+// the environment again.
+var envGeneration int64
+
+// loggerEnv is how the environment configures a logger.
+type loggerEnv struct {
+	generation int64
+	traceOn    bool
+	traceOut   io.Writer
+	printStack bool
+}
+
+// lazyEnv holds a logger's *loggerEnv, evaluated when it's first needed.
+type lazyEnv struct {
+	v  atomic.Value
+	mx sync.Mutex
+}
+
+// loggerEnv returns how the environment configures l, looking at it on first
+// use and after ReloadEnv.
+func (l *logger) loggerEnv() *loggerEnv {
+	generation := atomic.LoadInt64(&envGeneration)
+	if env, _ := l.env.v.Load().(*loggerEnv); env != nil && env.generation == generation {
+		return env
+	}
+
+	l.env.mx.Lock()
+	defer l.env.mx.Unlock()
+	previous, _ := l.env.v.Load().(*loggerEnv)
+	if previous != nil && previous.generation == generation {
+		return previous
+	}
+	prefix := l.prefix[:len(l.prefix)-2]
+	env := &loggerEnv{generation: generation, traceOn: shouldEnableTrace(prefix), traceOut: ioutil.Discard}
+	env.printStack, _ = strconv.ParseBool(os.Getenv("PRINT_STACK"))
+	switch {
+	case env.traceOn && previous != nil && previous.traceOn:
+		// keep using the existing trace writer
+		env.traceOut = previous.traceOut
+	case env.traceOn:
+		if !IsDisabled() {
+			fmt.Fprintf(redirectStdout(stdout), "TRACE logging is enabled for prefix [%s]\n", prefix)
+		}
+		// Trace through the logger without any bound fields
+		env.traceOut = (&logger{prefix: l.prefix, env: l.env}).newTraceWriter()
+	}
+	l.env.v.Store(env)
+	return env
+}
+
+// ReloadEnv looks at the environment variables that configure golog again,
+// for applications that set them in main, after loggers have been created and
+// golog has been initialized:
+//
+//   - TRACE and PRINT_STACK apply to all loggers from their next log call.
+//   - The level from GOLOG_CONFIG, if any, is applied with SetLevel.
+//   - GOLOG_SILENT, if set, disables or enables logging (see Disable).
+//   - The default outputs are recreated according to PRINT_JSON,
+//     GOLOG_SAMPLING and GOLOG_CONFIG, unless outputs were set explicitly
+//     since, with SetOutput or SetOutputs.
+//
+// Trace writers (see TraceOut) that were discarding their output keep
+// doing so.
+func ReloadEnv() {
+	getEnvConfig()
+	cfg := envConfigFromEnv()
+	envConfig.Store(cfg)
+	if cfg.Level != "" {
+		if err := SetLevel(cfg.Level); err != nil {
+			errorOnLogging(err)
+		}
+	}
+	if v, err := strconv.ParseBool(os.Getenv("GOLOG_SILENT")); err == nil {
+		if v {
+			Disable()
+		} else {
+			Enable()
+		}
+	}
+
+	out := newDefaultOutput()
+	outputMx.Lock()
+	if output == defaultOutput {
+		output, defaultOutput = out, out
+	}
+	outputMx.Unlock()
+	atomic.AddInt64(&envGeneration, 1)
+}
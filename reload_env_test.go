@@ -0,0 +1,61 @@
+package golog
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyEnv(t *testing.T) {
+	// created before the environment is set, like in a package-level var
+	l := LoggerFor("lazyprefix")
+
+	defer os.Unsetenv("TRACE")
+	os.Setenv("TRACE", "lazyprefix")
+	assert.True(t, l.IsTraceEnabled(), "environment should be evaluated on first use")
+	child := ChildLogger(l, Field{"bound", 1})
+	assert.True(t, child.IsTraceEnabled())
+	assert.Equal(t, l.TraceOut(), child.TraceOut(), "child should share the trace writer")
+
+	os.Unsetenv("TRACE")
+	assert.True(t, l.IsTraceEnabled(), "environment should only be evaluated again after ReloadEnv")
+	ReloadEnv()
+	assert.False(t, l.IsTraceEnabled())
+	assert.False(t, child.IsTraceEnabled())
+}
+
+func TestReloadEnv(t *testing.T) {
+	oldStderr, oldStdout := stderr, stdout
+	errBuf, outBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	stderr, stdout = errBuf, outBuf
+	defer func() {
+		stderr, stdout = oldStderr, oldStdout
+		os.Unsetenv("PRINT_JSON")
+		os.Unsetenv("GOLOG_CONFIG")
+		ReloadEnv()
+		SetLevel("")
+	}()
+	ResetOutputs()
+
+	l := LoggerFor("myprefix")
+	os.Setenv("PRINT_JSON", "true")
+	os.Setenv("GOLOG_CONFIG", "level=info&output=stderr")
+	ReloadEnv()
+	assert.Equal(t, "INFO", Level())
+	l.Debug("dropped")
+	l.Error("kept")
+	assert.Empty(t, outBuf.String())
+	assert.Regexp(t, `^\{("ts":"[^"]+",)?"msg":"kept","component":"myprefix".*"level":"ERROR"\}\n$`, errBuf.String())
+
+	// explicitly set outputs are left alone
+	custom := newBuffer()
+	SetOutputs(custom, custom)
+	os.Unsetenv("PRINT_JSON")
+	ReloadEnv()
+	require.NoError(t, SetLevel(""))
+	l.Debug("custom")
+	assert.Contains(t, custom.String(), `"msg":"custom"`)
+}
@@ -0,0 +1,144 @@
+package golog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const maxJSONLineSize = 16 * 1024 * 1024
+
+// ReadJSONEvents reads the events from a log written by JsonOutput. Lines that
+// aren't JSON objects, like output written to the same file by other means,
+// are skipped.
+func ReadJSONEvents(r io.Reader) ([]Event, error) {
+	recorded, err := ReadRecordedEvents(r)
+	events := make([]Event, 0, len(recorded))
+	for _, event := range recorded {
+		events = append(events, event.Event)
+	}
+	return events, err
+}
+
+// ReadRecordedEvents is like ReadJSONEvents but also reads the time at which
+// each event was logged, for logs that record it like RingBuffer dumps. Events
+// without a time have a zero Time.
+func ReadRecordedEvents(r io.Reader) ([]*RecordedEvent, error) {
+	var events []*RecordedEvent
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLineSize)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		event := &RecordedEvent{}
+		if err := json.Unmarshal(line, event); err != nil {
+			return events, fmt.Errorf("invalid event on line %d: %v", lineNumber, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// Replay writes the given events, e.g. as read with ReadJSONEvents, to out
+// using the same encoders as for live logging. The original time, component,
+// severity, caller and context of each event are preserved. Events are not
+// sent to ErrorReporters.
+func Replay(events []Event, out Output) {
+	for i := range events {
+		replay(&events[i], out)
+	}
+}
+
+func replay(event *Event, out Output) {
+	arg := (&injectedArg{
+		fieldsArg: fieldsArg{arg: event.Message},
+		caller:    event.Caller,
+		ts:        event.Timestamp,
+		stack:     event.Stack,
+	}).asArg()
+	prefix := event.Component + ": "
+	switch event.Severity {
+	case "ERROR", "FATAL":
+		out.Error(prefix, 3, false, event.Severity, arg, event.Context)
+	default:
+		out.Debug(prefix, 3, false, event.Severity, arg, event.Context)
+	}
+}
+
+// ReplayOptions configures ReplayRecorded.
+type ReplayOptions struct {
+	// Speed scales the original timing between events, 2 replaying twice as
+	// fast as they were logged. 0 replays everything as fast as possible.
+	Speed float64
+
+	// Components, if set, limits replay to events from these components.
+	Components []string
+
+	// MinSeverity, if set, limits replay to events with at least this
+	// severity, like "WARN" (case insensitive).
+	MinSeverity string
+
+	// Stop, if set, stops the replay when closed.
+	Stop <-chan struct{}
+}
+
+// ReplayRecorded is like Replay, but replays events read with
+// ReadRecordedEvents at their original timing (scaled by opts.Speed) and
+// optionally filtered, for reproducing timing sensitive issues against local
+// tooling. It blocks until the replay is done. It returns an error without
+// replaying anything if opts.MinSeverity is unknown. opts may be nil.
+func ReplayRecorded(events []*RecordedEvent, out Output, opts *ReplayOptions) error {
+	if opts == nil {
+		opts = &ReplayOptions{}
+	}
+	minSeverity := 0
+	if opts.MinSeverity != "" {
+		var err error
+		if minSeverity, err = parseLevel(opts.MinSeverity); err != nil {
+			return err
+		}
+	}
+	components := make(map[string]bool, len(opts.Components))
+	for _, component := range opts.Components {
+		components[component] = true
+	}
+	start := time.Now()
+	var first time.Time
+	for _, event := range events {
+		if len(components) > 0 && !components[event.Component] {
+			continue
+		}
+		if severityLevel(event.Severity) < minSeverity {
+			continue
+		}
+		if opts.Speed > 0 && !event.Time.IsZero() {
+			if first.IsZero() {
+				first = event.Time
+			}
+			due := start.Add(time.Duration(float64(event.Time.Sub(first)) / opts.Speed))
+			if wait := time.Until(due); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-opts.Stop:
+					timer.Stop()
+					return nil
+				}
+			}
+		}
+		select {
+		case <-opts.Stop:
+			return nil
+		default:
+		}
+		replay(&event.Event, out)
+	}
+	return nil
+}
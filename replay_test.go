@@ -0,0 +1,76 @@
+package golog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	jsonLog := &bytes.Buffer{}
+	reset := SetOutput(JsonOutput(jsonLog, jsonLog))
+	l := LoggerFor("myprefix")
+	l.Debug("Hello world")
+	l.Errorf("Hello %v", "error")
+	reset()
+	jsonLog.WriteString("TRACE logging is enabled for prefix [myprefix]\n")
+
+	events, err := ReadJSONEvents(strings.NewReader(jsonLog.String()))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	text := newBuffer()
+	Replay(events, TextOutput(text, text))
+	lines := strings.Split(text.String(), "\n")
+	assert.Equal(t, "DEBUG myprefix: replay_test.go:999 Hello world", lines[0])
+	assert.True(t, strings.HasPrefix(lines[1], "ERROR myprefix: replay_test.go:999 Hello error [error=Hello %v"), lines[1])
+
+	_, err = ReadJSONEvents(strings.NewReader("{broken\n"))
+	assert.Error(t, err)
+	Replay(nil, TextOutput(ioutil.Discard, ioutil.Discard))
+}
+
+func TestReplayRecorded(t *testing.T) {
+	rb := NewRingBuffer(nil, 10)
+	reset := SetOutput(rb)
+	a, b := LoggerFor("a"), LoggerFor("b")
+	a.Debug("first")
+	time.Sleep(100 * time.Millisecond)
+	b.Debug("second")
+	a.Error("third")
+	reset()
+	dump := &bytes.Buffer{}
+	_, err := rb.WriteTo(dump)
+	require.NoError(t, err)
+
+	events, err := ReadRecordedEvents(dump)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.False(t, events[0].Time.IsZero())
+
+	text := newBuffer()
+	start := time.Now()
+	require.NoError(t, ReplayRecorded(events, TextOutput(text, text), &ReplayOptions{Speed: 2}))
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 50*time.Millisecond, "should have kept (scaled) original timing, took %v", elapsed)
+	assert.Len(t, strings.Split(strings.TrimSpace(text.String()), "\n"), 3)
+
+	text = newBuffer()
+	require.NoError(t, ReplayRecorded(events, TextOutput(text, text), &ReplayOptions{Components: []string{"a"}, MinSeverity: "error"}))
+	assert.Equal(t, "ERROR a: replay_test.go:999 third\n", text.String(), "MinSeverity should be case insensitive")
+
+	text = newBuffer()
+	assert.EqualError(t, ReplayRecorded(events, TextOutput(text, text), &ReplayOptions{MinSeverity: "warning"}), `unknown level "warning"`)
+	assert.Empty(t, text.String())
+
+	stop := make(chan struct{})
+	close(stop)
+	text = newBuffer()
+	require.NoError(t, ReplayRecorded(events, TextOutput(text, text), &ReplayOptions{Speed: 1, Stop: stop}))
+	assert.Empty(t, text.String())
+}
@@ -0,0 +1,179 @@
+package golog
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// RecordedEvent is an Event along with the time at which it was logged.
+type RecordedEvent struct {
+	Time time.Time `json:"ts"`
+	Monotonic
+	Event
+}
+
+// MarshalJSON encodes e like its Event, with Time as "ts" and the Monotonic
+// time, if any, after it.
+func (e RecordedEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Time time.Time `json:"ts"`
+		Monotonic
+		*eventJSON
+	}{e.Time, e.Monotonic, e.Event.toJSON()})
+}
+
+// UnmarshalJSON decodes e from the JSON encoding of a RecordedEvent or an
+// Event, in which case Time is the Event's Timestamp.
+func (e *RecordedEvent) UnmarshalJSON(b []byte) error {
+	j := &struct {
+		Monotonic
+		*eventJSON
+	}{eventJSON: &eventJSON{}}
+	if err := json.Unmarshal(b, j); err != nil {
+		return err
+	}
+	*e = RecordedEvent{Monotonic: j.Monotonic, Event: j.toEvent()}
+	e.Time = e.Timestamp
+	return nil
+}
+
+// RingBuffer is an Output that keeps the most recent events in memory while
+// passing everything through to another Output, so that recent history is
+// available for crash dumps and debugging even if it was never persisted.
+type RingBuffer struct {
+	out           Output
+	mx            sync.RWMutex
+	events        []*RecordedEvent
+	next          int
+	full          bool
+	crashDumpPath string
+	bundlePath    string
+	bundleOpts    CrashBundleOptions
+}
+
+// NewRingBuffer creates a RingBuffer that retains the last size events and
+// writes everything through to out (which may be nil to only buffer).
+func NewRingBuffer(out Output, size int) *RingBuffer {
+	if size <= 0 {
+		size = 1
+	}
+	return &RingBuffer{
+		out:    out,
+		events: make([]*RecordedEvent, size),
+	}
+}
+
+// DumpOnFatal configures the RingBuffer to write its contents to the file at
+// path whenever a FATAL error is logged. An empty path disables dumping.
+func (rb *RingBuffer) DumpOnFatal(path string) {
+	rb.mx.Lock()
+	rb.crashDumpPath = path
+	rb.mx.Unlock()
+}
+
+// BundleOnFatal configures the RingBuffer to write a crash bundle including
+// its contents to the file at path whenever a FATAL error is logged, see
+// WriteCrashBundle. opts may be nil. An empty path disables bundling.
+func (rb *RingBuffer) BundleOnFatal(path string, opts *CrashBundleOptions) {
+	rb.mx.Lock()
+	rb.bundlePath = path
+	rb.bundleOpts = CrashBundleOptions{}
+	if opts != nil {
+		rb.bundleOpts = *opts
+	}
+	rb.bundleOpts.RingBuffer = rb
+	rb.mx.Unlock()
+}
+
+func (rb *RingBuffer) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	rb.record(prefix, skipFrames, printStack, severity, arg, values)
+	if rb.out != nil {
+		rb.out.Error(prefix, skipFrames+1, printStack, severity, arg, values)
+	}
+	if severity == Severity(FATAL).String() {
+		rb.mx.RLock()
+		path, bundlePath, bundleOpts := rb.crashDumpPath, rb.bundlePath, rb.bundleOpts
+		rb.mx.RUnlock()
+		if path != "" {
+			if err := rb.DumpTo(path); err != nil {
+				errorOnLogging(err)
+			}
+		}
+		if bundlePath != "" {
+			if err := WriteCrashBundleFile(bundlePath, &bundleOpts); err != nil {
+				errorOnLogging(err)
+			}
+		}
+	}
+}
+
+func (rb *RingBuffer) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	rb.record(prefix, skipFrames, printStack, severity, arg, values)
+	if rb.out != nil {
+		rb.out.Debug(prefix, skipFrames+1, printStack, severity, arg, values)
+	}
+}
+
+func (rb *RingBuffer) record(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	event := buildEvent(make([]uintptr, 10), prefix, skipFrames, printStack, severity, arg, values)
+	recorded := &RecordedEvent{Time: eventTime(arg), Monotonic: monotonicNow(arg), Event: *event}
+	rb.mx.Lock()
+	rb.events[rb.next] = recorded
+	rb.next = (rb.next + 1) % len(rb.events)
+	if rb.next == 0 {
+		rb.full = true
+	}
+	rb.mx.Unlock()
+}
+
+// Events returns the buffered events, oldest first.
+func (rb *RingBuffer) Events() []*RecordedEvent {
+	rb.mx.RLock()
+	defer rb.mx.RUnlock()
+	if !rb.full {
+		return append([]*RecordedEvent(nil), rb.events[:rb.next]...)
+	}
+	result := make([]*RecordedEvent, 0, len(rb.events))
+	result = append(result, rb.events[rb.next:]...)
+	return append(result, rb.events[:rb.next]...)
+}
+
+// WriteTo writes the buffered events to w as JSON, one event per line.
+func (rb *RingBuffer) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	encoder := json.NewEncoder(cw)
+	for _, event := range rb.Events() {
+		if err := encoder.Encode(event); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+// DumpTo writes the buffered events to the file at path, replacing anything
+// that was already there.
+func (rb *RingBuffer) DumpTo(path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := rb.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,80 @@
+package golog
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// RingBufferQuery selects events from a RingBuffer. Criteria that are left
+// empty match everything.
+type RingBufferQuery struct {
+	// Since and Until bound the time at which events were logged (Until is
+	// exclusive).
+	Since time.Time
+	Until time.Time
+
+	// MinSeverity is the lowest severity to match, for example "ERROR" (case
+	// insensitive).
+	MinSeverity string
+
+	// Component is the logger prefix to match, without the trailing ": ".
+	Component string
+
+	// Fields are context fields that events must have with the given values.
+	// Values are compared by their string representation, so 5 matches "5".
+	Fields map[string]interface{}
+
+	// Message, if set, must match the message.
+	Message *regexp.Regexp
+
+	// Limit, if positive, returns only the most recent Limit matches.
+	Limit int
+}
+
+// Query returns the buffered events that match q, oldest first. It returns an
+// error if q.MinSeverity is unknown.
+func (rb *RingBuffer) Query(q *RingBufferQuery) ([]*RecordedEvent, error) {
+	minSeverity := 0
+	if q.MinSeverity != "" {
+		var err error
+		if minSeverity, err = parseLevel(q.MinSeverity); err != nil {
+			return nil, err
+		}
+	}
+	var matches []*RecordedEvent
+	for _, event := range rb.Events() {
+		if q.matches(event, minSeverity) {
+			matches = append(matches, event)
+		}
+	}
+	if q.Limit > 0 && len(matches) > q.Limit {
+		matches = matches[len(matches)-q.Limit:]
+	}
+	return matches, nil
+}
+
+func (q *RingBufferQuery) matches(event *RecordedEvent, minSeverity int) bool {
+	if !q.Since.IsZero() && event.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !event.Time.Before(q.Until) {
+		return false
+	}
+	if severityLevel(event.Severity) < minSeverity {
+		return false
+	}
+	if q.Component != "" && event.Component != q.Component {
+		return false
+	}
+	for key, expected := range q.Fields {
+		actual, found := event.Context[key]
+		if !found || fmt.Sprint(actual) != fmt.Sprint(expected) {
+			return false
+		}
+	}
+	if q.Message != nil && !q.Message.MatchString(event.Message) {
+		return false
+	}
+	return true
+}
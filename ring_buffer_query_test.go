@@ -0,0 +1,45 @@
+package golog
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRingBufferQuery(t *testing.T) {
+	rb := NewRingBuffer(nil, 10)
+	reset := SetOutput(rb)
+	defer reset()
+
+	start := time.Now()
+	a, b := LoggerFor("a"), LoggerFor("b")
+	a.Debugw("dialing", Field{Key: "conn_id", Value: 1})
+	a.Error("dial failed: timeout")
+	b.Debugw("dialing", Field{Key: "conn_id", Value: 2})
+	b.Error(WithFields("dial failed: refused", Field{Key: "conn_id", Value: 2}))
+
+	messages := func(q *RingBufferQuery) []string {
+		var result []string
+		events, err := rb.Query(q)
+		require.NoError(t, err)
+		for _, event := range events {
+			result = append(result, event.Component+" "+event.Message)
+		}
+		return result
+	}
+
+	assert.Len(t, messages(&RingBufferQuery{}), 4)
+	assert.Equal(t, []string{"a dial failed: timeout", "b dial failed: refused"}, messages(&RingBufferQuery{MinSeverity: "error"}), "MinSeverity should be case insensitive")
+	assert.Equal(t, []string{"b dialing", "b dial failed: refused"}, messages(&RingBufferQuery{Component: "b"}))
+	assert.Equal(t, []string{"b dialing", "b dial failed: refused"}, messages(&RingBufferQuery{Fields: map[string]interface{}{"conn_id": "2"}}))
+	assert.Equal(t, []string{"a dial failed: timeout"}, messages(&RingBufferQuery{Message: regexp.MustCompile(`time`)}))
+	assert.Equal(t, []string{"b dial failed: refused"}, messages(&RingBufferQuery{Limit: 1}))
+	assert.Len(t, messages(&RingBufferQuery{Since: start, Until: time.Now().Add(time.Second)}), 4)
+	assert.Empty(t, messages(&RingBufferQuery{Until: start}))
+
+	_, err := rb.Query(&RingBufferQuery{MinSeverity: "warning"})
+	assert.EqualError(t, err, `unknown level "warning"`)
+}
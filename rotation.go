@@ -0,0 +1,89 @@
+package golog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+var (
+	// renameFile is os.Rename, replaceable for tests
+	renameFile = os.Rename
+
+	// renameRetryable is whether a failed rename is worth retrying,
+	// replaceable for tests
+	renameRetryable = isSharingViolation
+
+	renameRetries    = 5
+	renameRetryDelay = 50 * time.Millisecond
+)
+
+// Rotate moves what's been logged to the file so far to backupPath and
+// continues logging to a new, empty file.
+//
+// The file is closed before being renamed, since Windows doesn't allow renaming
+// open files, and renaming is retried for a while if it fails because something
+// else, like antivirus software, briefly holds the file open (a sharing or lock
+// violation). If it still can't be renamed, the contents are copied to
+// backupPath and the file is truncated instead, so that rotation keeps working.
+func (f *LogFile) Rotate(backupPath string) error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	f.file.Close()
+	err := rotateFile(f.path, backupPath)
+	if openErr := f.open(); openErr != nil {
+		f.file, f.w = nil, nil
+		if err == nil {
+			err = openErr
+		}
+		return fmt.Errorf("unable to reopen %v after rotating: %v", f.path, err)
+	}
+	return err
+}
+
+func rotateFile(path string, backupPath string) error {
+	delay := renameRetryDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = renameFile(path, backupPath); err == nil || os.IsNotExist(err) {
+			return nil
+		}
+		if attempt >= renameRetries || !renameRetryable(err) {
+			break
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+	if copyErr := copyTruncate(path, backupPath); copyErr != nil {
+		return fmt.Errorf("unable to rename %v (%v) or copy it (%v)", path, err, copyErr)
+	}
+	return nil
+}
+
+// copyTruncate copies the file at path to backupPath and then truncates it.
+// Anything written to the file in between by other processes is lost.
+func copyTruncate(path string, backupPath string) error {
+	in, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(backupPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(backupPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(backupPath)
+		return err
+	}
+	return in.Truncate(0)
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package golog
+
+// isSharingViolation returns false, since other platforms don't keep files
+// that are open elsewhere from being renamed.
+func isSharingViolation(err error) bool {
+	return false
+}
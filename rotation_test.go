@@ -0,0 +1,87 @@
+package golog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogFileRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	f, err := OpenLogFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.Write([]byte("first\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Rotate(path+".1"))
+	_, err = f.Write([]byte("second\n"))
+	require.NoError(t, err)
+
+	b, _ := ioutil.ReadFile(path + ".1")
+	assert.Equal(t, "first\n", string(b))
+	b, _ = ioutil.ReadFile(path)
+	assert.Equal(t, "second\n", string(b))
+}
+
+func TestLogFileRotateFallsBackToCopyTruncate(t *testing.T) {
+	attempts := 0
+	oldRename, oldRetryable, oldDelay := renameFile, renameRetryable, renameRetryDelay
+	renameFile = func(from, to string) error {
+		attempts++
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: fmt.Errorf("The process cannot access the file because it is being used by another process.")}
+	}
+	renameRetryable = func(err error) bool { return true }
+	renameRetryDelay = 0
+	defer func() { renameFile, renameRetryable, renameRetryDelay = oldRename, oldRetryable, oldDelay }()
+
+	dir, err := ioutil.TempDir("", "golog-rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	f, err := OpenLogFile(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.Write([]byte("first\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Rotate(path+".1"))
+	assert.Equal(t, renameRetries, attempts, "rename should have been retried")
+	_, err = f.Write([]byte("second\n"))
+	require.NoError(t, err)
+
+	b, _ := ioutil.ReadFile(path + ".1")
+	assert.Equal(t, "first\n", string(b))
+	b, _ = ioutil.ReadFile(path)
+	assert.Equal(t, "second\n", string(b))
+}
+
+func TestRotateFileOnlyRetriesSharingViolations(t *testing.T) {
+	attempts := 0
+	oldRename, oldDelay := renameFile, renameRetryDelay
+	renameFile = func(from, to string) error {
+		attempts++
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: os.ErrPermission}
+	}
+	renameRetryDelay = time.Hour
+	defer func() { renameFile, renameRetryDelay = oldRename, oldDelay }()
+
+	dir, err := ioutil.TempDir("", "golog-rotate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("first\n"), 0600))
+	require.NoError(t, rotateFile(path, path+".1"))
+	assert.Equal(t, 1, attempts, "other errors shouldn't be retried")
+	b, _ := ioutil.ReadFile(path + ".1")
+	assert.Equal(t, "first\n", string(b), "the file should still be copied")
+}
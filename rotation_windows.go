@@ -0,0 +1,24 @@
+//go:build windows
+// +build windows
+
+package golog
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	errorSharingViolation syscall.Errno = 32
+	errorLockViolation    syscall.Errno = 33
+)
+
+// isSharingViolation returns whether err is because another process has the
+// file open without sharing it or has locked part of it.
+func isSharingViolation(err error) bool {
+	if linkErr, ok := err.(*os.LinkError); ok {
+		err = linkErr.Err
+	}
+	errno, ok := err.(syscall.Errno)
+	return ok && (errno == errorSharingViolation || errno == errorLockViolation)
+}
@@ -0,0 +1,135 @@
+package golog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+const defaultRouterMaxRoutes = 1000
+
+// RouterOptions configures a router output.
+type RouterOptions struct {
+	// Key is the context field whose value selects the route, for example
+	// "tenant_id".
+	Key string
+
+	// Default receives events without the Key field, and events for which
+	// NewRoute failed.
+	Default Output
+
+	// NewRoute creates the output for a value of the Key field. It's called
+	// the first time an event with that value is logged.
+	NewRoute func(value string) (Output, error)
+
+	// MaxRoutes bounds the number of distinct values of the Key field that
+	// are routed, so that a field with unbounded values can't create outputs
+	// without end. Events with further values go to Default. Defaults to
+	// 1000.
+	MaxRoutes int
+}
+
+// RouterOutput creates an output that routes each event to an output selected
+// by the value of a context field, creating the outputs for new values on
+// demand. For example, to log to a file per tenant:
+//
+//	out, err := golog.RouterOutput(&golog.RouterOptions{
+//		Key:     "tenant_id",
+//		Default: golog.TextOutput(os.Stderr, os.Stdout),
+//		NewRoute: func(tenant string) (golog.Output, error) {
+//			f, err := os.Create(filepath.Join(dir, tenant+".log"))
+//			if err != nil {
+//				return nil, err
+//			}
+//			return golog.TextOutput(f, f), nil
+//		},
+//	})
+//
+// Closing the router closes the outputs that NewRoute created, if they can be
+// closed. The default output is left alone. Default and NewRoute are
+// required.
+func RouterOutput(opts *RouterOptions) (ClosableOutput, error) {
+	if opts.Default == nil {
+		return nil, errors.New("router output needs a Default output")
+	}
+	if opts.NewRoute == nil {
+		return nil, errors.New("router output needs a NewRoute function")
+	}
+	o := &routerOutput{
+		opts:   *opts,
+		routes: make(map[string]Output),
+	}
+	if o.opts.MaxRoutes <= 0 {
+		o.opts.MaxRoutes = defaultRouterMaxRoutes
+	}
+	return o, nil
+}
+
+type routerOutput struct {
+	opts   RouterOptions
+	mx     sync.Mutex
+	routes map[string]Output
+	// created are the outputs created by NewRoute, in order of creation
+	created []Output
+	// full is set once MaxRoutes has been reached and reported
+	full bool
+}
+
+func (o *routerOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.route(values).Error(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+func (o *routerOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.route(values).Debug(prefix, skipFrames+1, printStack, severity, arg, values)
+}
+
+func (o *routerOutput) route(values map[string]interface{}) Output {
+	value, found := values[o.opts.Key]
+	if !found {
+		return o.opts.Default
+	}
+	key := fmt.Sprint(value)
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	if out, found := o.routes[key]; found {
+		return out
+	}
+	if len(o.routes) >= o.opts.MaxRoutes {
+		if !o.full {
+			o.full = true
+			errorOnLogging(fmt.Errorf("routed %d values of %v, using default for further ones", len(o.routes), o.opts.Key))
+		}
+		return o.opts.Default
+	}
+	out, err := o.opts.NewRoute(key)
+	if err != nil {
+		errorOnLogging(fmt.Errorf("unable to create route for %v=%v, using default: %v", o.opts.Key, key, err))
+		out = o.opts.Default
+	} else {
+		o.created = append(o.created, out)
+	}
+	o.routes[key] = out
+	return out
+}
+
+// Close closes all outputs created by NewRoute that can be closed, returning
+// the first error encountered.
+func (o *routerOutput) Close() error {
+	o.mx.Lock()
+	created := o.created
+	o.created = nil
+	o.routes = make(map[string]Output)
+	o.full = false
+	o.mx.Unlock()
+
+	var firstErr error
+	for _, out := range created {
+		if c, ok := out.(io.Closer); ok {
+			if err := c.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
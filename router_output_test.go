@@ -0,0 +1,97 @@
+package golog
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouterOutput(t *testing.T) {
+	defaultBuf := &bytes.Buffer{}
+	bufs := make(map[string]*bytes.Buffer)
+	var closed []string
+	out, err := RouterOutput(&RouterOptions{
+		Key:     "tenant_id",
+		Default: TextOutput(defaultBuf, defaultBuf),
+		NewRoute: func(tenant string) (Output, error) {
+			if tenant == "broken" {
+				return nil, errors.New("no route")
+			}
+			buf := &bytes.Buffer{}
+			bufs[tenant] = buf
+			return &closeRecordingOutput{Output: TextOutput(buf, buf), onClose: func() {
+				closed = append(closed, tenant)
+			}}, nil
+		},
+	})
+	require.NoError(t, err)
+	reset := SetOutput(out)
+	defer reset()
+
+	l := LoggerFor("myprefix")
+	l.Debug("no tenant")
+	l.Debugw("for a", Field{Key: "tenant_id", Value: "a"})
+	l.Error(WithFields("for b", Field{Key: "tenant_id", Value: "b"}))
+	l.Debugw("again for a", Field{Key: "tenant_id", Value: "a"})
+	l.Debugw("for broken", Field{Key: "tenant_id", Value: "broken"})
+
+	require.Len(t, bufs, 2)
+	assert.Regexp(t, `^DEBUG myprefix: router_output_test.go:[0-9]+ for a \[tenant_id=a\]
+DEBUG myprefix: router_output_test.go:[0-9]+ again for a \[tenant_id=a\]
+$`, bufs["a"].String())
+	assert.Regexp(t, `^ERROR myprefix: router_output_test.go:[0-9]+ for b \[tenant_id=b\]\n$`, bufs["b"].String())
+	assert.Regexp(t, `^DEBUG myprefix: router_output_test.go:[0-9]+ no tenant
+DEBUG myprefix: router_output_test.go:[0-9]+ for broken \[tenant_id=broken\]
+$`, defaultBuf.String())
+
+	require.NoError(t, out.Close())
+	assert.Equal(t, []string{"a", "b"}, closed)
+}
+
+func TestRouterOutputMaxRoutes(t *testing.T) {
+	oldStderr := stderr
+	errs := &bytes.Buffer{}
+	stderr = errs
+	defer func() { stderr = oldStderr }()
+
+	defaultBuf := &bytes.Buffer{}
+	var routes []string
+	out, err := RouterOutput(&RouterOptions{
+		Key:     "tenant_id",
+		Default: TextOutput(defaultBuf, defaultBuf),
+		NewRoute: func(tenant string) (Output, error) {
+			routes = append(routes, tenant)
+			return TextOutput(ioutil.Discard, ioutil.Discard), nil
+		},
+		MaxRoutes: 2,
+	})
+	require.NoError(t, err)
+	for _, tenant := range []string{"a", "b", "c", "a", "d"} {
+		out.Debug("myprefix: ", 0, false, "DEBUG", "for "+tenant, map[string]interface{}{"tenant_id": tenant})
+	}
+	assert.Equal(t, []string{"a", "b"}, routes)
+	assert.Contains(t, defaultBuf.String(), "for c")
+	assert.Contains(t, defaultBuf.String(), "for d")
+	assert.Equal(t, "Unable to log: routed 2 values of tenant_id, using default for further ones\n", errs.String())
+}
+
+func TestRouterOutputValidation(t *testing.T) {
+	_, err := RouterOutput(&RouterOptions{Key: "tenant_id", NewRoute: func(string) (Output, error) { return nil, nil }})
+	assert.EqualError(t, err, "router output needs a Default output")
+	_, err = RouterOutput(&RouterOptions{Key: "tenant_id", Default: TextOutput(ioutil.Discard, ioutil.Discard)})
+	assert.EqualError(t, err, "router output needs a NewRoute function")
+}
+
+type closeRecordingOutput struct {
+	Output
+	onClose func()
+}
+
+func (o *closeRecordingOutput) Close() error {
+	o.onClose()
+	return nil
+}
@@ -0,0 +1,35 @@
+package golog
+
+import (
+	"runtime"
+)
+
+// WithRuntimeStats returns a "runtime" Field holding a snapshot of key runtime
+// metrics (heap usage, GC pauses and the number of goroutines), which is
+// handy when logging capacity related problems:
+//
+//	log.Debugw("dial queue is backing up", golog.WithRuntimeStats())
+//
+// Taking the snapshot briefly stops the world, so avoid it on hot paths.
+func WithRuntimeStats() Field {
+	return Field{"runtime", runtimeStats()}
+}
+
+func runtimeStats() map[string]interface{} {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	var lastPause uint64
+	if ms.NumGC > 0 {
+		lastPause = ms.PauseNs[(ms.NumGC+255)%256]
+	}
+	return map[string]interface{}{
+		"goroutines":        runtime.NumGoroutine(),
+		"heap_alloc":        ms.HeapAlloc,
+		"heap_sys":          ms.HeapSys,
+		"heap_objects":      ms.HeapObjects,
+		"sys":               ms.Sys,
+		"num_gc":            ms.NumGC,
+		"gc_pause_last_ns":  lastPause,
+		"gc_pause_total_ns": ms.PauseTotalNs,
+	}
+}
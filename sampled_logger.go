@@ -0,0 +1,137 @@
+package golog
+
+import (
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sampledLoggerFlushInterval is how long after dropping an event a sampled
+// logger logs the drops that haven't been counted in a written event yet.
+var sampledLoggerFlushInterval = time.Minute
+
+// SampledLogger returns a logger that logs like parent, which must have been
+// created by LoggerFor (or be a child or sampled logger), but only writes the
+// first of every everyN events logged from the same line of code with the
+// same severity, for repetitive lines in tight loops. Like with
+// SamplingOutput, the events that are written have the fields sampled=true
+// and dropped_count, the number of events from that line dropped since the
+// previous one that was written. So that drops from lines that aren't logged
+// again aren't lost, a minute after dropping an event the logger also writes
+// an event with the dropped_count of each line that has uncounted drops.
+// FATAL errors are always written, and dropped errors are still returned and
+// sent to the registered ErrorReporters. Child loggers of a sampled logger
+// share its counts. An everyN of 1 or less keeps everything. Like with
+// ChildLogger, any other parent is returned unchanged, which is reported on
+// stderr.
+func SampledLogger(parent Logger, everyN int) Logger {
+	p, ok := parent.(*logger)
+	if !ok {
+		reportForeignParent("SampledLogger", parent)
+		return parent
+	}
+	sampled := *p
+	sampled.sampler = nil
+	if everyN > 1 {
+		sampled.sampler = &logSampler{
+			everyN:    uint64(everyN),
+			component: strings.TrimSuffix(p.prefix, ": "),
+			sites:     make(map[samplingSite]*samplingCounter),
+		}
+	}
+	return &sampled
+}
+
+type logSampler struct {
+	everyN    uint64
+	component string
+	mx        sync.Mutex
+	sites     map[samplingSite]*samplingCounter
+	// flushScheduled is whether a flush of the drops is pending
+	flushScheduled bool
+}
+
+// samplingSite identifies the line of code that logged an event, either by
+// its program counter or, for events that carry their own caller, by that.
+type samplingSite struct {
+	pc       uintptr
+	caller   string
+	severity string
+}
+
+// pcOrigin is implemented by args that know the program counter of the line
+// that logged them, like records logged through AsSlogLogger.
+type pcOrigin interface {
+	originPC() uintptr
+}
+
+// sample returns whether to write an event logged with the given severity and
+// arg, and if so how many events from the same site were dropped before it.
+// skipFrames is how many frames above the caller of sample the site is.
+func (s *logSampler) sample(skipFrames int, severity string, arg interface{}) (keep bool, dropped uint64) {
+	site := samplingSite{severity: severity}
+	if o, ok := arg.(pcOrigin); ok {
+		site.pc = o.originPC()
+	}
+	if site.pc == 0 {
+		site.caller = callerOverride(arg)
+	}
+	if site.pc == 0 && site.caller == "" {
+		var pc [1]uintptr
+		runtime.Callers(skipFrames+2, pc[:])
+		site.pc = pc[0]
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	c := s.sites[site]
+	if c == nil {
+		c = &samplingCounter{everyN: s.everyN}
+		s.sites[site] = c
+	}
+	keep, dropped = c.sample()
+	if !keep && !s.flushScheduled {
+		s.flushScheduled = true
+		time.AfterFunc(sampledLoggerFlushInterval, s.flush)
+	}
+	return keep, dropped
+}
+
+// siteDrops are the drops from a site that haven't been counted in a written
+// event yet.
+type siteDrops struct {
+	caller   string
+	severity string
+	dropped  uint64
+}
+
+// flush writes an event for each site with drops that haven't been counted in
+// a written event yet.
+func (s *logSampler) flush() {
+	var drops []siteDrops
+	s.mx.Lock()
+	for site, c := range s.sites {
+		if c.dropped == 0 {
+			continue
+		}
+		caller := site.caller
+		if caller == "" {
+			caller = frameLocation([]uintptr{site.pc})
+		}
+		drops = append(drops, siteDrops{caller, site.severity, c.dropped})
+		c.dropped = 0
+	}
+	s.flushScheduled = false
+	s.mx.Unlock()
+
+	sort.Slice(drops, func(i, j int) bool { return drops[i].caller < drops[j].caller })
+	for _, d := range drops {
+		// The dropped errors were reported already
+		NewEvent(d.severity, s.component, "events dropped by sampling").
+			With(Field{"sampled", true}, Field{"dropped_count", d.dropped}).
+			Caller(d.caller).
+			Unreported().
+			Emit()
+	}
+}
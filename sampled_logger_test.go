@@ -0,0 +1,130 @@
+package golog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampledEvents returns the first line of the message, dropped_count and
+// sampled field of each event in a JSON log.
+func sampledEvents(t *testing.T, buf *bytes.Buffer) []string {
+	events, err := ReadJSONEvents(buf)
+	require.NoError(t, err)
+	var summaries []string
+	for _, event := range events {
+		summaries = append(summaries, fmt.Sprintf("%v %v %v", strings.SplitN(event.Message, "\n", 2)[0], event.Context["dropped_count"], event.Context["sampled"]))
+	}
+	return summaries
+}
+
+func TestSampledLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutput(JsonOutput(buf, buf))
+	defer reset()
+	OnFatal(func(err error) {})
+	defer DefaultOnFatal()
+	active, reported := true, 0
+	RegisterReporter(func(err error, severity Severity, ctx map[string]interface{}) {
+		if active {
+			reported++
+		}
+	})
+	defer func() { active = false }()
+
+	l := SampledLogger(LoggerFor("myprefix"), 4)
+	for i := 0; i < 9; i++ {
+		l.Debugf("debug %d", i)
+	}
+	for i := 0; i < 5; i++ {
+		assert.Error(t, l.Errorf("error %d", i), "dropped errors should still be returned")
+	}
+	l.Fatal("fatal")
+	l.Fatal("fatal")
+	l.Debug("other line")
+
+	assert.Equal(t, []string{
+		"debug 0 0 true",
+		"debug 4 3 true",
+		"debug 8 3 true",
+		"error 0 0 true",
+		"error 4 3 true",
+		"fatal <nil> <nil>",
+		"fatal <nil> <nil>",
+		"other line 0 true",
+	}, sampledEvents(t, buf))
+	assert.Equal(t, 7, reported, "all errors should be reported")
+}
+
+func TestSampledLoggerChildrenAndStreams(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutput(JsonOutput(buf, buf))
+	defer reset()
+
+	sampled := SampledLogger(LoggerFor("myprefix"), 2)
+	child := ChildLogger(sampled, Field{"child", true})
+	for i := 0; i < 4; i++ {
+		child.Debug(i)
+		sampled.DebugStream(func(w io.Writer) {
+			fmt.Fprintf(w, "stream %d", i)
+		})
+	}
+	unsampled := SampledLogger(sampled, 1)
+	unsampled.Debug("kept")
+	unsampled.Debug("kept")
+
+	assert.Equal(t, []string{
+		"0 0 true",
+		"stream 0 0 true",
+		"2 1 true",
+		"stream 2 1 true",
+		"kept <nil> <nil>",
+		"kept <nil> <nil>",
+	}, sampledEvents(t, buf))
+}
+
+func TestSampledLoggerFlushesDrops(t *testing.T) {
+	oldInterval := sampledLoggerFlushInterval
+	sampledLoggerFlushInterval = 20 * time.Millisecond
+	defer func() { sampledLoggerFlushInterval = oldInterval }()
+	rb := NewRingBuffer(nil, 10)
+	reset := SetOutput(rb)
+	defer reset()
+	active, reported := true, 0
+	RegisterReporter(func(err error, severity Severity, ctx map[string]interface{}) {
+		if active {
+			reported++
+		}
+	})
+	defer func() { active = false }()
+
+	l := SampledLogger(LoggerFor("myprefix"), 4)
+	for i := 0; i < 5; i++ {
+		l.Error("oh no")
+		if i != 2 {
+			continue
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for len(rb.Events()) < 2 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		events := rb.Events()
+		require.Len(t, events, 2)
+		assert.Equal(t, "oh no", events[0].Message)
+		assert.Equal(t, "events dropped by sampling", events[1].Message)
+		assert.Equal(t, "ERROR", events[1].Severity)
+		assert.Equal(t, events[0].Caller, events[1].Caller, "drops should be attributed to the line they came from")
+		assert.Equal(t, uint64(2), events[1].Context["dropped_count"])
+	}
+	time.Sleep(50 * time.Millisecond)
+	events := rb.Events()
+	require.Len(t, events, 3, "there should be nothing left to flush")
+	assert.Equal(t, uint64(1), events[2].Context["dropped_count"], "flushed drops shouldn't be counted again")
+	assert.Equal(t, 5, reported, "flushed drops shouldn't be reported again")
+}
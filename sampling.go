@@ -0,0 +1,226 @@
+package golog
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SamplingRule sets the fraction of events to keep for a component and
+// severity.
+type SamplingRule struct {
+	// Component is the logger prefix the rule applies to, or "*" (or "") for
+	// all components.
+	Component string
+
+	// Severity is the severity ("TRACE", "DEBUG", "INFO", "WARN" or "ERROR")
+	// the rule applies to, or "" for all severities.
+	Severity string
+
+	// Keep is the fraction of events to keep, between 0 and 1.
+	Keep float64
+}
+
+// ParseSamplingRules parses a comma separated list of sampling rules of the
+// form component[:SEVERITY]=rate, where rate is either a fraction (0.01) or a
+// percentage (1%) and component may be "*" to match all components. For
+// example:
+//
+//	proxy.dial:DEBUG=1%,proxy.dial=100%,*:TRACE=0.1
+func ParseSamplingRules(spec string) ([]SamplingRule, error) {
+	var rules []SamplingRule
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		eq := strings.LastIndex(part, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("invalid sampling rule %q, expected component[:SEVERITY]=rate", part)
+		}
+		var rule SamplingRule
+		target, rate := part[:eq], strings.TrimSpace(part[eq+1:])
+		if colon := strings.LastIndex(target, ":"); colon >= 0 {
+			rule.Severity = strings.ToUpper(strings.TrimSpace(target[colon+1:]))
+			target = target[:colon]
+		}
+		rule.Component = strings.TrimSpace(target)
+		percent := strings.HasSuffix(rate, "%")
+		keep, err := strconv.ParseFloat(strings.TrimSuffix(rate, "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate in sampling rule %q: %v", part, err)
+		}
+		if percent {
+			keep /= 100
+		}
+		if keep < 0 || keep > 1 {
+			return nil, fmt.Errorf("rate in sampling rule %q must be between 0 and 1 (or 0%% and 100%%)", part)
+		}
+		rule.Keep = keep
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// samplingRulesFromEnv returns the sampling rules configured with the
+// GOLOG_SAMPLING environment variable, if any.
+func samplingRulesFromEnv() []SamplingRule {
+	spec := os.Getenv("GOLOG_SAMPLING")
+	if spec == "" {
+		return nil
+	}
+	rules, err := ParseSamplingRules(spec)
+	if err != nil {
+		errorOnLogging(fmt.Errorf("ignoring GOLOG_SAMPLING: %v", err))
+		return nil
+	}
+	return rules
+}
+
+// SamplingOutput creates an output that only passes a fraction of events
+// through to out, as configured per component and severity by the given
+// rules. The most specific matching rule wins: a rule for the component and
+// severity beats a rule for just the component, which beats a rule for just
+// the severity, which beats a rule for everything. Events that match no rule
+// are all kept, as are FATAL errors.
+//
+// Sampling is deterministic: keeping 1% means keeping the first of every 100
+// events for each component and severity.
+//
+// So that it's clear that counts of sampled events are lower bounds, the kept
+// events of a component and severity that's sampled have the fields
+// sampled=true and dropped_count, the number of events dropped since the
+// previous kept one. Events that are all dropped leave no trace.
+func SamplingOutput(out Output, rules ...SamplingRule) Output {
+	return &samplingOutput{
+		out:      out,
+		rules:    rules,
+		counters: make(map[samplingKey]*samplingCounter),
+	}
+}
+
+type samplingOutput struct {
+	out      Output
+	rules    []SamplingRule
+	mx       sync.Mutex
+	counters map[samplingKey]*samplingCounter
+}
+
+type samplingKey struct {
+	component string
+	severity  string
+}
+
+type samplingCounter struct {
+	everyN uint64
+	seen   uint64
+	// dropped counts the events dropped since the last one that was kept
+	dropped uint64
+}
+
+func (o *samplingOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	if keep, sampled, dropped := o.keep(prefix, severity); keep {
+		if sampled {
+			values = withDropped(values, true, dropped)
+		}
+		o.out.Error(prefix, skipFrames+1, printStack, severity, arg, values)
+	}
+}
+
+func (o *samplingOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	if keep, sampled, dropped := o.keep(prefix, severity); keep {
+		if sampled {
+			values = withDropped(values, true, dropped)
+		}
+		o.out.Debug(prefix, skipFrames+1, printStack, severity, arg, values)
+	}
+}
+
+// keep returns whether to keep an event, whether it's sampled and if so, how
+// many events were dropped since the previous one that was kept.
+func (o *samplingOutput) keep(prefix string, severity string) (keep bool, sampled bool, dropped uint64) {
+	if severity == "FATAL" {
+		return true, false, 0
+	}
+	key := samplingKey{strings.TrimSuffix(prefix, ": "), severity}
+	o.mx.Lock()
+	defer o.mx.Unlock()
+	c := o.counters[key]
+	if c == nil {
+		c = &samplingCounter{everyN: o.everyN(key)}
+		o.counters[key] = c
+	}
+	if c.everyN == 0 {
+		return true, false, 0
+	}
+	keep, dropped = c.sample()
+	return keep, true, dropped
+}
+
+// sample counts an event, returning whether to keep it and if so, how many
+// events were dropped since the previous one that was kept. It keeps the
+// first of every everyN events, which must not be 0.
+func (c *samplingCounter) sample() (keep bool, dropped uint64) {
+	c.seen++
+	if c.everyN == math.MaxUint64 || (c.seen-1)%c.everyN != 0 {
+		c.dropped++
+		return false, 0
+	}
+	dropped = c.dropped
+	c.dropped = 0
+	return true, dropped
+}
+
+// withDropped returns a copy of values with dropped added to the
+// dropped_count field, and sampled=true if sampled. The copy leaves values
+// alone for any other outputs they're passed to.
+func withDropped(values map[string]interface{}, sampled bool, dropped uint64) map[string]interface{} {
+	copied := make(map[string]interface{}, len(values)+2)
+	for key, value := range values {
+		copied[key] = value
+	}
+	if sampled {
+		copied["sampled"] = true
+	}
+	if previous, ok := copied["dropped_count"].(uint64); ok {
+		dropped += previous
+	}
+	copied["dropped_count"] = dropped
+	return copied
+}
+
+// everyN returns how many events to see per kept event for the given key, 0
+// to keep everything and math.MaxUint64 to drop everything.
+func (o *samplingOutput) everyN(key samplingKey) uint64 {
+	best, bestScore := -1, -1
+	for i, rule := range o.rules {
+		score := 0
+		switch rule.Component {
+		case "", "*":
+		case key.component:
+			score += 2
+		default:
+			continue
+		}
+		switch rule.Severity {
+		case "":
+		case key.severity:
+			score++
+		default:
+			continue
+		}
+		if score >= bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 || o.rules[best].Keep >= 1 {
+		return 0
+	}
+	if o.rules[best].Keep <= 0 {
+		return math.MaxUint64
+	}
+	return uint64(math.Round(1 / o.rules[best].Keep))
+}
@@ -0,0 +1,85 @@
+package golog
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSamplingRules(t *testing.T) {
+	rules, err := ParseSamplingRules("proxy.dial:debug=1%, proxy.dial=100%,*:TRACE=0.1")
+	require.NoError(t, err)
+	assert.Equal(t, []SamplingRule{
+		{Component: "proxy.dial", Severity: "DEBUG", Keep: 0.01},
+		{Component: "proxy.dial", Keep: 1},
+		{Component: "*", Severity: "TRACE", Keep: 0.1},
+	}, rules)
+
+	_, err = ParseSamplingRules("proxy.dial")
+	assert.Error(t, err)
+	_, err = ParseSamplingRules("proxy.dial=200%")
+	assert.Error(t, err)
+}
+
+func TestSamplingOutput(t *testing.T) {
+	OnFatal(func(err error) {})
+	defer DefaultOnFatal()
+	out := newBuffer()
+	reset := SetOutput(SamplingOutput(TextOutput(out, out),
+		SamplingRule{Component: "sampled", Severity: "DEBUG", Keep: 0.25},
+		SamplingRule{Component: "*", Keep: 0},
+		SamplingRule{Component: "sampled", Keep: 1},
+	))
+	defer reset()
+
+	sampled := LoggerFor("sampled")
+	for i := 0; i < 8; i++ {
+		sampled.Debugf("debug %d", i)
+	}
+	sampled.Error("error")
+	LoggerFor("other").Debug("dropped")
+	LoggerFor("other").Fatal("fatal is always kept")
+
+	assert.Equal(t, `DEBUG sampled: sampling_test.go:999 debug 999 [dropped_count=999 sampled=true]
+DEBUG sampled: sampling_test.go:999 debug 999 [dropped_count=999 sampled=true]
+ERROR sampled: sampling_test.go:999 error
+FATAL other: sampling_test.go:999 fatal is always kept
+`, out.String())
+}
+
+func TestSamplingFromEnv(t *testing.T) {
+	require.NoError(t, os.Setenv("GOLOG_SAMPLING", "*=0"))
+	defer os.Unsetenv("GOLOG_SAMPLING")
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	defer reset()
+	LoggerFor("myprefix").Debug("dropped")
+	assert.Empty(t, out.String())
+	SetOutputs(ioutil.Discard, ioutil.Discard)
+}
+
+func TestSamplingFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	out := SamplingOutput(JsonOutput(buf, buf), SamplingRule{Component: "sampled", Keep: 0.25})
+	values := map[string]interface{}{"a": "b"}
+	for i := 0; i < 6; i++ {
+		out.Debug("sampled: ", 0, false, "DEBUG", i, values)
+	}
+	out.Debug("other: ", 0, false, "DEBUG", "unsampled", values)
+	assert.Equal(t, map[string]interface{}{"a": "b"}, values, "values shouldn't be modified")
+
+	events, err := ReadJSONEvents(buf)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "0", events[0].Message)
+	assert.Equal(t, map[string]interface{}{"a": "b", "sampled": true, "dropped_count": float64(0)}, events[0].Context)
+	assert.Equal(t, "4", events[1].Message)
+	assert.Equal(t, map[string]interface{}{"a": "b", "sampled": true, "dropped_count": float64(3)}, events[1].Context)
+	assert.Equal(t, map[string]interface{}{"a": "b"}, events[2].Context, "events that aren't sampled shouldn't have the fields")
+
+	assert.Equal(t, uint64(5), withDropped(map[string]interface{}{"dropped_count": uint64(2)}, false, 3)["dropped_count"], "counts should add up")
+}
@@ -0,0 +1,134 @@
+package golog
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+)
+
+// sanitizationDisabled is set to 1 to turn off sanitization, see SetSanitize.
+var sanitizationDisabled int32
+
+// SetSanitize controls whether messages and context values are sanitized
+// before being written, which is on by default. Sanitizing replaces invalid
+// UTF-8 with U+FFFD and escapes control characters other than newline and
+// tab (for example "\x1b"), so that binary garbage logged by mistake can't
+// corrupt terminals or break consumers of the logs.
+func SetSanitize(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&sanitizationDisabled, 0)
+	} else {
+		atomic.StoreInt32(&sanitizationDisabled, 1)
+	}
+}
+
+func sanitizing() bool {
+	return atomic.LoadInt32(&sanitizationDisabled) == 0
+}
+
+// needsSanitizing indicates whether b contains invalid UTF-8 or control
+// characters that need escaping.
+func needsSanitizing(b []byte) bool {
+	for i := 0; i < len(b); {
+		c := b[i]
+		if c < utf8.RuneSelf {
+			if (c < 0x20 && c != '\n' && c != '\t') || c == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(b[i:])
+		if (r == utf8.RuneError && size == 1) || unicode.IsControl(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
+
+// hiddenData matches data hidden with the hidden package, which is made of
+// control characters but mustn't be escaped so that it can still be extracted
+// or cleaned.
+var hiddenData = regexp.MustCompile("\x00[\x01-\x06\x0e-\x17]+\x00")
+
+// sanitize returns s with invalid UTF-8 replaced and control characters
+// escaped, see SetSanitize.
+func sanitize(s string) string {
+	if !sanitizing() || !needsSanitizing([]byte(s)) {
+		return s
+	}
+	var sb strings.Builder
+	sb.Grow(len(s) + 8)
+	last := 0
+	for _, loc := range hiddenData.FindAllStringIndex(s, -1) {
+		sanitizeTo(&sb, s[last:loc[0]])
+		sb.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	sanitizeTo(&sb, s[last:])
+	return sb.String()
+}
+
+func sanitizeTo(sb *strings.Builder, s string) {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			sb.WriteRune(utf8.RuneError)
+		case r == '\n' || r == '\t' || !unicode.IsControl(r):
+			sb.WriteString(s[i : i+size])
+		default:
+			// All control characters are in the Latin-1 range
+			sb.WriteString(`\x`)
+			if r < 0x10 {
+				sb.WriteByte('0')
+			}
+			sb.WriteString(strconv.FormatInt(int64(r), 16))
+		}
+		i += size
+	}
+}
+
+// sanitizeFrom sanitizes everything written to buf after position start.
+func sanitizeFrom(buf *bytes.Buffer, start int) {
+	if !sanitizing() || !needsSanitizing(buf.Bytes()[start:]) {
+		return
+	}
+	s := sanitize(string(buf.Bytes()[start:]))
+	buf.Truncate(start)
+	buf.WriteString(s)
+}
+
+// sanitizeValues returns values with string values sanitized, copying the map
+// only if something needs to change, into a map from acquireContext, in which
+// case copied is true.
+func sanitizeValues(values map[string]interface{}) (sanitized map[string]interface{}, copied bool) {
+	if !sanitizing() {
+		return values, false
+	}
+	var result map[string]interface{}
+	for key, value := range values {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if clean := sanitize(s); clean != s {
+			if result == nil {
+				result = acquireContext()
+				for k, v := range values {
+					result[k] = v
+				}
+			}
+			result[key] = clean
+		}
+	}
+	if result == nil {
+		return values, false
+	}
+	return result, true
+}
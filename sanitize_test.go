@@ -0,0 +1,34 @@
+package golog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "plain text\n\twith tabs", sanitize("plain text\n\twith tabs"))
+	assert.Equal(t, `red \x1b[31m bell \x07 nul \x00`, sanitize("red \x1b[31m bell \x07 nul \x00"))
+	assert.Equal(t, "bad � utf8 ünïcode", sanitize("bad \xff utf8 ünïcode"))
+	assert.Equal(t, `c1 \x85`, sanitize("c1 \u0085"))
+	hiddenErr := "hidden \x00\x01\x02\x00 data\x1b"
+	assert.Equal(t, "hidden \x00\x01\x02\x00 data\\x1b", sanitize(hiddenErr), "hidden data should be left alone")
+
+	SetSanitize(false)
+	assert.Equal(t, "raw \x1b", sanitize("raw \x1b"))
+	SetSanitize(true)
+}
+
+func TestSanitizedOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	out := TextOutput(buf, buf)
+	out.Debug("myprefix: ", 0, false, "DEBUG", "evil \x1b[2J", map[string]interface{}{"key": "\x00binary"})
+	assert.Contains(t, buf.String(), `evil \x1b[2J [key=\x00binary]`)
+
+	buf.Reset()
+	out = JsonOutput(buf, buf)
+	out.Debug("myprefix: ", 0, false, "DEBUG", "evil \x1b[2J", map[string]interface{}{"key": "\x00binary"})
+	assert.Contains(t, buf.String(), `"msg":"evil \\x1b[2J"`)
+	assert.Contains(t, buf.String(), `"key":"\\x00binary"`)
+}
@@ -0,0 +1,88 @@
+package golog
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// SeverityStyle is how a severity is decorated in the text output when
+// writing to a terminal.
+type SeverityStyle struct {
+	// Color is the ANSI SGR parameters to render the severity with, for
+	// example "31" for red or "1;35" for bold magenta. Empty for no color.
+	Color string
+
+	// Symbol, if set, is printed before the severity, for example "✖".
+	Symbol string
+}
+
+var (
+	severityStyles atomic.Value
+
+	// isTerminal reports whether w is a terminal, replaced in tests.
+	isTerminal = func(w io.Writer) bool {
+		f, ok := w.(*os.File)
+		if !ok {
+			return false
+		}
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+)
+
+// DefaultSeverityStyles returns a set of styles to start customizing from:
+// dim TRACE, plain DEBUG, yellow WARN, red ERROR and bold magenta FATAL, with
+// symbols for WARN and above.
+func DefaultSeverityStyles() map[string]SeverityStyle {
+	return map[string]SeverityStyle{
+		"TRACE": {Color: "2"},
+		"WARN":  {Color: "33", Symbol: "⚠"},
+		"ERROR": {Color: "31", Symbol: "✖"},
+		"FATAL": {Color: "1;35", Symbol: "‼"},
+	}
+}
+
+// SetSeverityStyles sets how severities are decorated by the text output
+// when it writes to a terminal. Severities without a style aren't decorated,
+// and nil disables decoration entirely, which is the default. Colors are left
+// out if the NO_COLOR environment variable is set, but symbols are kept. On
+// Windows, the first styled write to a console enables virtual terminal
+// processing and the UTF-8 code page for the process's console.
+func SetSeverityStyles(styles map[string]SeverityStyle) {
+	rendered := make(map[string]renderedStyle, len(styles))
+	noColor := os.Getenv("NO_COLOR") != ""
+	for severity, style := range styles {
+		var r renderedStyle
+		if style.Symbol != "" {
+			r.open = style.Symbol + " "
+		}
+		if style.Color != "" && !noColor {
+			r.open += "\x1b[" + style.Color + "m"
+			r.close = "\x1b[0m"
+		}
+		if r.open != "" {
+			rendered[severity] = r
+		}
+	}
+	severityStyles.Store(rendered)
+}
+
+type renderedStyle struct {
+	open  string
+	close string
+}
+
+// severityStyle returns the style for severity when writing to w, if any.
+func severityStyle(w io.Writer, severity string) (renderedStyle, bool) {
+	styles, _ := severityStyles.Load().(map[string]renderedStyle)
+	if len(styles) == 0 {
+		return renderedStyle{}, false
+	}
+	style, found := styles[severity]
+	if !found || !isTerminal(w) {
+		return renderedStyle{}, false
+	}
+	prepareConsole()
+	return style, true
+}
@@ -0,0 +1,50 @@
+package golog
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeverityStyles(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+	defer SetSeverityStyles(nil)
+	oldIsTerminal := isTerminal
+	defer func() {
+		isTerminal = oldIsTerminal
+	}()
+	terminal := true
+	isTerminal = func(w io.Writer) bool {
+		return terminal
+	}
+
+	styles := DefaultSeverityStyles()
+	delete(styles, "ERROR")
+	styles["DEBUG"] = SeverityStyle{Symbol: "·"}
+	SetSeverityStyles(styles)
+	l := LoggerFor("myprefix")
+	l.Debug("plain")
+	l.Error("undecorated")
+	NewEvent("WARN", "myprefix", "careful").Emit()
+	assert.Regexp(t, "^· DEBUG myprefix: severity_styles_test.go:[0-9]+ plain\n"+
+		"ERROR myprefix: severity_styles_test.go:[0-9]+ undecorated\n"+
+		"⚠ \x1b\\[33mWARN\x1b\\[0m myprefix: severity_styles_test.go:[0-9]+ careful\n$", buf.String())
+
+	buf.Reset()
+	terminal = false
+	NewEvent("WARN", "myprefix", "careful").Emit()
+	assert.Regexp(t, "^WARN myprefix: ", buf.String(), "shouldn't decorate when not writing to a terminal")
+
+	os.Setenv("NO_COLOR", "1")
+	defer os.Unsetenv("NO_COLOR")
+	SetSeverityStyles(DefaultSeverityStyles())
+	buf.Reset()
+	terminal = true
+	NewEvent("WARN", "myprefix", "careful").Emit()
+	assert.Regexp(t, "^⚠ WARN myprefix: ", buf.String())
+}
@@ -0,0 +1,107 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSeverityString(t *testing.T) {
+	for severity, name := range map[Severity]string{TRACE: "TRACE", DEBUG: "DEBUG", INFO: "INFO", WARN: "WARN", ERROR: "ERROR", FATAL: "FATAL", 42: "UNKNOWN"} {
+		assert.Equal(t, name, severity.String())
+		if name != "UNKNOWN" {
+			assert.EqualValues(t, severity, severityLevel(name))
+			level, err := parseLevel(strings.ToLower(name))
+			assert.NoError(t, err)
+			assert.EqualValues(t, severity, level)
+		}
+	}
+	_, err := parseLevel("verbose")
+	assert.EqualError(t, err, `unknown level "verbose"`)
+}
+
+func TestWarnAndInfo(t *testing.T) {
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	defer reset()
+
+	l := LoggerFor("myprefix")
+	l.Info("Hello")
+	l.Infof("Hello %v", "world")
+	l.Infow("Hello", Field{"cvarA", "a"})
+	l.Warn("Careful")
+	l.Warnf("Careful %v", "now")
+	l.Warnw("Careful", Field{"cvarA", "a"})
+	assert.Equal(t, "INFO myprefix: severity_test.go:999 Hello\n"+
+		"INFO myprefix: severity_test.go:999 Hello world\n"+
+		"INFO myprefix: severity_test.go:999 Hello [cvarA=a]\n"+
+		"WARN myprefix: severity_test.go:999 Careful\n"+
+		"WARN myprefix: severity_test.go:999 Careful now\n"+
+		"WARN myprefix: severity_test.go:999 Careful [cvarA=a]\n", normalized(out.String()))
+
+	out = newBuffer()
+	SetOutputs(out, out)
+	require.NoError(t, SetLevel("WARN"))
+	defer SetLevel("")
+	l.Info("dropped")
+	l.Warn("kept")
+	assert.Equal(t, "WARN myprefix: severity_test.go:999 kept\n", normalized(out.String()))
+}
+
+func TestWarnJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutput(JsonOutput(buf, buf))
+	defer reset()
+
+	LoggerFor("myprefix").Warnw("Careful", Field{"cvarA", "a"})
+	var event Event
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.Equal(t, "WARN", event.Severity)
+	assert.Equal(t, "Careful", event.Message)
+	assert.Equal(t, "severity_test.go:999", normalized(event.Caller))
+}
+
+func TestWarnZap(t *testing.T) {
+	buf := &bytes.Buffer{}
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(buf), zapcore.DebugLevel)
+	reset := SetOutput(ZapOutput(zap.New(core)))
+	defer reset()
+
+	l := LoggerFor("myprefix")
+	l.Info("Hello")
+	l.Warn("Careful")
+	decoder := json.NewDecoder(buf)
+	for _, expected := range []string{"info", "warn"} {
+		var entry map[string]interface{}
+		require.NoError(t, decoder.Decode(&entry))
+		assert.Equal(t, expected, entry["level"])
+	}
+}
+
+func TestReportWarnings(t *testing.T) {
+	reset := SetOutput(NewRingBuffer(nil, 10))
+	defer reset()
+
+	active := true
+	var severities []Severity
+	RegisterReporterAt(WARN, func(err error, severity Severity, ctx map[string]interface{}) {
+		if active {
+			severities = append(severities, severity)
+			assert.Equal(t, severity.String(), ctx["severity"])
+		}
+	})
+	defer func() { active = false }()
+
+	l := LoggerFor("myprefix")
+	l.Info("not reported")
+	l.Warn("reported")
+	assert.Error(t, l.Error("reported"))
+	NewEvent("WARN", "relay", "reported").Emit()
+	assert.Equal(t, []Severity{WARN, ERROR, WARN}, severities)
+}
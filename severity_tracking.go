@@ -0,0 +1,142 @@
+package golog
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/getlantern/ops"
+)
+
+var nextSeverityTrackerID uint64
+
+// eventObserver is implemented by ops context values that want to observe the
+// events logged within their op instead of being logged as context.
+type eventObserver interface {
+	observe(severity string, arg interface{}, values map[string]interface{})
+}
+
+// observe removes the eventObservers from values and notifies them of an
+// event with the given severity, arg and remaining values.
+func observe(values map[string]interface{}, severity string, arg interface{}) {
+	var observers []eventObserver
+	for key, value := range values {
+		if observer, ok := value.(eventObserver); ok {
+			observers = append(observers, observer)
+			delete(values, key)
+		}
+	}
+	for _, observer := range observers {
+		observer.observe(severity, arg, values)
+	}
+}
+
+// removeObservers removes any eventObservers from values, which must not be
+// shared, without notifying them.
+func removeObservers(values map[string]interface{}) {
+	for key, value := range values {
+		if _, ok := value.(eventObserver); ok {
+			delete(values, key)
+		}
+	}
+}
+
+// SeverityTrackingOp is an ops.Op that tracks the most severe event logged
+// within it, including within nested ops.
+type SeverityTrackingOp interface {
+	ops.Op
+
+	// MaxSeverity returns the most severe severity ("TRACE", "DEBUG", "INFO",
+	// "WARN", "ERROR" or "FATAL") logged within the op so far, or "" if
+	// nothing has been logged.
+	MaxSeverity() string
+
+	// Summarize logs message along with a max_severity field as an ERROR if
+	// an ERROR or FATAL was logged within the op, as a WARN if a WARN was, or
+	// as DEBUG otherwise. This is meant for "request completed" style summary
+	// lines.
+	Summarize(l Logger, message string, fields ...Field)
+}
+
+// TrackSeverity starts tracking the most severe event logged within op. When
+// the returned op ends, onEnd (if not nil) is called with the final maximum
+// severity and the op's context includes it as "max_severity" for ops
+// reporters. Make sure to call End on the returned Op rather than on the
+// original one.
+func TrackSeverity(op ops.Op, onEnd func(maxSeverity string)) SeverityTrackingOp {
+	t := &severityTrackingOp{Op: op, onEnd: onEnd}
+	key := fmt.Sprintf("golog_severity_tracker_%d", atomic.AddUint64(&nextSeverityTrackerID, 1))
+	op.Set(key, &severityObserver{t})
+	return t
+}
+
+type severityTrackingOp struct {
+	ops.Op
+	max   int32
+	onEnd func(string)
+}
+
+type severityObserver struct {
+	t *severityTrackingOp
+}
+
+func (o *severityObserver) observe(severity string, arg interface{}, values map[string]interface{}) {
+	level := int32(severityLevel(severity))
+	for {
+		max := atomic.LoadInt32(&o.t.max)
+		if level <= max || atomic.CompareAndSwapInt32(&o.t.max, max, level) {
+			return
+		}
+	}
+}
+
+func (t *severityTrackingOp) MaxSeverity() string {
+	max := atomic.LoadInt32(&t.max)
+	if max == 0 {
+		return ""
+	}
+	return Severity(max).String()
+}
+
+func (t *severityTrackingOp) Summarize(l Logger, message string, fields ...Field) {
+	arg := WithFields(message, append(fields, Field{"max_severity", t.MaxSeverity()})...)
+	max := atomic.LoadInt32(&t.max)
+	if ll, ok := l.(*logger); ok {
+		// log directly so that the caller is the caller of Summarize
+		switch {
+		case max >= ERROR:
+			_ = ll.errorSkipFrames(arg, 1, ERROR)
+		case max >= WARN:
+			ll.logSkipFrames(arg, 1, WARN)
+		default:
+			ll.print(getDebugOut(), 4, "DEBUG", arg)
+		}
+		return
+	}
+	switch {
+	case max >= ERROR:
+		_ = l.Error(arg)
+	case max >= WARN:
+		l.Warn(arg)
+	default:
+		l.Debug(arg)
+	}
+}
+
+func (t *severityTrackingOp) Set(key string, value interface{}) ops.Op {
+	t.Op.Set(key, value)
+	return t
+}
+
+func (t *severityTrackingOp) SetDynamic(key string, valueFN func() interface{}) ops.Op {
+	t.Op.SetDynamic(key, valueFN)
+	return t
+}
+
+func (t *severityTrackingOp) End() {
+	max := t.MaxSeverity()
+	t.Op.Set("max_severity", max)
+	if t.onEnd != nil {
+		t.onEnd(max)
+	}
+	t.Op.End()
+}
@@ -0,0 +1,52 @@
+package golog
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/getlantern/ops"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackSeverity(t *testing.T) {
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	defer reset()
+	l := LoggerFor("myprefix")
+
+	var final string
+	op := TrackSeverity(ops.Begin("request"), func(maxSeverity string) {
+		final = maxSeverity
+	})
+	assert.Equal(t, "", op.MaxSeverity())
+	l.Debug("working")
+	assert.Equal(t, "DEBUG", op.MaxSeverity())
+	nested := ops.Begin("nested")
+	l.Error("failed")
+	nested.End()
+	assert.Equal(t, "ERROR", op.MaxSeverity())
+	op.Summarize(l, "request completed")
+	op.End()
+	assert.Equal(t, "ERROR", final)
+
+	assert.Equal(t, `DEBUG myprefix: severity_tracking_test.go:999 working [op=request root_op=request]
+ERROR myprefix: severity_tracking_test.go:999 failed [op=nested root_op=request]
+ERROR myprefix: severity_tracking_test.go:999 request completed [max_severity=ERROR op=request root_op=request]
+`, out.String())
+
+	out = newBuffer()
+	SetOutputs(out, out)
+	op = TrackSeverity(ops.Begin("degraded"), nil)
+	l.Warn("retrying")
+	op.Summarize(l, "request completed")
+	op.End()
+	assert.Equal(t, `WARN myprefix: severity_tracking_test.go:999 retrying [op=degraded root_op=degraded]
+WARN myprefix: severity_tracking_test.go:999 request completed [max_severity=WARN op=degraded root_op=degraded]
+`, out.String())
+
+	SetOutputs(ioutil.Discard, ioutil.Discard)
+	op = TrackSeverity(ops.Begin("quiet"), nil)
+	op.Summarize(l, "request completed")
+	assert.Equal(t, "DEBUG", op.MaxSeverity(), "summary should count itself")
+	op.End()
+}
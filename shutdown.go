@@ -0,0 +1,148 @@
+package golog
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout bounds how long FlushOnShutdown waits in total for
+// asynchronous logging and outputs to drain.
+var shutdownTimeout = 5 * time.Second
+
+var (
+	shutdownHooks   []func()
+	shutdownHooksMx sync.Mutex
+	shutdownMx      sync.Mutex
+	shutdownDone    bool
+
+	// shutdownExit exits the process after shutting down, replaced in tests.
+	shutdownExit = osExit
+	osExit       = os.Exit
+)
+
+// OnShutdown registers fn to be run when the process shuts down because of a
+// signal handled by FlushOnShutdown, before outputs are flushed and closed.
+// Hooks run in reverse order of registration.
+func OnShutdown(fn func()) {
+	shutdownHooksMx.Lock()
+	defer shutdownHooksMx.Unlock()
+	shutdownHooks = append(shutdownHooks, fn)
+}
+
+// FlushOnShutdown installs a handler for the given signals, SIGINT and SIGTERM
+// if none are given, that runs the OnShutdown hooks, waits (for up to 5
+// seconds in all) for asynchronous logging to be written (see EnableAsync) and
+// for the current output to deliver what it has queued if it's a
+// DrainableOutput, flushes and closes it if it's a BufferingOutput or
+// ClosableOutput, and then exits with status 128 plus the signal number.
+// Outputs that pass events on to others, like MultiOutput, SamplingOutput or
+// RouterOutput, are looked through, so that the outputs they wrap are drained,
+// flushed and closed too.
+// Call the returned function to remove the handler again.
+//
+// launchd stops jobs with SIGTERM, and on Windows Go delivers SIGTERM when the
+// console is closed or the user logs off or the system shuts down, so the
+// default signals cover OS-initiated shutdowns on desktop platforms too. Note
+// that launchd kills jobs that haven't exited within their ExitTimeOut (20
+// seconds by default) and Windows kills processes about 5 seconds after a
+// shutdown event. Windows services are stopped through the service control
+// manager rather than a signal, so they should call Shutdown from their
+// handler on svc.Stop and svc.Shutdown instead.
+func FlushOnShutdown(signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, signals...)
+	go func() {
+		select {
+		case sig := <-ch:
+			signal.Stop(ch)
+			Shutdown()
+			code := 1
+			if s, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(s)
+			}
+			shutdownExit(code)
+		case <-done:
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// Shutdown runs the OnShutdown hooks and then closes asynchronous logging and
+// drains, flushes and closes the current output like FlushOnShutdown does,
+// but without exiting. It's meant for shutdowns that aren't signaled, like a
+// Windows service being stopped, and for programs that exit on their own.
+// Only the first call does anything.
+func Shutdown() {
+	shutdownMx.Lock()
+	defer shutdownMx.Unlock()
+	if shutdownDone {
+		return
+	}
+	shutdownDone = true
+
+	shutdownHooksMx.Lock()
+	hooks := make([]func(), len(shutdownHooks))
+	copy(hooks, shutdownHooks)
+	shutdownHooksMx.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+
+	// Share one deadline between everything that's waited for, so that
+	// shutting down stays within what the OS allows however many outputs
+	// there are
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	closeAsyncWithin(time.Until(deadline))
+	shutdownOutput(ctx, getOutput())
+}
+
+// shutdownOutput drains, flushes and closes out and the outputs it passes
+// events through to, like those wrapped by SamplingOutput or MultiOutput.
+// Everything is drained before anything is flushed or closed, and outputs
+// below a BufferingOutput or ClosableOutput aren't flushed or closed
+// separately since flushing or closing it is expected to take care of them.
+// Draining stops once ctx is done.
+func shutdownOutput(ctx context.Context, out Output) {
+	walkOutputs(out, func(out Output) bool {
+		if d, ok := out.(DrainableOutput); ok {
+			if err := d.WaitForDrain(ctx); err != nil {
+				errorOnLogging(err)
+			}
+		}
+		return true
+	})
+	walkOutputs(out, func(out Output) bool {
+		b, ok := out.(BufferingOutput)
+		if ok {
+			if err := b.Flush(); err != nil {
+				errorOnLogging(err)
+			}
+		}
+		return !ok
+	})
+	walkOutputs(out, func(out Output) bool {
+		c, ok := out.(ClosableOutput)
+		if ok {
+			if err := c.Close(); err != nil {
+				errorOnLogging(err)
+			}
+		}
+		return !ok
+	})
+}
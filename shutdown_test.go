@@ -0,0 +1,128 @@
+//go:build !windows && !(js && wasm)
+// +build !windows
+// +build !js !wasm
+
+package golog
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlushOnShutdown(t *testing.T) {
+	buf := newBuffer()
+	out := BufferedOutput(buf, TextOutput, 1024*1024, time.Hour)
+	reset := SetOutput(out)
+	defer reset()
+
+	exited := make(chan int, 1)
+	shutdownExit = func(code int) {
+		exited <- code
+	}
+	defer func() {
+		shutdownExit = osExit
+		resetShutdown()
+	}()
+	var hookRan bool
+	OnShutdown(func() {
+		hookRan = true
+	})
+
+	stop := FlushOnShutdown(syscall.SIGUSR1)
+	defer stop()
+	LoggerFor("myprefix").Debug("buffered")
+	assert.Empty(t, buf.String())
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	select {
+	case code := <-exited:
+		assert.Equal(t, 128+int(syscall.SIGUSR1), code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("didn't shut down")
+	}
+	assert.True(t, hookRan)
+	assert.Contains(t, buf.String(), "buffered")
+}
+
+func TestShutdown(t *testing.T) {
+	buf := &bytes.Buffer{}
+	out := BufferedOutput(buf, TextOutput, 1024*1024, time.Hour)
+	reset := SetOutput(out)
+	defer reset()
+	defer resetShutdown()
+
+	hookRuns := 0
+	OnShutdown(func() {
+		hookRuns++
+	})
+	LoggerFor("myprefix").Debug("buffered")
+	assert.Empty(t, buf.String())
+
+	Shutdown()
+	assert.Contains(t, buf.String(), "buffered")
+	Shutdown()
+	assert.Equal(t, 1, hookRuns, "only the first call should run the hooks")
+}
+
+func TestShutdownWrappedOutputs(t *testing.T) {
+	buffered, closed := &bytes.Buffer{}, &bytes.Buffer{}
+	closes := 0
+	closable := &closeRecordingOutput{Output: TextOutput(closed, closed), onClose: func() {
+		closes++
+	}}
+	out := SamplingOutput(MultiOutput(nil,
+		BufferedOutput(buffered, TextOutput, 1024*1024, time.Hour),
+		closable,
+	))
+	reset := SetOutput(out)
+	defer reset()
+	defer resetShutdown()
+
+	LoggerFor("myprefix").Debug("buffered")
+	assert.Empty(t, buffered.String())
+	Shutdown()
+	assert.Contains(t, buffered.String(), "buffered", "buffered outputs should be flushed through wrappers")
+	assert.Equal(t, 1, closes, "closable outputs should be closed through wrappers")
+}
+
+// stuckOutput is a DrainableOutput that never drains.
+type stuckOutput struct {
+	Output
+}
+
+func (o *stuckOutput) WaitForDrain(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (o *stuckOutput) Close() error {
+	return nil
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	out := MultiOutput(nil,
+		&stuckOutput{TextOutput(ioutil.Discard, ioutil.Discard)},
+		&stuckOutput{TextOutput(ioutil.Discard, ioutil.Discard)},
+		&stuckOutput{TextOutput(ioutil.Discard, ioutil.Discard)},
+	)
+	reset := SetOutput(out)
+	defer reset()
+	defer resetShutdown()
+	oldStderr := stderr
+	errs := &bytes.Buffer{}
+	stderr = errs
+	defer func() { stderr = oldStderr }()
+	defer func(timeout time.Duration) { shutdownTimeout = timeout }(shutdownTimeout)
+	shutdownTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	Shutdown()
+	assert.True(t, time.Since(start) < 2*shutdownTimeout, "all outputs should share one deadline, took %v", time.Since(start))
+	assert.Contains(t, errs.String(), "context deadline exceeded")
+}
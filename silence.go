@@ -0,0 +1,40 @@
+package golog
+
+import (
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+// disabled is 1 while logging is disabled, see Disable.
+var disabled = silentFromEnv()
+
+// silentFromEnv returns 1 if the GOLOG_SILENT environment variable is set to
+// true.
+func silentFromEnv() int32 {
+	if v, _ := strconv.ParseBool(os.Getenv("GOLOG_SILENT")); v {
+		return 1
+	}
+	return 0
+}
+
+// Disable turns every logger into a no-op, regardless of the outputs set,
+// which is useful for libraries that embed golog from polluting the output of
+// the applications that host them. It also silences the "TRACE logging is
+// enabled" notices and the errors golog reports about logging itself. Like
+// events below the level (see SetLevel), ERRORs are still sent to the
+// registered ErrorReporters. Setting the GOLOG_SILENT environment variable to
+// true disables logging from the start.
+func Disable() {
+	atomic.StoreInt32(&disabled, 1)
+}
+
+// Enable undoes Disable, including one done through GOLOG_SILENT.
+func Enable() {
+	atomic.StoreInt32(&disabled, 0)
+}
+
+// IsDisabled reports whether logging is disabled, see Disable.
+func IsDisabled() bool {
+	return atomic.LoadInt32(&disabled) == 1
+}
@@ -0,0 +1,40 @@
+package golog
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisable(t *testing.T) {
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	defer reset()
+
+	l := LoggerFor("silence")
+	Disable()
+	assert.True(t, IsDisabled())
+	l.Debug("debug")
+	assert.Error(t, l.Error("error"))
+	l.DebugStream(func(w io.Writer) { _, _ = io.WriteString(w, "stream") })
+	NewEvent("DEBUG", "silence", "emitted").Emit()
+	assert.False(t, l.IsTraceEnabled())
+	assert.Empty(t, out.String())
+
+	Enable()
+	assert.False(t, IsDisabled())
+	l.Debug("debug")
+	assert.Equal(t, "DEBUG silence: silence_test.go:999 debug\n", normalized(out.String()))
+}
+
+func TestSilentFromEnv(t *testing.T) {
+	defer os.Unsetenv("GOLOG_SILENT")
+	os.Setenv("GOLOG_SILENT", "true")
+	assert.EqualValues(t, 1, silentFromEnv())
+	os.Setenv("GOLOG_SILENT", "false")
+	assert.EqualValues(t, 0, silentFromEnv())
+	os.Setenv("GOLOG_SILENT", "bogus")
+	assert.EqualValues(t, 0, silentFromEnv())
+}
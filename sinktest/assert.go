@@ -0,0 +1,97 @@
+package sinktest
+
+import (
+	"testing"
+	"time"
+)
+
+// AssertBatchSizes checks that no batch is empty or holds more than max items.
+func AssertBatchSizes(t testing.TB, batches [][]string, max int) bool {
+	t.Helper()
+	ok := true
+	for i, batch := range batches {
+		if len(batch) == 0 || len(batch) > max {
+			t.Errorf("batch %d has %d items, expected between 1 and %d", i, len(batch), max)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// AssertDeliveredOnce checks that the batches include every expected item
+// exactly once and nothing else, in any order, as should be the case once
+// retries are done.
+func AssertDeliveredOnce(t testing.TB, expected []string, batches [][]string) bool {
+	t.Helper()
+	counts := make(map[string]int)
+	for _, batch := range batches {
+		for _, item := range batch {
+			counts[item]++
+		}
+	}
+	ok := true
+	for _, item := range expected {
+		switch counts[item] {
+		case 1:
+		case 0:
+			t.Errorf("%q was never delivered", item)
+			ok = false
+		default:
+			t.Errorf("%q was delivered %d times", item, counts[item])
+			ok = false
+		}
+		delete(counts, item)
+	}
+	for item := range counts {
+		t.Errorf("unexpected item %q was delivered", item)
+		ok = false
+	}
+	return ok
+}
+
+// AssertOrdered checks that the items in the batches are in the same order as
+// expected, which may contain items that weren't delivered.
+func AssertOrdered(t testing.TB, expected []string, batches [][]string) bool {
+	t.Helper()
+	positions := make(map[string]int, len(expected))
+	for i, item := range expected {
+		positions[item] = i
+	}
+	last, lastItem := -1, ""
+	for _, batch := range batches {
+		for _, item := range batch {
+			position, found := positions[item]
+			if !found {
+				continue
+			}
+			if position < last {
+				t.Errorf("%q was delivered after %q", item, lastItem)
+				return false
+			}
+			last, lastItem = position, item
+		}
+	}
+	return true
+}
+
+// AssertBackoff checks that the delays between consecutive attempts, made at
+// the given times, are at least min and never shrink by more than the given
+// jitter fraction (like 0.5 for delays that are randomized by up to half).
+func AssertBackoff(t testing.TB, attempts []time.Time, min time.Duration, jitter float64) bool {
+	t.Helper()
+	ok := true
+	var previous time.Duration
+	for i := 1; i < len(attempts); i++ {
+		delay := attempts[i].Sub(attempts[i-1])
+		if delay < min {
+			t.Errorf("attempt %d came %v after the previous one, expected at least %v", i, delay, min)
+			ok = false
+		}
+		if float64(delay) < float64(previous)*(1-jitter) {
+			t.Errorf("attempt %d came %v after the previous one, less than the previous delay of %v", i, delay, previous)
+			ok = false
+		}
+		previous = delay
+	}
+	return ok
+}
@@ -0,0 +1,72 @@
+package sinktest
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is a controllable golog.Clock, for testing outputs that take a clock
+// (like golog's Elasticsearch and spool outputs) instead of using the time
+// package directly. Time only passes when Advance is called.
+type Clock struct {
+	mx      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewClock creates a Clock starting at start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.now
+}
+
+// After is like time.After, firing once the clock has been advanced by d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, &waiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Waiters returns the number of pending After channels, so that tests can
+// wait for the code under test to start waiting before advancing the clock.
+func (c *Clock) Waiters() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return len(c.waiters)
+}
+
+// Advance moves the clock forward by d, firing the After channels that are
+// due, in order.
+func (c *Clock) Advance(d time.Duration) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.now = c.now.Add(d)
+	sort.Slice(c.waiters, func(i, j int) bool { return c.waiters[i].at.Before(c.waiters[j].at) })
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
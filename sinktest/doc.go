@@ -0,0 +1,5 @@
+// Package sinktest provides tools for testing custom golog Outputs the way
+// golog tests its own: a controllable Clock, writers that fail or are slow,
+// a Recorder Output, a scripted HTTP Server and assertions for batching and
+// retry behavior.
+package sinktest
@@ -0,0 +1,101 @@
+package sinktest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/getlantern/golog"
+)
+
+// Event is an event received by a Recorder.
+type Event struct {
+	Component string
+	Severity  string
+	Message   string
+	Error     bool
+	Context   map[string]interface{}
+}
+
+// Recorder is a golog.Output that records the events it receives, for
+// testing outputs that wrap other outputs.
+type Recorder struct {
+	mx     sync.Mutex
+	cond   *sync.Cond
+	events []*Event
+}
+
+// NewRecorder creates a Recorder.
+func NewRecorder() *Recorder {
+	r := &Recorder{}
+	r.cond = sync.NewCond(&r.mx)
+	return r
+}
+
+var _ golog.Output = (*Recorder)(nil)
+
+func (r *Recorder) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	r.record(true, prefix, severity, arg, values)
+}
+
+func (r *Recorder) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	r.record(false, prefix, severity, arg, values)
+}
+
+func (r *Recorder) record(isError bool, prefix string, severity string, arg interface{}, values map[string]interface{}) {
+	context := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		context[key] = value
+	}
+	message := ""
+	switch a := arg.(type) {
+	case error:
+		message = a.Error()
+	case interface{ String() string }:
+		message = a.String()
+	case string:
+		message = a
+	}
+	event := &Event{
+		Component: trimPrefix(prefix),
+		Severity:  severity,
+		Message:   message,
+		Error:     isError,
+		Context:   context,
+	}
+	r.mx.Lock()
+	r.events = append(r.events, event)
+	r.cond.Broadcast()
+	r.mx.Unlock()
+}
+
+func trimPrefix(prefix string) string {
+	if len(prefix) >= 2 && prefix[len(prefix)-2:] == ": " {
+		return prefix[:len(prefix)-2]
+	}
+	return prefix
+}
+
+// Events returns the events recorded so far.
+func (r *Recorder) Events() []*Event {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return append([]*Event(nil), r.events...)
+}
+
+// WaitFor waits up to timeout for at least n events to have been recorded,
+// returning the events recorded by then.
+func (r *Recorder) WaitFor(n int, timeout time.Duration) []*Event {
+	timer := time.AfterFunc(timeout, func() {
+		r.mx.Lock()
+		r.cond.Broadcast()
+		r.mx.Unlock()
+	})
+	defer timer.Stop()
+	deadline := time.Now().Add(timeout)
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	for len(r.events) < n && time.Now().Before(deadline) {
+		r.cond.Wait()
+	}
+	return append([]*Event(nil), r.events...)
+}
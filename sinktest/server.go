@@ -0,0 +1,77 @@
+package sinktest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// Request is a request received by a Server.
+type Request struct {
+	Time   time.Time
+	Header http.Header
+	Body   []byte
+	// Status is the status with which the request was answered.
+	Status int
+}
+
+// Server is an HTTP server that answers requests with a scripted sequence of
+// statuses, for testing how outputs retry and back off.
+type Server struct {
+	*httptest.Server
+	mx       sync.Mutex
+	statuses []int
+	requests []*Request
+}
+
+// NewServer starts a Server that answers the first requests with the given
+// statuses, in order, and all further requests with 200 OK. Close it when
+// done.
+func NewServer(statuses ...int) *Server {
+	s := &Server{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *Server) handle(resp http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	s.mx.Lock()
+	status := http.StatusOK
+	if len(s.requests) < len(s.statuses) {
+		status = s.statuses[len(s.requests)]
+	}
+	s.requests = append(s.requests, &Request{Time: time.Now(), Header: req.Header, Body: body, Status: status})
+	s.mx.Unlock()
+	resp.WriteHeader(status)
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []*Request {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return append([]*Request(nil), s.requests...)
+}
+
+// Accepted returns the bodies of the requests that were answered with a 2xx
+// status.
+func (s *Server) Accepted() [][]byte {
+	var bodies [][]byte
+	for _, req := range s.Requests() {
+		if req.Status >= 200 && req.Status < 300 {
+			bodies = append(bodies, req.Body)
+		}
+	}
+	return bodies
+}
+
+// Times returns the times at which requests were received, for use with
+// AssertBackoff.
+func (s *Server) Times() []time.Time {
+	var times []time.Time
+	for _, req := range s.Requests() {
+		times = append(times, req.Time)
+	}
+	return times
+}
@@ -0,0 +1,382 @@
+package sinktest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getlantern/golog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+	late := clock.After(2 * time.Second)
+	early := clock.After(time.Second)
+	assert.Equal(t, 2, clock.Waiters())
+
+	clock.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), <-early)
+	select {
+	case <-late:
+		t.Fatal("late shouldn't have fired yet")
+	default:
+	}
+	clock.Advance(time.Second)
+	assert.Equal(t, start.Add(2*time.Second), <-late)
+	assert.Equal(t, 0, clock.Waiters())
+	assert.Equal(t, start.Add(2*time.Second), clock.Now())
+}
+
+func TestFailingWriter(t *testing.T) {
+	w := &FailingWriter{FailFirst: 2, FailEvery: 4}
+	var errs []error
+	for i := 0; i < 5; i++ {
+		_, err := fmt.Fprintf(w, "%d", i)
+		errs = append(errs, err)
+	}
+	assert.Equal(t, []error{ErrSimulated, ErrSimulated, nil, ErrSimulated, nil}, errs)
+	assert.Equal(t, "24", w.String())
+	assert.Equal(t, 5, w.Writes())
+	assert.Equal(t, 3, w.Failures())
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	reset := golog.SetOutput(r)
+	defer reset()
+	go golog.LoggerFor("mysink").Debugw("hello", golog.Field{Key: "k", Value: 1})
+	events := r.WaitFor(1, 5*time.Second)
+	require.Len(t, events, 1)
+	assert.Equal(t, "mysink", events[0].Component)
+	assert.Equal(t, "DEBUG", events[0].Severity)
+	assert.Equal(t, "hello", events[0].Message)
+	assert.Equal(t, 1, events[0].Context["k"])
+	assert.Len(t, r.WaitFor(2, 10*time.Millisecond), 1)
+}
+
+// TestElasticsearchRetries shows how the kit is meant to be used, against one
+// of golog's own outputs.
+func TestElasticsearchRetries(t *testing.T) {
+	srv := NewServer(http.StatusTooManyRequests, http.StatusServiceUnavailable)
+	defer srv.Close()
+
+	out := golog.ElasticsearchOutput(&golog.ElasticsearchOptions{URL: srv.URL, FlushInterval: 10 * time.Millisecond})
+	reset := golog.SetOutput(out)
+	log := golog.LoggerFor("mysink")
+	expected := []string{"one", "two", "three"}
+	for _, msg := range expected {
+		log.Debug(msg)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for len(srv.Accepted()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, out.Close())
+	reset()
+
+	var batches [][]string
+	for _, body := range srv.Accepted() {
+		var batch []string
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for scanner.Scan() {
+			var doc struct {
+				Message string `json:"msg"`
+			}
+			if json.Unmarshal(scanner.Bytes(), &doc) == nil && doc.Message != "" {
+				batch = append(batch, doc.Message)
+			}
+		}
+		batches = append(batches, batch)
+	}
+	AssertBatchSizes(t, batches, 500)
+	AssertDeliveredOnce(t, expected, batches)
+	AssertOrdered(t, expected, batches)
+	AssertBackoff(t, srv.Times(), 0, 0.5)
+	assert.Len(t, srv.Requests(), 3)
+}
+
+// waitUntil polls cond for up to 5 seconds.
+func waitUntil(t *testing.T, cond func() bool, msg string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestElasticsearchClock(t *testing.T) {
+	srv := NewServer(http.StatusTooManyRequests)
+	defer srv.Close()
+	clock := NewClock(time.Now())
+
+	out := golog.ElasticsearchOutput(&golog.ElasticsearchOptions{
+		URL:           srv.URL,
+		FlushInterval: time.Minute,
+		Backoff:       &golog.BackoffOptions{BaseDelay: 10 * time.Second, MaxDelay: 10 * time.Second},
+		Clock:         clock,
+	})
+	defer out.Close()
+	out.Debug("mysink: ", 0, false, "DEBUG", "one", nil)
+	waitUntil(t, func() bool { return clock.Waiters() == 1 }, "the output should wait for the flush interval")
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, srv.Requests(), "nothing should be sent before the flush interval")
+
+	clock.Advance(time.Minute)
+	waitUntil(t, func() bool { return len(srv.Requests()) == 1 && clock.Waiters() == 2 }, "the output should back off after a 429")
+	clock.Advance(9 * time.Second)
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, srv.Requests(), 1, "the output should wait for the backoff delay")
+
+	clock.Advance(time.Second)
+	waitUntil(t, func() bool { return clock.Waiters() == 1 }, "the output should wait for the next flush")
+	clock.Advance(50 * time.Second)
+	waitUntil(t, func() bool { return len(srv.Accepted()) == 1 }, "the event should be retried at the next flush")
+	assert.Contains(t, string(srv.Accepted()[0]), `"delivered_late":true`)
+}
+
+// TestElasticsearchOutput checks what golog's Elasticsearch output sends, with
+// the backoff driven by a Clock.
+func TestElasticsearchOutput(t *testing.T) {
+	srv := NewServer(http.StatusTooManyRequests)
+	defer srv.Close()
+	clock := NewClock(time.Now())
+
+	out := golog.ElasticsearchOutput(&golog.ElasticsearchOptions{
+		URL:           srv.URL,
+		Index:         "logs-{component}",
+		FlushInterval: time.Minute,
+		Backoff:       &golog.BackoffOptions{BaseDelay: time.Second, MaxDelay: time.Second},
+		Clock:         clock,
+	})
+	reset := golog.SetOutput(out)
+	defer reset()
+
+	log := golog.LoggerFor("MyPrefix")
+	log.Debug("Hello world")
+	log.Error("Oh no")
+	waitUntil(t, func() bool { return clock.Waiters() == 1 }, "the output should wait for the flush interval")
+	clock.Advance(time.Minute)
+	waitUntil(t, func() bool { return len(srv.Requests()) == 1 && clock.Waiters() == 2 }, "the output should back off after a 429")
+	clock.Advance(time.Second)
+	waitUntil(t, func() bool { return clock.Waiters() == 1 }, "the output should wait for the next flush")
+	clock.Advance(time.Minute)
+	waitUntil(t, func() bool { return len(srv.Accepted()) == 1 }, "the first request should have been retried")
+	require.NoError(t, out.Close())
+
+	var indices, messages []string
+	var late []bool
+	scanner := bufio.NewScanner(bytes.NewReader(srv.Accepted()[0]))
+	for scanner.Scan() {
+		var action struct {
+			Index struct {
+				Index string `json:"_index"`
+			} `json:"index"`
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &action))
+		indices = append(indices, action.Index.Index)
+		require.True(t, scanner.Scan())
+		var doc struct {
+			Message       string `json:"msg"`
+			DeliveredLate bool   `json:"delivered_late"`
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &doc))
+		messages = append(messages, doc.Message)
+		late = append(late, doc.DeliveredLate)
+	}
+	assert.Len(t, srv.Requests(), 2)
+	assert.Equal(t, []string{"logs-myprefix", "logs-myprefix"}, indices)
+	assert.Equal(t, []string{"Hello world", "Oh no"}, messages)
+	assert.Equal(t, []bool{true, true}, late, "retried events should be marked as delivered late")
+}
+
+func TestSpoolOutputClock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinktest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	spool, err := golog.OpenSpool(&golog.SpoolOptions{Dir: dir})
+	require.NoError(t, err)
+	clock := NewClock(time.Now())
+
+	var mx sync.Mutex
+	var attempts []time.Time
+	var delivered []*golog.SpooledEvent
+	deliver := func(ctx context.Context, events []*golog.SpooledEvent) error {
+		mx.Lock()
+		defer mx.Unlock()
+		attempts = append(attempts, clock.Now())
+		if len(attempts) == 1 {
+			return ErrSimulated
+		}
+		delivered = append(delivered, events...)
+		return nil
+	}
+	countAttempts := func() int {
+		mx.Lock()
+		defer mx.Unlock()
+		return len(attempts)
+	}
+	out := golog.SpoolOutputWithOptions(context.Background(), spool, deliver, &golog.SpoolOutputOptions{RetryInterval: time.Minute, Clock: clock})
+	defer out.Close()
+
+	out.Debug("mysink: ", 0, false, "DEBUG", "one", nil)
+	waitUntil(t, func() bool { return countAttempts() == 1 }, "the event should be delivered right away")
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, countAttempts(), "failed deliveries should wait for the retry interval")
+
+	clock.Advance(time.Minute)
+	waitUntil(t, func() bool { return countAttempts() == 2 }, "the delivery should be retried after the retry interval")
+	mx.Lock()
+	defer mx.Unlock()
+	AssertBackoff(t, attempts, time.Minute, 0)
+	require.Len(t, delivered, 1)
+	assert.Equal(t, "one", delivered[0].Message)
+	assert.True(t, delivered[0].DeliveredLate)
+}
+
+type recordingT struct {
+	*testing.T
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertions(t *testing.T) {
+	rt := &recordingT{T: t}
+	assert.False(t, AssertBatchSizes(rt, [][]string{{"a", "b", "c"}, {}}, 2))
+	assert.Len(t, rt.errors, 2)
+
+	rt.errors = nil
+	assert.False(t, AssertDeliveredOnce(rt, []string{"a", "b", "c"}, [][]string{{"a", "a"}, {"d"}}))
+	assert.ElementsMatch(t, []string{`"a" was delivered 2 times`, `"b" was never delivered`, `"c" was never delivered`, `unexpected item "d" was delivered`}, rt.errors)
+
+	rt.errors = nil
+	assert.False(t, AssertOrdered(rt, []string{"a", "b"}, [][]string{{"b"}, {"a"}}))
+	assert.Equal(t, []string{`"a" was delivered after "b"`}, rt.errors)
+
+	start := time.Now()
+	rt.errors = nil
+	assert.True(t, AssertBackoff(rt, []time.Time{start, start.Add(time.Second), start.Add(3 * time.Second)}, time.Second, 0))
+	assert.False(t, AssertBackoff(rt, []time.Time{start, start.Add(4 * time.Second), start.Add(5 * time.Second)}, time.Second, 0.5))
+	assert.Len(t, rt.errors, 1)
+}
+
+func TestBatcher(t *testing.T) {
+	clock := NewClock(time.Now())
+	var mx sync.Mutex
+	var batches [][]string
+	b := golog.NewBatcher(&golog.BatcherOptions{
+		MaxEvents: 3,
+		MaxBytes:  10,
+		MaxAge:    time.Minute,
+		Clock:     clock,
+		Flush: func(batch [][]byte, reason golog.FlushReason) error {
+			mx.Lock()
+			defer mx.Unlock()
+			var events []string
+			for _, event := range batch {
+				events = append(events, string(event))
+			}
+			batches = append(batches, events)
+			if reason == golog.FlushAge {
+				return errors.New("fail")
+			}
+			return nil
+		},
+	})
+	flushed := func() int {
+		mx.Lock()
+		defer mx.Unlock()
+		return len(batches)
+	}
+
+	b.Add([]byte("a"))
+	b.Add([]byte("b"))
+	b.Add([]byte("c"))
+	b.Add([]byte("dddddddd"))
+	b.Add([]byte("eee"))
+	clock.Advance(59 * time.Second)
+	assert.Equal(t, 2, flushed(), "batch shouldn't be flushed before MaxAge")
+	clock.Advance(time.Second)
+	waitUntil(t, func() bool { return flushed() == 3 }, "aged batch not flushed")
+	b.Add([]byte("f"))
+	assert.NoError(t, b.Close())
+	b.Add([]byte("dropped"))
+
+	mx.Lock()
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"dddddddd"}, {"eee"}, {"f"}}, batches)
+	mx.Unlock()
+
+	stats := b.Stats()
+	assert.EqualValues(t, 4, stats.Batches)
+	assert.EqualValues(t, 6, stats.Events)
+	assert.EqualValues(t, 15, stats.Bytes)
+	assert.Equal(t, 3, stats.MaxBatchEvents)
+	assert.Equal(t, map[golog.FlushReason]uint64{golog.FlushFull: 2, golog.FlushAge: 1, golog.FlushExplicit: 1}, stats.Reasons)
+	assert.EqualValues(t, 1, stats.Errors)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := NewClock(time.Now())
+	cb := golog.NewCircuitBreaker(&golog.CircuitBreakerOptions{FailureThreshold: 2, ResetTimeout: 30 * time.Second, Clock: clock})
+	assert.True(t, cb.Allow())
+	cb.Failure()
+	assert.True(t, cb.Allow())
+	cb.Failure()
+	assert.True(t, cb.Open())
+	assert.False(t, cb.Allow())
+
+	clock.Advance(29 * time.Second)
+	assert.False(t, cb.Allow(), "shouldn't allow attempts before the reset timeout")
+	clock.Advance(time.Second)
+	assert.True(t, cb.Allow(), "should allow a trial attempt after reset timeout")
+	assert.False(t, cb.Allow(), "should allow only one trial attempt")
+	cb.Success()
+	assert.False(t, cb.Open())
+	assert.True(t, cb.Allow())
+}
+
+func TestRetryClock(t *testing.T) {
+	clock := NewClock(time.Now())
+	backoff := golog.NewBackoff(&golog.BackoffOptions{BaseDelay: time.Minute, Clock: clock})
+	var mx sync.Mutex
+	var attempts []time.Time
+	done := make(chan error, 1)
+	go func() {
+		done <- golog.Retry(backoff, nil, nil, func() error {
+			mx.Lock()
+			defer mx.Unlock()
+			attempts = append(attempts, clock.Now())
+			if len(attempts) < 3 {
+				return errors.New("fail")
+			}
+			return nil
+		})
+	}()
+	for i := 0; i < 2; i++ {
+		waitUntil(t, func() bool { return clock.Waiters() == 1 }, "Retry isn't waiting for the clock")
+		clock.Advance(time.Duration(1<<uint(i)) * time.Minute)
+	}
+	require.NoError(t, <-done)
+	mx.Lock()
+	defer mx.Unlock()
+	AssertBackoff(t, attempts, time.Minute, 0)
+	assert.Len(t, attempts, 3)
+}
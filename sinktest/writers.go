@@ -0,0 +1,88 @@
+package sinktest
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSimulated is the error returned by FailingWriter when it isn't given one.
+var ErrSimulated = errors.New("simulated failure")
+
+// FailingWriter is an io.Writer that fails according to a schedule and
+// otherwise records what's written to it.
+type FailingWriter struct {
+	// FailFirst is the number of writes that fail before writes start
+	// succeeding.
+	FailFirst int
+
+	// FailEvery, if positive, additionally fails every FailEvery-th write.
+	FailEvery int
+
+	// Err is the error returned by failed writes. Defaults to ErrSimulated.
+	Err error
+
+	mx       sync.Mutex
+	writes   int
+	failures int
+	buf      bytes.Buffer
+}
+
+func (w *FailingWriter) Write(p []byte) (int, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	w.writes++
+	if w.writes <= w.FailFirst || (w.FailEvery > 0 && w.writes%w.FailEvery == 0) {
+		w.failures++
+		if w.Err != nil {
+			return 0, w.Err
+		}
+		return 0, ErrSimulated
+	}
+	return w.buf.Write(p)
+}
+
+// Writes returns the number of attempted writes.
+func (w *FailingWriter) Writes() int {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.writes
+}
+
+// Failures returns the number of failed writes.
+func (w *FailingWriter) Failures() int {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.failures
+}
+
+// String returns what was successfully written.
+func (w *FailingWriter) String() string {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.buf.String()
+}
+
+// SlowWriter is an io.Writer that takes Delay for every write, simulating a
+// slow disk or network, and otherwise records what's written to it.
+type SlowWriter struct {
+	Delay time.Duration
+
+	mx  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *SlowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.Delay)
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.buf.Write(p)
+}
+
+// String returns what was written.
+func (w *SlowWriter) String() string {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.buf.String()
+}
@@ -0,0 +1,210 @@
+//go:build go1.21
+// +build go1.21
+
+package golog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// slogLogger is the part of Logger that needs log/slog.
+type slogLogger interface {
+	// AsSlogLogger returns a standard structured logger that logs through
+	// this Logger, keeping attributes as fields. Records below slog's
+	// LevelDebug are logged as TRACE (only if tracing is enabled), LevelInfo
+	// as INFO, LevelWarn as WARN and LevelError and above as ERROR, and sent
+	// to the registered ErrorReporters that want their severity.
+	AsSlogLogger() *slog.Logger
+}
+
+// Levels for severities that slog doesn't have.
+const (
+	slogLevelTrace = slog.LevelDebug - 4
+	slogLevelFatal = slog.LevelError + 4
+)
+
+// SlogOutput creates an output that emits events through the given slog
+// Handler. The prefix becomes the "component" attribute and the event's
+// context the other attributes, sorted by key. TRACE events are emitted at
+// slog.LevelDebug-4 and FATAL ones at slog.LevelError+4.
+func SlogOutput(handler slog.Handler) Output {
+	return &slogOutput{handler: handler}
+}
+
+type slogOutput struct {
+	handler slog.Handler
+}
+
+func (o *slogOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.handle(prefix, skipFrames, printStack, severity, arg, values)
+}
+
+func (o *slogOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.handle(prefix, skipFrames, printStack, severity, arg, values)
+}
+
+func (o *slogOutput) handle(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	ctx := context.Background()
+	level := slogLevel(severity)
+	if !o.handler.Enabled(ctx, level) {
+		return
+	}
+	pc := make([]uintptr, 10)
+	n := runtime.Callers(skipFrames-1, pc)
+	record := slog.NewRecord(eventTime(arg), level, sanitize(argToString(arg)), 0)
+	if n > 0 && callerOverride(arg) == "" {
+		record.PC = pc[0]
+	}
+	record.AddAttrs(slog.String("component", strings.TrimSuffix(prefix, ": ")))
+	if override := callerOverride(arg); override != "" {
+		record.AddAttrs(slog.String("caller", override))
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		record.AddAttrs(slog.Any(key, values[key]))
+	}
+	stack := stackOverride(arg)
+	if stack == "" && printStack && n > 0 {
+		buf := getBuffer()
+		_ = writeStack(buf, pc[:n])
+		stack = buf.String()
+		returnBuffer(buf)
+	}
+	if stack != "" {
+		record.AddAttrs(slog.String("stack", stack))
+	}
+	if err := o.handler.Handle(ctx, record); err != nil {
+		errorOnLogging(err)
+	}
+}
+
+// slogLevel returns the slog level for the given severity.
+func slogLevel(severity string) slog.Level {
+	switch severity {
+	case "TRACE":
+		return slogLevelTrace
+	case "DEBUG":
+		return slog.LevelDebug
+	case "INFO":
+		return slog.LevelInfo
+	case "WARN":
+		return slog.LevelWarn
+	case "FATAL":
+		return slogLevelFatal
+	default:
+		return slog.LevelError
+	}
+}
+
+// slogSeverity returns the severity to log a record at the given slog level
+// with.
+func slogSeverity(level slog.Level) string {
+	switch {
+	case level < slog.LevelDebug:
+		return "TRACE"
+	case level < slog.LevelInfo:
+		return "DEBUG"
+	case level < slog.LevelWarn:
+		return "INFO"
+	case level < slog.LevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+func (l *logger) AsSlogLogger() *slog.Logger {
+	return slog.New(&slogHandler{l: l})
+}
+
+// slogHandler is a slog.Handler that logs through a golog logger.
+type slogHandler struct {
+	l *logger
+	// fields are the attributes added with WithAttrs, already qualified with
+	// their groups
+	fields []Field
+	// group is the qualifier for attribute keys, made of the groups opened
+	// with WithGroup, each followed by a dot
+	group string
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	severity := slogSeverity(level)
+	if severity == "TRACE" {
+		return h.l.IsTraceEnabled()
+	}
+	return !IsDisabled() && levelEnabled(severity)
+}
+
+func (h *slogHandler) Handle(_ context.Context, record slog.Record) error {
+	severity := slogSeverity(record.Level)
+	if severity == "TRACE" && !h.l.IsTraceEnabled() {
+		return nil
+	}
+	fields := make([]Field, 0, len(h.fields)+record.NumAttrs())
+	fields = append(fields, h.fields...)
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.group, attr)
+		return true
+	})
+	arg := &injectedArg{
+		fieldsArg: fieldsArg{arg: record.Message, fields: fields},
+		ts:        record.Time,
+	}
+	if record.PC != 0 {
+		arg.caller, arg.pc = frameLocation([]uintptr{record.PC}), record.PC
+	}
+	if severity == "ERROR" {
+		h.l.print(getErrorOut(), 4, severity, arg.asArg())
+	} else {
+		h.l.print(getDebugOut(), 4, severity, arg.asArg())
+	}
+	report(arg, Severity(severityLevel(severity)), h.l.prefix)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	child := *h
+	child.fields = make([]Field, 0, len(h.fields)+len(attrs))
+	child.fields = append(child.fields, h.fields...)
+	for _, attr := range attrs {
+		child.fields = appendSlogAttr(child.fields, h.group, attr)
+	}
+	return &child
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	child := *h
+	child.group = h.group + name + "."
+	return &child
+}
+
+// appendSlogAttr appends attr to fields, flattening groups into dotted keys
+// and dropping empty attributes as slog handlers should.
+func appendSlogAttr(fields []Field, group string, attr slog.Attr) []Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			group += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			fields = appendSlogAttr(fields, group, member)
+		}
+		return fields
+	}
+	return append(fields, Field{group + attr.Key, attr.Value.Any()})
+}
@@ -0,0 +1,7 @@
+//go:build !go1.21
+// +build !go1.21
+
+package golog
+
+// slogLogger is empty before Go 1.21, which has no log/slog.
+type slogLogger interface{}
@@ -0,0 +1,109 @@
+//go:build go1.21
+// +build go1.21
+
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/getlantern/ops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogOutput(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := slog.NewJSONHandler(buf, &slog.HandlerOptions{AddSource: true, Level: slogLevelTrace})
+	reset := SetOutput(SlogOutput(handler))
+	defer reset()
+
+	op := ops.Begin("slog_op").Set("cvarA", "a")
+	defer op.End()
+	l := LoggerFor("myprefix")
+	l.Debugw("Hello world", Field{"n", 5})
+	assert.Error(t, l.Error("Oh no"))
+
+	decoder := json.NewDecoder(buf)
+	var debug, errorEvent map[string]interface{}
+	require.NoError(t, decoder.Decode(&debug))
+	require.NoError(t, decoder.Decode(&errorEvent))
+	assert.Equal(t, "DEBUG", debug["level"])
+	assert.Equal(t, "Hello world", debug["msg"])
+	assert.Equal(t, "myprefix", debug["component"])
+	assert.Equal(t, "a", debug["cvarA"])
+	assert.EqualValues(t, 5, debug["n"])
+	if source, ok := debug["source"].(map[string]interface{}); assert.True(t, ok) {
+		assert.Contains(t, source["file"], "slog_test.go")
+		assert.Contains(t, source["function"], "TestSlogOutput")
+	}
+	assert.Equal(t, "ERROR", errorEvent["level"])
+	assert.Equal(t, "Oh no", errorEvent["msg"])
+}
+
+func TestSlogOutputLevels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutput(SlogOutput(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelError})))
+	defer reset()
+
+	LoggerFor("myprefix").Debug("dropped")
+	assert.Empty(t, buf.String())
+	NewEvent("DEBUG", "relay", "from elsewhere").Caller("remote.c:12").Emit()
+	assert.Empty(t, buf.String())
+	NewEvent("FATAL", "relay", "from elsewhere").Caller("remote.c:12").Emit()
+	assert.Contains(t, buf.String(), "level=ERROR+4")
+	assert.Contains(t, buf.String(), "caller=remote.c:12")
+}
+
+func TestAsSlogLogger(t *testing.T) {
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	defer reset()
+
+	l := ChildLogger(LoggerFor("myprefix"), Field{"bound", 1})
+	sl := l.AsSlogLogger().With("conn", 7).WithGroup("req")
+	sl.Info("Hello world", "path", "/", slog.Group("user", "id", 3))
+	sl.Debug("Debugging")
+	sl.Log(nil, slogLevelTrace, "not tracing")
+	sl.Error("Oh no", "code", 500)
+	assert.Equal(t,
+		"INFO myprefix: slog_test.go:999 Hello world [bound=999 conn=999 req.path=/ req.user.id=999]\n"+
+			"DEBUG myprefix: slog_test.go:999 Debugging [bound=999 conn=999]\n"+
+			"ERROR myprefix: slog_test.go:999 Oh no [bound=999 conn=999 req.code=999]\n",
+		normalized(out.String()))
+}
+
+func TestAsSlogLoggerLevel(t *testing.T) {
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	defer reset()
+	require.NoError(t, SetLevel("WARN"))
+	defer SetLevel("")
+
+	sl := LoggerFor("myprefix").AsSlogLogger()
+	assert.False(t, sl.Enabled(nil, slog.LevelInfo))
+	assert.True(t, sl.Enabled(nil, slog.LevelWarn))
+	sl.Info("dropped")
+	sl.Warn("Careful")
+	assert.Equal(t, "WARN myprefix: slog_test.go:999 Careful\n", normalized(out.String()))
+}
+
+func TestAsSlogLoggerSampled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutput(JsonOutput(buf, buf))
+	defer reset()
+
+	sl := SampledLogger(LoggerFor("myprefix"), 2).AsSlogLogger()
+	for i := 0; i < 4; i++ {
+		// Both records share a line, but not a program counter
+		func() { sl.Info("first"); sl.Info("second") }()
+	}
+	assert.Equal(t, []string{
+		"first 0 true",
+		"second 0 true",
+		"first 1 true",
+		"second 1 true",
+	}, sampledEvents(t, buf), "records should be sampled by their own call site")
+}
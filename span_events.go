@@ -0,0 +1,50 @@
+package golog
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/getlantern/ops"
+)
+
+var nextSpanID uint64
+
+// Span is the part of an OpenTelemetry span on which golog records log
+// events. To use a go.opentelemetry.io/otel/trace.Span, wrap it so that
+// AddEvent converts the attributes to attribute.KeyValues and SetError calls
+// SetStatus(codes.Error, description).
+type Span interface {
+	// AddEvent records an event on the span.
+	AddEvent(name string, attributes map[string]interface{})
+
+	// SetError marks the span as failed.
+	SetError(description string)
+}
+
+// WithSpan associates span with op, so that everything logged within op,
+// including within nested ops, is also recorded as a "log" event on span with
+// the message, severity and context as attributes. ERROR and FATAL events
+// additionally mark the span as failed, so traces carry the error details.
+// The span isn't logged as part of the context.
+func WithSpan(op ops.Op, span Span) ops.Op {
+	key := fmt.Sprintf("golog_span_%d", atomic.AddUint64(&nextSpanID, 1))
+	return op.Set(key, &spanObserver{span})
+}
+
+type spanObserver struct {
+	span Span
+}
+
+func (o *spanObserver) observe(severity string, arg interface{}, values map[string]interface{}) {
+	message := argToString(arg)
+	attributes := make(map[string]interface{}, len(values)+2)
+	for key, value := range values {
+		attributes[key] = value
+	}
+	attributes["log.severity"] = severity
+	attributes["log.message"] = message
+	o.span.AddEvent("log", attributes)
+	if severityLevel(severity) >= ERROR {
+		o.span.SetError(message)
+	}
+}
@@ -0,0 +1,45 @@
+package golog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/getlantern/ops"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingSpan struct {
+	events []map[string]interface{}
+	err    string
+}
+
+func (s *recordingSpan) AddEvent(name string, attributes map[string]interface{}) {
+	s.events = append(s.events, attributes)
+}
+
+func (s *recordingSpan) SetError(description string) {
+	s.err = description
+}
+
+func TestWithSpan(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	span := &recordingSpan{}
+	op := WithSpan(ops.Begin("spanned"), span)
+	l := LoggerFor("myprefix")
+	l.Debug("hello")
+	_ = l.Error("oh no")
+	op.End()
+	l.Debug("after")
+
+	if assert.Len(t, span.events, 2) {
+		assert.Equal(t, "DEBUG", span.events[0]["log.severity"])
+		assert.Equal(t, "hello", span.events[0]["log.message"])
+		assert.Equal(t, "spanned", span.events[0]["op"])
+		assert.Equal(t, "ERROR", span.events[1]["log.severity"])
+	}
+	assert.Equal(t, "oh no", span.err)
+	assert.NotContains(t, buf.String(), "golog_span")
+}
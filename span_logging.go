@@ -0,0 +1,85 @@
+package golog
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getlantern/ops"
+)
+
+const spanStartKey = "golog_span_start"
+
+var (
+	spanLogger         atomic.Value
+	registerSpanLogger sync.Once
+)
+
+// spanStart records when a span began. It implements eventObserver only so
+// that it's kept out of logged context.
+type spanStart struct {
+	time.Time
+}
+
+func (s spanStart) observe(severity string, arg interface{}, values map[string]interface{}) {}
+
+// LogSpans logs a structured "span end" DEBUG event with l whenever an op
+// ends anywhere in the process, carrying the op's context along with an
+// outcome of "success" or "failure", plus the error if it failed. Ops begun
+// with BeginSpan additionally log a "span start" event and include
+// duration_ms in their end event; the ops package offers no hook into Begin,
+// so other ops are only logged when they end. Canceled ops aren't logged.
+// Call the returned function to stop logging spans.
+func LogSpans(l Logger) (stop func()) {
+	spanLogger.Store(&l)
+	registerSpanLogger.Do(func() {
+		ops.RegisterReporter(logSpanEnd)
+	})
+	return func() {
+		spanLogger.Store((*Logger)(nil))
+	}
+}
+
+func getSpanLogger() Logger {
+	l, _ := spanLogger.Load().(*Logger)
+	if l == nil {
+		return nil
+	}
+	return *l
+}
+
+// BeginSpan is like ops.Begin but also logs a "span start" event if LogSpans
+// is enabled and records the start time so that the end event includes the
+// op's duration.
+func BeginSpan(name string) ops.Op {
+	op := ops.Begin(name).Set(spanStartKey, spanStart{time.Now()})
+	if l := getSpanLogger(); l != nil {
+		l.Debug(WithFields("span start", Field{"span", "start"}))
+	}
+	return op
+}
+
+func logSpanEnd(failure error, ctx map[string]interface{}) {
+	l := getSpanLogger()
+	if l == nil {
+		return
+	}
+	fields := make([]Field, 0, len(ctx)+3)
+	if start, ok := ctx[spanStartKey].(spanStart); ok {
+		fields = append(fields, DurationMS("duration_ms", time.Since(start.Time)))
+	}
+	// ctx is shared with whoever else gets the op's context, so leave out
+	// observers while copying rather than deleting them from it
+	for key, value := range ctx {
+		if _, ok := value.(eventObserver); !ok {
+			fields = append(fields, Field{key, value})
+		}
+	}
+	fields = append(fields, Field{"span", "end"})
+	if failure != nil {
+		fields = append(fields, Field{"outcome", "failure"})
+	} else {
+		fields = append(fields, Field{"outcome", "success"})
+	}
+	l.Debug(WithFields("span end", fields...))
+}
@@ -0,0 +1,45 @@
+package golog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/getlantern/ops"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogSpans(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	stop := LogSpans(LoggerFor("spans"))
+	op := BeginSpan("myspan")
+	op.FailIf(errors.New("broken"))
+	op.End()
+	ops.Begin("plain").End()
+	stop()
+	ops.Begin("unlogged").End()
+
+	logged := buf.String()
+	assert.Regexp(t, `span start \[.*op=myspan.*span=start\]`, logged)
+	assert.Regexp(t, `span end \[.*duration_ms=[0-9.]+ error=broken .*op=myspan outcome=failure .*span=end\]`, logged)
+	assert.Regexp(t, `span end \[.*op=plain outcome=success .*span=end\]`, logged)
+	assert.NotContains(t, logged, "unlogged")
+	assert.NotContains(t, logged, spanStartKey)
+}
+
+func TestLogSpanEndLeavesContextAlone(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+	stop := LogSpans(LoggerFor("spans"))
+	defer stop()
+
+	observer := &severityObserver{&severityTrackingOp{}}
+	ctx := map[string]interface{}{"op": "shared", "golog_severity_tracker_0": observer}
+	logSpanEnd(nil, ctx)
+	assert.Regexp(t, `span end \[op=shared outcome=success span=end\]`, buf.String())
+	assert.Equal(t, map[string]interface{}{"op": "shared", "golog_severity_tracker_0": observer}, ctx, "the shared context shouldn't be modified")
+}
@@ -0,0 +1,597 @@
+package golog
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSpoolSegmentSize   = 4 * 1024 * 1024
+	defaultSpoolMaxSize       = 64 * 1024 * 1024
+	defaultSpoolRetryInterval = 5 * time.Second
+	defaultSpoolBatchSize     = 100
+	spoolSegmentSuffix        = ".spool"
+	spoolOffsetFile           = "offset"
+	spoolRecordHeaderSize     = 4
+	spoolMaxRecordSize        = 16 * 1024 * 1024
+)
+
+// SpoolOptions configures a Spool.
+type SpoolOptions struct {
+	// Dir is the directory holding the spool's files. It's created if
+	// necessary.
+	Dir string
+
+	// SegmentSize is the size at which the spool starts a new segment file.
+	// Defaults to 4 MB.
+	SegmentSize int64
+
+	// MaxSize bounds the total size of the spool. When it's exceeded, the
+	// oldest segments are discarded. Defaults to 64 MB.
+	MaxSize int64
+}
+
+// SpoolPosition is a position in a Spool.
+type SpoolPosition struct {
+	segment uint64
+	offset  int64
+}
+
+// Spool is a persistent queue of records, stored as append-only segment files
+// plus a file recording how far the queue has been consumed. Network outputs
+// use it so that events logged while offline are delivered once connectivity
+// returns, even across restarts of the process. A Spool is safe for
+// concurrent use, but is meant to have a single consumer.
+type Spool struct {
+	opts     SpoolOptions
+	mx       sync.Mutex
+	read     SpoolPosition
+	writeSeg uint64
+	write    *os.File
+	size     int64
+	segments map[uint64]int64
+	dropped  int
+}
+
+// OpenSpool opens the spool in opts.Dir, picking up anything left over from
+// previous runs.
+func OpenSpool(opts *SpoolOptions) (*Spool, error) {
+	s := &Spool{
+		opts:     *opts,
+		segments: make(map[uint64]int64),
+	}
+	if s.opts.SegmentSize <= 0 {
+		s.opts.SegmentSize = defaultSpoolSegmentSize
+	}
+	if s.opts.MaxSize <= 0 {
+		s.opts.MaxSize = defaultSpoolMaxSize
+	}
+	if err := os.MkdirAll(s.opts.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	infos, err := ioutil.ReadDir(s.opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var segs []uint64
+	for _, info := range infos {
+		name := info.Name()
+		if !strings.HasSuffix(name, spoolSegmentSuffix) {
+			continue
+		}
+		seg, err := strconv.ParseUint(strings.TrimSuffix(name, spoolSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segs = append(segs, seg)
+		s.segments[seg] = info.Size()
+		s.size += info.Size()
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i] < segs[j] })
+
+	s.read = s.readOffset()
+	if len(segs) > 0 {
+		if s.read.segment < segs[0] {
+			s.read = SpoolPosition{segment: segs[0]}
+		}
+		s.writeSeg = segs[len(segs)-1]
+		// Drop a partially written record left behind by a crash
+		if err := s.truncateTail(s.writeSeg); err != nil {
+			return nil, err
+		}
+	} else {
+		s.writeSeg = s.read.segment
+	}
+	if err := s.openWriteSegment(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Spool) segmentPath(seg uint64) string {
+	return filepath.Join(s.opts.Dir, fmt.Sprintf("%016d%s", seg, spoolSegmentSuffix))
+}
+
+func (s *Spool) readOffset() SpoolPosition {
+	b, err := ioutil.ReadFile(filepath.Join(s.opts.Dir, spoolOffsetFile))
+	if err != nil {
+		return SpoolPosition{}
+	}
+	var pos SpoolPosition
+	if _, err := fmt.Sscanf(string(b), "%d %d", &pos.segment, &pos.offset); err != nil {
+		return SpoolPosition{}
+	}
+	return pos
+}
+
+func (s *Spool) writeOffset() error {
+	tmp := filepath.Join(s.opts.Dir, spoolOffsetFile+".tmp")
+	if err := ioutil.WriteFile(tmp, []byte(fmt.Sprintf("%d %d", s.read.segment, s.read.offset)), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.opts.Dir, spoolOffsetFile))
+}
+
+// truncateTail truncates the given segment after its last complete record.
+func (s *Spool) truncateTail(seg uint64) error {
+	f, err := os.OpenFile(s.segmentPath(seg), os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	valid := int64(0)
+	for {
+		record, err := readSpoolRecord(f)
+		if err != nil {
+			break
+		}
+		valid += spoolRecordHeaderSize + int64(len(record))
+	}
+	if valid < s.segments[seg] {
+		s.size -= s.segments[seg] - valid
+		s.segments[seg] = valid
+		return f.Truncate(valid)
+	}
+	return nil
+}
+
+func (s *Spool) openWriteSegment() error {
+	f, err := os.OpenFile(s.segmentPath(s.writeSeg), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	s.write = f
+	if _, ok := s.segments[s.writeSeg]; !ok {
+		s.segments[s.writeSeg] = 0
+	}
+	return nil
+}
+
+// Append adds a record to the end of the spool.
+func (s *Spool) Append(record []byte) error {
+	if len(record) > spoolMaxRecordSize {
+		return fmt.Errorf("record of %d bytes exceeds maximum of %d", len(record), spoolMaxRecordSize)
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	length := spoolRecordHeaderSize + int64(len(record))
+	if s.segments[s.writeSeg] > 0 && s.segments[s.writeSeg]+length > s.opts.SegmentSize {
+		if err := s.write.Close(); err != nil {
+			return err
+		}
+		s.writeSeg++
+		if err := s.openWriteSegment(); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, length)
+	binary.BigEndian.PutUint32(buf, uint32(len(record)))
+	copy(buf[spoolRecordHeaderSize:], record)
+	n, err := s.write.Write(buf)
+	s.segments[s.writeSeg] += int64(n)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return s.enforceMaxSize()
+}
+
+// enforceMaxSize discards the oldest segments while the spool is too big.
+func (s *Spool) enforceMaxSize() error {
+	for s.size > s.opts.MaxSize && s.read.segment < s.writeSeg {
+		s.dropped++
+		if err := s.removeSegment(s.read.segment); err != nil {
+			return err
+		}
+		s.read = SpoolPosition{segment: s.read.segment + 1}
+	}
+	return s.writeOffset()
+}
+
+func (s *Spool) removeSegment(seg uint64) error {
+	s.size -= s.segments[seg]
+	delete(s.segments, seg)
+	if err := os.Remove(s.segmentPath(seg)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Read returns up to max records from the front of the spool, along with the
+// position just after them. The records stay in the spool until they're
+// committed with Commit.
+func (s *Spool) Read(max int) ([][]byte, SpoolPosition, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	var records [][]byte
+	pos := s.read
+	for len(records) < max && pos.segment <= s.writeSeg {
+		if pos.offset >= s.segments[pos.segment] {
+			if pos.segment == s.writeSeg {
+				break
+			}
+			pos = SpoolPosition{segment: pos.segment + 1}
+			continue
+		}
+		f, err := os.Open(s.segmentPath(pos.segment))
+		if err != nil {
+			return records, pos, err
+		}
+		if _, err := f.Seek(pos.offset, io.SeekStart); err != nil {
+			f.Close()
+			return records, pos, err
+		}
+		for len(records) < max && pos.offset < s.segments[pos.segment] {
+			record, err := readSpoolRecord(f)
+			if err != nil {
+				// Corrupt segment, skip the rest of it
+				pos.offset = s.segments[pos.segment]
+				break
+			}
+			records = append(records, record)
+			pos.offset += spoolRecordHeaderSize + int64(len(record))
+		}
+		f.Close()
+	}
+	return records, pos, nil
+}
+
+// Commit marks everything before pos, as returned by Read, as consumed.
+// Fully consumed segments are deleted.
+func (s *Spool) Commit(pos SpoolPosition) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if pos.segment < s.read.segment || (pos.segment == s.read.segment && pos.offset < s.read.offset) {
+		// Already consumed past this, e.g. because segments were dropped
+		return nil
+	}
+	for seg := s.read.segment; seg < pos.segment; seg++ {
+		if err := s.removeSegment(seg); err != nil {
+			return err
+		}
+	}
+	s.read = pos
+	return s.writeOffset()
+}
+
+// Empty indicates whether everything in the spool has been committed.
+func (s *Spool) Empty() bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for seg := s.read.segment; seg <= s.writeSeg; seg++ {
+		size := s.segments[seg]
+		if seg == s.read.segment {
+			size -= s.read.offset
+		}
+		if size > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Dropped returns the number of segments discarded so far because the spool
+// exceeded its maximum size, and resets the count.
+func (s *Spool) Dropped() int {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	dropped := s.dropped
+	s.dropped = 0
+	return dropped
+}
+
+// Close closes the spool's files.
+func (s *Spool) Close() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return s.write.Close()
+}
+
+func readSpoolRecord(r io.Reader) ([]byte, error) {
+	var header [spoolRecordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length > spoolMaxRecordSize {
+		return nil, fmt.Errorf("invalid record length %d", length)
+	}
+	record := make([]byte, length)
+	if _, err := io.ReadFull(r, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// lateDeliveryThreshold is how long after being captured an event may be
+// delivered before it's considered late.
+const lateDeliveryThreshold = 30 * time.Second
+
+// deliveredLate indicates whether an event captured at ts and being delivered
+// now counts as late, which it always does if a previous attempt to deliver
+// it failed.
+func deliveredLate(now time.Time, ts time.Time, retried bool) bool {
+	return retried || now.Sub(ts) > lateDeliveryThreshold
+}
+
+// SpooledEvent is an event as stored in a spool. Time is always the time at
+// which the event was originally captured. DeliveredLate is set on events
+// handed to the deliver function after an earlier delivery failed or long
+// after they were captured, for example after a restart, so that downstream
+// ordering and latency analysis can account for them.
+type SpooledEvent struct {
+	Time          time.Time `json:"ts"`
+	DeliveredLate bool      `json:"delivered_late,omitempty"`
+	Monotonic
+	Event
+}
+
+// MarshalJSON encodes e like its Event, with Time as "ts" and DeliveredLate
+// and the Monotonic time, if any, after it.
+func (e SpooledEvent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Time          time.Time `json:"ts"`
+		DeliveredLate bool      `json:"delivered_late,omitempty"`
+		Monotonic
+		*eventJSON
+	}{e.Time, e.DeliveredLate, e.Monotonic, e.Event.toJSON()})
+}
+
+// UnmarshalJSON decodes e from the JSON encoding of a SpooledEvent.
+func (e *SpooledEvent) UnmarshalJSON(b []byte) error {
+	j := &struct {
+		DeliveredLate bool `json:"delivered_late,omitempty"`
+		Monotonic
+		*eventJSON
+	}{eventJSON: &eventJSON{}}
+	if err := json.Unmarshal(b, j); err != nil {
+		return err
+	}
+	*e = SpooledEvent{DeliveredLate: j.DeliveredLate, Monotonic: j.Monotonic, Event: j.toEvent()}
+	e.Time = e.Timestamp
+	return nil
+}
+
+// SpoolOutput creates an output that appends events to spool as JSON
+// SpooledEvents and delivers them in batches using deliver from a background
+// goroutine. If delivery fails, the batch stays in the spool and is retried
+// every retryInterval (default 5 seconds), including after the process
+// restarts. The first of a run of failures is logged on logging errors, and
+// all of them are counted in the output's Stats. Close stops delivery and
+// closes the spool.
+func SpoolOutput(spool *Spool, deliver func(events []*SpooledEvent) error, retryInterval time.Duration) SpoolingOutput {
+	return SpoolOutputContext(context.Background(), spool, func(ctx context.Context, events []*SpooledEvent) error {
+		return deliver(events)
+	}, retryInterval)
+}
+
+// SpoolOutputContext is like SpoolOutput but passes ctx to deliver so that
+// in-flight deliveries can be aborted. Once ctx is done, delivery stops.
+// Undelivered events are already safely in the spool, so they're delivered
+// the next time the spool is used.
+func SpoolOutputContext(ctx context.Context, spool *Spool, deliver func(ctx context.Context, events []*SpooledEvent) error, retryInterval time.Duration) SpoolingOutput {
+	return SpoolOutputWithOptions(ctx, spool, deliver, &SpoolOutputOptions{RetryInterval: retryInterval})
+}
+
+// SpoolingOutput is a DrainableOutput that spools events, see SpoolOutput.
+type SpoolingOutput interface {
+	DrainableOutput
+
+	// Stats returns statistics about the deliveries so far.
+	Stats() SpoolOutputStats
+}
+
+// SpoolOutputStats describes the deliveries of a spool output, so that a sink
+// that keeps failing is visible.
+type SpoolOutputStats struct {
+	// Delivered counts delivered events.
+	Delivered uint64
+	// DeliveryErrors counts failed deliveries.
+	DeliveryErrors uint64
+	// LastError is the error of the most recent failed delivery, if any.
+	LastError string
+}
+
+// SpoolOutputOptions configures a spool output, see SpoolOutputWithOptions.
+type SpoolOutputOptions struct {
+	// RetryInterval is how often failed deliveries are retried. Defaults to 5
+	// seconds.
+	RetryInterval time.Duration
+
+	// Clock times retries and decides whether events are delivered late.
+	// Defaults to the real clock.
+	Clock Clock
+}
+
+// SpoolOutputWithOptions is like SpoolOutputContext but configured with opts,
+// which may be nil.
+func SpoolOutputWithOptions(ctx context.Context, spool *Spool, deliver func(ctx context.Context, events []*SpooledEvent) error, opts *SpoolOutputOptions) SpoolingOutput {
+	if opts == nil {
+		opts = &SpoolOutputOptions{}
+	}
+	retryInterval, clock := opts.RetryInterval, opts.Clock
+	if retryInterval <= 0 {
+		retryInterval = defaultSpoolRetryInterval
+	}
+	if clock == nil {
+		clock = realClock{}
+	}
+	o := &spoolOutput{
+		ctx:           ctx,
+		spool:         spool,
+		deliver:       deliver,
+		retryInterval: retryInterval,
+		clock:         clock,
+		appended:      make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go o.run()
+	return o
+}
+
+type spoolOutput struct {
+	ctx           context.Context
+	spool         *Spool
+	deliver       func(context.Context, []*SpooledEvent) error
+	retryInterval time.Duration
+	clock         Clock
+	appended      chan struct{}
+	stop          chan struct{}
+	stopOnce      sync.Once
+	done          chan struct{}
+	failing       bool
+	statsMx       sync.Mutex
+	stats         SpoolOutputStats
+}
+
+func (o *spoolOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.print(prefix, skipFrames, printStack, severity, arg, values)
+}
+
+func (o *spoolOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	o.print(prefix, skipFrames, printStack, severity, arg, values)
+}
+
+func (o *spoolOutput) print(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	event := buildEvent(make([]uintptr, 10), prefix, skipFrames, printStack, severity, arg, values)
+	record, err := json.Marshal(&SpooledEvent{Time: eventTime(arg), Monotonic: monotonicNow(arg), Event: *event})
+	event.Release()
+	if err != nil {
+		errorOnLogging(err)
+		return
+	}
+	if err := o.spool.Append(record); err != nil {
+		errorOnLogging(err)
+		return
+	}
+	select {
+	case o.appended <- struct{}{}:
+	default:
+	}
+}
+
+func (o *spoolOutput) run() {
+	defer close(o.done)
+	retryDue := o.clock.After(o.retryInterval)
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-o.ctx.Done():
+			return
+		case <-o.appended:
+		case <-retryDue:
+			retryDue = o.clock.After(o.retryInterval)
+		}
+		o.deliverAll()
+	}
+}
+
+// deliverAll delivers everything in the spool, stopping at the first failure.
+func (o *spoolOutput) deliverAll() {
+	if dropped := o.spool.Dropped(); dropped > 0 {
+		errorOnLogging(fmt.Errorf("spool full, discarded %d segments of undelivered events", dropped))
+	}
+	for o.ctx.Err() == nil {
+		records, pos, err := o.spool.Read(defaultSpoolBatchSize)
+		if err != nil {
+			errorOnLogging(err)
+		}
+		if len(records) == 0 {
+			return
+		}
+		events := make([]*SpooledEvent, 0, len(records))
+		for _, record := range records {
+			event := &SpooledEvent{}
+			if err := json.Unmarshal(record, event); err != nil {
+				// Skip garbage rather than getting stuck on it
+				continue
+			}
+			event.DeliveredLate = deliveredLate(o.clock.Now(), event.Time, o.failing)
+			events = append(events, event)
+		}
+		if len(events) > 0 {
+			if err := o.deliver(o.ctx, events); err != nil {
+				if !o.failing {
+					errorOnLogging(fmt.Errorf("unable to deliver spooled events, will retry: %v", err))
+				}
+				o.failing = true
+				o.statsMx.Lock()
+				o.stats.DeliveryErrors++
+				o.stats.LastError = err.Error()
+				o.statsMx.Unlock()
+				return
+			}
+			o.statsMx.Lock()
+			o.stats.Delivered += uint64(len(events))
+			o.statsMx.Unlock()
+		}
+		o.failing = false
+		if err := o.spool.Commit(pos); err != nil {
+			errorOnLogging(err)
+			return
+		}
+	}
+}
+
+func (o *spoolOutput) Stats() SpoolOutputStats {
+	o.statsMx.Lock()
+	defer o.statsMx.Unlock()
+	return o.stats
+}
+
+// WaitForDrain waits until everything in the spool has been delivered, or
+// until ctx is done.
+func (o *spoolOutput) WaitForDrain(ctx context.Context) error {
+	select {
+	case o.appended <- struct{}{}:
+	default:
+	}
+	return waitForDrain(ctx, o.spool.Empty)
+}
+
+// Close stops delivery and closes the spool. Undelivered events remain in the
+// spool for next time.
+func (o *spoolOutput) Close() error {
+	o.stopOnce.Do(func() {
+		close(o.stop)
+	})
+	<-o.done
+	return o.spool.Close()
+}
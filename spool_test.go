@@ -0,0 +1,104 @@
+package golog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	opts := &SpoolOptions{Dir: dir, SegmentSize: 30}
+	s, err := OpenSpool(opts)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, s.Append([]byte(fmt.Sprintf("record %d", i))))
+	}
+
+	records, pos, err := s.Read(2)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("record 0"), []byte("record 1")}, records)
+	require.NoError(t, s.Commit(pos))
+	require.NoError(t, s.Close())
+
+	// Reopen and make sure we pick up where we left off
+	s, err = OpenSpool(opts)
+	require.NoError(t, err)
+	defer s.Close()
+	records, pos, err = s.Read(10)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("record 2"), []byte("record 3"), []byte("record 4")}, records)
+	require.NoError(t, s.Commit(pos))
+	records, _, err = s.Read(10)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	require.NoError(t, s.Append([]byte("record 5")))
+	records, _, err = s.Read(10)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("record 5")}, records)
+}
+
+func TestSpoolOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldStderr := stderr
+	errs := &bytes.Buffer{}
+	stderr = errs
+	defer func() { stderr = oldStderr }()
+
+	s, err := OpenSpool(&SpoolOptions{Dir: dir})
+	require.NoError(t, err)
+	online := make(chan bool, 1)
+	online <- false
+	delivered := make(chan []*SpooledEvent, 10)
+	out := SpoolOutput(s, func(events []*SpooledEvent) error {
+		ok := <-online
+		online <- ok
+		if !ok {
+			return fmt.Errorf("offline")
+		}
+		delivered <- events
+		return nil
+	}, 10*time.Millisecond)
+	defer out.Close()
+
+	out.Error("myprefix: ", 0, false, "ERROR", "offline event", nil)
+	time.Sleep(50 * time.Millisecond)
+	<-online
+	online <- true
+
+	select {
+	case events := <-delivered:
+		require.Len(t, events, 1)
+		assert.Equal(t, "offline event", events[0].Message)
+		assert.Equal(t, "myprefix", events[0].Component)
+		assert.False(t, events[0].Time.IsZero())
+		assert.True(t, events[0].DeliveredLate, "event delivered after a failure should be marked late")
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not delivered")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, out.WaitForDrain(ctx))
+	assert.True(t, s.Empty())
+
+	stats := out.Stats()
+	assert.EqualValues(t, 1, stats.Delivered)
+	assert.True(t, stats.DeliveryErrors > 0, "failed deliveries should be counted")
+	assert.Equal(t, "offline", stats.LastError)
+	assert.Equal(t, "Unable to log: unable to deliver spooled events, will retry: offline\n", errs.String(), "only the first of a run of failures should be logged")
+}
@@ -0,0 +1,96 @@
+package golog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// StartupOptions configures the event logged by LogStartup.
+type StartupOptions struct {
+	// App is the name of the application. Defaults to the path of the main
+	// module.
+	App string
+
+	// Version is the version of the application. Defaults to the version of
+	// the main module, which is "(devel)" for local builds.
+	Version string
+
+	// Config, if set, is digested into the config_digest field, so that
+	// configurations can be told apart without logging them. It's marshaled
+	// as JSON.
+	Config interface{}
+
+	// Features are the names of the enabled features, logged in sorted order.
+	Features []string
+
+	// Fields are additional fields to log.
+	Fields []Field
+}
+
+// LogStartup logs a single structured event at startup describing exactly
+// what binary is running: the app and its version, the build (go_version,
+// module, revision, vcs_time and vcs_modified when known), the host (see
+// HostMetadata), the clock (see ClockFields), config_digest and features. The
+// event is logged at INFO, so that it's kept at the info level, and has the
+// field startup=true, so that it's easy to find. opts may be nil.
+func LogStartup(log Logger, opts *StartupOptions) {
+	if opts == nil {
+		opts = &StartupOptions{}
+	}
+	app, version := opts.App, opts.Version
+	fields := []Field{{"startup", true}, {"go_version", runtime.Version()}}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if app == "" {
+			app = info.Main.Path
+		}
+		if version == "" {
+			version = info.Main.Version
+		}
+		fields = append(fields, Field{"module", info.Main.Path})
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				fields = append(fields, Field{"revision", setting.Value})
+			case "vcs.time":
+				fields = append(fields, Field{"vcs_time", setting.Value})
+			case "vcs.modified":
+				fields = append(fields, Field{"vcs_modified", setting.Value == "true"})
+			}
+		}
+	}
+	fields = append(fields, Field{"app", app}, Field{"version", version})
+	fields = append(fields, HostMetadata()...)
+	fields = append(fields, ClockFields()...)
+	if opts.Config != nil {
+		digest, err := configDigest(opts.Config)
+		if err != nil {
+			errorOnLogging(fmt.Errorf("unable to digest config: %v", err))
+		} else {
+			fields = append(fields, Field{"config_digest", digest})
+		}
+	}
+	if len(opts.Features) > 0 {
+		features := append([]string(nil), opts.Features...)
+		sort.Strings(features)
+		fields = append(fields, Field{"features", strings.Join(features, ",")})
+	}
+	fields = append(fields, opts.Fields...)
+	log.Infow(fmt.Sprintf("Starting %v %v", app, version), fields...)
+}
+
+// configDigest returns the first 16 hex characters of the SHA-256 of cfg as
+// JSON. Since encoding/json sorts map keys, equal configs give equal digests.
+func configDigest(cfg interface{}) (string, error) {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8]), nil
+}
@@ -0,0 +1,48 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogStartup(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutput(JsonOutput(buf, buf))
+	defer reset()
+	require.NoError(t, SetLevel("info"))
+	defer SetLevel("")
+
+	config := map[string]interface{}{"proxy": "example.com", "port": 443}
+	LogStartup(LoggerFor("myapp"), &StartupOptions{
+		App:      "myapp",
+		Version:  "1.2.3",
+		Config:   config,
+		Features: []string{"zeta", "alpha"},
+		Fields:   []Field{{"channel", "beta"}},
+	})
+
+	var event Event
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.Equal(t, "Starting myapp 1.2.3", event.Message)
+	assert.Equal(t, "INFO", event.Severity)
+	assert.Equal(t, true, event.Context["startup"])
+	assert.Equal(t, "myapp", event.Context["app"])
+	assert.Equal(t, "1.2.3", event.Context["version"])
+	assert.Equal(t, runtime.Version(), event.Context["go_version"])
+	assert.Equal(t, runtime.GOOS, event.Context["os"])
+	assert.Equal(t, runtime.GOARCH, event.Context["arch"])
+	assert.Equal(t, "alpha,zeta", event.Context["features"])
+	assert.Equal(t, "beta", event.Context["channel"])
+	assert.Len(t, event.Context["config_digest"], 16)
+
+	digest, err := configDigest(map[string]interface{}{"port": 443, "proxy": "example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, event.Context["config_digest"], digest, "digest shouldn't depend on map order")
+	other, _ := configDigest(map[string]interface{}{"port": 80})
+	assert.NotEqual(t, digest, other)
+}
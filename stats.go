@@ -0,0 +1,162 @@
+package golog
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	statsTopErrors       = 10
+	statsMaxFingerprints = 1000
+)
+
+var (
+	statsSince = time.Now()
+
+	// eventCounts maps statsKeys to *uint64 counts of events.
+	eventCounts sync.Map
+
+	// errorStats maps fingerprints to *errorStat counts of the errors
+	// reported since the process started
+	errorStats      sync.Map
+	errorStatsCount int32
+)
+
+// errorStat counts the errors reported with a fingerprint.
+type errorStat struct {
+	component string
+	count     uint64
+	// lastSeen is in nanoseconds since the epoch
+	lastSeen int64
+	// lastErr is a reportedError holding the most recent error, which is only
+	// formatted when stats are requested
+	lastErr atomic.Value
+}
+
+type reportedError struct {
+	err error
+}
+
+type statsKey struct {
+	component string
+	severity  string
+}
+
+// LogStats summarizes what has been logged since the process started.
+type LogStats struct {
+	// Since is when counting started.
+	Since time.Time `json:"since"`
+
+	// Counts are the number of events logged per component and severity,
+	// sorted by component and then severity.
+	Counts []EventCount `json:"counts"`
+
+	// TopErrors are the most frequently reported errors, most frequent
+	// first, grouped by their Fingerprint.
+	TopErrors []ErrorStat `json:"top_errors"`
+}
+
+// EventCount is the number of events logged for a component and severity.
+type EventCount struct {
+	Component string `json:"component"`
+	Severity  string `json:"severity"`
+	Count     uint64 `json:"count"`
+}
+
+// ErrorStat counts the errors reported with a fingerprint.
+type ErrorStat struct {
+	Fingerprint string    `json:"fingerprint"`
+	Component   string    `json:"component"`
+	Message     string    `json:"message"`
+	Count       uint64    `json:"count"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// Stats returns counts of the events logged by severity and component since
+// the process started, along with the 10 most frequently reported errors, for
+// use in health endpoints and diagnostics. Reported errors are always tracked
+// from startup, so that the first call to Stats already includes them, which
+// costs a fingerprint and a few atomic updates per ERROR. Only the first 1000
+// distinct errors are tracked.
+func Stats() *LogStats {
+	return getStats(statsTopErrors)
+}
+
+func getStats(topErrors int) *LogStats {
+	stats := &LogStats{Since: statsSince}
+	eventCounts.Range(func(key, value interface{}) bool {
+		k := key.(statsKey)
+		stats.Counts = append(stats.Counts, EventCount{
+			Component: k.component,
+			Severity:  k.severity,
+			Count:     atomic.LoadUint64(value.(*uint64)),
+		})
+		return true
+	})
+	sort.Slice(stats.Counts, func(i, j int) bool {
+		a, b := stats.Counts[i], stats.Counts[j]
+		if a.Component != b.Component {
+			return a.Component < b.Component
+		}
+		return severityLevel(a.Severity) < severityLevel(b.Severity)
+	})
+
+	errorStats.Range(func(key, value interface{}) bool {
+		stat := value.(*errorStat)
+		last, _ := stat.lastErr.Load().(reportedError)
+		stats.TopErrors = append(stats.TopErrors, ErrorStat{
+			Fingerprint: key.(string),
+			Component:   stat.component,
+			Message:     fmt.Sprint(last.err),
+			Count:       atomic.LoadUint64(&stat.count),
+			LastSeen:    time.Unix(0, atomic.LoadInt64(&stat.lastSeen)),
+		})
+		return true
+	})
+	sort.Slice(stats.TopErrors, func(i, j int) bool {
+		a, b := stats.TopErrors[i], stats.TopErrors[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.LastSeen.After(b.LastSeen)
+	})
+	if len(stats.TopErrors) > topErrors {
+		stats.TopErrors = stats.TopErrors[:topErrors]
+	}
+	return stats
+}
+
+// countEvent counts an event logged by the logger with the given prefix.
+func countEvent(prefix string, severity string) {
+	key := statsKey{component: prefix[:len(prefix)-2], severity: severity}
+	count, found := eventCounts.Load(key)
+	if !found {
+		count, _ = eventCounts.LoadOrStore(key, new(uint64))
+	}
+	atomic.AddUint64(count.(*uint64), 1)
+}
+
+// countError counts a reported error. ctx is the context passed to
+// ErrorReporters.
+func countError(err error, ctx map[string]interface{}) {
+	fingerprint := Fingerprint(err, ctx)
+	value, found := errorStats.Load(fingerprint)
+	if !found {
+		if atomic.LoadInt32(&errorStatsCount) >= statsMaxFingerprints {
+			return
+		}
+		component, _ := ctx["component"].(string)
+		var loaded bool
+		value, loaded = errorStats.LoadOrStore(fingerprint, &errorStat{component: component})
+		if !loaded {
+			atomic.AddInt32(&errorStatsCount, 1)
+		}
+	}
+	stat := value.(*errorStat)
+	atomic.AddUint64(&stat.count, 1)
+	atomic.StoreInt64(&stat.lastSeen, time.Now().UnixNano())
+	stat.lastErr.Store(reportedError{err})
+}
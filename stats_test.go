@@ -0,0 +1,51 @@
+package golog
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	l := LoggerFor("stats_test")
+	l.Debug("one")
+	l.Debug("two")
+	for i := 0; i < 3; i++ {
+		l.Error("frequent")
+	}
+	l.Error("rare")
+	NewEvent("DEBUG", "stats_test", "emitted").Emit()
+
+	// Look at all errors, other tests logged errors too
+	stats := getStats(statsMaxFingerprints)
+	var counts []EventCount
+	for _, count := range stats.Counts {
+		if count.Component == "stats_test" {
+			counts = append(counts, count)
+		}
+	}
+	assert.Equal(t, []EventCount{
+		{Component: "stats_test", Severity: "DEBUG", Count: 3},
+		{Component: "stats_test", Severity: "ERROR", Count: 4},
+	}, counts)
+
+	var errs []ErrorStat
+	for _, stat := range stats.TopErrors {
+		if stat.Component == "stats_test" {
+			errs = append(errs, stat)
+		}
+	}
+	require.Len(t, errs, 2)
+	assert.Equal(t, "frequent", errs[0].Message)
+	assert.EqualValues(t, 3, errs[0].Count)
+	assert.False(t, errs[0].LastSeen.IsZero())
+	assert.Equal(t, "rare", errs[1].Message)
+	assert.EqualValues(t, 1, errs[1].Count)
+	assert.True(t, len(Stats().TopErrors) <= 10)
+}
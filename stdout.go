@@ -0,0 +1,30 @@
+package golog
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+var avoidStdout int32
+
+// AvoidStdout guarantees that golog's outputs never write to stdout. Debug and
+// trace output that would have gone to stdout goes to stderr instead. This is
+// for CLI tools whose stdout carries machine readable program output. To send
+// debug output somewhere else, use SetOutputs with the desired writer.
+//
+// This applies wherever golog's outputs write to stdout, including through
+// BufferedOutput, FallbackOutput and GroupingOutput and when they're combined
+// with MultiOutput or other wrappers, but not to writers that merely wrap
+// stdout, like a bufio.Writer.
+func AvoidStdout() {
+	atomic.StoreInt32(&avoidStdout, 1)
+}
+
+// redirectStdout returns stderr in place of w if w is stdout and AvoidStdout
+// is in effect.
+func redirectStdout(w io.Writer) io.Writer {
+	if w == stdout && atomic.LoadInt32(&avoidStdout) == 1 {
+		return stderr
+	}
+	return w
+}
@@ -0,0 +1,40 @@
+package golog
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAvoidStdout(t *testing.T) {
+	oldStderr, oldStdout := stderr, stdout
+	errBuf, outBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	stderr, stdout = errBuf, outBuf
+	defer func() {
+		stderr, stdout = oldStderr, oldStdout
+		atomic.StoreInt32(&avoidStdout, 0)
+	}()
+
+	AvoidStdout()
+	reset := SetOutputs(errBuf, outBuf)
+	l := LoggerFor("myprefix")
+	l.Debug("top level")
+	reset()
+	assert.Empty(t, outBuf.String())
+	assert.Contains(t, errBuf.String(), "top level")
+
+	buffered := BufferedOutput(outBuf, TextOutput, 1024*1024, time.Hour)
+	reset = SetOutput(MultiOutput(nil,
+		buffered,
+		FallbackOutput(outBuf, outBuf, JsonOutput, nil),
+	))
+	defer reset()
+	l.Debug("wrapped")
+	assert.NoError(t, buffered.Close())
+	assert.Empty(t, outBuf.String(), "wrapped outputs shouldn't write to stdout")
+	assert.Contains(t, errBuf.String(), "DEBUG myprefix: stdout_test.go:")
+	assert.Contains(t, errBuf.String(), `"msg":"wrapped"`)
+}
@@ -0,0 +1,207 @@
+package golog
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/getlantern/hidden"
+)
+
+// maxStreamLine bounds how much of a single line a streaming writer holds on
+// to before writing it out.
+const maxStreamLine = 64 * 1024
+
+// hiddenChars are the characters that hidden encodes data with, between a
+// leading and a trailing NUL.
+const hiddenChars = "\x01\x02\x03\x04\x05\x06\x0e\x0f\x10\x11\x12\x13\x14\x15\x16\x17"
+
+// StreamingOutput is implemented by outputs that can write a debug message of
+// unbounded size straight to their destination, see Logger.DebugStream.
+type StreamingOutput interface {
+	Output
+
+	// DebugStream writes a message header and then whatever fn writes to w.
+	DebugStream(prefix string, skipFrames int, printStack bool, values map[string]interface{}, fn func(w io.Writer))
+}
+
+func getOutput() Output {
+	outputMx.RLock()
+	defer outputMx.RUnlock()
+	return output
+}
+
+func (l *logger) DebugStream(fn func(w io.Writer)) {
+	if IsDisabled() || !levelEnabled("DEBUG") {
+		return
+	}
+	countEvent(l.prefix, "DEBUG")
+	printStack := l.loggerEnv().printStack || atomic.LoadInt32(&emergencyVerbosity) == 1
+	values := eventValues(nil, l.fields)
+	observe(values, "DEBUG", nil)
+	if l.sampler != nil {
+		keep, dropped := l.sampler.sample(1, "DEBUG", nil)
+		if !keep {
+			return
+		}
+		values = withDropped(values, true, dropped)
+	}
+	out := getOutput()
+	subs := currentSubscriptions()
+	if so, ok := out.(StreamingOutput); ok {
+		if len(subs) == 0 {
+			so.DebugStream(l.prefix, 4, printStack, values, fn)
+			return
+		}
+		// Keep the start of the stream for the subscribers, which get the
+		// event once it's been streamed
+		published := copyValues(values)
+		start := &streamStart{}
+		so.DebugStream(l.prefix, 4, printStack, values, func(w io.Writer) {
+			fn(io.MultiWriter(w, start))
+		})
+		publish(subs, l.prefix, 4, printStack, "DEBUG", start.String(), published)
+		return
+	}
+	// The output can't stream, so collect everything and log it in one go
+	var buf bytes.Buffer
+	fn(&buf)
+	if len(subs) > 0 {
+		publish(subs, l.prefix, 4, printStack, "DEBUG", buf.String(), values)
+	}
+	out.Debug(l.prefix, 5, printStack, "DEBUG", buf.String(), values)
+}
+
+// streamStart keeps the first maxStreamLine bytes written to it and discards
+// the rest.
+type streamStart struct {
+	bytes.Buffer
+}
+
+func (s *streamStart) Write(p []byte) (int, error) {
+	if room := maxStreamLine - s.Len(); room > 0 {
+		if len(p) > room {
+			s.Buffer.Write(p[:room])
+		} else {
+			s.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (o *textOutput) DebugStream(prefix string, skipFrames int, printStack bool, values map[string]interface{}, fn func(w io.Writer)) {
+	writer := redirectStdout(o.D)
+	buf := getBuffer()
+	global, logger := o.prepend(buf, prefix)
+	if style, styled := severityStyle(writer, "DEBUG"); styled {
+		buf.WriteString(style.open + "DEBUG" + style.close + " " + prefix)
+	} else {
+		buf.WriteString(headerFragment("DEBUG", prefix))
+	}
+	var locationBuf [64]byte
+	buf.Write(o.appendLocation(locationBuf[:0], skipFrames, nil))
+	buf.WriteString("streaming output follows")
+	printContext(buf, values)
+	o.append(buf, global, logger)
+	buf.WriteByte('\n')
+	_, err := writer.Write([]byte(hidden.Clean(buf.String())))
+	returnBuffer(buf)
+	if err != nil {
+		errorOnLogging(err)
+		return
+	}
+
+	sw := &scrubbingWriter{w: writer}
+	fn(sw)
+	if err := sw.Close(); err != nil {
+		errorOnLogging(err)
+	}
+	if printStack {
+		if err := writeStack(writer, o.pc); err != nil {
+			errorOnLogging(err)
+		}
+	}
+}
+
+// scrubbingWriter scrubs hidden data from what's written to it and sanitizes it
+// line by line before passing it on to w, so that it never needs to hold more
+// than a line. Very long lines are written out in chunks, holding back the
+// start of any hidden value that may continue in the next chunk, as long as
+// it's shorter than maxStreamLine.
+type scrubbingWriter struct {
+	w          io.Writer
+	line       []byte
+	unfinished bool
+}
+
+func (sw *scrubbingWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			sw.line = append(sw.line, p...)
+			if len(sw.line) >= maxStreamLine {
+				// Very long line, write out what we have so far
+				if err := sw.flush(true); err != nil {
+					return 0, err
+				}
+			}
+			break
+		}
+		sw.line = append(sw.line, p[:i+1]...)
+		p = p[i+1:]
+		if err := sw.flush(false); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// flush writes out the current line, or if partial, the part of it that can't
+// be the start of a hidden value.
+func (sw *scrubbingWriter) flush(partial bool) error {
+	if len(sw.line) == 0 {
+		return nil
+	}
+	cleaned, carry := hidden.Clean(string(sw.line)), ""
+	if partial {
+		if i := openHiddenValue(cleaned); i >= 0 && len(cleaned)-i < maxStreamLine {
+			cleaned, carry = cleaned[:i], cleaned[i:]
+		}
+	}
+	sw.line = append(sw.line[:0], carry...)
+	if cleaned == "" {
+		return nil
+	}
+	_, err := io.WriteString(sw.w, sanitize(cleaned))
+	sw.unfinished = cleaned[len(cleaned)-1] != '\n'
+	return err
+}
+
+// openHiddenValue returns the index of the hidden value that s ends in the
+// middle of, or -1 if there is none.
+func openHiddenValue(s string) int {
+	i := strings.LastIndexByte(s, 0)
+	if i < 0 {
+		return -1
+	}
+	for j := i + 1; j < len(s); j++ {
+		if strings.IndexByte(hiddenChars, s[j]) < 0 {
+			return -1
+		}
+	}
+	return i
+}
+
+// Close writes out whatever remains, terminating it with a newline.
+func (sw *scrubbingWriter) Close() error {
+	if err := sw.flush(false); err != nil {
+		return err
+	}
+	if sw.unfinished {
+		_, err := sw.w.Write([]byte{'\n'})
+		return err
+	}
+	return nil
+}
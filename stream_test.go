@@ -0,0 +1,69 @@
+package golog
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/getlantern/hidden"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugStream(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	big := strings.Repeat("x", 3*maxStreamLine)
+	l := LoggerFor("myprefix")
+	l.DebugStream(func(w io.Writer) {
+		io.WriteString(w, "line one\n")
+		io.WriteString(w, big)
+	})
+	lines := strings.Split(buf.String(), "\n")
+	assert.Regexp(t, `^DEBUG myprefix: stream_test.go:[0-9]+ streaming output follows$`, lines[0])
+	assert.Equal(t, "line one", lines[1])
+	assert.Equal(t, big, lines[2])
+	assert.Equal(t, "", lines[3])
+
+	buf.Reset()
+	reset = SetOutput(JsonOutput(buf, buf))
+	defer reset()
+	l.DebugStream(func(w io.Writer) {
+		io.WriteString(w, "not streamed")
+	})
+	assert.Contains(t, buf.String(), `"msg":"not streamed"`)
+	assert.Regexp(t, `"caller":"stream_test.go:[0-9]+"`, buf.String())
+}
+
+func TestDebugStreamHiddenAcrossChunks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	secret := hidden.ToString([]byte("secret address"))
+	padding := strings.Repeat("x", maxStreamLine-5)
+	LoggerFor("myprefix").DebugStream(func(w io.Writer) {
+		io.WriteString(w, padding+secret[:10])
+		io.WriteString(w, secret[10:]+"end\n")
+	})
+	lines := strings.Split(buf.String(), "\n")
+	assert.Equal(t, padding+"end", lines[1], "hidden values split between writes should be scrubbed")
+}
+
+func TestDebugStreamDecorators(t *testing.T) {
+	SetDecorators(&Decorators{
+		Prependers: []func(io.Writer){func(w io.Writer) { io.WriteString(w, "<g>") }},
+		Appenders:  []func(io.Writer){func(w io.Writer) { io.WriteString(w, "</g>") }},
+	})
+	defer SetDecorators(nil)
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	LoggerFor("myprefix").DebugStream(func(w io.Writer) {
+		io.WriteString(w, "streamed\n")
+	})
+	assert.Regexp(t, `^<g>DEBUG myprefix: stream_test.go:[0-9]+ streaming output follows</g>\nstreamed\n$`, buf.String())
+}
@@ -0,0 +1,239 @@
+//go:build stress
+// +build stress
+
+package golog
+
+// The stress tests hammer the global configuration and the loggers from many
+// goroutines at once, to shake out races and torn events. They only build
+// with the stress tag and are meant to be run under the race detector, for
+// example:
+//
+//	go test -tags stress -race -run Stress -stress.duration 5m .
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	stressDuration   = flag.Duration("stress.duration", 10*time.Second, "how long each stress test runs for")
+	stressGoroutines = flag.Int("stress.goroutines", 4*runtime.GOMAXPROCS(0), "how many goroutines log concurrently")
+)
+
+// lineCheckWriter checks that every write is exactly one whole event, as
+// outputs write each event in one go. Events with a stack take several lines,
+// one per frame.
+type lineCheckWriter struct {
+	mx     sync.Mutex
+	lines  int64
+	errors []string
+}
+
+func (w *lineCheckWriter) Write(p []byte) (int, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	w.lines++
+	if len(w.errors) < 10 {
+		switch {
+		case len(p) == 0 || p[len(p)-1] != '\n':
+			w.errors = append(w.errors, fmt.Sprintf("unterminated write %q", p))
+		case bytes.Count(p, []byte("\n")) > bytes.Count(p, []byte("   at "))+1:
+			w.errors = append(w.errors, fmt.Sprintf("more than one event in write %q", p))
+		}
+	}
+	return len(p), nil
+}
+
+func (w *lineCheckWriter) check(t *testing.T) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	assert.Empty(t, w.errors)
+	t.Logf("wrote %d events", w.lines)
+}
+
+// stress runs each of the given functions over and over from its own
+// goroutines, plus the loggers from stressGoroutines goroutines, until
+// stressDuration is up.
+func stress(t *testing.T, reconfigure ...func(r *rand.Rand)) {
+	var stop int32
+	var wg sync.WaitGroup
+	var logged int64
+	run := func(seed int64, fn func(r *rand.Rand)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for atomic.LoadInt32(&stop) == 0 {
+				fn(r)
+			}
+		}()
+	}
+
+	for i := 0; i < *stressGoroutines; i++ {
+		run(int64(i), func(r *rand.Rand) {
+			l := LoggerFor(fmt.Sprintf("stress%d", r.Intn(8)))
+			if r.Intn(4) == 0 {
+				l = ChildLogger(l, Field{"child", r.Intn(100)})
+			}
+			switch r.Intn(5) {
+			case 0:
+				l.Debug("debug")
+			case 1:
+				l.Debugf("debug %d", r.Int())
+			case 2:
+				l.Debugw("debugw", Field{"n", r.Int()}, Field{"s", "text"})
+			case 3:
+				_ = l.Errorf("error %d", r.Int())
+			case 4:
+				l.Trace("trace")
+			}
+			atomic.AddInt64(&logged, 1)
+		})
+	}
+	for i, fn := range reconfigure {
+		fn := fn
+		run(int64(1000+i), func(r *rand.Rand) {
+			fn(r)
+			// Give the loggers a chance to log with each configuration
+			time.Sleep(time.Duration(r.Intn(1000)) * time.Microsecond)
+		})
+	}
+
+	time.Sleep(*stressDuration)
+	atomic.StoreInt32(&stop, 1)
+	wg.Wait()
+	t.Logf("made %d log calls", atomic.LoadInt64(&logged))
+}
+
+func TestStressConfiguration(t *testing.T) {
+	w := &lineCheckWriter{}
+	reset := SetOutputs(w, w)
+	defer reset()
+	defer SetLevel("")
+	defer DisableTrace()
+	defer Enable()
+	defer SetKeyNormalization(nil)
+	defer SetCallerFormat(CallerBase)
+
+	stress(t,
+		func(r *rand.Rand) {
+			switch r.Intn(3) {
+			case 0:
+				SetOutputs(w, w)
+			case 1:
+				SetOutput(JsonOutput(w, w))
+			case 2:
+				SetOutput(TextOutput(w, w))
+			}
+		},
+		func(r *rand.Rand) {
+			levels := []string{"", "TRACE", "DEBUG", "WARN", "ERROR"}
+			assert.NoError(t, SetLevel(levels[r.Intn(len(levels))]))
+		},
+		func(r *rand.Rand) {
+			if r.Intn(2) == 0 {
+				EnableTrace(fmt.Sprintf("stress%d", r.Intn(8)))
+			} else {
+				DisableTrace()
+			}
+		},
+		func(r *rand.Rand) {
+			if r.Intn(10) == 0 {
+				Disable()
+			} else {
+				Enable()
+			}
+		},
+		func(r *rand.Rand) {
+			if r.Intn(2) == 0 {
+				SetKeyNormalization(&KeyNormalization{SnakeCase: true, Collisions: KeyCollisionPrefix})
+			} else {
+				SetKeyNormalization(nil)
+			}
+			SetCallerFormat(CallerFormat(r.Intn(int(CallerHash) + 1)))
+		},
+		func(r *rand.Rand) {
+			if r.Intn(20) == 0 {
+				ReloadEnv()
+			}
+		},
+		func(r *rand.Rand) {
+			unregister := RegisterFieldProvider(time.Millisecond, func() (string, interface{}) {
+				return "provided", rand.Int()
+			})
+			time.Sleep(time.Millisecond)
+			unregister()
+		},
+	)
+	w.check(t)
+}
+
+func TestStressBufferedOutput(t *testing.T) {
+	w := &lineCheckWriter{}
+	reset := SetOutput(getOutput())
+	defer reset()
+
+	var out BufferingOutput
+	var outMx sync.Mutex
+	swap := func() {
+		outMx.Lock()
+		defer outMx.Unlock()
+		old := out
+		out = BufferedOutput(w, TextOutput, 64*1024, time.Millisecond)
+		SetOutput(out)
+		if old != nil {
+			assert.NoError(t, old.Close())
+		}
+	}
+	swap()
+
+	stress(t,
+		func(r *rand.Rand) {
+			if r.Intn(100) == 0 {
+				swap()
+			}
+		},
+	)
+	outMx.Lock()
+	assert.NoError(t, out.Close())
+	outMx.Unlock()
+	// Buffered outputs write many events at once, so only check that writes
+	// end on event boundaries
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	for _, err := range w.errors {
+		assert.NotContains(t, err, "unterminated")
+	}
+}
+
+func TestStressAsync(t *testing.T) {
+	w := &lineCheckWriter{}
+	reset := SetOutputs(w, w)
+	defer reset()
+	defer Close()
+
+	policies := []DropPolicy{DropNone, DropNewest, DropOldest}
+	stress(t,
+		func(r *rand.Rand) {
+			switch r.Intn(10) {
+			case 0:
+				Close()
+			case 1, 2:
+				Flush()
+			default:
+				EnableAsync(1+r.Intn(1000), policies[r.Intn(len(policies))])
+			}
+		},
+	)
+	Close()
+	w.check(t)
+}
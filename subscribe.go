@@ -0,0 +1,113 @@
+package golog
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// subscriptionBufferSize is how many events a subscription buffers for its
+// consumer.
+const subscriptionBufferSize = 1000
+
+var (
+	// subscriptions is a []*subscription, replaced whenever subscriptions
+	// are added or canceled
+	subscriptions   atomic.Value
+	subscriptionsMx sync.Mutex
+)
+
+type subscription struct {
+	filter func(event *Event) bool
+	mx     sync.Mutex
+	events chan Event
+	closed bool
+	// dropped counts the events dropped since the last one delivered
+	dropped uint64
+}
+
+// Subscribe returns a channel that receives the events logged from now on
+// that filter accepts (all of them if filter is nil), whatever the output,
+// for in-process consumers like a UI, tests or alerting. The events are those
+// the output receives, including their caller, stack and context, which
+// consumers must not modify. filter is called as events are logged, so it
+// should be quick. Messages streamed with Logger.DebugStream are cut to their
+// first 64KB.
+//
+// Events are buffered so that logging doesn't wait for the consumer. If the
+// consumer falls behind by more than 1000 events, further events are dropped
+// until it catches up, and the number of dropped ones is added to the
+// dropped_count field of the next event it receives. Call cancel to stop
+// receiving events and close the channel.
+func Subscribe(filter func(event *Event) bool) (events <-chan Event, cancel func()) {
+	sub := &subscription{filter: filter, events: make(chan Event, subscriptionBufferSize)}
+	subscriptionsMx.Lock()
+	existing, _ := subscriptions.Load().([]*subscription)
+	subscriptions.Store(append(existing[:len(existing):len(existing)], sub))
+	subscriptionsMx.Unlock()
+
+	var once sync.Once
+	return sub.events, func() {
+		once.Do(func() {
+			subscriptionsMx.Lock()
+			existing, _ := subscriptions.Load().([]*subscription)
+			updated := make([]*subscription, 0, len(existing))
+			for _, s := range existing {
+				if s != sub {
+					updated = append(updated, s)
+				}
+			}
+			subscriptions.Store(updated)
+			subscriptionsMx.Unlock()
+
+			sub.mx.Lock()
+			sub.closed = true
+			close(sub.events)
+			sub.mx.Unlock()
+		})
+	}
+}
+
+// currentSubscriptions returns the current subscriptions, if any.
+func currentSubscriptions() []*subscription {
+	subs, _ := subscriptions.Load().([]*subscription)
+	return subs
+}
+
+// publishingOutputFn returns a function that publishes events to the current
+// subscriptions before passing them on to write.
+func publishingOutputFn(write outputFn, subs []*subscription) outputFn {
+	return func(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+		publish(subs, prefix, skipFrames, printStack, severity, arg, values)
+		write(prefix, skipFrames+1, printStack, severity, arg, values)
+	}
+}
+
+func publish(subs []*subscription, prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
+	pooled := buildEvent(make([]uintptr, 10), prefix, skipFrames, printStack, severity, arg, values)
+	event := *pooled
+	pooled.Release()
+	// outputs may modify values once they have them
+	event.Context = copyValues(event.Context)
+	for _, sub := range subs {
+		if sub.filter == nil || sub.filter(&event) {
+			sub.deliver(event)
+		}
+	}
+}
+
+func (s *subscription) deliver(event Event) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return
+	}
+	if s.dropped > 0 {
+		event.Context = withDropped(event.Context, false, s.dropped)
+	}
+	select {
+	case s.events <- event:
+		s.dropped = 0
+	default:
+		s.dropped++
+	}
+}
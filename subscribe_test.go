@@ -0,0 +1,114 @@
+package golog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	defer reset()
+
+	events, cancel := Subscribe(func(event *Event) bool {
+		return event.Component == "subscribed"
+	})
+	all, cancelAll := Subscribe(nil)
+	defer cancelAll()
+	l := LoggerFor("subscribed")
+	l.Debugw("hello", Field{"a", "b"})
+	LoggerFor("other").Debug("filtered out")
+	_ = l.Error("oh no")
+
+	hello := <-events
+	assert.Equal(t, "hello", hello.Message)
+	assert.Equal(t, "DEBUG", hello.Severity)
+	assert.Equal(t, "subscribed", hello.Component)
+	assert.True(t, strings.HasPrefix(hello.Caller, "subscribe_test.go:"), hello.Caller)
+	assert.Equal(t, "b", hello.Context["a"])
+	assert.False(t, hello.Timestamp.IsZero())
+	ohNo := <-events
+	assert.Equal(t, "oh no", ohNo.Message)
+	assert.Equal(t, "ERROR", ohNo.Severity)
+	assert.Len(t, all, 3)
+	assert.Equal(t, 3, strings.Count(out.String(), "\n"), "events should still be written to the output")
+
+	cancel()
+	cancel()
+	l.Debug("after cancel")
+	_, open := <-events
+	assert.False(t, open, "cancel should close the channel")
+	assert.Len(t, all, 4)
+}
+
+func TestSubscribeDropsWhenFull(t *testing.T) {
+	reset := SetOutputs(ioutil.Discard, ioutil.Discard)
+	defer reset()
+	events, cancel := Subscribe(nil)
+	defer cancel()
+
+	l := LoggerFor("myprefix")
+	for i := 0; i < subscriptionBufferSize+5; i++ {
+		l.Debug(i)
+	}
+	for i := 0; i < subscriptionBufferSize; i++ {
+		event := <-events
+		require.Equal(t, fmt.Sprint(i), event.Message)
+		require.NotContains(t, event.Context, "dropped_count")
+	}
+	l.Debug("caught up")
+	event := <-events
+	assert.Equal(t, "caught up", event.Message)
+	assert.Equal(t, uint64(5), event.Context["dropped_count"])
+	l.Debug("again")
+	assert.NotContains(t, (<-events).Context, "dropped_count")
+}
+
+func TestSubscribeAsync(t *testing.T) {
+	reset := SetOutputs(ioutil.Discard, ioutil.Discard)
+	defer reset()
+	EnableAsync(10, DropNone)
+	defer Close()
+	events, cancel := Subscribe(nil)
+	defer cancel()
+
+	LoggerFor("myprefix").Debug("hello")
+	event := <-events
+	assert.Equal(t, "hello", event.Message)
+	assert.True(t, strings.HasPrefix(event.Caller, "subscribe_test.go:"), event.Caller)
+}
+
+func TestSubscribeDebugStream(t *testing.T) {
+	events, cancel := Subscribe(nil)
+	defer cancel()
+	l := LoggerFor("myprefix")
+	stream := func(w io.Writer) {
+		fmt.Fprint(w, "streamed\n")
+		w.Write(bytes.Repeat([]byte("x"), maxStreamLine))
+	}
+
+	out := newBuffer()
+	reset := SetOutputs(out, out)
+	l.DebugStream(stream)
+	reset()
+	event := <-events
+	assert.Equal(t, "DEBUG", event.Severity)
+	assert.True(t, strings.HasPrefix(event.Message, "streamed\nxxx"), event.Message)
+	assert.Len(t, event.Message, maxStreamLine, "subscribers should only get the start of the stream")
+	assert.True(t, strings.HasPrefix(event.Caller, "subscribe_test.go:"), event.Caller)
+	assert.Contains(t, out.String(), "streaming output follows", "the stream should still be written to the output")
+
+	reset = SetOutput(NewRingBuffer(nil, 10))
+	l.DebugStream(stream)
+	reset()
+	event = <-events
+	assert.True(t, strings.HasPrefix(event.Message, "streamed\nxxx"), event.Message)
+	assert.True(t, strings.HasPrefix(event.Caller, "subscribe_test.go:"), event.Caller)
+}
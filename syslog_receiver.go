@@ -0,0 +1,358 @@
+package golog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogRecord is a record received from syslog or journald.
+type SyslogRecord struct {
+	Time     time.Time
+	Facility int
+	// Severity is the syslog severity, 0 (emergency) to 7 (debug).
+	Severity int
+	Hostname string
+	App      string
+	ProcID   string
+	MsgID    string
+	Message  string
+	// Caller is the file:line of the record, if the sender recorded it.
+	Caller string
+	// Fields are the structured data (as "id.param") of RFC 5424 records and
+	// the user fields of journald records (lower cased).
+	Fields map[string]string
+}
+
+// goSeverity maps the syslog severity to a golog severity.
+func (r *SyslogRecord) goSeverity() string {
+	switch {
+	case r.Severity <= 3:
+		return "ERROR"
+	case r.Severity == 4:
+		return "WARN"
+	case r.Severity <= 6:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// Emit emits the record like NewEvent, with the App (or prefix, if there's no
+// App) as component and the host, pid, msgid and facility along with the
+// record's Fields as fields. Emergency, alert, critical and error records are
+// emitted as ERRORs, warnings as WARN, notice and informational records as
+// INFO and debug records as DEBUG.
+func (r *SyslogRecord) Emit(prefix string, fields ...Field) {
+	r.event(prefix, fields...).Emit()
+}
+
+func (r *SyslogRecord) event(prefix string, fields ...Field) *EventBuilder {
+	component := r.App
+	if component == "" {
+		component = prefix
+	}
+	all := make([]Field, 0, len(r.Fields)+len(fields)+4)
+	for key, value := range r.Fields {
+		all = append(all, Field{key, value})
+	}
+	if r.Hostname != "" {
+		all = append(all, Field{"host", r.Hostname})
+	}
+	if r.ProcID != "" {
+		all = append(all, Field{"pid", r.ProcID})
+	}
+	if r.MsgID != "" {
+		all = append(all, Field{"msgid", r.MsgID})
+	}
+	all = append(all, Field{"facility", r.Facility})
+	all = append(all, fields...)
+	caller := r.Caller
+	if caller == "" {
+		caller = "syslog"
+	}
+	return NewEvent(r.goSeverity(), component, r.Message).With(all...).Caller(caller).At(r.Time)
+}
+
+// ParseSyslog parses an RFC 5424 or RFC 3164 (BSD) syslog message.
+func ParseSyslog(msg []byte) (*SyslogRecord, error) {
+	s := strings.TrimRight(string(msg), "\r\n\x00")
+	if !strings.HasPrefix(s, "<") {
+		return nil, fmt.Errorf("missing priority")
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 2 || end > 4 {
+		return nil, fmt.Errorf("invalid priority")
+	}
+	pri, err := strconv.ParseUint(s[1:end], 10, 8)
+	if err != nil || pri > 191 {
+		return nil, fmt.Errorf("invalid priority %q", s[1:end])
+	}
+	r := &SyslogRecord{Facility: int(pri / 8), Severity: int(pri % 8), Fields: make(map[string]string)}
+	s = s[end+1:]
+	if strings.HasPrefix(s, "1 ") {
+		return r, parseRFC5424(r, s[2:])
+	}
+	parseRFC3164(r, s)
+	return r, nil
+}
+
+func parseRFC5424(r *SyslogRecord, s string) error {
+	parts := strings.SplitN(s, " ", 6)
+	if len(parts) < 6 {
+		return fmt.Errorf("truncated RFC 5424 header")
+	}
+	if parts[0] != "-" {
+		ts, err := time.Parse(time.RFC3339Nano, parts[0])
+		if err != nil {
+			return fmt.Errorf("invalid timestamp: %v", err)
+		}
+		r.Time = ts
+	}
+	nilable := func(s string) string {
+		if s == "-" {
+			return ""
+		}
+		return s
+	}
+	r.Hostname, r.App, r.ProcID, r.MsgID = nilable(parts[1]), nilable(parts[2]), nilable(parts[3]), nilable(parts[4])
+	rest := parts[5]
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	} else {
+		var err error
+		if rest, err = parseStructuredData(r, rest); err != nil {
+			return err
+		}
+	}
+	rest = strings.TrimPrefix(rest, " ")
+	r.Message = strings.TrimPrefix(rest, "\xef\xbb\xbf")
+	return nil
+}
+
+// parseStructuredData parses the SD-ELEMENTs at the start of s into
+// r.Fields, returning what follows them.
+func parseStructuredData(r *SyslogRecord, s string) (string, error) {
+	for strings.HasPrefix(s, "[") {
+		i := 1
+		for i < len(s) && s[i] != ' ' && s[i] != ']' {
+			i++
+		}
+		id := s[1:i]
+		for i < len(s) && s[i] == ' ' {
+			i++
+			eq := strings.IndexByte(s[i:], '=')
+			if eq < 0 || i+eq+1 >= len(s) || s[i+eq+1] != '"' {
+				return "", fmt.Errorf("invalid structured data")
+			}
+			name := s[i : i+eq]
+			i += eq + 2
+			var value strings.Builder
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return "", fmt.Errorf("unterminated structured data")
+			}
+			i++
+			r.Fields[id+"."+name] = value.String()
+		}
+		if i >= len(s) || s[i] != ']' {
+			return "", fmt.Errorf("unterminated structured data")
+		}
+		s = s[i+1:]
+	}
+	return s, nil
+}
+
+func parseRFC3164(r *SyslogRecord, s string) {
+	// Mmm dd hh:mm:ss host tag[pid]: message
+	if len(s) >= 16 {
+		if ts, err := time.ParseInLocation(time.Stamp, s[:15], time.Local); err == nil {
+			now := time.Now()
+			r.Time = ts.AddDate(now.Year(), 0, 0)
+			if r.Time.After(now.Add(24 * time.Hour)) {
+				// logged in December, received in January
+				r.Time = r.Time.AddDate(-1, 0, 0)
+			}
+			s = s[16:]
+			if space := strings.IndexByte(s, ' '); space > 0 {
+				r.Hostname, s = s[:space], s[space+1:]
+			}
+		}
+	}
+	if colon := strings.Index(s, ": "); colon > 0 && !strings.ContainsAny(s[:colon], " ") {
+		tag := s[:colon]
+		if open := strings.IndexByte(tag, '['); open > 0 && strings.HasSuffix(tag, "]") {
+			r.ProcID = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		r.App, s = tag, s[colon+2:]
+	}
+	r.Message = s
+}
+
+// SyslogReceiver receives syslog messages over UDP and emits them, see
+// ListenSyslog.
+type SyslogReceiver struct {
+	conn     net.PacketConn
+	prefix   string
+	fields   []Field
+	wg       sync.WaitGroup
+	closeErr error
+	once     sync.Once
+}
+
+// ListenSyslog listens for syslog messages (RFC 5424 or RFC 3164) on the UDP
+// address addr and emits each as an event through the configured outputs,
+// see SyslogRecord.Emit, so that a single process can aggregate the logs of
+// co-located components that aren't written in Go. Records without an app
+// name use prefix as their component. fields are added to every event.
+// Messages that can't be parsed are reported on stderr.
+//
+// Syslog over UDP is unauthenticated, so anyone who can reach addr can inject
+// events into the logs. If addr doesn't include a host, like ":514", ListenSyslog
+// only listens on the loopback interface; listening on other interfaces
+// requires giving their address explicitly. For the same reason, received
+// records aren't sent to the registered ErrorReporters, so that they can't
+// raise alerts or incidents.
+func ListenSyslog(addr string, prefix string, fields ...Field) (*SyslogReceiver, error) {
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	r := &SyslogReceiver{conn: conn, prefix: prefix, fields: fields}
+	r.wg.Add(1)
+	go r.run()
+	return r, nil
+}
+
+// Addr returns the address on which r is listening.
+func (r *SyslogReceiver) Addr() net.Addr {
+	return r.conn.LocalAddr()
+}
+
+func (r *SyslogReceiver) run() {
+	defer r.wg.Done()
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		record, err := ParseSyslog(buf[:n])
+		if err != nil {
+			errorOnLogging(fmt.Errorf("unable to parse syslog message: %v", err))
+			continue
+		}
+		record.event(r.prefix, r.fields...).Unreported().Emit()
+	}
+}
+
+// Close stops receiving, waiting for messages already received to be emitted.
+func (r *SyslogReceiver) Close() error {
+	r.once.Do(func() {
+		r.closeErr = r.conn.Close()
+		r.wg.Wait()
+	})
+	return r.closeErr
+}
+
+// ReadJournalExport reads records in the journald export format, as written
+// by journalctl -o export, from rd and emits each of them, like
+// ListenSyslog. It returns once rd is exhausted.
+func ReadJournalExport(rd io.Reader, prefix string, fields ...Field) error {
+	br := bufio.NewReader(rd)
+	entry := make(map[string]string)
+	flush := func() {
+		if len(entry) > 0 {
+			journalRecord(entry).Emit(prefix, fields...)
+			entry = make(map[string]string)
+		}
+	}
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			flush()
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			flush()
+			continue
+		}
+		if eq := strings.IndexByte(line, '='); eq >= 0 {
+			entry[line[:eq]] = line[eq+1:]
+			continue
+		}
+		// binary field: name, then little endian uint64 length, then data
+		var size uint64
+		if err := binary.Read(br, binary.LittleEndian, &size); err != nil {
+			return fmt.Errorf("truncated binary field %v: %v", line, err)
+		}
+		if size > maxJSONLineSize {
+			return fmt.Errorf("binary field %v of %d bytes is too large", line, size)
+		}
+		data := make([]byte, size+1)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return fmt.Errorf("truncated binary field %v: %v", line, err)
+		}
+		entry[line] = string(bytes.TrimSuffix(data, []byte("\n")))
+	}
+}
+
+func journalRecord(entry map[string]string) *SyslogRecord {
+	r := &SyslogRecord{Severity: 6, Facility: 1, Fields: make(map[string]string)}
+	for key, value := range entry {
+		switch key {
+		case "MESSAGE":
+			r.Message = value
+		case "PRIORITY":
+			if severity, err := strconv.Atoi(value); err == nil && severity >= 0 && severity <= 7 {
+				r.Severity = severity
+			}
+		case "SYSLOG_FACILITY":
+			if facility, err := strconv.Atoi(value); err == nil {
+				r.Facility = facility
+			}
+		case "SYSLOG_IDENTIFIER":
+			r.App = value
+		case "_HOSTNAME":
+			r.Hostname = value
+		case "_PID":
+			r.ProcID = value
+		case "__REALTIME_TIMESTAMP":
+			if usec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				r.Time = time.Unix(0, usec*int64(time.Microsecond))
+			}
+		case "CODE_FILE", "CODE_LINE":
+		default:
+			if !strings.HasPrefix(key, "_") {
+				r.Fields[strings.ToLower(key)] = value
+			}
+		}
+	}
+	if file := entry["CODE_FILE"]; file != "" {
+		r.Caller = file
+		if line := entry["CODE_LINE"]; line != "" {
+			r.Caller += ":" + line
+		}
+	}
+	return r
+}
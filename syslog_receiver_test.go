@@ -0,0 +1,127 @@
+package golog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSyslogRFC5424(t *testing.T) {
+	r, err := ParseSyslog([]byte(`<165>1 2026-10-11T22:14:15.003Z mymachine.example.com evntslog 42 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication"] An application event log entry...` + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 20, r.Facility)
+	assert.Equal(t, 5, r.Severity)
+	assert.True(t, r.Time.Equal(time.Date(2026, 10, 11, 22, 14, 15, 3000000, time.UTC)))
+	assert.Equal(t, "mymachine.example.com", r.Hostname)
+	assert.Equal(t, "evntslog", r.App)
+	assert.Equal(t, "42", r.ProcID)
+	assert.Equal(t, "ID47", r.MsgID)
+	assert.Equal(t, map[string]string{"exampleSDID@32473.iut": "3", "exampleSDID@32473.eventSource": `App"lication`}, r.Fields)
+	assert.Equal(t, "An application event log entry...", r.Message)
+
+	r, err = ParseSyslog([]byte("<11>1 - - - - - - no header"))
+	require.NoError(t, err)
+	assert.True(t, r.Time.IsZero())
+	assert.Empty(t, r.App)
+	assert.Equal(t, "no header", r.Message)
+
+	_, err = ParseSyslog([]byte("no priority"))
+	assert.Error(t, err)
+	_, err = ParseSyslog([]byte("<11>1 - - - - - [unterminated"))
+	assert.Error(t, err)
+}
+
+func TestParseSyslogRFC3164(t *testing.T) {
+	r, err := ParseSyslog([]byte("<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, r.Facility)
+	assert.Equal(t, 2, r.Severity)
+	assert.Equal(t, time.October, r.Time.Month())
+	assert.Equal(t, 11, r.Time.Day())
+	assert.Equal(t, "mymachine", r.Hostname)
+	assert.Equal(t, "su", r.App)
+	assert.Equal(t, "123", r.ProcID)
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", r.Message)
+
+	r, err = ParseSyslog([]byte("<13>just a message"))
+	require.NoError(t, err)
+	assert.Equal(t, "just a message", r.Message)
+}
+
+func TestListenSyslog(t *testing.T) {
+	rb := NewRingBuffer(nil, 10)
+	reset := SetOutput(rb)
+	defer reset()
+	active, reported := true, 0
+	RegisterReporter(func(err error, severity Severity, ctx map[string]interface{}) {
+		if active {
+			reported++
+		}
+	})
+	defer func() { active = false }()
+
+	receiver, err := ListenSyslog(":0", "sidecar", Field{"source", "udp"})
+	require.NoError(t, err)
+	assert.True(t, receiver.Addr().(*net.UDPAddr).IP.IsLoopback(), "receiver should only listen on loopback by default")
+	conn, err := net.Dial("udp", receiver.Addr().String())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("<12>1 2026-10-11T22:14:15Z host nginx 7 - - upstream timed out"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("<11>1 2026-10-11T22:14:16Z host nginx 7 - - upstream failed"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("<14>1 2026-10-11T22:14:17Z host nginx 7 - - upstream recovered"))
+	require.NoError(t, err)
+	conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rb.Events()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, receiver.Close())
+
+	events := rb.Events()
+	require.Len(t, events, 3)
+	assert.Equal(t, "nginx", events[0].Component)
+	assert.Equal(t, "WARN", events[0].Severity)
+	assert.Equal(t, "upstream timed out", events[0].Message)
+	assert.Equal(t, "host", events[0].Context["host"])
+	assert.Equal(t, "udp", events[0].Context["source"])
+	assert.True(t, events[0].Time.Equal(time.Date(2026, 10, 11, 22, 14, 15, 0, time.UTC)))
+	assert.Equal(t, "ERROR", events[1].Severity)
+	assert.Equal(t, "INFO", events[2].Severity)
+	assert.Equal(t, 0, reported, "received records shouldn't be reported")
+}
+
+func TestReadJournalExport(t *testing.T) {
+	var export bytes.Buffer
+	export.WriteString("__REALTIME_TIMESTAMP=1760220855000000\nPRIORITY=3\nSYSLOG_IDENTIFIER=dnsmasq\n_PID=99\n_HOSTNAME=box\nCODE_FILE=main.c\nCODE_LINE=12\nUNIT_NAME=dns\n")
+	export.WriteString("MESSAGE\n")
+	binary.Write(&export, binary.LittleEndian, uint64(11))
+	export.WriteString("two\nlines!!\n\n")
+	export.WriteString("MESSAGE=second\n")
+
+	rb := NewRingBuffer(nil, 10)
+	reset := SetOutput(rb)
+	err := ReadJournalExport(&export, "journal")
+	reset()
+	require.NoError(t, err)
+
+	events := rb.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "dnsmasq", events[0].Component)
+	assert.Equal(t, "ERROR", events[0].Severity)
+	assert.Equal(t, "main.c:12", events[0].Caller)
+	assert.Equal(t, "99", events[0].Context["pid"])
+	assert.Equal(t, "dns", events[0].Context["unit_name"])
+	assert.Contains(t, events[0].Message, "two\nlines!!")
+	assert.Equal(t, int64(1760220855), events[0].Time.Unix())
+
+	assert.Equal(t, "journal", events[1].Component)
+	assert.Equal(t, "INFO", events[1].Severity)
+	assert.Equal(t, "second", events[1].Message)
+}
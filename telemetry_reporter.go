@@ -0,0 +1,244 @@
+package golog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTelemetryInterval        = 1 * time.Hour
+	defaultTelemetryMaxFingerprints = 1000
+)
+
+// TelemetryOptions configures a Telemetry reporter.
+type TelemetryOptions struct {
+	// Endpoint is the URL to which reports are POSTed.
+	Endpoint string
+
+	// OptedIn reports whether the user has opted in to telemetry. Nothing is
+	// collected or submitted while it returns false.
+	OptedIn func() bool
+
+	// Interval is how often counts are submitted, as long as there's
+	// something to submit. Defaults to 1 hour.
+	Interval time.Duration
+
+	// MaxFingerprints bounds the number of distinct fingerprints counted per
+	// interval. Errors with further fingerprints are only counted in total.
+	// Defaults to 1000.
+	MaxFingerprints int
+
+	// Client is the HTTP client used for submitting. Defaults to a client
+	// created with NewHTTPClient(Transport).
+	Client *http.Client
+
+	// Transport configures TLS and proxying when Client isn't set.
+	Transport *TransportOptions
+
+	// Token, if set, supplies a bearer token for each submission.
+	Token TokenSource
+
+	// InvalidateToken, if set, is called whenever the endpoint rejects the
+	// token with a 401 or 403, so that a cached token gets replaced before the
+	// submission is retried once, see CachedTokenSourceWithInvalidate.
+	InvalidateToken func()
+
+	// Compression, if set, compresses reports.
+	Compression *CompressionOptions
+}
+
+// TelemetryReport is the JSON body POSTed by a Telemetry reporter. It contains
+// no messages, fields or anything else about errors beyond their Fingerprint.
+type TelemetryReport struct {
+	Since     time.Time        `json:"since"`
+	Until     time.Time        `json:"until"`
+	Errors    []TelemetryCount `json:"errors"`
+	Untracked int              `json:"untracked,omitempty"`
+}
+
+// TelemetryCount is the number of times errors with a fingerprint were
+// reported, at their highest severity.
+type TelemetryCount struct {
+	Fingerprint string `json:"fingerprint"`
+	Severity    string `json:"severity"`
+	Count       int    `json:"count"`
+}
+
+// Telemetry is an ErrorReporter for opt-in, privacy-preserving telemetry. It
+// only counts errors by their Fingerprint and periodically submits the counts
+// to an endpoint, so that errors can be tracked across a fleet while
+// collecting as little data as possible.
+//
+// Typical usage:
+//
+//	telemetry := golog.NewTelemetry(opts)
+//	defer telemetry.Close()
+//	golog.RegisterReporter(telemetry.Report)
+type Telemetry struct {
+	opts      TelemetryOptions
+	codecs    *compressionNegotiator
+	mx        sync.Mutex
+	sendMx    sync.Mutex
+	counts    map[string]*TelemetryCount
+	untracked int
+	since     time.Time
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+}
+
+// NewTelemetry creates a new Telemetry reporter and starts its schedule.
+func NewTelemetry(opts *TelemetryOptions) *Telemetry {
+	t := &Telemetry{
+		opts:   *opts,
+		counts: make(map[string]*TelemetryCount),
+		since:  time.Now(),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if t.opts.OptedIn == nil {
+		t.opts.OptedIn = func() bool { return false }
+	}
+	if t.opts.Interval <= 0 {
+		t.opts.Interval = defaultTelemetryInterval
+	}
+	if t.opts.MaxFingerprints <= 0 {
+		t.opts.MaxFingerprints = defaultTelemetryMaxFingerprints
+	}
+	if t.opts.Client == nil {
+		t.opts.Client = NewHTTPClient(t.opts.Transport)
+	}
+	t.codecs = newCompressionNegotiator(t.opts.Compression)
+	go t.run()
+	return t
+}
+
+// Report implements ErrorReporter.
+func (t *Telemetry) Report(err error, severity Severity, ctx map[string]interface{}) {
+	if !t.opts.OptedIn() {
+		return
+	}
+	fingerprint := Fingerprint(err, ctx)
+
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	count := t.counts[fingerprint]
+	if count == nil {
+		if len(t.counts) >= t.opts.MaxFingerprints {
+			t.untracked++
+			return
+		}
+		count = &TelemetryCount{Fingerprint: fingerprint}
+		t.counts[fingerprint] = count
+	}
+	count.Count++
+	if severityLevel(count.Severity) < int(severity) {
+		count.Severity = severity.String()
+	}
+}
+
+func (t *Telemetry) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			t.submit()
+			return
+		case <-ticker.C:
+			t.submit()
+		}
+	}
+}
+
+// submit submits the counts accumulated so far, if there are any and the user
+// is still opted in.
+func (t *Telemetry) submit() {
+	t.sendMx.Lock()
+	defer t.sendMx.Unlock()
+
+	now := time.Now()
+	t.mx.Lock()
+	counts, untracked, since := t.counts, t.untracked, t.since
+	t.counts = make(map[string]*TelemetryCount)
+	t.untracked, t.since = 0, now
+	t.mx.Unlock()
+
+	if (len(counts) == 0 && untracked == 0) || !t.opts.OptedIn() {
+		return
+	}
+	report := &TelemetryReport{Since: since, Until: now, Untracked: untracked}
+	for _, count := range counts {
+		report.Errors = append(report.Errors, *count)
+	}
+	sort.Slice(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Fingerprint < report.Errors[j].Fingerprint
+	})
+	body, err := json.Marshal(report)
+	if err != nil {
+		errorOnLogging(err)
+		return
+	}
+	resp, err := t.post(body)
+	if err == nil && t.rejectedToken(resp) && t.opts.InvalidateToken != nil {
+		// the token may have been revoked, try once more with a fresh one
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		t.opts.InvalidateToken()
+		resp, err = t.post(body)
+	}
+	if err != nil {
+		errorOnLogging(fmt.Errorf("unable to submit telemetry: %v", err))
+		return
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if t.rejectedToken(resp) {
+		if t.opts.InvalidateToken != nil {
+			t.opts.InvalidateToken()
+		}
+		errorOnLogging(fmt.Errorf("unable to submit telemetry: endpoint rejected token with status %v", resp.Status))
+	} else if resp.StatusCode >= 300 {
+		errorOnLogging(fmt.Errorf("unable to submit telemetry: unexpected status %v", resp.Status))
+	}
+}
+
+// post POSTs a report body to the endpoint.
+func (t *Telemetry) post(body []byte) (*http.Response, error) {
+	return t.codecs.do(t.opts.Client, body, func(body io.Reader) (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, t.opts.Endpoint, body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if t.opts.Token != nil {
+			token, err := t.opts.Token()
+			if err != nil {
+				return nil, fmt.Errorf("unable to get token: %v", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return req, nil
+	})
+}
+
+// rejectedToken reports whether resp rejected the configured token.
+func (t *Telemetry) rejectedToken(resp *http.Response) bool {
+	return t.opts.Token != nil && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden)
+}
+
+// Close submits any remaining counts and stops the schedule.
+func (t *Telemetry) Close() error {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+	})
+	<-t.done
+	return nil
+}
@@ -0,0 +1,96 @@
+package golog
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelemetry(t *testing.T) {
+	var reports []*TelemetryReport
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var report TelemetryReport
+		decoder := json.NewDecoder(req.Body)
+		require.NoError(t, decoder.Decode(&report))
+		reports = append(reports, &report)
+		raw, _ := json.Marshal(report)
+		bodies = append(bodies, string(raw))
+	}))
+	defer server.Close()
+
+	var optedIn int32
+	telemetry := NewTelemetry(&TelemetryOptions{
+		Endpoint: server.URL,
+		OptedIn:  func() bool { return atomic.LoadInt32(&optedIn) == 1 },
+	})
+	ctx := map[string]interface{}{"component": "myprefix", "error_type": "errors.Error"}
+	secret := errors.New("failed for alice@example.com")
+
+	telemetry.Report(secret, ERROR, ctx)
+	atomic.StoreInt32(&optedIn, 1)
+	telemetry.Report(secret, ERROR, ctx)
+	telemetry.Report(secret, FATAL, ctx)
+	telemetry.Report(errors.New("other"), ERROR, ctx)
+	require.NoError(t, telemetry.Close())
+
+	require.Len(t, reports, 1)
+	report := reports[0]
+	require.Len(t, report.Errors, 2)
+	counts := make(map[string]TelemetryCount)
+	for _, count := range report.Errors {
+		counts[count.Fingerprint] = count
+	}
+	assert.Equal(t, TelemetryCount{Fingerprint: Fingerprint(secret, ctx), Severity: "FATAL", Count: 2}, counts[Fingerprint(secret, ctx)])
+	assert.NotContains(t, bodies[0], "alice")
+	assert.NotContains(t, bodies[0], "myprefix")
+}
+
+func TestTelemetryOptedOut(t *testing.T) {
+	submitted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		submitted = true
+	}))
+	defer server.Close()
+
+	telemetry := NewTelemetry(&TelemetryOptions{Endpoint: server.URL})
+	telemetry.Report(errors.New("oh no"), ERROR, nil)
+	require.NoError(t, telemetry.Close())
+	assert.False(t, submitted, "shouldn't submit without opt in")
+}
+
+func TestTelemetryInvalidatesRejectedToken(t *testing.T) {
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		tokens = append(tokens, req.Header.Get("Authorization"))
+		if req.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	fetches := 0
+	token, invalidate := CachedTokenSourceWithInvalidate(func() (string, time.Time, error) {
+		fetches++
+		if fetches == 1 {
+			return "revoked", time.Now().Add(time.Hour), nil
+		}
+		return "fresh", time.Now().Add(time.Hour), nil
+	})
+	telemetry := NewTelemetry(&TelemetryOptions{
+		Endpoint:        server.URL,
+		OptedIn:         func() bool { return true },
+		Token:           token,
+		InvalidateToken: invalidate,
+	})
+	telemetry.Report(errors.New("failed"), ERROR, map[string]interface{}{})
+	require.NoError(t, telemetry.Close())
+	assert.Equal(t, []string{"Bearer revoked", "Bearer fresh"}, tokens, "a rejected token should be replaced and the submission retried")
+}
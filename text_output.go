@@ -4,9 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"path/filepath"
 	"runtime"
 	"sort"
+	"sync"
 
 	"github.com/getlantern/hidden"
 )
@@ -24,8 +24,9 @@ type textOutput struct {
 	// E is the error writer
 	E io.Writer
 	// D is the debug writer
-	D  io.Writer
-	pc []uintptr
+	D          io.Writer
+	pc         []uintptr
+	decorators *Decorators
 }
 
 func (o *textOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
@@ -40,28 +41,46 @@ func (o *textOutput) print(writer io.Writer, prefix string, skipFrames int, prin
 	buf := getBuffer()
 	defer returnBuffer(buf)
 
-	GetPrepender()(buf)
-	linePrefix := o.linePrefix(prefix, skipFrames)
+	global, logger := o.prepend(buf, prefix)
+	appendAll := func() {
+		o.append(buf, global, logger)
+	}
+	writer = redirectStdout(writer)
+	header := headerFragment(severity, prefix)
+	if style, styled := severityStyle(writer, severity); styled {
+		header = style.open + severity + style.close + " " + prefix
+	}
+	var locationBuf [64]byte
+	location := o.appendLocation(locationBuf[:0], skipFrames, arg)
 	writeHeader := func() {
-		buf.WriteString(severity)
-		buf.WriteString(" ")
-		buf.WriteString(linePrefix)
+		buf.WriteString(header)
+		buf.Write(location)
 	}
 	if arg != nil {
 		ml, isMultiline := arg.(MultiLine)
 		if !isMultiline {
 			writeHeader()
-			_, _ = fmt.Fprintf(buf, "%v", arg)
+			start := buf.Len()
+			if s, ok := arg.(string); ok {
+				buf.WriteString(s)
+			} else {
+				_, _ = fmt.Fprintf(buf, "%v", arg)
+			}
+			sanitizeFrom(buf, start)
 			printContext(buf, values)
+			appendAll()
 			buf.WriteByte('\n')
 		} else {
 			mlp := ml.MultiLinePrinter()
 			first := true
 			for {
 				writeHeader()
+				start := buf.Len()
 				more := mlp(buf)
+				sanitizeFrom(buf, start)
 				if first {
 					printContext(buf, values)
+					appendAll()
 					first = false
 				}
 				buf.WriteByte('\n')
@@ -76,20 +95,83 @@ func (o *textOutput) print(writer io.Writer, prefix string, skipFrames int, prin
 	if err != nil {
 		errorOnLogging(err)
 	}
-	if printStack {
+	if stack := stackOverride(arg); stack != "" {
+		if _, err := io.WriteString(writer, stack); err != nil {
+			errorOnLogging(err)
+		}
+	} else if printStack {
 		if err := writeStack(writer, o.pc); err != nil {
 			errorOnLogging(err)
 		}
 	}
 }
 
-// attaches the file and line number corresponding to
-// the log message
-func (o *textOutput) linePrefix(prefix string, skipFrames int) string {
-	runtime.Callers(skipFrames, o.pc)
-	funcForPc := runtime.FuncForPC(o.pc[0])
-	file, line := funcForPc.FileLine(o.pc[0] - 1)
-	return fmt.Sprintf("%s%s:%d ", prefix, filepath.Base(file), line)
+// prepend writes the prepender and the prependers of the decorators that apply
+// to messages from the logger with the given prefix to buf, returning the
+// global and logger decorators for append.
+func (o *textOutput) prepend(buf *bytes.Buffer, prefix string) (global *Decorators, logger *Decorators) {
+	global, logger = getDecorators(), decoratorsForLogger(prefix)
+	GetPrepender()(buf)
+	global.prepend(buf)
+	o.decorators.prepend(buf)
+	logger.prepend(buf)
+	return global, logger
+}
+
+// append writes the appenders of the decorators in the reverse order of
+// prepend.
+func (o *textOutput) append(buf *bytes.Buffer, global *Decorators, logger *Decorators) {
+	logger.append(buf)
+	o.decorators.append(buf)
+	global.append(buf)
+}
+
+// appends the file and line number corresponding to
+// the log message to dst
+func (o *textOutput) appendLocation(dst []byte, skipFrames int, arg interface{}) []byte {
+	n := runtime.Callers(skipFrames, o.pc)
+	if override := callerOverride(arg); override != "" {
+		dst = append(dst, override...)
+		return append(dst, ' ')
+	}
+	if n == 0 {
+		// skipped past the top of the stack, make do with what's in pc
+		n = 1
+	}
+	dst = appendCaller(dst, firstFrame(o.pc[:n]))
+	return append(dst, ' ')
+}
+
+const maxHeaderFragments = 4096
+
+type headerKey struct {
+	severity string
+	prefix   string
+}
+
+var (
+	headerFragments   = make(map[headerKey]string)
+	headerFragmentsMx sync.RWMutex
+)
+
+// headerFragment returns the "SEVERITY prefix: " fragment that starts each
+// line, reusing a previously built copy so that it isn't rebuilt for every
+// line.
+func headerFragment(severity string, prefix string) string {
+	key := headerKey{severity, prefix}
+	headerFragmentsMx.RLock()
+	header, found := headerFragments[key]
+	headerFragmentsMx.RUnlock()
+	if found {
+		return header
+	}
+	header = severity + " " + prefix
+	headerFragmentsMx.Lock()
+	if len(headerFragments) < maxHeaderFragments {
+		headerFragments[key] = header
+	}
+	headerFragmentsMx.Unlock()
+	return header
 }
 
 func printContext(buf *bytes.Buffer, values map[string]interface{}) {
@@ -107,9 +189,11 @@ func printContext(buf *bytes.Buffer, values map[string]interface{}) {
 		if i > 0 {
 			buf.WriteString(" ")
 		}
+		start := buf.Len()
 		buf.WriteString(key)
 		buf.WriteString("=")
 		_, _ = fmt.Fprintf(buf, "%v", value)
+		sanitizeFrom(buf, start)
 	}
 	buf.WriteByte(']')
 }
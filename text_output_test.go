@@ -0,0 +1,25 @@
+package golog
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderFragment(t *testing.T) {
+	assert.Equal(t, "DEBUG myprefix: ", headerFragment("DEBUG", "myprefix: "))
+	assert.Equal(t, "DEBUG myprefix: ", headerFragment("DEBUG", "myprefix: "), "cached fragment should match")
+	assert.Equal(t, "ERROR myprefix: ", headerFragment("ERROR", "myprefix: "))
+}
+
+func TestTextOutputAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector adds allocations")
+	}
+	out := TextOutput(ioutil.Discard, ioutil.Discard)
+	allocs := testing.AllocsPerRun(100, func() {
+		out.Debug("myprefix: ", 0, false, "DEBUG", "hello", nil)
+	})
+	assert.True(t, allocs <= 6, "expected at most 6 allocations per line, got %v", allocs)
+}
@@ -0,0 +1,73 @@
+package golog
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+)
+
+var tlsVersions = map[uint16]string{
+	tls.VersionSSL30: "SSL 3.0",
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+var tlsCipherSuites = map[uint16]string{
+	tls.TLS_RSA_WITH_AES_128_CBC_SHA:                  "TLS_RSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_256_CBC_SHA:                  "TLS_RSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_RSA_WITH_AES_128_GCM_SHA256:               "TLS_RSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_RSA_WITH_AES_256_GCM_SHA384:               "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA:          "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA:          "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA:            "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA:            "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:         "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256:       "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384:         "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384:       "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256:   "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256: "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+	tls.TLS_AES_128_GCM_SHA256:                        "TLS_AES_128_GCM_SHA256",
+	tls.TLS_AES_256_GCM_SHA384:                        "TLS_AES_256_GCM_SHA384",
+	tls.TLS_CHACHA20_POLY1305_SHA256:                  "TLS_CHACHA20_POLY1305_SHA256",
+}
+
+// TLSState returns fields describing a TLS connection: tls_version,
+// tls_cipher_suite, tls_alpn, tls_sni, tls_resumed and, if the peer presented
+// certificates, tls_peer_certs with the SHA-256 fingerprints of its
+// certificate chain, leaf first. Log them with Debugw or WithFields.
+func TLSState(cs tls.ConnectionState) []Field {
+	fields := []Field{
+		{"tls_version", tlsVersionName(cs.Version)},
+		{"tls_cipher_suite", tlsCipherSuiteName(cs.CipherSuite)},
+		{"tls_alpn", cs.NegotiatedProtocol},
+		{"tls_sni", cs.ServerName},
+		{"tls_resumed", cs.DidResume},
+	}
+	if len(cs.PeerCertificates) > 0 {
+		fingerprints := make([]string, 0, len(cs.PeerCertificates))
+		for _, cert := range cs.PeerCertificates {
+			sum := sha256.Sum256(cert.Raw)
+			fingerprints = append(fingerprints, hex.EncodeToString(sum[:]))
+		}
+		fields = append(fields, Field{"tls_peer_certs", fingerprints})
+	}
+	return fields
+}
+
+func tlsVersionName(version uint16) string {
+	if name, found := tlsVersions[version]; found {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+func tlsCipherSuiteName(suite uint16) string {
+	if name, found := tlsCipherSuites[suite]; found {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", suite)
+}
@@ -0,0 +1,36 @@
+package golog
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLSState(t *testing.T) {
+	cert := &x509.Certificate{Raw: []byte("not really a certificate")}
+	sum := sha256.Sum256(cert.Raw)
+	fields := TLSState(tls.ConnectionState{
+		Version:            tls.VersionTLS13,
+		CipherSuite:        tls.TLS_AES_128_GCM_SHA256,
+		NegotiatedProtocol: "h2",
+		ServerName:         "example.com",
+		PeerCertificates:   []*x509.Certificate{cert},
+	})
+	assert.Equal(t, []Field{
+		{"tls_version", "TLS 1.3"},
+		{"tls_cipher_suite", "TLS_AES_128_GCM_SHA256"},
+		{"tls_alpn", "h2"},
+		{"tls_sni", "example.com"},
+		{"tls_resumed", false},
+		{"tls_peer_certs", []string{hex.EncodeToString(sum[:])}},
+	}, fields)
+
+	fields = TLSState(tls.ConnectionState{Version: 0x0999, CipherSuite: 0xffff})
+	assert.Equal(t, "0x0999", fields[0].Value)
+	assert.Equal(t, "0xffff", fields[1].Value)
+	assert.Len(t, fields, 5)
+}
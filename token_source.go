@@ -0,0 +1,58 @@
+package golog
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenSource returns the bearer token with which an output authenticates. It
+// is called for every request, so that short-lived credentials can be
+// refreshed without recreating the output. See CachedTokenSource for caching
+// tokens until they expire.
+type TokenSource func() (string, error)
+
+// CachedTokenSource creates a TokenSource that caches the tokens returned by
+// fetch until shortly (a tenth of their lifetime, at most 1 minute) before
+// they expire. A zero expiry caches the token forever.
+func CachedTokenSource(fetch func() (token string, expiry time.Time, err error)) TokenSource {
+	source, _ := CachedTokenSourceWithInvalidate(fetch)
+	return source
+}
+
+// CachedTokenSourceWithInvalidate is like CachedTokenSource, but also returns
+// a function that drops the cached token so that the next call fetches a new
+// one. Pass it to outputs as their InvalidateToken option, so that a token
+// that's revoked before it expires is replaced as soon as it's rejected.
+func CachedTokenSourceWithInvalidate(fetch func() (token string, expiry time.Time, err error)) (source TokenSource, invalidate func()) {
+	var mx sync.Mutex
+	var token string
+	var refreshAt time.Time
+	cached := false
+	invalidate = func() {
+		mx.Lock()
+		cached = false
+		mx.Unlock()
+	}
+	source = func() (string, error) {
+		mx.Lock()
+		defer mx.Unlock()
+		now := time.Now()
+		if cached && (refreshAt.IsZero() || now.Before(refreshAt)) {
+			return token, nil
+		}
+		newToken, expiry, err := fetch()
+		if err != nil {
+			return "", err
+		}
+		token, cached, refreshAt = newToken, true, time.Time{}
+		if !expiry.IsZero() {
+			early := expiry.Sub(now) / 10
+			if early > time.Minute {
+				early = time.Minute
+			}
+			refreshAt = expiry.Add(-early)
+		}
+		return token, nil
+	}
+	return source, invalidate
+}
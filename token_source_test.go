@@ -0,0 +1,121 @@
+package golog
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedTokenSource(t *testing.T) {
+	fetches := 0
+	lifetime := time.Hour
+	fetch := func() (string, time.Time, error) {
+		fetches++
+		if fetches == 3 {
+			return "", time.Time{}, fmt.Errorf("unavailable")
+		}
+		return fmt.Sprintf("token%d", fetches), time.Now().Add(lifetime), nil
+	}
+	source := CachedTokenSource(fetch)
+
+	token, err := source()
+	require.NoError(t, err)
+	assert.Equal(t, "token1", token)
+	token, _ = source()
+	assert.Equal(t, "token1", token, "token should be cached")
+	assert.Equal(t, 1, fetches)
+
+	lifetime = 5 * time.Millisecond
+	source = CachedTokenSource(fetch)
+	token, _ = source()
+	assert.Equal(t, "token2", token)
+	time.Sleep(10 * time.Millisecond)
+	_, err = source()
+	assert.Error(t, err)
+	token, err = source()
+	require.NoError(t, err)
+	assert.Equal(t, "token4", token, "expired token should be refreshed")
+}
+
+func TestElasticsearchOutputToken(t *testing.T) {
+	var mx sync.Mutex
+	var authorizations []string
+	delivered := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mx.Lock()
+		defer mx.Unlock()
+		authorizations = append(authorizations, req.Header.Get("Authorization"))
+		if req.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		delivered++
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+
+	var calls int32
+	out := ElasticsearchOutput(&ElasticsearchOptions{
+		URL:           srv.URL,
+		FlushInterval: 10 * time.Millisecond,
+		Token: func() (string, error) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				return "stale", nil
+			}
+			return "fresh", nil
+		},
+	})
+	reset := SetOutput(out)
+	LoggerFor("myprefix").Debug("hello")
+	time.Sleep(elasticsearchMinBackoff + 100*time.Millisecond)
+	assert.NoError(t, out.Close())
+	reset()
+
+	mx.Lock()
+	defer mx.Unlock()
+	assert.Equal(t, []string{"Bearer stale", "Bearer fresh"}, authorizations)
+	assert.Equal(t, 1, delivered, "event should have been retried with the refreshed token")
+}
+
+func TestElasticsearchOutputInvalidatesToken(t *testing.T) {
+	var mx sync.Mutex
+	var authorizations []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mx.Lock()
+		defer mx.Unlock()
+		authorizations = append(authorizations, req.Header.Get("Authorization"))
+		if req.Header.Get("Authorization") != "Bearer token2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer srv.Close()
+
+	var fetches int32
+	token, invalidate := CachedTokenSourceWithInvalidate(func() (string, time.Time, error) {
+		return fmt.Sprintf("token%d", atomic.AddInt32(&fetches, 1)), time.Now().Add(time.Hour), nil
+	})
+	out := ElasticsearchOutput(&ElasticsearchOptions{
+		URL:             srv.URL,
+		FlushInterval:   10 * time.Millisecond,
+		Token:           token,
+		InvalidateToken: invalidate,
+	})
+	reset := SetOutput(out)
+	LoggerFor("myprefix").Debug("hello")
+	time.Sleep(elasticsearchMinBackoff + 100*time.Millisecond)
+	assert.NoError(t, out.Close())
+	reset()
+
+	mx.Lock()
+	defer mx.Unlock()
+	assert.Equal(t, []string{"Bearer token1", "Bearer token2"}, authorizations, "a rejected token should be replaced even though it hasn't expired")
+}
@@ -0,0 +1,123 @@
+package golog
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// TransportOptions configures how HTTP based outputs and reporters connect
+// when they aren't given their own http.Client.
+type TransportOptions struct {
+	// TLS configures TLS, for example with client certificates for mutual TLS
+	// or with custom RootCAs.
+	TLS *tls.Config
+
+	// Proxy selects the proxy for each request, see http.Transport. Defaults
+	// to EnvironmentProxy.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// Timeout bounds each request. Defaults to 1 minute.
+	Timeout time.Duration
+}
+
+// NewHTTPClient creates an http.Client using opts, which may be nil.
+func NewHTTPClient(opts *TransportOptions) *http.Client {
+	resolved := TransportOptions{}
+	if opts != nil {
+		resolved = *opts
+	}
+	if resolved.Proxy == nil {
+		resolved.Proxy = EnvironmentProxy
+	}
+	if resolved.Timeout <= 0 {
+		resolved.Timeout = time.Minute
+	}
+	transport := &http.Transport{
+		Proxy: resolved.Proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if resolved.TLS != nil {
+		transport.TLSClientConfig = resolved.TLS.Clone()
+	}
+	return &http.Client{Transport: transport, Timeout: resolved.Timeout}
+}
+
+// EnvironmentProxy is like http.ProxyFromEnvironment, honouring HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY, but also falls back to ALL_PROXY when neither of
+// the former are set. Any of them may be socks5:// URLs.
+func EnvironmentProxy(req *http.Request) (*url.URL, error) {
+	if getenvAny("HTTP_PROXY", "http_proxy") != "" || getenvAny("HTTPS_PROXY", "https_proxy") != "" {
+		return http.ProxyFromEnvironment(req)
+	}
+	allProxy := getenvAny("ALL_PROXY", "all_proxy")
+	if allProxy == "" || noProxy(req.URL, getenvAny("NO_PROXY", "no_proxy")) {
+		return nil, nil
+	}
+	proxy, err := url.Parse(allProxy)
+	if err != nil || (proxy.Scheme != "http" && proxy.Scheme != "https" && proxy.Scheme != "socks5") {
+		// like http.ProxyFromEnvironment, treat a bare host as an HTTP proxy
+		if proxy, err := url.Parse("http://" + allProxy); err == nil {
+			return proxy, nil
+		}
+	}
+	return proxy, err
+}
+
+func getenvAny(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// noProxy reports whether u is excluded from proxying by a NO_PROXY list of
+// hosts, domain suffixes (like .example.com) and "*".
+func noProxy(u *url.URL, list string) bool {
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			return true
+		}
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != u.Port() {
+				continue
+			}
+			entry = h
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip := net.ParseIP(host); ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		entry = strings.TrimPrefix(entry, "*")
+		if host == strings.TrimPrefix(entry, ".") || strings.HasSuffix(host, "."+strings.TrimPrefix(entry, ".")) {
+			return true
+		}
+	}
+	return false
+}
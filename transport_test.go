@@ -0,0 +1,98 @@
+package golog
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPClientMutualTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	clientCert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	client := NewHTTPClient(&TransportOptions{TLS: &tls.Config{RootCAs: roots}})
+	_, err = client.Get(srv.URL)
+	assert.Error(t, err, "server should require a client certificate")
+
+	client = NewHTTPClient(&TransportOptions{TLS: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}})
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestEnvironmentProxy(t *testing.T) {
+	for _, name := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy"} {
+		if os.Getenv(name) != "" {
+			t.Skip("HTTP(S)_PROXY is set")
+		}
+	}
+	defer os.Setenv("ALL_PROXY", os.Getenv("ALL_PROXY"))
+	defer os.Setenv("NO_PROXY", os.Getenv("NO_PROXY"))
+	os.Setenv("ALL_PROXY", "socks5://proxy.example.com:1080")
+	os.Setenv("NO_PROXY", ".internal.example.com,10.0.0.0/8")
+
+	proxyFor := func(rawURL string) string {
+		u, _ := url.Parse(rawURL)
+		proxy, err := EnvironmentProxy(&http.Request{URL: u})
+		require.NoError(t, err)
+		if proxy == nil {
+			return ""
+		}
+		return proxy.String()
+	}
+	assert.Equal(t, "socks5://proxy.example.com:1080", proxyFor("https://logs.example.com/bulk"))
+	assert.Empty(t, proxyFor("https://es.internal.example.com/bulk"))
+	assert.Empty(t, proxyFor("http://10.1.2.3:9200"))
+	assert.Empty(t, proxyFor("http://localhost:9200"))
+
+	os.Setenv("ALL_PROXY", "proxy.example.com:3128")
+	assert.Equal(t, "http://proxy.example.com:3128", proxyFor("https://logs.example.com/bulk"))
+}
+
+func TestNoProxy(t *testing.T) {
+	u, _ := url.Parse("https://api.example.com:8443/x")
+	assert.True(t, noProxy(u, "*"))
+	assert.True(t, noProxy(u, "example.com"))
+	assert.True(t, noProxy(u, "*.example.com"))
+	assert.True(t, noProxy(u, "api.example.com:8443"))
+	assert.False(t, noProxy(u, "api.example.com:443"))
+	assert.False(t, noProxy(u, "other.com, myexample.com"))
+}
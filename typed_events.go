@@ -0,0 +1,162 @@
+package golog
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// typedEventSchemas caches the eventSchema of each struct type.
+var typedEventSchemas sync.Map
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// EventFields returns the exported fields of the struct event (or pointer to
+// one) as Fields, so that an event type like
+//
+//	type DialResult struct {
+//		Addr     string
+//		Duration time.Duration
+//		Err      error `log:"error,omitempty"`
+//	}
+//
+// is logged with the same field names everywhere it's used:
+//
+//	l.Debugw("dialed", golog.EventFields(&DialResult{addr, elapsed, err})...)
+//
+// Field names are taken from the log struct tag, or else are the snake_case of
+// the Go field name. Durations are logged as Milliseconds, and untagged
+// duration fields get an "_ms" suffix. Errors are logged as their message. The
+// tag options "omitempty", which omits zero values, and "-", which skips the
+// field, work as in encoding/json.
+func EventFields(event interface{}) []Field {
+	v := reflect.ValueOf(event)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	schema := schemaFor(v.Type())
+	fields := make([]Field, 0, len(schema))
+	for _, f := range schema {
+		value := v.Field(f.index)
+		if f.omitEmpty && isZeroValue(value) {
+			continue
+		}
+		switch {
+		case f.duration:
+			fields = append(fields, Field{f.name, Milliseconds(value.Int())})
+		case f.err:
+			if value.IsNil() {
+				if !f.omitEmpty {
+					fields = append(fields, Field{f.name, nil})
+				}
+				continue
+			}
+			fields = append(fields, Field{f.name, value.Interface().(error).Error()})
+		default:
+			fields = append(fields, Field{f.name, value.Interface()})
+		}
+	}
+	return fields
+}
+
+// EventFieldNames returns the names of the fields that EventFields logs for
+// events of the same type as event, for documentation and for checking
+// naming conventions in tests.
+func EventFieldNames(event interface{}) []string {
+	t := reflect.TypeOf(event)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	schema := schemaFor(t)
+	names := make([]string, 0, len(schema))
+	for _, f := range schema {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+type eventSchemaField struct {
+	index     int
+	name      string
+	omitEmpty bool
+	duration  bool
+	err       bool
+}
+
+func schemaFor(t reflect.Type) []eventSchemaField {
+	if schema, found := typedEventSchemas.Load(t); found {
+		return schema.([]eventSchemaField)
+	}
+	var schema []eventSchemaField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		tag := sf.Tag.Get("log")
+		if tag == "-" {
+			continue
+		}
+		f := eventSchemaField{
+			index:    i,
+			duration: sf.Type == durationType,
+			err:      sf.Type == errorType,
+		}
+		parts := strings.Split(tag, ",")
+		f.name = parts[0]
+		for _, option := range parts[1:] {
+			if option == "omitempty" {
+				f.omitEmpty = true
+			}
+		}
+		if f.name == "" {
+			f.name = snakeCase(sf.Name)
+			if f.duration && !strings.HasSuffix(f.name, "_ms") {
+				f.name += "_ms"
+			}
+		}
+		schema = append(schema, f)
+	}
+	typedEventSchemas.Store(t, schema)
+	return schema
+}
+
+// snakeCase converts a Go identifier like DialAddr or HTTPStatus to
+// dial_addr or http_status.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			startsWord := i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])))
+			if startsWord {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isZeroValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
@@ -0,0 +1,49 @@
+package golog
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type dialResult struct {
+	Addr       string
+	Dur        time.Duration
+	Err        error  `log:"error,omitempty"`
+	HTTPStatus int    `log:",omitempty"`
+	Internal   string `log:"-"`
+	unexported string
+}
+
+func TestEventFields(t *testing.T) {
+	assert.Equal(t, []string{"addr", "dur_ms", "error", "http_status"}, EventFieldNames(&dialResult{}))
+
+	assert.Equal(t, []Field{
+		{"addr", "1.2.3.4:443"},
+		{"dur_ms", Milliseconds(1500 * time.Microsecond)},
+		{"error", "refused"},
+		{"http_status", 502},
+	}, EventFields(&dialResult{Addr: "1.2.3.4:443", Dur: 1500 * time.Microsecond, Err: errors.New("refused"), HTTPStatus: 502, Internal: "x"}))
+
+	assert.Equal(t, []Field{
+		{"addr", "1.2.3.4:443"},
+		{"dur_ms", Milliseconds(0)},
+	}, EventFields(dialResult{Addr: "1.2.3.4:443"}))
+
+	assert.Nil(t, EventFields((*dialResult)(nil)))
+	assert.Nil(t, EventFields("not a struct"))
+	assert.Equal(t, "dial_addr", snakeCase("DialAddr"))
+	assert.Equal(t, "url", snakeCase("URL"))
+}
+
+func TestEventFieldsLogging(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reset := SetOutputs(buf, buf)
+	defer reset()
+
+	LoggerFor("myprefix").Debugw("dialed", EventFields(&dialResult{Addr: "1.2.3.4:443", Dur: 2 * time.Millisecond})...)
+	assert.Regexp(t, `^DEBUG myprefix: typed_events_test.go:[0-9]+ dialed \[addr=1.2.3.4:443 dur_ms=2.000\]\n$`, buf.String())
+}
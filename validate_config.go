@@ -0,0 +1,234 @@
+package golog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config describes a logging setup to be checked by ValidateConfig before
+// it's installed.
+type Config struct {
+	// Level is the lowest severity that will be logged, see SetLevel.
+	Level string
+
+	// Format is the name of the format of the outputs, see NewOutput.
+	Format string
+
+	// Sampling are the sampling rules that will be applied.
+	Sampling []SamplingRule
+
+	// Files are the paths of files that will be logged to.
+	Files []string
+
+	// URLs are the endpoints of network outputs, like
+	// "https://logs.example.com/bulk" or "tcp://localhost:5140". http and
+	// https URLs are probed with a HEAD request through the same kind of
+	// client that the HTTP based outputs use (see Transport), and any
+	// response counts as reachable. Other URLs are probed by connecting, with
+	// a TLS handshake for wss and tls URLs.
+	URLs []string
+
+	// TLS is the configuration used for the TLS handshake when probing URLs,
+	// instead of the one in Transport. Its client certificates are also
+	// checked for expiry.
+	TLS *tls.Config
+
+	// Transport configures proxying, and TLS unless TLS is set, for probing
+	// http and https URLs, like it does for the outputs, see NewHTTPClient.
+	// Proxies default to EnvironmentProxy.
+	Transport *TransportOptions
+
+	// Timeout bounds each probe of a URL. Defaults to 5 seconds.
+	Timeout time.Duration
+}
+
+// ConfigErrors are all the problems found by ValidateConfig.
+type ConfigErrors []error
+
+func (e ConfigErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return "invalid logging configuration: " + strings.Join(msgs, "; ")
+}
+
+// ValidateConfig checks cfg without installing anything, so that services can
+// fail fast at startup rather than silently dropping logs later. Files are
+// checked for being writable without being modified, URLs are probed as
+// described for Config.URLs without logging anything, and the
+// level, format and sampling rules are checked for being known and in range.
+// All problems are returned together as ConfigErrors.
+func ValidateConfig(cfg *Config) error {
+	var errs ConfigErrors
+	if cfg.Level != "" {
+		if _, err := parseLevel(cfg.Level); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg.Format != "" {
+		if _, err := NewOutput(cfg.Format, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, rule := range cfg.Sampling {
+		if rule.Keep < 0 || rule.Keep > 1 {
+			errs = append(errs, fmt.Errorf("sampling rule for %q keeps %v, expected between 0 and 1", rule.Component, rule.Keep))
+		}
+	}
+	for _, path := range cfg.Files {
+		if err := checkWritable(path); err != nil {
+			errs = append(errs, fmt.Errorf("file %v is not writable: %v", path, err))
+		}
+	}
+	if cfg.TLS != nil {
+		now := time.Now()
+		for i, cert := range cfg.TLS.Certificates {
+			if err := checkCertificate(cert, now); err != nil {
+				errs = append(errs, fmt.Errorf("client certificate %d: %v", i, err))
+			}
+		}
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	for _, u := range cfg.URLs {
+		if err := probeURL(u, cfg.TLS, cfg.Transport, timeout); err != nil {
+			errs = append(errs, fmt.Errorf("url %v is unreachable: %v", u, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func checkWritable(path string) error {
+	info, err := os.Stat(path)
+	if err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("is a directory")
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+	if !os.IsNotExist(err) {
+		return err
+	}
+	// the file will be created, so make sure that's possible
+	f, err := ioutil.TempFile(filepath.Dir(path), ".golog-validate-")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+func checkCertificate(cert tls.Certificate, now time.Time) error {
+	if len(cert.Certificate) == 0 {
+		return fmt.Errorf("empty")
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		leaf, err = x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return err
+		}
+	}
+	if now.Before(leaf.NotBefore) {
+		return fmt.Errorf("not valid until %v", leaf.NotBefore)
+	}
+	if now.After(leaf.NotAfter) {
+		return fmt.Errorf("expired at %v", leaf.NotAfter)
+	}
+	return nil
+}
+
+func probeURL(rawURL string, tlsConfig *tls.Config, transport *TransportOptions, timeout time.Duration) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Host == "" {
+		return fmt.Errorf("no host")
+	}
+	useTLS := false
+	port := u.Port()
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return probeHTTP(u, tlsConfig, transport, timeout)
+	case "wss", "tls":
+		useTLS = true
+		if port == "" {
+			port = "443"
+		}
+	case "ws":
+		if port == "" {
+			port = "80"
+		}
+	}
+	if port == "" {
+		return fmt.Errorf("no port")
+	}
+	addr := net.JoinHostPort(u.Hostname(), port)
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if !useTLS {
+		conn, err := dialer.Dial("tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	} else {
+		tlsConfig = tlsConfig.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = u.Hostname()
+	}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeHTTP sends a HEAD request to u with a client like the one that the
+// HTTP based outputs create from transport, so that proxies are honoured.
+func probeHTTP(u *url.URL, tlsConfig *tls.Config, transport *TransportOptions, timeout time.Duration) error {
+	opts := TransportOptions{}
+	if transport != nil {
+		opts = *transport
+	}
+	if tlsConfig != nil {
+		opts.TLS = tlsConfig
+	}
+	opts.Timeout = timeout
+	client := NewHTTPClient(&opts)
+	defer client.CloseIdleConnections()
+	req, err := http.NewRequest(http.MethodHead, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
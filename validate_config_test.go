@@ -0,0 +1,102 @@
+package golog
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golog-validate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+	tlsConfig := srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+	cfg := &Config{
+		Level:    "info",
+		Format:   "json",
+		Sampling: []SamplingRule{{Component: "*", Keep: 0.5}},
+		Files:    []string{filepath.Join(dir, "new.log")},
+		URLs:     []string{srv.URL},
+		TLS:      tlsConfig,
+	}
+	require.NoError(t, ValidateConfig(cfg))
+	files, _ := ioutil.ReadDir(dir)
+	assert.Empty(t, files, "validation shouldn't leave anything behind")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := l.Addr().String()
+	l.Close()
+
+	err = ValidateConfig(&Config{
+		Level:    "loud",
+		Format:   "xml",
+		Sampling: []SamplingRule{{Component: "*", Keep: 2}},
+		Files:    []string{filepath.Join(dir, "missing", "new.log"), dir},
+		URLs:     []string{"tcp://" + closedAddr, srv.URL},
+		Timeout:  time.Second,
+	})
+	require.Error(t, err)
+	errs, ok := err.(ConfigErrors)
+	require.True(t, ok)
+	assert.Len(t, errs, 7)
+	assert.Contains(t, err.Error(), `unknown level "loud"`)
+	assert.Contains(t, err.Error(), `unknown log format "xml"`)
+	assert.Contains(t, err.Error(), "keeps 2")
+	assert.Contains(t, err.Error(), "is a directory")
+	assert.Contains(t, err.Error(), "url tcp://"+closedAddr+" is unreachable")
+	assert.Contains(t, err.Error(), "url "+srv.URL+" is unreachable", "server certificate shouldn't be trusted without the TLS config")
+}
+
+func TestValidateConfigExpiredClientCert(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-48 * time.Hour),
+		NotAfter:     time.Now().Add(-24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	err = ValidateConfig(&Config{TLS: &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "client certificate 0: expired at")
+}
+
+func TestValidateConfigProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		proxied = append(proxied, req.Method+" "+req.URL.String())
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	cfg := &Config{
+		URLs:      []string{"http://logs.invalid/bulk"},
+		Transport: &TransportOptions{Proxy: http.ProxyURL(proxyURL)},
+		Timeout:   time.Second,
+	}
+	require.NoError(t, ValidateConfig(cfg), "URLs should be probed through the configured proxy")
+	assert.Equal(t, []string{"HEAD http://logs.invalid/bulk"}, proxied)
+}
@@ -14,23 +14,39 @@ type zapOutput struct {
 }
 
 func (o *zapOutput) Error(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
-	fields, configuredLogger := prepareLogger(prefix, values, o, skipFrames)
+	fields, configuredLogger := prepareLogger(prefix, arg, values, o, skipFrames)
 	configuredLogger.Error(argToString(arg), fields...)
 }
 
-func prepareLogger(prefix string, values map[string]interface{}, o *zapOutput, skipFrames int) ([]zap.Field, *zap.Logger) {
+func prepareLogger(prefix string, arg interface{}, values map[string]interface{}, o *zapOutput, skipFrames int) ([]zap.Field, *zap.Logger) {
 	// prefix contains ': ' at the end, strip it
 	cleanPrefix := prefix[0 : len(prefix)-2]
 	fields := []zap.Field{}
 	for k, v := range values {
 		fields = append(fields, zap.Any(k, v))
 	}
+	if override := callerOverride(arg); override != "" {
+		// zap can't be told about a caller that's not on the stack, so record
+		// it as a field instead
+		fields = append(fields, zap.String("origin_caller", override))
+	}
 
+	if getCallerFormat() != CallerBase {
+		// zap only renders callers its own way, so record ours as a field
+		fields = append(fields, zap.String("caller", caller(make([]uintptr, 10), skipFrames)))
+		return fields, o.Logger.Named(cleanPrefix).WithOptions(zap.WithCaller(false), zap.AddStacktrace(zap.ErrorLevel))
+	}
 	return fields, o.Logger.Named(cleanPrefix).WithOptions(zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel), zap.AddCallerSkip(skipFrames-3))
 }
 
 func (o *zapOutput) Debug(prefix string, skipFrames int, printStack bool, severity string, arg interface{}, values map[string]interface{}) {
-	fields, configuredLogger := prepareLogger(prefix, values, o, skipFrames)
-	configuredLogger.Debug(argToString(arg), fields...)
-
+	fields, configuredLogger := prepareLogger(prefix, arg, values, o, skipFrames)
+	switch severity {
+	case "INFO":
+		configuredLogger.Info(argToString(arg), fields...)
+	case "WARN":
+		configuredLogger.Warn(argToString(arg), fields...)
+	default:
+		configuredLogger.Debug(argToString(arg), fields...)
+	}
 }